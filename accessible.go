@@ -0,0 +1,212 @@
+package thermalize
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// BlockKind is the kind of an element of the accessible representation of a document.
+type BlockKind byte
+
+const (
+	BlockHeading   BlockKind = iota // BlockHeading is a line printed in bold, enlarged or centered on its own
+	BlockParagraph                  // BlockParagraph is a line or a word-wrapped text
+	BlockList                       // BlockList holds the items of a List
+	BlockTable                      // BlockTable holds the consecutive lines of several columns, such as the items
+	BlockTotal                      // BlockTotal is a line of a label and an amount printed in bold or labeled as a total
+	BlockCode                       // BlockCode holds the data of a barcode or a QR code
+	BlockImage                      // BlockImage is an image, which the document can't describe
+)
+
+// Block is an element of the accessible representation of a document, see Document.Accessible.
+type Block struct {
+	Kind BlockKind
+
+	// Text is the text of the headings, the paragraphs and the codes.
+	Text string
+
+	// Items are the items of the lists, Rows are the rows of the tables and the totals split into cells.
+	Items []string
+	Rows  [][]string
+}
+
+var (
+	// cellSeparator separates the columns padded with spaces within a line of text.
+	cellSeparator = regexp.MustCompile(`\s{2,}`)
+
+	// totalLabel matches the labels of the totals, labeledAmount splits a line of a label and an amount.
+	totalLabel    = regexp.MustCompile(`(?i)\b(sub)?total\b`)
+	labeledAmount = regexp.MustCompile(`^(.*\D)\s+(\S*\d\S*)$`)
+)
+
+// Accessible returns a structured representation of the receipt for the screen readers, e.g. to offer a digital receipt
+// next to the printed one from the same rendering code. The lines of the document are classified by their layout:
+// the lines of several columns, laid out with Tab, RightAt, TriLine or padded with spaces, form tables,
+// the bold or enlarged lines of a label and an amount and the lines labeled "total" are totals,
+// the other bold, enlarged or centered lines are headings. The rules of dashes or box drawing characters are dropped.
+func (d *Document) Accessible() []Block {
+	a := accessor{}
+	a.walk(d.ops)
+	a.endLine()
+	return a.blocks
+}
+
+// AccessibleHTML formats the blocks as an HTML fragment of headings, paragraphs, lists and tables.
+func AccessibleHTML(blocks []Block) string {
+	var b strings.Builder
+	for _, bl := range blocks {
+		switch bl.Kind {
+		case BlockHeading:
+			b.WriteString("<h2>" + html.EscapeString(bl.Text) + "</h2>\n")
+		case BlockParagraph:
+			b.WriteString("<p>" + html.EscapeString(bl.Text) + "</p>\n")
+		case BlockList:
+			b.WriteString("<ul>\n")
+			for _, item := range bl.Items {
+				b.WriteString("<li>" + html.EscapeString(item) + "</li>\n")
+			}
+			b.WriteString("</ul>\n")
+		case BlockTable, BlockTotal:
+			class := ""
+			if bl.Kind == BlockTotal {
+				class = ` class="total"`
+			}
+			b.WriteString("<table" + class + ">\n")
+			for _, row := range bl.Rows {
+				b.WriteString("<tr>")
+				for i, cell := range row {
+					if i == 0 {
+						b.WriteString(`<th scope="row">` + html.EscapeString(cell) + "</th>")
+					} else {
+						b.WriteString("<td>" + html.EscapeString(cell) + "</td>")
+					}
+				}
+				b.WriteString("</tr>\n")
+			}
+			b.WriteString("</table>\n")
+		case BlockCode:
+			b.WriteString("<p>" + html.EscapeString(bl.Text) + "</p>\n")
+		case BlockImage:
+			b.WriteString(`<p role="img" aria-label="image"></p>` + "\n")
+		}
+	}
+	return b.String()
+}
+
+// accessor classifies the lines of the document, cells holds the columns of the current line.
+type accessor struct {
+	blocks []Block
+
+	cells    []string
+	cell     strings.Builder
+	bold     bool
+	enlarged bool
+	centered bool
+}
+
+func (a *accessor) walk(ops []Op) {
+	for _, op := range ops {
+		switch op.Name {
+		case "Init":
+			a.endLine()
+			a.bold, a.enlarged, a.centered = false, false, false
+		case "Bold":
+			a.bold = op.Args[0].(bool)
+		case "CharSize":
+			a.enlarged = op.Args[0].(byte) > 0 || op.Args[1].(byte) > 0
+		case "Align":
+			a.centered = op.Args[0].(byte) == Center
+		case "Text":
+			lines := strings.Split(op.Args[0].(string), "\n")
+			for i, l := range lines {
+				if i > 0 {
+					a.endLine()
+				}
+				a.cell.WriteString(l)
+			}
+		case "Tab", "AbsolutePosition":
+			a.endCell()
+		case "RightAt":
+			a.endCell()
+			a.cell.WriteString(op.Args[1].(string))
+		case "TriLine":
+			a.endLine()
+			for _, s := range op.Args[:3] {
+				a.cell.WriteString(s.(string))
+				a.endCell()
+			}
+			a.endLine()
+		case "TextWrap", "Paragraph":
+			a.endLine()
+			a.cell.WriteString(strings.Join(strings.Fields(op.Args[0].(string)), " "))
+			a.endLine()
+		case "List":
+			a.endLine()
+			a.blocks = append(a.blocks, Block{Kind: BlockList, Items: op.Args[0].([]string)})
+		case "Barcode", "QRCode":
+			a.endLine()
+			a.blocks = append(a.blocks, Block{Kind: BlockCode, Text: op.Args[len(op.Args)-1].(string)})
+		case "Image", "InlineImage":
+			a.endLine()
+			a.blocks = append(a.blocks, Block{Kind: BlockImage})
+		case "LineFeed", "Feed", "Print":
+			a.endLine()
+		case "KeepTogether":
+			a.walk(op.Args[0].([]Op))
+		case "Lanes":
+			for _, ops := range op.Args[0].([][]Op) {
+				a.endLine()
+				a.walk(ops)
+			}
+			a.endLine()
+		}
+	}
+}
+
+func (a *accessor) endCell() {
+	if s := strings.Trim(a.cell.String(), " │|"); s != "" {
+		a.cells = append(a.cells, cellSeparator.Split(s, -1)...)
+	}
+	a.cell.Reset()
+}
+
+// endLine adds the block of the current line.
+func (a *accessor) endLine() {
+	a.endCell()
+	cells := a.cells
+	a.cells = nil
+	if len(cells) == 0 || isRule(cells) {
+		return
+	}
+
+	if m := labeledAmount.FindStringSubmatch(cells[0]); len(cells) == 1 && m != nil && totalLabel.MatchString(m[1]) {
+		cells = m[1:]
+	}
+
+	emphasized := a.bold || a.enlarged
+	switch {
+	case len(cells) == 2 && (emphasized || totalLabel.MatchString(cells[0])):
+		a.blocks = append(a.blocks, Block{Kind: BlockTotal, Rows: [][]string{cells}})
+	case len(cells) > 1:
+		if n := len(a.blocks); n > 0 && a.blocks[n-1].Kind == BlockTable {
+			a.blocks[n-1].Rows = append(a.blocks[n-1].Rows, cells)
+			return
+		}
+		a.blocks = append(a.blocks, Block{Kind: BlockTable, Rows: [][]string{cells}})
+	case emphasized || a.centered:
+		a.blocks = append(a.blocks, Block{Kind: BlockHeading, Text: cells[0]})
+	default:
+		a.blocks = append(a.blocks, Block{Kind: BlockParagraph, Text: cells[0]})
+	}
+}
+
+// isRule reports whether the line is a rule of dashes, asterisks or box drawing characters.
+func isRule(cells []string) bool {
+	for _, c := range cells {
+		if strings.Trim(c, "-=_*+~#.:─│┌┐└┘├┤ ") != "" {
+			return false
+		}
+	}
+	return true
+}
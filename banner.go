@@ -0,0 +1,22 @@
+package thermalize
+
+// CopyBanner prints a centered banner with the label, such as "COPY" or "DUPLICATE",
+// in white/black reverse mode if the command set supports it (see Reverser), or framed with asterisks otherwise.
+func CopyBanner(cmd Cmd, label string) {
+	cmd.Align(Center)
+	cmd.Bold(true)
+	cmd.CharSize(1, 1)
+
+	if r, ok := cmd.(Reverser); ok {
+		r.Reverse(true)
+		cmd.Text(" "+label+" ", nil)
+		r.Reverse(false)
+	} else {
+		cmd.Text("** "+label+" **", nil)
+	}
+	cmd.LineFeed()
+
+	cmd.CharSize(0, 0)
+	cmd.Bold(false)
+	cmd.Align(Left)
+}
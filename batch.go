@@ -0,0 +1,107 @@
+package thermalize
+
+import "math"
+
+// BatchOption customizes the separation of the documents printed by Batch.
+type BatchOption interface {
+	apply(*batch)
+}
+
+type batch struct {
+	feed    float64
+	cut     bool
+	partial bool
+}
+
+type batchOptionFunc func(*batch)
+
+func (fn batchOptionFunc) apply(b *batch) {
+	fn(b)
+}
+
+// WithBatchFeed feeds the paper mm millimeters past the cutting position after each document, by default 0.
+func WithBatchFeed(mm float64) BatchOption {
+	return batchOptionFunc(func(b *batch) { b.feed = mm })
+}
+
+// WithBatchFullCuts cuts the documents apart across the full width, instead of the partial cuts
+// that keep the stack of tickets together.
+func WithBatchFullCuts() BatchOption {
+	return batchOptionFunc(func(b *batch) { b.partial = false })
+}
+
+// WithoutBatchCuts doesn't cut the documents apart, they are separated by the feed only
+// and the batch is cut once after the last one.
+func WithoutBatchCuts() BatchOption {
+	return batchOptionFunc(func(b *batch) { b.cut = false })
+}
+
+// endingOps are the feeds, cuts and prints a document ends with, which Batch replaces with its separators.
+var endingOps = map[string]bool{
+	"Feed": true, "Cut": true, "FullCut": true, "CutWithFeed": true, "FullCutAtPosition": true,
+	"PartialCutAtPosition": true, "FeedAndFullCut": true, "Print": true, "Flush": true,
+}
+
+// Batch prints the documents back to back, e.g. the kitchen tickets of an order, and returns the error of the command set.
+// The feeds and cuts each document ends with are replaced with a single separator: the paper is fed to the cutting position
+// (see Cutter.CutWithFeed) and cut partially between the documents, and cut across the full width after the last one,
+// which wastes less paper than printing the documents one by one. The data is printed once, by the final Print.
+//
+// Example Usage:
+//
+//	err := thermalize.Batch(cmd, tickets, thermalize.WithBatchFeed(2))
+func Batch(cmd Cmd, docs []*Document, opts ...BatchOption) error {
+	b := batch{cut: true, partial: true}
+	for _, opt := range opts {
+		opt.apply(&b)
+	}
+
+	for i, d := range docs {
+		end := len(d.ops)
+		for end > 0 && endingOps[d.ops[end-1].Name] {
+			end--
+		}
+		for _, op := range d.ops[:end] {
+			op.play(cmd)
+		}
+
+		switch {
+		case i == len(docs)-1:
+			cutWithFeed(cmd, false, b.feed)
+		case b.cut:
+			cutWithFeed(cmd, b.partial, b.feed)
+		case b.feed > 0:
+			feedMM(cmd, b.feed)
+		}
+	}
+	return cmd.Print()
+}
+
+// millimeterFeeder is implemented by the command sets converting the feeds in millimeters
+// to their vertical motion unit.
+type millimeterFeeder interface {
+	feedMM(mm float64)
+}
+
+// feedMM feeds the paper mm millimeters, in the vertical motion unit of 203 dpi printers, 1/8 mm,
+// unless the command set converts it.
+func feedMM(cmd Cmd, mm float64) {
+	if f, ok := cmd.(millimeterFeeder); ok {
+		f.feedMM(mm)
+		return
+	}
+	cmd.Feed(byte(math.Min(math.Round(mm*8), 255)))
+}
+
+// cutWithFeed feeds the paper to the cutting position and mm millimeters further, then cuts it,
+// partially if partial is set. The command sets not implementing Cutter feed the paper and cut it with FullCut.
+func cutWithFeed(cmd Cmd, partial bool, mm float64) {
+	if c, ok := cmd.(Cutter); ok {
+		c.CutWithFeed(partial, mm)
+		return
+	}
+	if mm > 0 {
+		feedMM(cmd, mm)
+	}
+	cmd.FullCut()
+}
@@ -0,0 +1,71 @@
+package thermalize
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Visual converts a right-to-left string from the logical order, in which it is stored,
+// to the visual order, in which it must be sent to a printer that prints from left to right.
+//
+// The string is reversed, except for the runs of left-to-right text and numbers, such as Latin words,
+// prices or dates, which keep their order. Mirrored characters, such as brackets, are swapped.
+// The joining forms of Arabic letters are not changed.
+func Visual(s string) string {
+	rs := []rune(s)
+
+	var units [][]rune
+	for i := 0; i < len(rs); {
+		if !isLeftToRight(rs[i]) {
+			units = append(units, []rune{mirror(rs[i])})
+			i++
+			continue
+		}
+
+		// A left-to-right run includes the neutral characters between its strong characters, e.g. "12:30".
+		end := i + 1
+		for j := end; j < len(rs) && !isRightToLeft(rs[j]); j++ {
+			if isLeftToRight(rs[j]) {
+				end = j + 1
+			}
+		}
+		units = append(units, rs[i:end])
+		i = end
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := len(units) - 1; i >= 0; i-- {
+		for _, r := range units[i] {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// isLeftToRight reports whether the rune is a left-to-right letter or a digit, including Arabic-Indic digits.
+func isLeftToRight(r rune) bool {
+	if unicode.IsDigit(r) {
+		return true
+	}
+	return unicode.IsLetter(r) && !isRightToLeft(r)
+}
+
+func isRightToLeft(r rune) bool {
+	switch {
+	case unicode.IsDigit(r):
+		return false
+	case r >= 0x0590 && r <= 0x08FF, r >= 0xFB1D && r <= 0xFDFF, r >= 0xFE70 && r <= 0xFEFF:
+		return true
+	}
+	return false
+}
+
+var mirrored = map[rune]rune{'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<', '«': '»', '»': '«'}
+
+func mirror(r rune) rune {
+	if m, ok := mirrored[r]; ok {
+		return m
+	}
+	return r
+}
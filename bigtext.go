@@ -0,0 +1,356 @@
+package thermalize
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+)
+
+// BigText prints the text rasterized to the height in dots, for text larger than CharSize allows,
+// such as ticket numbers or totals. Lines are separated with "\n".
+// The height is reduced if the longest line does not fit the print width.
+//
+// The text is drawn in solid black by default, WithOutline and WithShadow make headers stand out
+// on low-contrast paper without a reverse block.
+//
+// Example Usage:
+//
+//	thermalize.BigText(cmd, "TOTAL 12.50", 160)
+//	thermalize.BigText(cmd, "SALE", 96, thermalize.WithOutline(2), thermalize.WithShadow(4))
+func BigText(cmd Cmd, s string, height int, opts ...TextOption) {
+	cmd.Image(StyledTextImage(s, fitHeight(cmd, s, height, opts), opts...), false)
+}
+
+// NegativeText prints the text rasterized to the height in dots (see BigText) in white, knocked out of a black band
+// as wide as the line, for bold section headers on the printers that print only images.
+// The band extends by a quarter of the height above and below the text.
+//
+// Example Usage:
+//
+//	thermalize.NegativeText(cmd, "DRINKS", 48)
+func NegativeText(cmd Cmd, s string, height int, opts ...TextOption) {
+	height = fitHeight(cmd, s, height, opts)
+	text := StyledTextImage(s, height, opts...)
+
+	size, pad := text.Bounds().Size(), height/4
+	band := image.NewGray(image.Rect(0, 0, maxByte(cmd.PPL(), size.X), size.Y+2*pad))
+	for i := range band.Pix {
+		band.Pix[i] = 0xFF
+	}
+	draw.Draw(band, text.Bounds().Add(image.Pt((band.Bounds().Dx()-size.X)/2, pad)), text, image.Point{}, draw.Src)
+	cmd.Image(band, true)
+}
+
+// fitHeight reduces the height of the rasterized text, so the longest line fits the print width.
+func fitHeight(cmd Cmd, s string, height int, opts []TextOption) int {
+	var st textStyle
+	for _, opt := range opts {
+		opt.apply(&st)
+	}
+
+	ppl := cmd.PPL() - st.width()
+	if n := maxLineLen(s); n > 0 && ppl > 0 && n*glyphCols*height > ppl*glyphRows {
+		height = ppl * glyphRows / (n * glyphCols)
+	}
+	return height
+}
+
+// TextRotated prints the text rasterized to the height of the standard font and rotated clockwise by deg degrees,
+// for vertical labels along the edge of the receipt. Lines are separated with "\n".
+// The angle must be a multiple of 90, other angles are rounded to the nearest one.
+//
+// Unlike ClockwiseRotation, which rotates each character in place, the whole text is rotated,
+// so it runs along the paper at 90 and 270 degrees.
+func TextRotated(cmd Cmd, s string, deg int, opts ...TextOption) {
+	if deg%90 != 0 {
+		if c := skipperOf(cmd); c != nil && c.invalid("TextRotated", "%d is not a multiple of 90", deg) {
+			return
+		}
+	}
+	cmd.Image(RotateImage(StyledTextImage(s, textHeight, opts...), deg), false)
+}
+
+// textHeight is the height of the standard font (font A) in dots, including the spacing between the lines.
+const textHeight = 24
+
+// RotateImage returns the image rotated clockwise by deg degrees, rounded to the nearest multiple of 90.
+func RotateImage(img image.Image, deg int) image.Image {
+	turns := ((deg+45)/90%4 + 4) % 4
+	if deg < 0 {
+		turns = ((deg-45)/90%4 + 4) % 4
+	}
+	if turns == 0 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if turns != 2 {
+		w, h = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := img.At(b.Min.X+x, b.Min.Y+y)
+			switch turns {
+			case 1:
+				dst.Set(b.Dy()-1-y, x, c)
+			case 2:
+				dst.Set(b.Dx()-1-x, b.Dy()-1-y, c)
+			case 3:
+				dst.Set(y, b.Dx()-1-x, c)
+			}
+		}
+	}
+	return dst
+}
+
+// TextImage draws the text as black characters of the embedded 5×7 dot font on a white background,
+// each line scaled to the height in dots, which includes the spacing between the lines.
+// Lines are separated with "\n". Characters outside of printable ASCII are drawn as '?'.
+func TextImage(s string, height int) image.Image {
+	return StyledTextImage(s, height)
+}
+
+// StyledTextImage draws the text the same way as TextImage, in the styles set by the options,
+// which enlarge the image by their width.
+func StyledTextImage(s string, height int, opts ...TextOption) image.Image {
+	if height < glyphRows {
+		height = glyphRows
+	}
+
+	lines := strings.Split(s, "\n")
+	cols := maxLineLen(s) * glyphCols
+
+	// The font dots are scaled to squares of height/glyphRows dots.
+	w := cols * height / glyphRows
+	img := image.NewGray(image.Rect(0, 0, maxByte(w, 1), len(lines)*height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	for i, line := range lines {
+		rs := []rune(line)
+		for y := 0; y < height; y++ {
+			row := y * glyphRows / height
+			if row >= glyphRows-1 {
+				break
+			}
+			pix := img.Pix[(i*height+y)*img.Stride:]
+			for x := 0; x < w; x++ {
+				col := x * glyphRows / height
+				n, c := col/glyphCols, col%glyphCols
+				if n >= len(rs) || c >= glyphCols-1 {
+					continue
+				}
+				if glyph(rs[n])[c]>>row&1 != 0 {
+					pix[x] = 0
+				}
+			}
+		}
+	}
+
+	var st textStyle
+	for _, opt := range opts {
+		opt.apply(&st)
+	}
+	return st.draw(img)
+}
+
+// TextOption sets the style of the rasterized text.
+type TextOption interface {
+	apply(*textStyle)
+}
+
+type textStyle struct {
+	outline int
+	shadow  int
+}
+
+type textOptionFunc func(*textStyle)
+
+func (fn textOptionFunc) apply(st *textStyle) {
+	fn(st)
+}
+
+// WithOutline draws the characters hollow, outlined with a black line of w dots.
+func WithOutline(w int) TextOption {
+	return textOptionFunc(func(st *textStyle) { st.outline = maxByte(w, 0) })
+}
+
+// WithShadow casts a shadow of the characters, offset by d dots down and to the right.
+// The shadow is dotted, so it reads lighter than the characters.
+func WithShadow(d int) TextOption {
+	return textOptionFunc(func(st *textStyle) { st.shadow = maxByte(d, 0) })
+}
+
+// width returns the number of dots the styles add to the width of the text.
+func (st textStyle) width() int {
+	return 2*st.outline + st.shadow
+}
+
+// draw applies the styles to the black text on the white background.
+func (st textStyle) draw(img *image.Gray) *image.Gray {
+	if st.outline == 0 && st.shadow == 0 {
+		return img
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	pad := st.outline
+	ink := func(x, y int) bool {
+		x, y = x-pad, y-pad
+		return x >= 0 && y >= 0 && x < w && y < h && img.Pix[y*img.Stride+x] == 0
+	}
+
+	// near reports whether there is ink within r dots of the point.
+	near := func(x, y, r int) bool {
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if ink(x+dx, y+dy) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, w+2*pad+st.shadow, h+2*pad+st.shadow))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			black := false
+			switch {
+			case st.outline > 0 && near(x, y, st.outline):
+				black = !ink(x, y)
+			case st.outline == 0 && ink(x, y):
+				black = true
+			case st.shadow > 0 && near(x-st.shadow, y-st.shadow, st.outline):
+				black = (x+y)%2 == 0
+			}
+			if !black {
+				dst.Pix[y*dst.Stride+x] = 0xFF
+			}
+		}
+	}
+	return dst
+}
+
+// The glyphs are 5×7 dots, the cells include one column and one row of spacing.
+const (
+	glyphCols = 6
+	glyphRows = 8
+)
+
+func maxLineLen(s string) int {
+	n := 0
+	for _, line := range strings.Split(s, "\n") {
+		n = maxByte(n, len([]rune(line)))
+	}
+	return n
+}
+
+func glyph(r rune) [5]byte {
+	if r < ' ' || r > '~' {
+		r = '?'
+	}
+	return font5x7[r-' ']
+}
+
+// font5x7 holds the glyphs of printable ASCII, column by column from the left, the least significant bit at the top.
+var font5x7 = [...][5]byte{
+	{0x00, 0x00, 0x00, 0x00, 0x00}, // ' '
+	{0x00, 0x00, 0x5F, 0x00, 0x00}, // '!'
+	{0x00, 0x07, 0x00, 0x07, 0x00}, // '"'
+	{0x14, 0x7F, 0x14, 0x7F, 0x14}, // '#'
+	{0x24, 0x2A, 0x7F, 0x2A, 0x12}, // '$'
+	{0x23, 0x13, 0x08, 0x64, 0x62}, // '%'
+	{0x36, 0x49, 0x55, 0x22, 0x50}, // '&'
+	{0x00, 0x05, 0x03, 0x00, 0x00}, // '\''
+	{0x00, 0x1C, 0x22, 0x41, 0x00}, // '('
+	{0x00, 0x41, 0x22, 0x1C, 0x00}, // ')'
+	{0x14, 0x08, 0x3E, 0x08, 0x14}, // '*'
+	{0x08, 0x08, 0x3E, 0x08, 0x08}, // '+'
+	{0x00, 0x50, 0x30, 0x00, 0x00}, // ','
+	{0x08, 0x08, 0x08, 0x08, 0x08}, // '-'
+	{0x00, 0x60, 0x60, 0x00, 0x00}, // '.'
+	{0x20, 0x10, 0x08, 0x04, 0x02}, // '/'
+	{0x3E, 0x51, 0x49, 0x45, 0x3E}, // '0'
+	{0x00, 0x42, 0x7F, 0x40, 0x00}, // '1'
+	{0x42, 0x61, 0x51, 0x49, 0x46}, // '2'
+	{0x21, 0x41, 0x45, 0x4B, 0x31}, // '3'
+	{0x18, 0x14, 0x12, 0x7F, 0x10}, // '4'
+	{0x27, 0x45, 0x45, 0x45, 0x39}, // '5'
+	{0x3C, 0x4A, 0x49, 0x49, 0x30}, // '6'
+	{0x01, 0x71, 0x09, 0x05, 0x03}, // '7'
+	{0x36, 0x49, 0x49, 0x49, 0x36}, // '8'
+	{0x06, 0x49, 0x49, 0x29, 0x1E}, // '9'
+	{0x00, 0x36, 0x36, 0x00, 0x00}, // ':'
+	{0x00, 0x56, 0x36, 0x00, 0x00}, // ';'
+	{0x08, 0x14, 0x22, 0x41, 0x00}, // '<'
+	{0x14, 0x14, 0x14, 0x14, 0x14}, // '='
+	{0x00, 0x41, 0x22, 0x14, 0x08}, // '>'
+	{0x02, 0x01, 0x51, 0x09, 0x06}, // '?'
+	{0x32, 0x49, 0x79, 0x41, 0x3E}, // '@'
+	{0x7E, 0x11, 0x11, 0x11, 0x7E}, // 'A'
+	{0x7F, 0x49, 0x49, 0x49, 0x36}, // 'B'
+	{0x3E, 0x41, 0x41, 0x41, 0x22}, // 'C'
+	{0x7F, 0x41, 0x41, 0x22, 0x1C}, // 'D'
+	{0x7F, 0x49, 0x49, 0x49, 0x41}, // 'E'
+	{0x7F, 0x09, 0x09, 0x09, 0x01}, // 'F'
+	{0x3E, 0x41, 0x49, 0x49, 0x7A}, // 'G'
+	{0x7F, 0x08, 0x08, 0x08, 0x7F}, // 'H'
+	{0x00, 0x41, 0x7F, 0x41, 0x00}, // 'I'
+	{0x20, 0x40, 0x41, 0x3F, 0x01}, // 'J'
+	{0x7F, 0x08, 0x14, 0x22, 0x41}, // 'K'
+	{0x7F, 0x40, 0x40, 0x40, 0x40}, // 'L'
+	{0x7F, 0x02, 0x0C, 0x02, 0x7F}, // 'M'
+	{0x7F, 0x04, 0x08, 0x10, 0x7F}, // 'N'
+	{0x3E, 0x41, 0x41, 0x41, 0x3E}, // 'O'
+	{0x7F, 0x09, 0x09, 0x09, 0x06}, // 'P'
+	{0x3E, 0x41, 0x51, 0x21, 0x5E}, // 'Q'
+	{0x7F, 0x09, 0x19, 0x29, 0x46}, // 'R'
+	{0x46, 0x49, 0x49, 0x49, 0x31}, // 'S'
+	{0x01, 0x01, 0x7F, 0x01, 0x01}, // 'T'
+	{0x3F, 0x40, 0x40, 0x40, 0x3F}, // 'U'
+	{0x1F, 0x20, 0x40, 0x20, 0x1F}, // 'V'
+	{0x3F, 0x40, 0x38, 0x40, 0x3F}, // 'W'
+	{0x63, 0x14, 0x08, 0x14, 0x63}, // 'X'
+	{0x07, 0x08, 0x70, 0x08, 0x07}, // 'Y'
+	{0x61, 0x51, 0x49, 0x45, 0x43}, // 'Z'
+	{0x00, 0x7F, 0x41, 0x41, 0x00}, // '['
+	{0x02, 0x04, 0x08, 0x10, 0x20}, // '\\'
+	{0x00, 0x41, 0x41, 0x7F, 0x00}, // ']'
+	{0x04, 0x02, 0x01, 0x02, 0x04}, // '^'
+	{0x40, 0x40, 0x40, 0x40, 0x40}, // '_'
+	{0x00, 0x01, 0x02, 0x04, 0x00}, // '`'
+	{0x20, 0x54, 0x54, 0x54, 0x78}, // 'a'
+	{0x7F, 0x48, 0x44, 0x44, 0x38}, // 'b'
+	{0x38, 0x44, 0x44, 0x44, 0x20}, // 'c'
+	{0x38, 0x44, 0x44, 0x48, 0x7F}, // 'd'
+	{0x38, 0x54, 0x54, 0x54, 0x18}, // 'e'
+	{0x08, 0x7E, 0x09, 0x01, 0x02}, // 'f'
+	{0x0C, 0x52, 0x52, 0x52, 0x3E}, // 'g'
+	{0x7F, 0x08, 0x04, 0x04, 0x78}, // 'h'
+	{0x00, 0x44, 0x7D, 0x40, 0x00}, // 'i'
+	{0x20, 0x40, 0x44, 0x3D, 0x00}, // 'j'
+	{0x7F, 0x10, 0x28, 0x44, 0x00}, // 'k'
+	{0x00, 0x41, 0x7F, 0x40, 0x00}, // 'l'
+	{0x7C, 0x04, 0x18, 0x04, 0x78}, // 'm'
+	{0x7C, 0x08, 0x04, 0x04, 0x78}, // 'n'
+	{0x38, 0x44, 0x44, 0x44, 0x38}, // 'o'
+	{0x7C, 0x14, 0x14, 0x14, 0x08}, // 'p'
+	{0x08, 0x14, 0x14, 0x18, 0x7C}, // 'q'
+	{0x7C, 0x08, 0x04, 0x04, 0x08}, // 'r'
+	{0x48, 0x54, 0x54, 0x54, 0x20}, // 's'
+	{0x04, 0x3F, 0x44, 0x40, 0x20}, // 't'
+	{0x3C, 0x40, 0x40, 0x20, 0x7C}, // 'u'
+	{0x1C, 0x20, 0x40, 0x20, 0x1C}, // 'v'
+	{0x3C, 0x40, 0x30, 0x40, 0x3C}, // 'w'
+	{0x44, 0x28, 0x10, 0x28, 0x44}, // 'x'
+	{0x0C, 0x50, 0x50, 0x50, 0x3C}, // 'y'
+	{0x44, 0x64, 0x54, 0x4C, 0x44}, // 'z'
+	{0x00, 0x08, 0x36, 0x41, 0x00}, // '{'
+	{0x00, 0x00, 0x7F, 0x00, 0x00}, // '|'
+	{0x00, 0x41, 0x36, 0x08, 0x00}, // '}'
+	{0x08, 0x04, 0x08, 0x10, 0x08}, // '~'
+}
@@ -0,0 +1,176 @@
+package thermalize
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+// recordedImage returns the image of the last Image recorded in the document.
+func recordedImage(t *testing.T, doc *Document) image.Image {
+	t.Helper()
+	ops := doc.Ops()
+	if len(ops) == 0 || ops[len(ops)-1].Name != "Image" {
+		t.Fatalf("the document doesn't end with an image: %v", ops)
+	}
+	return ops[len(ops)-1].Args[0].(image.Image)
+}
+
+func black(img image.Image, x, y int) bool {
+	r, _, _, _ := img.At(x, y).RGBA()
+	return r < 0x8000
+}
+
+func TestTextImage(t *testing.T) {
+	// At 16 dots, each dot of the font is drawn as 2×2 dots.
+	img := TextImage("A\nBC", 16)
+	if size := img.Bounds().Size(); size != image.Pt(24, 32) {
+		t.Fatalf("the size is %v, want (24,32)", size)
+	}
+	for i, line := range []string{"A", "BC"} {
+		for n, r := range line {
+			for c := 0; c < glyphCols; c++ {
+				for row := 0; row < glyphRows; row++ {
+					want := c < glyphCols-1 && row < glyphRows-1 && glyph(r)[c]>>row&1 != 0
+					x, y := (n*glyphCols+c)*2, (i*glyphRows+row)*2
+					if black(img, x, y) != want || black(img, x+1, y+1) != want {
+						t.Fatalf("the dot %d, %d of %q is black %v, want %v", c, row, r, !want, want)
+					}
+				}
+			}
+		}
+	}
+	if string(TextImage("\x01", 8).(*image.Gray).Pix) != string(TextImage("?", 8).(*image.Gray).Pix) {
+		t.Error("the control character isn't drawn as '?'")
+	}
+}
+
+func TestBigText(t *testing.T) {
+	for _, tc := range []struct {
+		s      string
+		height int
+		want   image.Point
+	}{
+		{"12", 64, image.Pt(96, 64)},
+		// The height is reduced to 384*8/66 dots, so the 11 characters fit the 384 dots.
+		{"TOTAL 12.50", 160, image.Pt(379, 46)},
+		{"TOTAL\n12.50", 64, image.Pt(240, 128)},
+	} {
+		doc := NewDocument(32, 384)
+		BigText(doc, tc.s, tc.height)
+		if size := recordedImage(t, doc).Bounds().Size(); size != tc.want {
+			t.Errorf("BigText(%q, %d) is %v, want %v", tc.s, tc.height, size, tc.want)
+		}
+	}
+}
+
+func TestRotateImage(t *testing.T) {
+	// The image is 3×2 dots, black at the top left.
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	img.Pix[0] = 0
+
+	for _, tc := range []struct {
+		deg       int
+		size, dot image.Point
+	}{
+		{0, image.Pt(3, 2), image.Pt(0, 0)},
+		{90, image.Pt(2, 3), image.Pt(1, 0)},
+		{100, image.Pt(2, 3), image.Pt(1, 0)},
+		{180, image.Pt(3, 2), image.Pt(2, 1)},
+		{270, image.Pt(2, 3), image.Pt(0, 2)},
+		{-90, image.Pt(2, 3), image.Pt(0, 2)},
+		{450, image.Pt(2, 3), image.Pt(1, 0)},
+	} {
+		got := RotateImage(img, tc.deg)
+		if size := got.Bounds().Size(); size != tc.size {
+			t.Errorf("rotated by %d, the size is %v, want %v", tc.deg, size, tc.size)
+			continue
+		}
+		for y := 0; y < tc.size.Y; y++ {
+			for x := 0; x < tc.size.X; x++ {
+				if want := image.Pt(x, y) == tc.dot; black(got, x, y) != want {
+					t.Errorf("rotated by %d, the dot %d, %d is black %v, want %v", tc.deg, x, y, !want, want)
+				}
+			}
+		}
+	}
+}
+
+func TestTextRotated(t *testing.T) {
+	doc := NewDocument(32, 384)
+	TextRotated(doc, "AB", 90)
+	// The text of 2 characters at the height of the standard font is 36×24 dots before the rotation.
+	if size := recordedImage(t, doc).Bounds().Size(); size != image.Pt(24, 36) {
+		t.Errorf("the rotated text is %v, want (24,36)", size)
+	}
+
+	var buf bytes.Buffer
+	cmd := NewEscape(48, 576, &buf)
+	TextRotated(cmd, "AB", 45)
+	if err := cmdErr(cmd); err != nil || buf.Len() == 0 {
+		t.Errorf("the text rotated by 45 degrees: %v, %d bytes", err, buf.Len())
+	}
+
+	buf.Reset()
+	cmd = NewEscape(48, 576, &buf, WithStrict())
+	TextRotated(cmd, "AB", 45)
+	var v *ValidationError
+	if err := cmdErr(cmd); !errors.As(err, &v) || v.Command != "TextRotated" {
+		t.Errorf("the strict text rotated by 45 degrees: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("the strict text rotated by 45 degrees is printed: % x", buf.Bytes())
+	}
+}
+
+func TestTextStyles(t *testing.T) {
+	plain := TextImage("HI", 16).(*image.Gray)
+	w, h := plain.Rect.Dx(), plain.Rect.Dy()
+
+	// The outlined characters are hollow: the dots of the plain text are white and their neighbours black.
+	outlined := StyledTextImage("HI", 16, WithOutline(1))
+	if size := outlined.Bounds().Size(); size != image.Pt(w+2, h+2) {
+		t.Fatalf("the outlined text is %v, want (%d,%d)", size, w+2, h+2)
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !black(plain, x, y) {
+				continue
+			}
+			if black(outlined, x+1, y+1) {
+				t.Fatalf("the dot %d, %d of the outlined text is black", x, y)
+			}
+			if (x == 0 || !black(plain, x-1, y)) && !black(outlined, x, y+1) {
+				t.Fatalf("the dot left of %d, %d of the outlined text is white", x, y)
+			}
+		}
+	}
+
+	// The shadow is a dotted copy of the text offset down and to the right, under the text.
+	const d = 4
+	shadowed := StyledTextImage("HI", 16, WithShadow(d))
+	if size := shadowed.Bounds().Size(); size != image.Pt(w+d, h+d) {
+		t.Fatalf("the shadowed text is %v, want (%d,%d)", size, w+d, h+d)
+	}
+	for y := 0; y < h+d; y++ {
+		for x := 0; x < w+d; x++ {
+			ink := x < w && y < h && black(plain, x, y)
+			shadow := x >= d && y >= d && black(plain, x-d, y-d)
+			want := ink || shadow && (x+y)%2 == 0
+			if black(shadowed, x, y) != want {
+				t.Fatalf("the dot %d, %d of the shadowed text is black %v, want %v", x, y, !want, want)
+			}
+		}
+	}
+
+	// The styles are included in the width the height is reduced to.
+	doc := NewDocument(32, 384)
+	BigText(doc, "TOTAL 12.50", 160, WithOutline(2), WithShadow(4))
+	if dx := recordedImage(t, doc).Bounds().Dx(); dx > 384 {
+		t.Errorf("the styled text is %d dots wide, more than 384", dx)
+	}
+}
@@ -0,0 +1,83 @@
+package thermalize
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The characters of the frames: the horizontal and vertical edges and the top left, top right,
+// bottom left and bottom right corners.
+const (
+	boxDrawing = "─│┌┐└┘"
+	boxASCII   = "-|++++"
+)
+
+// BoxStart prints the top edge of a frame spanning the line, e.g. around the totals of a receipt.
+// The lines within the frame are printed with BoxLine, and the frame is closed by BoxEnd.
+//
+// The frame is drawn with the box drawing characters of code page 437, which the code pages CP437, CP850, CP866
+// and others share, if the encoder of the selected code page can encode them, or with ASCII (+, -, |) otherwise,
+// so it prints on any printer. The frame is as wide as the line, less the indentation (see TextFormatter.Indent),
+// in characters of the standard size.
+func BoxStart(cmd Cmd) {
+	box := boxChars(cmd)
+	boxEdge(cmd, box[2], box[0], box[3])
+}
+
+// BoxLine prints the text within the frame started by BoxStart, word-wrapped to the width of the frame.
+func BoxLine(cmd Cmd, s string) {
+	box := boxChars(cmd)
+	w := boxWidth(cmd) - 4
+	if w <= 0 {
+		return
+	}
+	for _, l := range Wrap(s, w) {
+		cmd.Text(box[1]+" "+l+strings.Repeat(" ", w-utf8.RuneCountInString(l))+" "+box[1], nil)
+		cmd.LineFeed()
+	}
+}
+
+// BoxEnd prints the bottom edge of the frame started by BoxStart.
+func BoxEnd(cmd Cmd) {
+	box := boxChars(cmd)
+	boxEdge(cmd, box[4], box[0], box[5])
+}
+
+func boxEdge(cmd Cmd, left, edge, right string) {
+	w := boxWidth(cmd)
+	if w < 2 {
+		return
+	}
+	cmd.Text(left+strings.Repeat(edge, w-2)+right, nil)
+	cmd.LineFeed()
+}
+
+// boxWidth returns the width of the frame in characters.
+func boxWidth(cmd Cmd) int {
+	if c := skipperOf(cmd); c != nil {
+		return c.wrapWidth()
+	}
+	return cmd.CPL()
+}
+
+// boxChars returns the characters of the frame the selected code page of the command set can encode.
+func boxChars(cmd Cmd) [6]string {
+	chars := boxASCII
+	if c := skipperOf(cmd); c != nil && c.enc != nil {
+		chars = boxDrawing
+		for _, r := range boxDrawing {
+			if !c.enc.CanEncode(r) {
+				chars = boxASCII
+				break
+			}
+		}
+	}
+
+	var box [6]string
+	i := 0
+	for _, r := range chars {
+		box[i] = string(r)
+		i++
+	}
+	return box
+}
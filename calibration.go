@@ -0,0 +1,84 @@
+package thermalize
+
+import "image"
+
+// Calibration is the gray level and print density of a swatch printed by CalibrationPage.
+type Calibration struct {
+	GrayLevel uint8
+
+	// Density is the print density step, see DensityController.
+	Density int
+}
+
+// Apply sets the gray level of the command set, if it implements GrayLeveler as all command sets do,
+// and, if it implements DensityController, the print density.
+func (cal Calibration) Apply(cmd Cmd) {
+	if c, ok := cmd.(GrayLeveler); ok {
+		c.GrayLevel(cal.GrayLevel)
+	}
+	if c, ok := cmd.(DensityController); ok {
+		c.PrintDensity(cal.Density)
+	}
+}
+
+// defaultCalibrationLevels are the gray levels of the swatches printed by CalibrationPage by default.
+var defaultCalibrationLevels = []uint8{64, 96, 127, 160, 192}
+
+// CalibrationPage prints numbered swatches of the image, or of a gray gradient if img is nil,
+// converted with each of the gray levels and printed with each of the print densities.
+// It returns the calibrations of the swatches, so the one chosen on the printout is applied with
+//
+//	calibrations[n-1].Apply(cmd)
+//
+// where n is the number of the swatch. By default, the levels 64 to 192 are printed with the standard density.
+// The densities are skipped if the command set doesn't implement DensityController,
+// the command set is left at the standard density and the gray level of the last swatch.
+//
+// Example Usage:
+//
+//	calibrations := thermalize.CalibrationPage(cmd, logo, nil, []int{-2, 0, 2})
+func CalibrationPage(cmd Cmd, img image.Image, levels []uint8, densities []int) []Calibration {
+	if len(levels) == 0 {
+		levels = defaultCalibrationLevels
+	}
+	dc, ok := cmd.(DensityController)
+	if !ok || len(densities) == 0 {
+		densities = []int{0}
+	}
+	if img == nil {
+		img = testGradient(cmd.PPL(), 48)
+	}
+
+	cmd.Align(Center)
+	cmd.Bold(true)
+	cmd.Text(Translate(cmd, MessageCalibration), nil)
+	cmd.Bold(false)
+	cmd.LineFeed()
+
+	var calibrations []Calibration
+	for _, d := range densities {
+		for _, l := range levels {
+			cal := Calibration{GrayLevel: l, Density: d}
+			calibrations = append(calibrations, cal)
+
+			cmd.LineFeed()
+			cmd.Text(Translate(cmd, MessageCalibrationRow, len(calibrations), l, d), nil)
+			cmd.LineFeed()
+			if ok {
+				dc.PrintDensity(d)
+			}
+			if c, ok := cmd.(GrayLeveler); ok {
+				c.GrayLevel(l)
+			}
+			cmd.Image(img, false)
+			cmd.LineFeed()
+		}
+	}
+	if ok {
+		dc.PrintDensity(0)
+	}
+
+	cmd.Align(Left)
+	cutWithFeed(cmd, false, 0)
+	return calibrations
+}
@@ -0,0 +1,593 @@
+package thermalize
+
+import (
+	"image"
+	"io"
+	"time"
+)
+
+// PageModer is implemented by command sets that support page mode,
+// in which the printer lays the data out in a print area before printing it at once.
+type PageModer interface {
+	// PageMode switches between page mode and standard mode.
+	PageMode(b bool)
+
+	// PrintArea sets the position and the size of the print area in page mode, measured in dots.
+	PrintArea(x, y, w, h int)
+
+	// PrintPage prints the data buffered in page mode and returns to standard mode.
+	PrintPage()
+}
+
+// Beeper is implemented by command sets that can sound the buzzer of the printer.
+type Beeper interface {
+	// Beep sounds the buzzer n times, t specifies the duration of each beep.
+	Beep(n, t byte)
+}
+
+// ColorPrinter is implemented by command sets of two-color printers.
+type ColorPrinter interface {
+	// Color selects the print color.
+	//
+	//	b = 0, the first color (black);
+	//	b = 1, the second color (red).
+	Color(b byte)
+}
+
+// Reverser is implemented by command sets that support white/black reverse printing.
+type Reverser interface {
+	// Reverse turns white/black reverse print mode on/off.
+	Reverse(b bool)
+}
+
+// StatusQuerier is implemented by command sets that can request the real-time status of the printer.
+// The status is transmitted by the printer and must be read from the connection.
+type StatusQuerier interface {
+	// RequestStatus requests the real-time status of the printer.
+	//
+	//	n = 1, printer status;
+	//	n = 2, offline cause status;
+	//	n = 3, error cause status;
+	//	n = 4, roll paper sensor status.
+	RequestStatus(n byte)
+}
+
+// Labeler is implemented by command sets of label printers, such as Epson TM-L90 and TM-L100.
+type Labeler interface {
+	// LabelAdjust moves the print start position (LabelPrintPosition)
+	// or the cut and peel position (LabelCutPosition, LabelPeelPosition) by n dots, backwards if n is negative.
+	LabelAdjust(p byte, n int)
+
+	// LabelFeed feeds the label to the peel (LabelPeelPosition), cut (LabelCutPosition)
+	// or print start (LabelPrintPosition) position.
+	LabelFeed(p byte)
+
+	// RequestLabelTaken requests the status of the label taken sensor of the peeler.
+	// The status is transmitted by the printer and must be read from the connection.
+	RequestLabelTaken()
+}
+
+// QRModeler is implemented by command sets that can select the model of QR codes.
+type QRModeler interface {
+	// QRCodeModel selects QRModel1, QRModel2 or QRMicro.
+	QRCodeModel(b byte)
+}
+
+// QRVersioner is implemented by command sets that can lock the version and the mask pattern of QR codes,
+// such as fixed version QR codes required by fiscal regulations.
+type QRVersioner interface {
+	// QRCodeVersion locks the version (1 - 40) of the QR codes, 0 selects the smallest version holding the data.
+	QRCodeVersion(v byte)
+	// QRCodeMask locks the mask pattern (0 - 7) of the QR codes, QRMaskAuto selects it for the data.
+	QRCodeMask(m byte)
+}
+
+// PDF417Printer is implemented by command sets that print PDF417 symbols natively.
+type PDF417Printer interface {
+	// PDF417Size sets the number of rows (3 - 90) and columns (1 - 30) of the symbol, 0 selects them automatically.
+	PDF417Size(rows, cols byte)
+
+	// PDF417CorrectionLevel sets the error correction level (0 - 8).
+	PDF417CorrectionLevel(b byte)
+
+	// PDF417Module sets the width of the module in dots and the ratio of its height to its width.
+	PDF417Module(width, ratio byte)
+
+	// PDF417 prints the symbol encoding s.
+	PDF417(s string)
+}
+
+// SlipPrinter is implemented by command sets of hybrid printers with a slip station and a MICR reader,
+// such as Epson TM-H6000.
+type SlipPrinter interface {
+	// SelectStation selects the station printing the data: StationRoll, StationSlip or StationValidation.
+	SelectStation(s byte)
+
+	// SelectSheet selects the sheets printing the data [ESC c 0], a combination of SheetJournal, SheetRoll,
+	// SheetSlip and SheetValidation, e.g. the journal and the receipt rolls printed at once.
+	SelectSheet(mask byte)
+
+	// SheetSettings selects the sheets the following settings apply to [ESC c 1], such as the line spacing
+	// and the margins, so each sheet keeps its own layout.
+	SheetSettings(mask byte)
+
+	// SlipWait sets the time the printer waits for the slip to be inserted in minutes,
+	// and the delay before printing once it has been detected in 100 ms units.
+	SlipWait(insert, delay byte)
+
+	// EjectSlip ejects the slip.
+	EjectSlip()
+
+	// ReadMICR reads the MICR line of the inserted check in the font (MICRFontE13B or MICRFontCMC7)
+	// and requests its transmission. The reply must be read from the connection and parsed with ParseMICR.
+	ReadMICR(font byte)
+}
+
+// DeviceSelector is implemented by command sets that route the data to the devices chained to the printer,
+// such as a customer display connected through the printer.
+type DeviceSelector interface {
+	// SelectDevice enables or disables the printer and the customer display, the disabled devices ignore the data.
+	SelectDevice(printer, display bool)
+}
+
+// Recoverer is implemented by command sets that can recover the printer from errors, such as a cutter jam.
+// The commands are processed by the printer in real time, even while it is offline because of the error.
+// See RecoverAndReprint for the recovery flow.
+type Recoverer interface {
+	// RecoverError recovers the printer from a recoverable error, such as a cutter jam once it has been removed,
+	// with the mode RecoverRestart or RecoverClear.
+	RecoverError(mode byte)
+
+	// SoftReset clears the receive and print buffers and initializes the printer, discarding the interrupted job.
+	SoftReset()
+}
+
+// DensityController is implemented by command sets that can adjust the print density of the printer.
+type DensityController interface {
+	// PrintDensity adjusts the print density by n steps, darker if n is positive, 0 is the standard density.
+	// Epson printers support -6 to 6 steps, star printers -3 to 3.
+	PrintDensity(n int)
+}
+
+// ProcessIDResponder is implemented by command sets that can confirm the processing of a job,
+// such as the escape command set (see ParseProcessID).
+type ProcessIDResponder interface {
+	// RequestProcessID requests the transmission of the ID once the data before it has been processed,
+	// e.g. at the end of a job, so the application learns that the job has been printed completely.
+	// The ID is 4 characters from 32 to 126, other IDs are skipped and reported through Err.
+	RequestProcessID(id string)
+}
+
+// DocumentMarker is implemented by command sets that mark the documents and the checkpoints of the job,
+// such as the star command set on StarPRNT printers.
+//
+// The printer counts the checkpoints it has reached in the ETB counter of its automatic status (see ParseStarStatus),
+// modulo 32. An application ending each document with a checkpoint knows that the document has been printed
+// once the counter reaches its number, and reprints the documents after the counter if the printer fails.
+//
+// Example Usage:
+//
+//	m.ResetCheckpoints()
+//	for i, doc := range docs {
+//		m.StartDocument()
+//		doc.Render(cmd)
+//		m.Checkpoint() // reported as the ETB counter i+1 once printed
+//		m.EndDocument()
+//	}
+type DocumentMarker interface {
+	// StartDocument marks the start of a document.
+	StartDocument()
+
+	// EndDocument marks the end of the document.
+	EndDocument()
+
+	// Checkpoint increments the checkpoint counter of the printer once the data before it has been printed.
+	Checkpoint()
+
+	// ResetCheckpoints clears the checkpoint counter of the printer.
+	ResetCheckpoints()
+}
+
+// ColumnPositioner is implemented by command sets that can place the text at a character column,
+// such as the escape and star command sets.
+//
+// Example Usage:
+//
+//	cmd.Text("Coffee", nil)
+//	cmd.(thermalize.ColumnPositioner).RightAt(cmd.CPL(), "3.50")
+//	cmd.LineFeed()
+type ColumnPositioner interface {
+	// RightAt prints the text so it ends at the column col, counted in the characters of the normal size
+	// from the left margin, e.g. to build the right-aligned price columns. The width of the text follows CharSize.
+	RightAt(col int, s string)
+}
+
+// Smoother is implemented by command sets that can smooth the outlines of the enlarged characters,
+// such as the escape command set.
+type Smoother interface {
+	// Smoothing turns smoothing mode on/off, which applies to the characters of the double size and larger.
+	Smoothing(b bool)
+}
+
+// GlyphPrinter is implemented by command sets that can print small glyphs within the text, such as the boxes of Checkbox.
+// The escape command set prints them as user-defined characters, the postscript command set as inline images.
+type GlyphPrinter interface {
+	// Glyph prints GlyphBox, GlyphBoxChecked or GlyphBoxFilled as wide as a character of the current size.
+	Glyph(g byte)
+}
+
+// InlineImager is implemented by command sets that can print small images within the text,
+// such as the escape and postscript command sets. The star command set prints them on their own line.
+type InlineImager interface {
+	// InlineImage adds a small image, such as an icon or a card brand logo, to the current line of text.
+	// The image is aligned vertically with the text according to valign:
+	//
+	//	VAlignTop, VAlignMiddle or VAlignBottom.
+	InlineImage(img image.Image, valign byte)
+}
+
+// Watermarker is implemented by command sets that can mark the output with a watermark,
+// such as the postscript command set and, with WithWatermarkBand, the escape and star command sets.
+type Watermarker interface {
+	// Watermark draws large, rotated and faint text, such as "COPY", "VOID" or "TRAINING",
+	// behind the content of the pages of the postscript command set. Thermal printers can't print
+	// behind the content, they print a light raster band at the current position if WithWatermarkBand is used.
+	// An empty string removes the watermark.
+	Watermark(s string, opts ...WatermarkOption)
+}
+
+// Cutter is implemented by command sets that cut the paper the same way on all their printers,
+// such as the escape and star command sets, unlike the modes of Cmd.Cut.
+type Cutter interface {
+	// FullCutAtPosition cuts the paper across the full width at the current position, without feeding it.
+	FullCutAtPosition()
+
+	// PartialCutAtPosition cuts the paper partially, leaving a point uncut, at the current position, without feeding it.
+	PartialCutAtPosition()
+
+	// FeedAndFullCut feeds the paper to the cutting position and p/4 mm further, then cuts it across the full width.
+	FeedAndFullCut(p byte)
+
+	// CutWithFeed feeds the paper to the cutting position and mm millimeters further, then cuts it,
+	// partially if partial is set. The millimeters are converted to the feed units of the command set.
+	CutWithFeed(partial bool, mm float64)
+}
+
+// ClockPrinter is implemented by command sets that can set, read and print the real-time clock of the printer
+// with the vendor commands of its clock profile, such as the escape and star command sets (see WithClockProfile).
+// The commands the clock profile lacks are reported through Err.
+type ClockPrinter interface {
+	// SetClock sets the clock of the printer to the time.
+	SetClock(t time.Time)
+
+	// RequestClock requests the transmission of the time of the clock.
+	// The reply must be read from the connection and parsed with ClockProfile.ParseClock.
+	RequestClock()
+
+	// PrintClock prints the time of the clock at the current position, e.g. where the regulation requires
+	// the receipts to be stamped by the device.
+	PrintClock()
+}
+
+// Paginator is implemented by command sets that lay the output out on pages, such as the postscript command set.
+type Paginator interface {
+	// KeepTogether prints the block written by fn on one page, starting a new page if it doesn't fit the current one.
+	KeepTogether(fn func(Cmd))
+}
+
+// Buffered is implemented by all command sets, it returns the output accumulated when WithBuffer is used.
+//
+// Example Usage:
+//
+//	cmd := thermalize.NewEscape(48, 576, nil, thermalize.WithBuffer())
+//	cmd.Text("Hello", nil)
+//	cmd.Print()
+//	data := cmd.(thermalize.Buffered).Bytes()
+type Buffered interface {
+	// Bytes returns the accumulated output, which is valid until the next command.
+	Bytes() []byte
+
+	// WriteTo writes the accumulated output to w and empties the buffer.
+	WriteTo(w io.Writer) (int64, error)
+
+	// Reset discards the accumulated output.
+	Reset()
+}
+
+// LineMeasurer is implemented by all command sets, it reports the state of the current line,
+// so the layout helpers can decide where to wrap the text.
+//
+// Example Usage:
+//
+//	cmd.Text(name, nil)
+//	if m, ok := cmd.(thermalize.LineMeasurer); ok && m.RemainingWidth() < priceWidth {
+//		cmd.LineFeed()
+//	}
+type LineMeasurer interface {
+	// CurrentLineWidth returns the width of the current line in dots.
+	CurrentLineWidth() int
+
+	// RemainingWidth returns the width left in the current line within the printing area in dots.
+	RemainingWidth() int
+}
+
+// GrayLeveler is implemented by all command sets, it sets the gray level of the images they print.
+type GrayLeveler interface {
+	// GrayLevel sets the level of gray printed as black by this command set, overriding SetGrayLevel,
+	// e.g. as chosen from the swatches printed by CalibrationPage.
+	GrayLevel(l uint8)
+}
+
+// TriLiner is implemented by all command sets, it lays out the three segments of a line.
+type TriLiner interface {
+	// TriLine adds the left, center and right segments, such as "date | store | terminal", on a single line,
+	// placed with AbsolutePosition and followed by a line feed. Segments that don't fit are truncated,
+	// the center segment first, then the left one, so the right segment is kept whole as long as possible.
+	// The line is laid out in characters of the standard size with left alignment.
+	TriLine(left, center, right string)
+}
+
+// ErrorReporter is implemented by all command sets, it reports the errors recorded while the commands are written,
+// such as the parameter violations in strict mode (see WithStrict) or the failed writes.
+type ErrorReporter interface {
+	// Err returns the first error recorded by the command set.
+	Err() error
+}
+
+// RawWriter is implemented by all command sets, it writes raw data without copying it to a variadic argument.
+type RawWriter interface {
+	// WriteBytes writes raw bytes from a slice.
+	// Unlike Write, it doesn't require the bytes to be passed as a variadic argument.
+	WriteBytes(bs []byte)
+
+	// WriteString writes a raw string without converting it to a byte slice first.
+	WriteString(s string)
+
+	// Raw writes vendor-specific commands that are not supported by the command set.
+	// In strict mode, the escape command set checks that the commands don't leave the printer
+	// in an inconsistent state, such as with unterminated graphics data, and reports a violation through Err.
+	Raw(bs []byte)
+}
+
+// Flusher is implemented by all command sets, it writes the data buffered with WithBufferSize.
+type Flusher interface {
+	// Flush writes any buffered data to the writer and returns the error of the failed write, if any.
+	// Print and Cut flush the buffered data automatically.
+	Flush() error
+}
+
+// TextFormatter is implemented by all command sets, it wraps the text between words and indents it.
+//
+// Example Usage:
+//
+//	if f, ok := cmd.(thermalize.TextFormatter); ok {
+//		f.Indent(2)
+//		f.TextWrap(note, nil)
+//		f.Outdent()
+//	}
+type TextFormatter interface {
+	// TextWrap adds printable string wrapped between words to lines of CPL characters, each followed by a line feed.
+	// The encoder is used the same way as by Text.
+	TextWrap(s string, enc func(string) []byte)
+
+	// Paragraph adds the string wrapped the same way as by TextWrap, with the lines indented by indent characters
+	// and the continuation lines by further hang characters, e.g. for the modifiers listed under an item.
+	Paragraph(s string, indent, hang int)
+
+	// List adds the items as a bulleted or numbered list, wrapped the same way as by TextWrap.
+	// The continuation lines of an item are aligned with its text.
+	List(items []string, opts ...ListOption)
+
+	// Justify turns justification of the word-wrapped text on/off.
+	// Justified lines, except the last line of the text, are flush on both margins.
+	Justify(b bool)
+
+	// Indent shifts the left origin of the following lines by n characters of the standard size,
+	// on top of the left margin and the enclosing indentations, e.g. for the modifiers of an item and
+	// their sub-items. The word-wrapped text is wrapped to the width left.
+	Indent(n int)
+
+	// Outdent restores the left origin of the enclosing indentation.
+	Outdent()
+}
+
+// DrawerOpener is implemented by command sets that open the cash drawers with the pulses of the drawer profiles,
+// such as the escape and star command sets.
+type DrawerOpener interface {
+	// OpenCashDrawerProfile generates the pulse of the profile, such as DrawerEpson, on the pin (DrawerPin2 or DrawerPin5).
+	OpenCashDrawerProfile(m byte, p DrawerProfile)
+
+	// OpenBothDrawers generates the pulse set by WithDrawerProfile on both pins, opening the drawers connected to them.
+	OpenBothDrawers()
+}
+
+// Capability reports which optional features are supported by a command set.
+type Capability struct {
+	PageMode  bool
+	Beep      bool
+	Color     bool
+	Reverse   bool
+	Status    bool
+	Label     bool
+	Paginate  bool
+	PDF417    bool
+	QRModel   bool
+	QRVersion bool
+	Device    bool
+	Slip      bool
+	Density   bool
+	Recover   bool
+	Markers   bool
+	ProcessID bool
+	Columns   bool
+	Smoothing bool
+	Glyphs    bool
+	Clock     bool
+	Inline    bool
+	Watermark bool
+	Cut       bool
+	Drawers   bool
+}
+
+// Capabilities reports which optional capability interfaces are implemented by the command set,
+// so an application can degrade gracefully when a feature is not supported.
+//
+// Example Usage:
+//
+//	if thermalize.Capabilities(cmd).Beep {
+//		cmd.(thermalize.Beeper).Beep(2, 3)
+//	}
+func Capabilities(cmd Cmd) Capability {
+	_, pageMode := cmd.(PageModer)
+	_, beep := cmd.(Beeper)
+	_, color := cmd.(ColorPrinter)
+	_, reverse := cmd.(Reverser)
+	_, status := cmd.(StatusQuerier)
+	_, label := cmd.(Labeler)
+	_, paginate := cmd.(Paginator)
+	_, pdf417 := cmd.(PDF417Printer)
+	_, qrModel := cmd.(QRModeler)
+	_, qrVersion := cmd.(QRVersioner)
+	_, device := cmd.(DeviceSelector)
+	_, slip := cmd.(SlipPrinter)
+	_, density := cmd.(DensityController)
+	_, recoverer := cmd.(Recoverer)
+	_, markers := cmd.(DocumentMarker)
+	_, processID := cmd.(ProcessIDResponder)
+	_, columns := cmd.(ColumnPositioner)
+	_, smoothing := cmd.(Smoother)
+	_, glyphs := cmd.(GlyphPrinter)
+	_, clock := cmd.(ClockPrinter)
+	_, inline := cmd.(InlineImager)
+	_, watermark := cmd.(Watermarker)
+	_, cut := cmd.(Cutter)
+	_, drawers := cmd.(DrawerOpener)
+
+	return Capability{
+		PageMode:  pageMode,
+		Beep:      beep,
+		Color:     color,
+		Reverse:   reverse,
+		Status:    status,
+		Label:     label,
+		Paginate:  paginate,
+		PDF417:    pdf417,
+		QRModel:   qrModel,
+		QRVersion: qrVersion,
+		Device:    device,
+		Slip:      slip,
+		Density:   density,
+		Recover:   recoverer,
+		Markers:   markers,
+		ProcessID: processID,
+		Columns:   columns,
+		Smoothing: smoothing,
+		Glyphs:    glyphs,
+		Clock:     clock,
+		Inline:    inline,
+		Watermark: watermark,
+		Cut:       cut,
+		Drawers:   drawers,
+	}
+}
+
+// CapabilityReport describes the support of the commands by a command set, see Describe.
+type CapabilityReport struct {
+	// Backend is the kind of the command set: "escape", "star", "postscript", "skipper" or "document".
+	Backend string
+
+	// Capability reports the optional capability interfaces implemented by the command set.
+	Capability
+
+	// Skipped lists the methods of Cmd the command set skips, because the printer or the format can't do them
+	// or the configuration lacks a function they need, e.g. QRCode of the escape command set with QuirkNoQRCode
+	// and without WithQRCodeFunc, or the cuts of the escape command set with QuirkNoCut.
+	Skipped []string
+}
+
+// skippedMethods lists the methods of Cmd the command sets inherit from the no-ops of the skipper,
+// it must be updated when a command set implements one of them.
+var skippedMethods = map[string][]string{
+	"star": {"ClockwiseRotation"},
+	"postscript": {
+		"ClockwiseRotation", "Cut", "Feed", "FullCut", "OpenCashDrawer", "UpsideDown",
+	},
+	"skipper": {
+		"AbsolutePosition", "Align", "Barcode", "BarcodeHeight", "BarcodeWidth", "Bold", "CharSize", "ClockwiseRotation",
+		"Cut", "Feed", "FullCut", "HRIFont", "HRIPosition", "Image", "Init", "LeftMargin", "LineFeed", "OpenCashDrawer",
+		"QRCode", "QRCodeCorrectionLevel", "QRCodeSize", "Tab", "TabPositions", "Underling", "UpsideDown", "WidthArea",
+	},
+}
+
+// barcodeSettings lists the methods of Cmd the command sets inherit from the skipper, which only record
+// the settings passed to the barcode function, see WithBarcodeRenderer.
+var barcodeSettings = map[string][]string{
+	"star":       {"HRIFont"},
+	"postscript": {"BarcodeHeight", "BarcodeWidth", "HRIFont", "HRIPosition"},
+}
+
+// cutMethods are the methods of Cmd and Cutter cutting the paper, which the printers without a cutter replace
+// with a feed to the tear bar, see QuirkNoCut.
+var cutMethods = []string{"Cut", "CutWithFeed", "FeedAndFullCut", "FullCut", "FullCutAtPosition", "PartialCutAtPosition"}
+
+// Describe reports which methods of Cmd and which capability interfaces the command set implements,
+// so an application can adapt the layout to the device, e.g. print a barcode when QR codes are not supported.
+// A Document records all commands, they are skipped when it is replayed on a command set that doesn't support them.
+//
+// Example Usage:
+//
+//	if r := thermalize.Describe(cmd); !r.Supports("QRCode") {
+//		log.Printf("QR codes are not supported by the %s printer, falling back to a barcode", r.Backend)
+//		cmd.Barcode(thermalize.Code128, ref)
+//	}
+func Describe(cmd Cmd) CapabilityReport {
+	r := CapabilityReport{Capability: Capabilities(cmd)}
+	var barCodeFunc, renderer, qrCodeFunc, noCut bool
+	switch c := cmd.(type) {
+	case *escape:
+		r.Backend, barCodeFunc, qrCodeFunc = "escape", true, c.qrCodeFunc != nil || c.quirks&QuirkNoQRCode == 0
+		noCut = c.quirks&QuirkNoCut != 0
+	case *star:
+		r.Backend, barCodeFunc, qrCodeFunc = "star", true, c.qrCodeFunc != nil || c.quirks&QuirkNoQRCode == 0
+		renderer = c.barCodeFunc != nil
+	case *postscript:
+		r.Backend, barCodeFunc, qrCodeFunc = "postscript", c.barCodeFunc != nil, c.qrCodeFunc != nil
+		renderer = c.barCodeFunc != nil
+	case *skipper:
+		r.Backend = "skipper"
+	case *Document:
+		r.Backend = "document"
+		return r
+	}
+
+	r.Skipped = append(r.Skipped, skippedMethods[r.Backend]...)
+	if r.Backend == "skipper" {
+		return r
+	}
+	if !renderer {
+		r.Skipped = append(r.Skipped, barcodeSettings[r.Backend]...)
+	}
+	if !barCodeFunc {
+		r.Skipped = append(r.Skipped, "Barcode")
+	}
+	if !qrCodeFunc {
+		r.Skipped = append(r.Skipped, "QRCode", "QRCodeSize", "QRCodeCorrectionLevel")
+	}
+	if noCut {
+		r.Skipped = append(r.Skipped, cutMethods...)
+	}
+	return r
+}
+
+// Supports reports whether the command set implements the method of Cmd or of a capability interface, e.g. "QRCode" or "Beep".
+func (r CapabilityReport) Supports(method string) bool {
+	for _, m := range r.Skipped {
+		if m == method {
+			return false
+		}
+	}
+	if supported, ok := capabilityOps[method]; ok {
+		return supported(r.Capability)
+	}
+	return true
+}
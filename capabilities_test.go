@@ -0,0 +1,227 @@
+package thermalize
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"image"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// skipperWorks are the methods of Cmd the skipper implements for all the command sets.
+var skipperWorks = map[string]bool{
+	"CPL": true, "CodePage": true, "PPL": true, "Print": true, "Sizing": true, "Text": true, "Write": true,
+}
+
+// sourceMethods parses the sources of the package and returns the methods of Cmd and the methods of each type.
+func sourceMethods(t *testing.T) ([]string, map[string]map[string]bool) {
+	t.Helper()
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cmd []string
+	methods := make(map[string]map[string]bool)
+	for _, f := range pkgs["thermalize"].Files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					continue
+				}
+				typ := d.Recv.List[0].Type
+				if star, ok := typ.(*ast.StarExpr); ok {
+					typ = star.X
+				}
+				if id, ok := typ.(*ast.Ident); ok {
+					if methods[id.Name] == nil {
+						methods[id.Name] = make(map[string]bool)
+					}
+					methods[id.Name][d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == "Cmd" {
+						for _, m := range ts.Type.(*ast.InterfaceType).Methods.List {
+							for _, name := range m.Names {
+								cmd = append(cmd, name.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return cmd, methods
+}
+
+// inherited returns the methods of Cmd the command set of the types inherits from the skipper,
+// except those the skipper implements for all of them.
+func inherited(cmd []string, methods map[string]map[string]bool, types ...string) map[string]bool {
+	got := make(map[string]bool)
+	for _, m := range cmd {
+		own := skipperWorks[m]
+		for _, typ := range types {
+			own = own || methods[typ][m]
+		}
+		if !own {
+			got[m] = true
+		}
+	}
+	return got
+}
+
+func checkSkipped(t *testing.T, name string, cmd Cmd, want map[string]bool) {
+	t.Helper()
+	got := make(map[string]bool)
+	for _, m := range Describe(cmd).Skipped {
+		if got[m] {
+			t.Errorf("%s: %s is skipped twice", name, m)
+		}
+		got[m] = true
+	}
+
+	var missing, extra []string
+	for m := range want {
+		if !got[m] {
+			missing = append(missing, m)
+		}
+	}
+	for m := range got {
+		if !want[m] {
+			extra = append(extra, m)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	if len(missing) > 0 || len(extra) > 0 {
+		t.Errorf("%s: the skipped methods lack %v and include %v", name, missing, extra)
+	}
+}
+
+func with(set map[string]bool, methods ...string) map[string]bool {
+	out := make(map[string]bool, len(set)+len(methods))
+	for m := range set {
+		out[m] = true
+	}
+	for _, m := range methods {
+		out[m] = true
+	}
+	return out
+}
+
+func without(set map[string]bool, methods ...string) map[string]bool {
+	out := with(set)
+	for _, m := range methods {
+		delete(out, m)
+	}
+	return out
+}
+
+func is[T any](cmd Cmd) bool {
+	_, ok := cmd.(T)
+	return ok
+}
+
+func TestDescribeSkipped(t *testing.T) {
+	cmd, methods := sourceMethods(t)
+	barcode := func(byte, string, BarcodeOptions) image.Image { return image.NewGray(image.Rect(0, 0, 8, 8)) }
+	qrCode := func(string, QRCodeOptions) image.Image { return image.NewGray(image.Rect(0, 0, 8, 8)) }
+
+	escape := inherited(cmd, methods, "escape", "lineMode")
+	checkSkipped(t, "escape", NewEscape(48, 576, io.Discard), escape)
+	checkSkipped(t, "escape QuirkNoQRCode", NewEscape(48, 576, io.Discard, WithQuirks(QuirkNoQRCode)),
+		with(escape, "QRCode", "QRCodeSize", "QRCodeCorrectionLevel"))
+	checkSkipped(t, "escape QuirkNoCut", NewEscape(48, 576, io.Discard, WithQuirks(QuirkNoCut)),
+		with(escape, cutMethods...))
+
+	star := inherited(cmd, methods, "star", "lineMode")
+	checkSkipped(t, "star", NewStar(48, 576, io.Discard), star)
+	checkSkipped(t, "star renderer", NewStar(48, 576, io.Discard, WithBarcodeRenderer(barcode)),
+		without(star, barcodeSettings["star"]...))
+
+	postscript := inherited(cmd, methods, "postscript")
+	checkSkipped(t, "postscript", NewPostscript(48, 576, io.Discard), with(postscript, "Barcode", "QRCode"))
+	checkSkipped(t, "postscript renderers",
+		NewPostscript(48, 576, io.Discard, WithBarcodeRenderer(barcode), WithQRCodeRenderer(qrCode)),
+		without(postscript, append(barcodeSettings["postscript"], "QRCodeSize", "QRCodeCorrectionLevel")...))
+
+	checkSkipped(t, "skipper", NewSkipper(48, 576, io.Discard), inherited(cmd, methods))
+}
+
+func TestCapabilitiesOptional(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cmd  Cmd
+		want Capability
+	}{
+		{"escape", NewEscape(48, 576, io.Discard), Capability{Inline: true, Watermark: true, Cut: true, Drawers: true}},
+		{"star", NewStar(48, 576, io.Discard), Capability{Inline: true, Watermark: true, Cut: true, Drawers: true}},
+		{"postscript", NewPostscript(48, 576, io.Discard), Capability{Inline: true, Watermark: true}},
+		{"skipper", NewSkipper(48, 576, io.Discard), Capability{}},
+		{"document", NewDocument(48, 576), Capability{Inline: true, Watermark: true, Cut: true, Drawers: true}},
+	} {
+		got := Capabilities(tc.cmd)
+		if got.Inline != tc.want.Inline || got.Watermark != tc.want.Watermark || got.Cut != tc.want.Cut ||
+			got.Drawers != tc.want.Drawers {
+			t.Errorf("%s: inline %v, watermark %v, cut %v, drawers %v, want %v, %v, %v, %v", tc.name,
+				got.Inline, got.Watermark, got.Cut, got.Drawers, tc.want.Inline, tc.want.Watermark, tc.want.Cut, tc.want.Drawers)
+		}
+
+		// The interfaces implemented by all command sets.
+		for name, ok := range map[string]bool{
+			"GrayLeveler":   is[GrayLeveler](tc.cmd),
+			"TriLiner":      is[TriLiner](tc.cmd),
+			"ErrorReporter": is[ErrorReporter](tc.cmd),
+			"RawWriter":     is[RawWriter](tc.cmd),
+			"Flusher":       is[Flusher](tc.cmd),
+			"TextFormatter": is[TextFormatter](tc.cmd),
+		} {
+			if !ok {
+				t.Errorf("%s doesn't implement %s", tc.name, name)
+			}
+		}
+	}
+
+	if Describe(NewEscape(48, 576, io.Discard, WithQuirks(QuirkNoCut))).Supports("CutWithFeed") {
+		t.Error("the escape command set with QuirkNoCut supports CutWithFeed")
+	}
+}
+
+// plainCmd implements only the methods of Cmd, like the command sets implemented outside of the package.
+type plainCmd struct {
+	Cmd
+}
+
+func TestDocumentReplayPlainCmd(t *testing.T) {
+	replay := func(cmd Cmd) {
+		doc := NewDocument(48, 576)
+		doc.Init()
+		doc.WriteString("raw string")
+		doc.WriteBytes([]byte{LF})
+		doc.Raw([]byte{ESC, 'a', 1})
+		doc.TextWrap("the quick brown fox jumps over the lazy dog near the bank of the river", nil)
+		doc.Paragraph("2 x Burger no onions, extra cheese and a side of fries", 2, 2)
+		doc.List([]string{"Burger", "Fries"})
+		doc.Print()
+		if err := doc.Replay(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var want, got bytes.Buffer
+	replay(NewEscape(48, 576, &want, WithBufferSize(64)))
+	replay(plainCmd{NewEscape(48, 576, &got, WithBufferSize(64))})
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("the command set without the capability interfaces wrote\n% x\nwant\n% x", got.Bytes(), want.Bytes())
+	}
+}
@@ -0,0 +1,76 @@
+package thermalize
+
+import "fmt"
+
+// Message identifies a string printed by the library itself, such as the label of the copies of a document.
+type Message int
+
+const (
+	MessageCopy         Message = iota // MessageCopy is the label of the copies printed by Document.Replay
+	MessageCutHere                     // MessageCutHere is printed in the rules of CouponBlock
+	MessageValidUntil                  // MessageValidUntil precedes the expiry date of a coupon
+	MessageImageTooTall                // MessageImageTooTall replaces the images taller than the postscript page, see TallImageMessage
+	MessageTestPage                    // MessageTestPage is the title of TestPage
+	MessageAlignment                   // MessageAlignment and the following messages are the sections of TestPage
+	MessageCharsPerLine
+	MessageCharSizes
+	MessageBarcodes
+	MessageGrayGradient
+	MessageLeft
+	MessageCenter
+	MessageRight
+	MessageBold
+	MessageUnderlined
+	MessageQRCode
+	MessageGrayLevel      // MessageGrayLevel formats the gray level, e.g. "gray level %d"
+	MessageCalibration    // MessageCalibration is the title of CalibrationPage
+	MessageCalibrationRow // MessageCalibrationRow formats the number, the gray level and the density of a swatch
+	MessagePageOf         // MessagePageOf formats the footer of the postscript pages, e.g. "page %d of %d"
+	MessageDigitalReceipt // MessageDigitalReceipt is printed above the QR code of DigitalReceipt
+)
+
+// Catalog holds the translations of the messages. The translations of the formatting messages,
+// such as MessageGrayLevel, take the same arguments in the same order.
+// An empty translation prints nothing, the messages missing from the catalog are printed in English.
+type Catalog map[Message]string
+
+// CatalogEnglish holds the messages in English.
+var CatalogEnglish = Catalog{
+	MessageCopy:           "COPY",
+	MessageCutHere:        "cut here",
+	MessageValidUntil:     "Valid until",
+	MessageImageTooTall:   "the height of the image is greater than the height of the page",
+	MessageTestPage:       "TEST PAGE",
+	MessageAlignment:      "Alignment",
+	MessageCharsPerLine:   "Characters per line",
+	MessageCharSizes:      "Character sizes",
+	MessageBarcodes:       "Barcodes",
+	MessageGrayGradient:   "Gray gradient",
+	MessageLeft:           "left",
+	MessageCenter:         "center",
+	MessageRight:          "right",
+	MessageBold:           "Bold",
+	MessageUnderlined:     "Underlined",
+	MessageQRCode:         "QR code",
+	MessageGrayLevel:      "gray level %d",
+	MessageCalibration:    "GRAY CALIBRATION",
+	MessageCalibrationRow: "#%d level %d density %+d",
+	MessagePageOf:         "page %d of %d",
+	MessageDigitalReceipt: "Scan for your digital receipt",
+}
+
+// Translate returns the message in the catalog set by WithCatalog, or in English if there is none,
+// formatted with the arguments if there are any.
+func Translate(cmd Cmd, m Message, args ...any) string {
+	s, ok := "", false
+	if c := skipperOf(cmd); c != nil && c.catalog != nil {
+		s, ok = c.catalog[m]
+	}
+	if !ok {
+		s = CatalogEnglish[m]
+	}
+	if len(args) > 0 && s != "" {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
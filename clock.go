@@ -0,0 +1,127 @@
+package thermalize
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ClockProfile holds the vendor commands of the real-time clock of a printer, such as a fiscal printer
+// stamping the receipts with the time of its own clock. The commands differ between the vendors and the models,
+// so they are registered for the model with RegisterClockProfile and selected with WithProfile or WithClockProfile.
+//
+// Example Usage, with the commands of a hypothetical model:
+//
+//	thermalize.RegisterClockProfile("fp-80", thermalize.ClockProfile{
+//		Set: func(t time.Time) []byte {
+//			return append([]byte{thermalize.GS, 'T'}, t.Format("060102150405")...)
+//		},
+//		Read:  []byte{thermalize.GS, 't'},
+//		Parse: func(reply []byte) (time.Time, error) { return time.Parse("060102150405", string(reply)) },
+//		Print: []byte{thermalize.GS, 'p'},
+//	})
+//	cmd := thermalize.NewEscape(48, 576, conn, thermalize.WithProfile("fp-80"))
+type ClockProfile struct {
+	// Set returns the command setting the clock to the time.
+	Set func(t time.Time) []byte
+
+	// Read is the command requesting the transmission of the time of the clock, Parse parses the reply.
+	Read  []byte
+	Parse func(reply []byte) (time.Time, error)
+
+	// Print is the command printing the time of the clock at the current position.
+	Print []byte
+}
+
+// ErrNoClock is returned by ParseClock if the profile can't parse the reply.
+var ErrNoClock = errors.New("thermalize: no clock reply parser")
+
+// ParseClock parses the reply of the printer to RequestClock with the profile.
+func (p ClockProfile) ParseClock(reply []byte) (time.Time, error) {
+	if p.Parse == nil {
+		return time.Time{}, ErrNoClock
+	}
+	return p.Parse(reply)
+}
+
+var clockProfiles = struct {
+	sync.RWMutex
+	m map[string]ClockProfile
+}{m: make(map[string]ClockProfile)}
+
+// RegisterClockProfile registers the clock commands of the printer model, which are selected with WithProfile.
+func RegisterClockProfile(name string, p ClockProfile) {
+	clockProfiles.Lock()
+	clockProfiles.m[name] = p
+	clockProfiles.Unlock()
+}
+
+// LookupClockProfile returns the clock commands of the printer model registered with the name.
+func LookupClockProfile(name string) (ClockProfile, bool) {
+	clockProfiles.RLock()
+	defer clockProfiles.RUnlock()
+
+	p, ok := clockProfiles.m[name]
+	return p, ok
+}
+
+type clockProfileOption ClockProfile
+
+func (cpo clockProfileOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		p := ClockProfile(cpo)
+		c.clock = &p
+	}
+}
+
+// WithClockProfile sets the clock commands of the printer used by ClockPrinter.
+func WithClockProfile(p ClockProfile) Options {
+	return clockProfileOption(p)
+}
+
+// clockCommand reports whether the clock profile has the command checked by fn,
+// otherwise the command is reported through Err.
+func (c *skipper) clockCommand(command string, fn func(p *ClockProfile) bool) bool {
+	if c.clock == nil || !fn(c.clock) {
+		c.fail(command, "the clock profile lacks the command, see WithClockProfile")
+		return false
+	}
+	return true
+}
+
+func (c *skipper) setClock(t time.Time) {
+	if c.clockCommand("SetClock", func(p *ClockProfile) bool { return p.Set != nil }) {
+		c.WriteBytes(c.clock.Set(t))
+	}
+}
+
+func (c *skipper) requestClock() {
+	if c.clockCommand("RequestClock", func(p *ClockProfile) bool { return len(p.Read) > 0 }) {
+		c.WriteBytes(c.clock.Read)
+		c.Flush()
+	}
+}
+
+func (c *skipper) printClock() {
+	if c.clockCommand("PrintClock", func(p *ClockProfile) bool { return len(p.Print) > 0 }) {
+		c.WriteBytes(c.clock.Print)
+	}
+}
+
+// Timestamp prints the time of the clock of the printer, if the command set implements ClockPrinter
+// and its clock profile has the print command, otherwise the current time formatted with the layout.
+// Where the regulation requires the time of the device, use ClockPrinter.PrintClock, which reports
+// the missing command through Err instead. A Document records the timestamp, which is resolved when it is replayed.
+func Timestamp(cmd Cmd, layout string) {
+	if d, ok := cmd.(*Document); ok {
+		d.record("Timestamp", func(c Cmd) { Timestamp(c, layout) }, layout)
+		return
+	}
+	if p, ok := cmd.(ClockPrinter); ok {
+		if c := skipperOf(cmd); c != nil && c.clock != nil && len(c.clock.Print) > 0 {
+			p.PrintClock()
+			return
+		}
+	}
+	cmd.Text(time.Now().Format(layout), nil)
+}
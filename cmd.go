@@ -5,7 +5,9 @@ import (
 )
 
 type Cmd interface {
-	// Sizing sets the number of characters per line (CPL) and pixels per line (PPL) after the command set has been initialized.
+	// Sizing sets the number of characters per line (CPL) and pixels per line (PPL) after the command set has been initialized,
+	// e.g. after switching to a smaller font. The word wrapping and the layout of the command set follow the new sizing,
+	// see WithResizeTabs and WithResizeHook.
 	Sizing(cpl, ppl int)
 
 	// CPL returns the set number of characters per line.
@@ -15,7 +17,8 @@ type Cmd interface {
 	PPL() int
 
 	// Write writes raw bytes.
-	// If a writer is not provided or an error occurs during writing, it will panic.
+	// If a writer is not provided, it will panic. If an error occurs during writing, the following writes are skipped
+	// and the error is returned by Print.
 	Write(bs ...byte)
 
 	// Text adds printable string along with encoding, if an encoder is provided.
@@ -23,7 +26,9 @@ type Cmd interface {
 	// Since Golang uses UTF-8 character encoding by default, you must provide an encoder
 	// to convert the string according to the specified code page.
 	//
-	// If the encoder is not provided, the text will be printed using the default UTF-8 encoding,
+	// If the encoder is not provided, the encoder of the selected code page is used (see RegisterCodePage and WithCodePage),
+	// in strict mode the runes it can't encode are reported.
+	// If there is none, the text will be printed using the default UTF-8 encoding,
 	// which may result in incorrect printing.
 	Text(s string, enc func(string) []byte)
 
@@ -59,6 +64,8 @@ type Cmd interface {
 	Tab()
 
 	// CodePage selects character code table.
+	// Text uses the encoder registered for the code page by default (see RegisterCodePage).
+	// The symbolic code pages, such as CodePageCP866, are mapped to the code of the command set.
 	CodePage(b byte)
 
 	// CharSize selects character width and height.
@@ -144,14 +151,19 @@ type Cmd interface {
 	LineFeed()
 
 	// Cut executes the auto-cutter.
+	// The modes differ between the command sets, the methods of Cutter cut the same way with all of them.
 	Cut(m byte, p byte)
 
 	// FullCut executes the auto-cutter across the full width of the paper.
+	// It keeps the feed of the earlier versions, which differs between the command sets, use Cutter.FeedAndFullCut instead.
 	FullCut()
 
 	// OpenCashDrawer generates pulse to open a cache drawer.
 	OpenCashDrawer(m byte, t1 byte, t2 byte)
 
-	// Print performs final preparation of the document before printing.
-	Print()
+	// Print performs final preparation of the document before printing, flushes the buffered data
+	// and returns the first error recorded by the command set (see ErrorReporter).
+	// If WithCompletion is used and the printer can be read, Print waits until the printer confirms
+	// that the job has been printed.
+	Print() error
 }
@@ -1,8 +1,10 @@
 package thermalize
 
 import (
+	"fmt"
 	"image"
 	"io"
+	"time"
 )
 
 // NewEscape returns the most popular set of printer commands for the given configuration.
@@ -18,10 +20,40 @@ import (
 // Options:
 // You can customize various aspects of the postscript command set using the following options:
 //   - WithBarCodeFunc(barCodeFunc): sets a custom function for generating barcodes.
+//   - WithBarcodeRenderer(fn): sets a custom function for generating barcodes following their settings.
+//   - WithQRCodeRenderer(fn): sets a custom function for generating QR codes following their settings.
+//   - WithDPI(n): sets the resolution of the printer passed to the barcode and QR code renderers.
 //   - WithQRCodeFunc(qrCodeFunc): sets a custom function for generating QR codes.
+//   - WithStrict(): validates all parameters and reports violations through Err instead of clamping them.
+//   - WithBufferSize(n): buffers up to n bytes of output, which is flushed by Print, Cut and Flush.
+//   - WithBuffer(): accumulates the output in an internal buffer returned by Bytes (see Buffered).
+//   - WithImageCache(size): caches up to size bytes of converted images shared by all command sets.
+//   - WithGrayLevel(l): sets the level of gray printed as black by the command set.
+//   - WithAlphaBlending(bg): composites the translucent pixels of the images on the background.
+//   - WithBandFeed(n): sets the paper feed after each band of the images.
+//   - WithImagePadding(): pads narrow images according to the alignment.
+//   - WithCodePage(page, enc): selects the code page on Init and encodes text with enc by default.
+//   - WithEncoder(enc): selects the code page of the encoder on Init and encodes text with it by default.
+//   - WithCurrencySymbols(): substitutes the currency symbols the code page lacks with their ISO 4217 codes.
+//   - WithCatalog(c): translates the messages printed by the library.
+//   - WithMetrics(m): updates the metrics of the printed documents, writes and errors.
+//   - WithBarcodeTypeMap(types): overrides the barcode type codes sent to the printer.
+//   - WithTabStops(every): sets a tab stop every n characters on Init.
+//   - WithResizeTabs(): sets the default tab stops within the new line on Sizing.
+//   - WithWideFormat(): sets the command set up for the 112 mm wide-format printers (see WideCPL).
+//   - WithResizeHook(fn): calls fn with the new sizing on Sizing.
+//   - WithCompletion(timeout): makes Print wait until the printer confirms the job.
+//   - WithConstrainedDevice(): behaves like a constrained device, so the fallback paths are tested.
+//   - WithProfile(name), WithQuirks(q): works around the quirks of the ESC/POS clones.
+//   - WithDrawerProfile(p): sets the pulse used by OpenBothDrawers.
+//   - WithClockProfile(p): sets the commands of the real-time clock of the printer (see ClockPrinter).
+//   - WithSlipWait(insert, delay): waits for the slip insertion each time the slip is selected.
 //   - WithImageFuncVersion(n): switches the image printing function, where:
 //   - n = 1: uses the [GS 8 L ... GS ( L] print image command.
 //   - n = 2: uses the [ESC * ! ... ESC J] print image command.
+//   - WithBitImageMode(m): sets the density of the images printed with [ESC * m ... ESC J].
+//   - WithRasterBufferSize(n): limits the graphics data stored by [GS 8 L] at once to n bytes,
+//     splitting tall images into several store and print cycles.
 //
 // Note: By default, the obsolete [GS v ...] print image command is used.
 //
@@ -32,8 +64,9 @@ import (
 // In this example, a new escape sequence command set is created with 48 characters per line,
 // 576 pixels per line. The image printing function is set to use the [ESC * ! ... ESC J] command sequence (version 2).
 func NewEscape(cpl, ppl int, w io.Writer, opts ...Options) Cmd {
-	cmd := &escape{Cmd: NewSkipper(cpl, ppl, w)}
+	cmd := &escape{lineMode: newLineMode(cpl, ppl, w, escapeDialect), bitImageMode: BitImage24Double}
 	cmd.imageFunc = cmd.imageObsolete
+	cmd.onResize(cmd.resize)
 	for _, opt := range opts {
 		opt.apply(cmd)
 	}
@@ -41,36 +74,150 @@ func NewEscape(cpl, ppl int, w io.Writer, opts ...Options) Cmd {
 }
 
 type escape struct {
-	Cmd
+	lineMode
 
-	barCodeFunc func(byte, string) image.Image
-	qrCodeFunc  func(string) image.Image
-	imageFunc   func(image.Image, bool)
+	imageFunc func(image.Image, bool)
+
+	// bitImageMode is the mode of the bit images printed by [ESC * m] (see WithBitImageMode).
+	bitImageMode byte
+
+	// jobID is the process ID requested by the last Print, if WithCompletion is used.
+	jobID int
+
+	// rasterLimit is the maximum size of the graphics data stored in the print buffer at once.
+	rasterLimit int
+
+	// charW and charH are the character size set by CharSize, rotated is set in 90° clockwise rotation mode.
+	charW, charH byte
+	rotated      bool
+
+	// glyphs is set once the glyphs of GlyphPrinter are defined as user-defined characters.
+	glyphs bool
+
+	// slipWait is set by WithSlipWait, slipInsert and slipDelay are the arguments of SlipWait.
+	slipWait              bool
+	slipInsert, slipDelay byte
+}
+
+// Raw validates the commands in strict mode before writing them.
+func (c *escape) Raw(bs []byte) {
+	if c.strict {
+		if reason, ok := checkEscape(bs); !ok {
+			c.invalid("Raw", "%s", reason)
+			return
+		}
+	}
+	c.WriteBytes(bs)
+}
+
+func (c *escape) TextWrap(s string, enc func(string) []byte) {
+	c.lines(paragraph(s, c.wrapWidth(), 0, 0, c.justify), enc)
+}
+
+func (c *escape) Paragraph(s string, indent, hang int) {
+	if (indent < 0 || hang < 0 || indent+hang >= c.wrapWidth()) &&
+		c.invalid("Paragraph", "indent %d and hang %d are out of range [0, %d)", indent, hang, c.wrapWidth()) {
+		return
+	}
+	c.lines(paragraph(s, c.wrapWidth(), indent, hang, c.justify), nil)
+}
+
+func (c *escape) List(items []string, opts ...ListOption) {
+	c.lines(listLines(items, c.wrapWidth(), c.justify, opts), nil)
+}
+
+func (c *escape) TriLine(left, center, right string) {
+	printTriLine(c, left, center, right)
+}
+
+// lines prints each line followed by a line feed.
+func (c *escape) lines(ls []string, enc func(string) []byte) {
+	for _, l := range ls {
+		c.Text(l, enc)
+		c.LineFeed()
+	}
 }
 
 func (c *escape) Init() {
+	c.justify, c.align = false, Left
+	c.charW, c.charH, c.rotated = 0, 0, false
+	c.glyphs = false
+	c.barcode, c.qrcode = defaultBarcode, defaultQRCode
+	c.resetLine()
 	c.Write(ESC, '@')
+	if page, ok := c.defaultCodePage(); ok {
+		c.CodePage(page)
+	}
+	if c.tabStops > 0 {
+		c.TabPositions(c.defaultTabStops(32)...)
+	}
+}
+
+// resize sets the default tab stops within the new line, if WithResizeTabs is used.
+func (c *escape) resize(int, int) {
+	if c.resizeTabs {
+		c.TabPositions(c.defaultTabStops(32)...)
+	}
 }
 
 func (c *escape) LeftMargin(n int) {
-	if n < c.PPL() {
-		c.Write(GS, 'L', byte(n), byte(n>>8))
+	if n < 0 || n >= c.PPL() {
+		c.invalid("LeftMargin", "%d is out of range [0, %d)", n, c.PPL())
+		return
+	}
+	c.left = n
+	c.writeMargin()
+}
+
+// Indent shifts the left margin by the indentation, see TextFormatter.Indent.
+func (c *escape) Indent(n int) {
+	if c.pushIndent(n, c.lineChars()) {
+		c.writeMargin()
+	}
+}
+
+func (c *escape) Outdent() {
+	if c.popIndent() {
+		c.writeMargin()
 	}
 }
 
+// writeMargin sets the left margin of the printer to the left margin plus the indentation.
+func (c *escape) writeMargin() {
+	n := c.left + c.indentDots()
+	c.Write(GS, 'L', byte(n), byte(n>>8))
+}
+
 func (c *escape) WidthArea(n int) {
-	if n <= c.PPL() {
-		c.Write(GS, 'W', byte(n), byte(n>>8))
+	if n < 0 || n > c.PPL() {
+		c.invalid("WidthArea", "%d is out of range [0, %d]", n, c.PPL())
+		return
 	}
+	c.area = n
+	c.Write(GS, 'W', byte(n), byte(n>>8))
 }
 
 func (c *escape) AbsolutePosition(n int) {
-	if n < c.PPL() {
-		c.Write(ESC, '$', byte(n), byte(n>>8))
+	if n < 0 || n >= c.PPL() {
+		c.invalid("AbsolutePosition", "%d is out of range [0, %d)", n, c.PPL())
+		return
+	}
+	c.col = n
+	c.Write(ESC, '$', byte(n), byte(n>>8))
+}
+
+func (c *escape) RightAt(col int, s string) {
+	if x, ok := c.rightAt(col, s); ok {
+		c.AbsolutePosition(x)
+		c.Text(s, nil)
 	}
 }
 
 func (c *escape) Align(b byte) {
+	if b > 2 && c.invalid("Align", "%d is out of range [0, 2]", b) {
+		return
+	}
+	c.align = minByte(b, 2)
 	c.Write(ESC, 'a', minByte(b, 2))
 }
 
@@ -82,37 +229,19 @@ func (c *escape) UpsideDown(b bool) {
 	c.Write(ESC, '{', 0)
 }
 
-// TabPositions maximum of 32 horizontal tabs can be set.
-func (c *escape) TabPositions(bs ...byte) {
-	l := len(bs)
-	if l == 0 {
+func (c *escape) CodePage(b byte) {
+	b, ok := c.supportedCodePage(escapeCodePages, b)
+	if !ok {
 		return
-	} else if l > 32 {
-		bs = bs[:32]
 	}
-
-	buf := make([]byte, 0, 3+l)
-	buf = append(buf, ESC, 'D')
-
-	var previous byte
-	for _, n := range bs {
-		if n <= previous {
-			continue
-		}
-		buf = append(buf, n)
-		previous = n
+	n, ok := nativeCodePage(escapeCodePages, b)
+	if !ok {
+		c.invalid("CodePage", "symbolic code page %#x is not supported", b)
+		return
 	}
-
-	buf = append(buf, NUL)
-	c.Write(buf...)
-}
-
-func (c *escape) Tab() {
-	c.Write(HT)
-}
-
-func (c *escape) CodePage(b byte) {
-	c.Write(ESC, 't', b)
+	c.Write(ESC, 't', n)
+	c.selectEncoder(b)
+	c.selectTable(escapeCodePages, n)
 }
 
 // CharSize
@@ -120,7 +249,22 @@ func (c *escape) CodePage(b byte) {
 //	w: character width (0 - x1 `normal`, 1 - x2, 2 - x3, 3 - x4, 4 - x5, 5 - x6, 6 - x7, 7 - x8)
 //	h: character height (0 - x1 `normal`, 1 - x2, 2 - x3, 3 - x4, 4 - x5, 5 - x6, 6 - x7, 7 - x8)
 func (c *escape) CharSize(w, h byte) {
-	c.Write(GS, '!', minByte(w, 7)<<4+minByte(h, 7))
+	if (w > 7 || h > 7) && c.invalid("CharSize", "%dx%d is out of range [0, 7]", w, h) {
+		return
+	}
+	c.charW, c.charH = minByte(w, 7), minByte(h, 7)
+	c.sizeX = int(c.charW) + 1
+	c.writeCharSize()
+}
+
+// writeCharSize selects the character size, w is always the horizontal magnification on the paper,
+// so the alignment and the tab stops are computed the same way in 90° clockwise rotation mode.
+func (c *escape) writeCharSize() {
+	w, h := c.charW, c.charH
+	if c.rotated && c.quirks&QuirkRotationKeepsSize == 0 {
+		w, h = h, w
+	}
+	c.Write(GS, '!', w<<4+h)
 }
 
 func (c *escape) Bold(b bool) {
@@ -131,15 +275,32 @@ func (c *escape) Bold(b bool) {
 	c.Write(ESC, 'E', 0)
 }
 
+// ClockwiseRotation [ESC V] selects the character size again if the rotation changes the directions
+// the printer enlarges the characters in, see QuirkRotationKeepsSize.
 func (c *escape) ClockwiseRotation(b bool) {
 	if b {
 		c.Write(ESC, 'V', 1)
+	} else {
+		c.Write(ESC, 'V', 0)
+	}
+	if swap(&c.rotated, b) && c.charW != c.charH && c.quirks&QuirkRotationKeepsSize == 0 {
+		c.writeCharSize()
+	}
+}
+
+// Smoothing [GS b].
+func (c *escape) Smoothing(b bool) {
+	if b {
+		c.Write(GS, 'b', 1)
 		return
 	}
-	c.Write(ESC, 'V', 0)
+	c.Write(GS, 'b', 0)
 }
 
 func (c *escape) Underling(b byte) {
+	if b > 2 && c.invalid("Underling", "%d is out of range [0, 2]", b) {
+		return
+	}
 	c.Write(ESC, '-', minByte(b, 2))
 }
 
@@ -147,55 +308,128 @@ func (c *escape) Underling(b byte) {
 //
 //	1 <= b <= 6.
 func (c *escape) BarcodeWidth(b byte) {
-	b = maxByte(b, 1)
-	c.Write(GS, 'w', minByte(b, 6))
+	if b, ok := c.checkBarcodeWidth(b); ok {
+		c.barcode.Width = b
+		c.Write(GS, 'w', b)
+	}
 }
 
 func (c *escape) BarcodeHeight(b byte) {
-	c.Write(GS, 'h', maxByte(b, 1))
+	if b < 1 && c.invalid("BarcodeHeight", "%d is out of range [1, 255]", b) {
+		return
+	}
+	c.barcode.Height = maxByte(b, 1)
+	c.Write(GS, 'h', c.barcode.Height)
 }
 
 func (c *escape) HRIFont(b byte) {
-	c.Write(GS, 'f', minByte(b, 1))
+	if b > 1 && c.invalid("HRIFont", "%d is out of range [0, 1]", b) {
+		return
+	}
+	c.barcode.HRIFont = minByte(b, 1)
+	c.Write(GS, 'f', c.barcode.HRIFont)
 }
 
 func (c *escape) HRIPosition(b byte) {
-	c.Write(GS, 'H', minByte(b, 3))
+	if b > 3 && c.invalid("HRIPosition", "%d is out of range [0, 3]", b) {
+		return
+	}
+	c.barcode.HRIPosition = minByte(b, 3)
+	c.Write(GS, 'H', c.barcode.HRIPosition)
 }
 
 func (c *escape) Barcode(m byte, s string) {
-	l := len(s)
-	if l == 0 {
+	code, ok := c.checkBarcode(m, s)
+	if !ok {
 		return
-	}
-
-	if c.barCodeFunc != nil {
-		code := c.barCodeFunc(m, s)
+	} else if code != nil {
 		c.Image(code, false)
 		return
 	}
 
-	c.Write(GS, 'k', c.barcodeType(m), byte(l))
+	c.Write(GS, 'k', c.barcodeType(m), byte(len(s)))
 	c.Text(s, nil)
+	c.newLine()
+}
+
+// PrintDensity (GS ( K fn = 49) adjusts the print density by n steps.
+//
+//	-6 <= n <= 6.
+func (c *escape) PrintDensity(n int) {
+	if n, ok := c.checkDensity(n); ok {
+		c.Write(GS, '(', 'K', 2, 0, 49, byte(int8(n)))
+	}
+}
+
+// QRCodeModel (cn = 49, fn = 65) selects QRModel1, QRModel2 or QRMicro.
+func (c *escape) QRCodeModel(b byte) {
+	if b > QRMicro && c.invalid("QRCodeModel", "%d is out of range [0, 2]", b) {
+		return
+	}
+	if c.quirks&QuirkNoQRCode != 0 {
+		return
+	}
+	c.Write(GS, '(', 'k', 4, 0, 49, 65, 49+minByte(b, QRMicro), 0)
 }
 
 // QRCodeSize (cn = 49, fn = 67).
 //
 //	1 <= b <= 16.
 func (c *escape) QRCodeSize(b byte) {
-	b = maxByte(b, 1)
-	c.Write(GS, '(', 'k', 3, 0, 49, 67, minByte(b, 16))
+	if (b < 1 || b > 16) && c.invalid("QRCodeSize", "%d is out of range [1, 16]", b) {
+		return
+	}
+	c.qrcode.Size = clamp(b, 1, 16)
+	if c.quirks&QuirkNoQRCode != 0 {
+		return
+	}
+	c.Write(GS, '(', 'k', 3, 0, 49, 67, clamp(b, 1, 16))
 }
 
 // QRCodeCorrectionLevel (cn = 49, fn = 69).
 func (c *escape) QRCodeCorrectionLevel(b byte) {
+	if b > 3 && c.invalid("QRCodeCorrectionLevel", "%d is out of range [0, 3]", b) {
+		return
+	}
+	c.qrcode.CorrectionLevel = minByte(b, 3)
+	if c.quirks&QuirkNoQRCode != 0 {
+		return
+	}
 	b += 48
 	c.Write(GS, '(', 'k', 3, 0, 49, 69, b)
 }
 
+// QRCodeVersion locks the version of the QR codes, 0 selects the smallest version holding the data.
+//
+//	0 <= v <= 40.
+//
+// The command set defines no function code locking the version, the QR codes of a locked version or mask
+// are printed as images by the function set with WithQRCodeRenderer, which receives them in QRCodeOptions.
+func (c *escape) QRCodeVersion(v byte) {
+	if v > 40 && c.invalid("QRCodeVersion", "%d is out of range [0, 40]", v) {
+		return
+	}
+	c.qrcode.Version = minByte(v, 40)
+}
+
+// QRCodeMask locks the mask pattern of the QR codes, QRMaskAuto selects the pattern for the data.
+//
+//	0 <= m <= 7 or m = QRMaskAuto.
+//
+// As with QRCodeVersion, the QR codes of a locked mask are printed by the function set with WithQRCodeRenderer.
+func (c *escape) QRCodeMask(m byte) {
+	if m > QRMaskAuto && c.invalid("QRCodeMask", "%d is out of range [0, 8]", m) {
+		return
+	}
+	c.qrcode.Mask = minByte(m, QRMaskAuto)
+}
+
 func (c *escape) QRCode(s string) {
 	l := len(s)
 	if l == 0 {
+		c.invalid("QRCode", "empty data")
+		return
+	} else if l > 7089 && c.invalid("QRCode", "%d bytes exceed the maximum of 7089", l) {
 		return
 	}
 
@@ -203,10 +437,18 @@ func (c *escape) QRCode(s string) {
 	h, w := byte(l), byte(l>>8)
 
 	if c.qrCodeFunc != nil {
-		code := c.qrCodeFunc(s)
+		code := c.qrCodeFunc(s, c.qrCodeOptions())
 		c.Image(code, false)
 		return
 	}
+	if c.quirks&QuirkNoQRCode != 0 {
+		c.fail("QRCode", "QR codes are not supported by the printer, see WithQRCodeFunc")
+		return
+	}
+	if c.qrcode.Version != 0 || c.qrcode.Mask != QRMaskAuto {
+		c.fail("QRCode", "the printer can't lock the version or the mask of QR codes, see WithQRCodeRenderer")
+		return
+	}
 
 	// Store the data in the symbol storage area (cn = 49, fn = 80).
 	c.Write(GS, '(', 'k', h, w, 49, 80, 48)
@@ -214,61 +456,106 @@ func (c *escape) QRCode(s string) {
 
 	// Print the symbol data in the symbol storage area (cn = 49, fn = 81).
 	c.Write(GS, '(', 'k', 3, 0, 49, 81, 48)
+	c.newLine()
 }
 
 func (c *escape) Image(img image.Image, invert bool) {
-	c.imageFunc(img, invert)
+	if img == nil {
+		c.invalid("Image", "nil image")
+		return
+	}
+	if w := img.Bounds().Dx(); w > c.PPL() && c.invalid("Image", "width %d exceeds %d pixels per line", w, c.PPL()) {
+		return
+	}
+	c.imageFunc(c.padImage(img, invert), invert)
+	c.newLine()
+}
+
+// InlineImage adds the image to the line buffer as a 24-dot bit image [ESC * 33],
+// which is printed along with the text of the line. Images taller than the line,
+// which is 24 dots high in font A, are scaled down to fit it.
+func (c *escape) InlineImage(img image.Image, valign byte) {
+	if img == nil {
+		c.invalid("InlineImage", "nil image")
+		return
+	}
+	if valign > VAlignBottom && c.invalid("InlineImage", "vertical alignment %d is out of range [0, 2]", valign) {
+		return
+	}
+
+	band := inlineBand(c.blendImage(img), minByte(valign, VAlignBottom))
+	if w := band.Bounds().Dx(); w > c.PPL() && c.invalid("InlineImage", "width %d exceeds %d pixels per line", w, c.PPL()) {
+		return
+	}
+
+	buf := rasterPool.Get().(*[]byte)
+	defer rasterPool.Put(buf)
+
+	w, bs := formatBin.convert(buf, band, false, c.imageLevel())
+	c.Write(ESC, '*', BitImage24Single|c.bitImageMode&1, byte(w), byte(w>>8))
+	c.WriteBytes(bs)
+	c.col += w
 }
 
 func (c *escape) imageV1(img image.Image, invert bool) {
-	w, bs := ImageToBit(img, invert)
+	buf := rasterPool.Get().(*[]byte)
+	defer rasterPool.Put(buf)
+
+	w, bs := c.convertImage(buf, img, invert, formatBit)
 
 	l := len(bs)
 	if l == 0 {
 		return
 	}
 
-	p := 10 + l
-	p1, p2, p3, p4 := byte(p), byte(p>>8), byte(p>>16), byte(p>>24)
-
 	var bx, by byte = 1, 1
 
 	x := w * 8
 	xl, xh := byte(x), byte(x>>8)
 
-	y := l / w
-	yl, yh := byte(y), byte(y>>8)
+	// If the printer buffer is limited, the image is split into bands of rows that fit into it.
+	block := l
+	if c.rasterLimit > 0 {
+		block = maxByte(c.rasterLimit/w, 1) * w
+	}
 
-	// Store the graphics data in the print buffer (fn = 112).
-	c.Write(GS, '8', 'L', p1, p2, p3, p4, 48, 112, 48, bx, by, 49, xl, xh, yl, yh)
-	c.Write(bs...)
+	bands(l, block, func(start, end int) {
+		p := 10 + end - start
+		p1, p2, p3, p4 := byte(p), byte(p>>8), byte(p>>16), byte(p>>24)
 
-	// Print the graphics data in the print buffer (fn = 2, 50).
-	c.Write(GS, '(', 'L', 2, 0, 48, 2)
-}
+		y := (end - start) / w
+		yl, yh := byte(y), byte(y>>8)
 
-func (c *escape) imageV2(img image.Image, invert bool) {
-	w, bs := ImageToBin(img, invert)
+		// Store the graphics data in the print buffer (fn = 112).
+		c.Write(GS, '8', 'L', p1, p2, p3, p4, 48, 112, 48, bx, by, 49, xl, xh, yl, yh)
+		c.WriteBytes(bs[start:end])
 
-	xl, xh := byte(w), byte(w>>8)
+		// Print the graphics data in the print buffer (fn = 2, 50).
+		c.Write(GS, '(', 'L', 2, 0, 48, 2)
+	})
+}
 
-	l := len(bs)
-	block := w * 3
-	start := 0
+func (c *escape) imageV2(img image.Image, invert bool) {
+	buf := rasterPool.Get().(*[]byte)
+	defer rasterPool.Put(buf)
 
-	for end := block; start < l; end += block {
-		end = minByte(end, l)
+	// The 8-dot modes print bands of 8 rows, each column of a band is one byte.
+	format, k := formatBin, 3
+	if c.bitImageMode < BitImage24Single {
+		format, k = formatBin8, 1
+	}
 
-		c.Write(ESC, '*', '!', xl, xh)
-		c.Write(bs[start:end]...)
-		c.Write(ESC, 'J', 24)
+	w, bs := c.convertImage(buf, img, invert, format)
 
-		start = end
-	}
+	feed, last := c.bandFeeds(byte(8*k), img.Bounds().Dy(), 8*k)
+	c.writeBands(bs, w*k, []byte{ESC, '*', c.bitImageMode, byte(w), byte(w >> 8)}, feed, last)
 }
 
 func (c *escape) imageObsolete(img image.Image, invert bool) {
-	w, bs := ImageToBit(img, invert)
+	buf := rasterPool.Get().(*[]byte)
+	defer rasterPool.Put(buf)
+
+	w, bs := c.convertImage(buf, img, invert, formatBit)
 
 	l := len(bs)
 	if l == 0 {
@@ -278,16 +565,28 @@ func (c *escape) imageObsolete(img image.Image, invert bool) {
 	h := l / w
 
 	c.Write(GS, 'v', 0, 0, byte(w), byte(w>>8), byte(h), byte(h>>8))
-	c.Write(bs...)
+	c.WriteBytes(bs)
+	if c.quirks&QuirkRasterNUL != 0 {
+		c.Write(NUL)
+	}
+}
+
+// Watermark prints the watermark as a light raster band, if WithWatermarkBand is used.
+func (c *escape) Watermark(s string, opts ...WatermarkOption) {
+	if w := newWatermark(s, opts); w.band && s != "" {
+		c.Image(w.raster(c.PPL()), false)
+	}
 }
 
 func (c *escape) Feed(b byte) {
 	if b > 0 {
+		c.newLine()
 		c.Write(ESC, 'J', b)
 	}
 }
 
 func (c *escape) LineFeed() {
+	c.newLine()
 	c.Write(LF)
 }
 
@@ -296,36 +595,346 @@ func (c *escape) LineFeed() {
 //	m = 0  | 1  - cuts paper;
 //	m = 65 | 66 - feeds paper to  (cutting position + [p x (vertical motion unit)]) and cuts the paper;
 func (c *escape) Cut(m, p byte) {
-	switch m {
-	case 0, 1:
+	switch {
+	case m != 0 && m != 1 && m != 65 && m != 66:
+		c.invalid("Cut", "unknown mode %d", m)
+	case c.quirks&QuirkNoCut != 0:
+		c.Write(ESC, 'J', tearFeed)
+	case m <= 1:
 		c.Write(GS, 'V', m)
-	case 65, 66:
+	default:
 		c.Write(GS, 'V', m, p)
 	}
+	c.newLine()
+	c.Flush()
 }
 
+// FullCut feeds the paper to the cutting position and 10 vertical motion units further, then cuts it [GS V 65 10].
 func (c *escape) FullCut() {
 	c.Cut(65, 10)
 }
 
+// FullCutAtPosition cuts the paper at the current position [GS V 0].
+func (c *escape) FullCutAtPosition() {
+	c.Cut(0, 0)
+}
+
+// PartialCutAtPosition cuts the paper partially at the current position [GS V 1].
+func (c *escape) PartialCutAtPosition() {
+	c.Cut(1, 0)
+}
+
+// FeedAndFullCut feeds the paper to the cutting position and p/4 mm further, then cuts it [GS V 65 n].
+func (c *escape) FeedAndFullCut(p byte) {
+	c.CutWithFeed(false, float64(p)/4)
+}
+
+// CutWithFeed feeds the paper to the cutting position and mm millimeters further, then cuts it [GS V 65|66 n].
+// The feed is measured in the vertical motion unit of 203 dpi printers, 1/8 mm, up to 31.875 mm.
+func (c *escape) CutWithFeed(partial bool, mm float64) {
+	n, ok := c.feedUnits("CutWithFeed", mm, 8)
+	if !ok {
+		return
+	}
+	if partial {
+		c.Cut(66, n)
+		return
+	}
+	c.Cut(65, n)
+}
+
+// feedMM feeds the paper mm millimeters in the vertical motion unit of CutWithFeed [ESC J n].
+func (c *escape) feedMM(mm float64) {
+	if n, ok := c.feedUnits("Feed", mm, 8); ok {
+		c.Feed(n)
+	}
+}
+
 // OpenCashDrawer
 //
 //	1 <= t1 <= 255 - specifies the pulse on time (2 ms x t1).
 //	1 <= t2 <= 255 - specifies the pulse off time (2 ms x t2).
-//	t1 must be less than t2.
+//	t1 must not exceed t2.
 func (c *escape) OpenCashDrawer(m byte, t1, t2 byte) {
 	switch {
 	case t1 == 0 || t2 == 0:
+		c.invalid("OpenCashDrawer", "pulse times must be positive, got %d and %d", t1, t2)
 		return
 	case t1 > t2:
+		if c.invalid("OpenCashDrawer", "pulse on time %d exceeds pulse off time %d", t1, t2) {
+			return
+		}
 		t1, t2 = t2, t1
 	}
+	if m > 1 && c.invalid("OpenCashDrawer", "pin %d is out of range [0, 1]", m) {
+		return
+	}
 	c.Write(ESC, 'p', minByte(m, 1), t1, t2)
 }
 
-func (c *escape) barcodeType(m byte) byte {
-	if m > 13 {
-		m = 4
+// OpenCashDrawerProfile generates the pulse of the profile in units of 2 ms, up to 510 ms.
+func (c *escape) OpenCashDrawerProfile(m byte, p DrawerProfile) {
+	if t1, t2, ok := c.pulses("OpenCashDrawerProfile", p, 2*time.Millisecond); ok {
+		c.OpenCashDrawer(m, t1, t2)
+	}
+}
+
+// OpenBothDrawers generates the pulse on pin 2, then on pin 5, by default with DrawerEpson.
+func (c *escape) OpenBothDrawers() {
+	p := c.drawerProfile(DrawerEpson)
+	c.OpenCashDrawerProfile(DrawerPin2, p)
+	c.OpenCashDrawerProfile(DrawerPin5, p)
+}
+
+// SetClock sets the clock of the printer with the command of the clock profile.
+func (c *escape) SetClock(t time.Time) {
+	c.setClock(t)
+}
+
+// RequestClock requests the time of the clock with the command of the clock profile.
+func (c *escape) RequestClock() {
+	c.requestClock()
+}
+
+// PrintClock prints the time of the clock with the command of the clock profile.
+func (c *escape) PrintClock() {
+	c.printClock()
+}
+
+// SelectDevice selects the devices receiving the data [ESC = n].
+func (c *escape) SelectDevice(printer, display bool) {
+	var n byte
+	if printer {
+		n |= 1
+	}
+	if display {
+		n |= 2
+	}
+	c.Write(ESC, '=', n)
+}
+
+// SelectStation selects the print station [ESC c 0 n].
+func (c *escape) SelectStation(s byte) {
+	if s > StationValidation && c.invalid("SelectStation", "%d is out of range [0, 2]", s) {
+		return
 	}
-	return [14]byte{65, 66, 68, 67, 69, 72, 73, 70, 71, 74, 75, 76, 77, 78}[m]
+	c.selectSheet(2 << minByte(s, StationValidation))
+}
+
+// SelectSheet selects the sheets printing the data [ESC c 0].
+func (c *escape) SelectSheet(mask byte) {
+	if (mask == 0 || mask > 0x0F) && c.invalid("SelectSheet", "mask %#x is out of range [0x01, 0x0F]", mask) {
+		return
+	}
+	if mask &= 0x0F; mask != 0 {
+		c.selectSheet(mask)
+	}
+}
+
+// selectSheet selects the sheets, the slip wait set by WithSlipWait follows the selection of the slip
+// or the validation paper, since the printer waits for them to be inserted.
+func (c *escape) selectSheet(mask byte) {
+	c.Write(ESC, 'c', '0', mask)
+	if c.slipWait && mask&(SheetSlip|SheetValidation) != 0 {
+		c.SlipWait(c.slipInsert, c.slipDelay)
+	}
+}
+
+// SheetSettings selects the sheets the following settings apply to [ESC c 1].
+func (c *escape) SheetSettings(mask byte) {
+	if (mask == 0 || mask > 0x0F) && c.invalid("SheetSettings", "mask %#x is out of range [0x01, 0x0F]", mask) {
+		return
+	}
+	if mask &= 0x0F; mask != 0 {
+		c.Write(ESC, 'c', '1', mask)
+	}
+}
+
+// SlipWait sets the wait time for the slip insertion (0 - 64 minutes) and the delay before printing [ESC f t1 t2].
+func (c *escape) SlipWait(insert, delay byte) {
+	if insert > 64 && c.invalid("SlipWait", "%d minutes are out of range [0, 64]", insert) {
+		return
+	}
+	c.Write(ESC, 'f', minByte(insert, 64), delay)
+}
+
+// EjectSlip ejects the slip [FF] after printing the data on it.
+func (c *escape) EjectSlip() {
+	c.Write(FF)
+}
+
+// ReadMICR reads the MICR line [FS a 0 n] and requests the transmission of the result [FS b 1].
+func (c *escape) ReadMICR(font byte) {
+	if font > MICRFontCMC7 && c.invalid("ReadMICR", "font %d is out of range [0, 1]", font) {
+		return
+	}
+	c.Write(FS, 'a', '0', minByte(font, MICRFontCMC7))
+	c.Write(FS, 'b', 1)
+	c.Flush()
+}
+
+// PageMode selects page mode [ESC L] or standard mode [ESC S].
+func (c *escape) PageMode(b bool) {
+	if b {
+		c.Write(ESC, 'L')
+		return
+	}
+	c.Write(ESC, 'S')
+}
+
+// PrintArea sets the print area in page mode [ESC W].
+func (c *escape) PrintArea(x, y, w, h int) {
+	if x < 0 || y < 0 || w <= 0 || h <= 0 {
+		c.invalid("PrintArea", "area %dx%d at %d,%d is out of range", w, h, x, y)
+		return
+	}
+	c.Write(ESC, 'W', byte(x), byte(x>>8), byte(y), byte(y>>8), byte(w), byte(w>>8), byte(h), byte(h>>8))
+}
+
+// PrintPage prints the data buffered in page mode and returns to standard mode [FF].
+func (c *escape) PrintPage() {
+	c.Write(FF)
+}
+
+// Beep [ESC B] is supported by most ESC/POS compatible printers with a buzzer.
+//
+//	1 <= n <= 9 - the number of beeps.
+//	1 <= t <= 9 - the duration of each beep (t x 50 ms).
+func (c *escape) Beep(n, t byte) {
+	if (n < 1 || n > 9 || t < 1 || t > 9) && c.invalid("Beep", "%d beeps of %d is out of range [1, 9]", n, t) {
+		return
+	}
+	c.Write(ESC, 'B', clamp(n, 1, 9), clamp(t, 1, 9))
+}
+
+// Color [ESC r].
+func (c *escape) Color(b byte) {
+	if b > 1 && c.invalid("Color", "%d is out of range [0, 1]", b) {
+		return
+	}
+	c.Write(ESC, 'r', minByte(b, 1))
+}
+
+// Reverse [GS B].
+func (c *escape) Reverse(b bool) {
+	if b {
+		c.Write(GS, 'B', 1)
+		return
+	}
+	c.Write(GS, 'B', 0)
+}
+
+// RequestStatus [DLE EOT].
+func (c *escape) RequestStatus(n byte) {
+	if (n < 1 || n > 4) && c.invalid("RequestStatus", "%d is out of range [1, 4]", n) {
+		return
+	}
+	c.Write(DLE, EOT, clamp(n, 1, 4))
+	c.Flush()
+}
+
+// RecoverError [DLE ENQ n].
+//
+//	n = 1, RecoverRestart;
+//	n = 2, RecoverClear.
+func (c *escape) RecoverError(mode byte) {
+	if (mode < RecoverRestart || mode > RecoverClear) && c.invalid("RecoverError", "%d is out of range [1, 2]", mode) {
+		return
+	}
+	c.Write(DLE, ENQ, clamp(mode, RecoverRestart, RecoverClear))
+	c.Flush()
+}
+
+// SoftReset clears the buffers [DLE DC4 8 1 3 20 1 6 2 8] and initializes the printer [ESC @].
+func (c *escape) SoftReset() {
+	c.Write(DLE, DC4, 8, 1, 3, 20, 1, 6, 2, 8)
+	c.Flush()
+	c.Init()
+	c.Flush()
+}
+
+// LabelAdjust [GS ( F].
+//
+//	-65535 <= n <= 65535.
+func (c *escape) LabelAdjust(p byte, n int) {
+	a := byte(2)
+	switch p {
+	case LabelPrintPosition:
+		a = 1
+	case LabelPeelPosition, LabelCutPosition:
+	default:
+		c.invalid("LabelAdjust", "unknown position %d", p)
+		return
+	}
+
+	m := byte(0)
+	if n < 0 {
+		m, n = 1, -n
+	}
+	if n > 0xFFFF {
+		if c.invalid("LabelAdjust", "%d is out of range [0, 65535]", n) {
+			return
+		}
+		n = 0xFFFF
+	}
+
+	c.Write(GS, '(', 'F', 4, 0, a, m, byte(n), byte(n>>8))
+}
+
+// LabelFeed [FS ( L].
+func (c *escape) LabelFeed(p byte) {
+	var fn byte
+	switch p {
+	case LabelPeelPosition:
+		fn = 65
+	case LabelCutPosition:
+		fn = 66
+	case LabelPrintPosition:
+		fn = 67
+	default:
+		c.invalid("LabelFeed", "unknown position %d", p)
+		return
+	}
+	c.Write(FS, '(', 'L', 2, 0, fn, '0')
+}
+
+// RequestLabelTaken [DLE EOT 8 3].
+func (c *escape) RequestLabelTaken() {
+	c.Write(DLE, EOT, 8, 3)
+	c.Flush()
+}
+
+// RequestProcessID (GS ( H fn = 48) requests the transmission of the process ID once the data before it
+// has been processed. The ID is 4 characters from 32 to 126.
+func (c *escape) RequestProcessID(id string) {
+	if reason := processIDReason(id); reason != "" {
+		c.fail("RequestProcessID", "%s", reason)
+		return
+	}
+	c.Write(GS, '(', 'H', 6, 0, 48, 48, id[0], id[1], id[2], id[3])
+	c.Flush()
+}
+
+// Print flushes the output and, if WithCompletion is used, requests the process ID of the job [GS ( H]
+// and waits for the printer to transmit it once the job has been printed.
+func (c *escape) Print() error {
+	if c.completion <= 0 {
+		return c.skipper.Print()
+	}
+
+	c.jobID = c.jobID%9999 + 1
+	id := fmt.Sprintf("%04d", c.jobID)
+	c.RequestProcessID(id)
+	if err := c.skipper.Print(); err != nil {
+		return err
+	}
+
+	// Other data, such as the automatic status, may precede the response.
+	var reply [7]byte
+	return c.awaitReply(func(b byte) bool {
+		copy(reply[:], reply[1:])
+		reply[6] = b
+		got, err := ParseProcessID(reply[:])
+		return err == nil && got == id
+	})
 }
@@ -0,0 +1,59 @@
+package thermalize
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestEscapeQRCodeVersion(t *testing.T) {
+	var got QRCodeOptions
+	renderer := WithQRCodeRenderer(func(_ string, o QRCodeOptions) image.Image {
+		got = o
+		return image.NewGray(image.Rect(0, 0, 8, 8))
+	})
+
+	var buf bytes.Buffer
+	cmd := NewEscape(48, 576, &buf, renderer)
+	cmd.QRCode("auto")
+	if got.Version != 0 || got.Mask != QRMaskAuto {
+		t.Errorf("the default version %d and mask %d, want 0 and %d", got.Version, got.Mask, QRMaskAuto)
+	}
+	cmd.(QRVersioner).QRCodeVersion(5)
+	cmd.(QRVersioner).QRCodeMask(3)
+	cmd.QRCode("locked")
+	if got.Version != 5 || got.Mask != 3 {
+		t.Errorf("the locked version %d and mask %d, want 5 and 3", got.Version, got.Mask)
+	}
+	cmd.Init()
+	cmd.QRCode("reset")
+	if got.Version != 0 || got.Mask != QRMaskAuto {
+		t.Errorf("the version %d and mask %d after Init, want 0 and %d", got.Version, got.Mask, QRMaskAuto)
+	}
+
+	// The printer draws the QR codes of the default version and mask only.
+	buf.Reset()
+	cmd = NewEscape(48, 576, &buf)
+	cmd.QRCode("auto")
+	if !bytes.Contains(buf.Bytes(), []byte{GS, '(', 'k', 3, 0, 49, 81, 48}) {
+		t.Errorf("the QR code isn't printed by the printer: % x", buf.Bytes())
+	}
+	buf.Reset()
+	cmd.(QRVersioner).QRCodeVersion(5)
+	cmd.QRCode("locked")
+	var v *ValidationError
+	if err := cmdErr(cmd); !errors.As(err, &v) || v.Command != "QRCode" {
+		t.Errorf("the locked version without a renderer: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("the QR code of a locked version is printed by the printer: % x", buf.Bytes())
+	}
+
+	cmd = NewEscape(48, 576, &buf, WithStrict())
+	cmd.(QRVersioner).QRCodeVersion(41)
+	cmd.(QRVersioner).QRCodeMask(QRMaskAuto)
+	if err := cmdErr(cmd); !errors.As(err, &v) || v.Command != "QRCodeVersion" {
+		t.Errorf("version 41: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package thermalize
+
+import (
+	"image"
+	"io"
+)
+
+// lineDialect holds the commands and the limits in which the line-mode command sets, escape and star, differ.
+type lineDialect struct {
+	// tabs is the maximum number of tab stops, column is the command moving to a position in dots,
+	// which is followed by the position in two bytes.
+	tabs   int
+	column []byte
+
+	// barcodeTypes are the codes of the barcode types, UpcA to GS1Expanded, sent to the printer.
+	barcodeTypes [14]byte
+
+	// barcodeWidth is the maximum module width of the barcodes, density the maximum steps of PrintDensity.
+	barcodeWidth byte
+	density      int
+}
+
+var escapeDialect = &lineDialect{
+	tabs:         32,
+	column:       []byte{ESC, '$'},
+	barcodeTypes: [14]byte{65, 66, 68, 67, 69, 72, 73, 70, 71, 74, 75, 76, 77, 78},
+	barcodeWidth: 6,
+	density:      6,
+}
+
+var starDialect = &lineDialect{
+	tabs:         16,
+	column:       []byte{ESC, GS, 'A'},
+	barcodeTypes: [14]byte{49, 48, 50, 51, 52, 55, 54, 53, 56, 57, 65, 66, 67, 68},
+	barcodeWidth: 9,
+	density:      3,
+}
+
+// lineMode is the engine of the line-mode command sets, which print the text, the barcodes and the images
+// line by line, and differ only in the commands of their dialect.
+type lineMode struct {
+	*skipper
+	dialect *lineDialect
+
+	barCodeFunc func(byte, string, BarcodeOptions) image.Image
+	qrCodeFunc  func(string, QRCodeOptions) image.Image
+}
+
+func newLineMode(cpl, ppl int, w io.Writer, d *lineDialect) lineMode {
+	return lineMode{skipper: newSkipper(cpl, ppl, w), dialect: d}
+}
+
+// TabPositions sets up to the maximum of tab stops of the dialect [ESC D ... NUL], in ascending order.
+func (c *lineMode) TabPositions(bs ...byte) {
+	l := len(bs)
+	if l == 0 {
+		return
+	} else if l > c.dialect.tabs {
+		if c.invalid("TabPositions", "%d positions exceed the maximum of %d", l, c.dialect.tabs) {
+			return
+		}
+		bs = bs[:c.dialect.tabs]
+	}
+
+	var arr [35]byte
+	buf := append(arr[:0], ESC, 'D')
+
+	var previous byte
+	for _, n := range bs {
+		if n <= previous {
+			if c.invalid("TabPositions", "positions must be in ascending order, got %d after %d", n, previous) {
+				return
+			}
+			continue
+		}
+		buf = append(buf, n)
+		previous = n
+	}
+
+	buf = append(buf, NUL)
+	c.tabs = append(c.tabs[:0], buf[2:len(buf)-1]...)
+	c.WriteBytes(buf)
+}
+
+// Tab moves to the next tab stop. The printer counts the tab stops in the characters of the selected size,
+// so the wide characters move to the position of the tab stop in the characters of the normal size instead,
+// keeping the columns of the lines aligned.
+func (c *lineMode) Tab() {
+	if c.sizeX <= 1 {
+		c.tabTo()
+		c.Write(HT)
+	} else if c.tabTo() {
+		c.Write(c.dialect.column...)
+		c.Write(byte(c.col), byte(c.col>>8))
+	}
+}
+
+// barcodeType returns the code of the barcode type of the dialect, unless it is overridden by WithBarcodeTypeMap.
+// The unknown types, which WithBarcodeTypeMap doesn't map either, are sent as Code39.
+func (c *lineMode) barcodeType(m byte) byte {
+	if t, ok := c.barcodeTypes[m]; ok {
+		return t
+	}
+	if m > GS1Expanded {
+		m = Code39
+	}
+	return c.dialect.barcodeTypes[m]
+}
+
+// checkBarcode validates the barcode, the second result is false if it must be skipped.
+// The image is returned if the barcode is drawn by the function set by WithBarCodeFunc.
+func (c *lineMode) checkBarcode(m byte, s string) (image.Image, bool) {
+	if len(s) == 0 {
+		c.invalid("Barcode", "empty data")
+		return nil, false
+	}
+	// The data of the custom types of the device, mapped by WithBarcodeTypeMap, is left to the printer.
+	if _, custom := c.barcodeTypes[m]; !custom || m <= GS1Expanded {
+		if reason := validateBarcode(m, s); reason != "" && c.invalid("Barcode", "%s", reason) {
+			return nil, false
+		}
+	}
+	if c.barCodeFunc != nil {
+		return c.barCodeFunc(m, s, c.barcodeOptions()), true
+	}
+	return nil, true
+}
+
+// checkBarcodeWidth validates the module width of the barcodes, which is clamped to the range of the dialect.
+// The second result is false if the command must be skipped.
+func (c *lineMode) checkBarcodeWidth(b byte) (byte, bool) {
+	if (b < 1 || b > c.dialect.barcodeWidth) &&
+		c.invalid("BarcodeWidth", "%d is out of range [1, %d]", b, c.dialect.barcodeWidth) {
+		return 0, false
+	}
+	return clamp(b, 1, c.dialect.barcodeWidth), true
+}
+
+// checkDensity validates the steps of PrintDensity, which are clamped to the range of the dialect.
+// The second result is false if the command must be skipped.
+func (c *lineMode) checkDensity(n int) (int, bool) {
+	d := c.dialect.density
+	if (n < -d || n > d) && c.invalid("PrintDensity", "%d is out of range [-%d, %d]", n, d, d) {
+		return 0, false
+	}
+	return clamp(n, -d, d), true
+}
+
+// writeBands writes the image data in bands of block bytes, each band preceded by the prefix and followed
+// by the feed [ESC J], the last band by the last feed.
+func (c *lineMode) writeBands(bs []byte, block int, prefix []byte, feed, last byte) {
+	bands(len(bs), block, func(start, end int) {
+		if end == len(bs) {
+			feed = last
+		}
+		c.WriteBytes(prefix)
+		c.WriteBytes(bs[start:end])
+		c.Write(ESC, 'J', feed)
+	})
+}
+
+// bands calls fn with the bounds of the consecutive bands of block bytes of the data of l bytes,
+// the last band may be shorter.
+func bands(l, block int, fn func(start, end int)) {
+	block = maxByte(block, 1)
+	for start := 0; start < l; start += block {
+		fn(start, minByte(start+block, l))
+	}
+}
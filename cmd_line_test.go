@@ -0,0 +1,519 @@
+package thermalize
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"testing"
+	"time"
+)
+
+// dialects are the constructors of the line-mode command sets.
+var dialects = []struct {
+	name string
+	new  func(cpl, ppl int, w io.Writer, opts ...Options) Cmd
+}{
+	{"escape", NewEscape},
+	{"star", NewStar},
+}
+
+// lineCase is a command written on both dialects in lenient and strict mode.
+type lineCase struct {
+	name string
+	fn   func(Cmd)
+
+	// escape and star are the bytes written in lenient mode, strictEscape and strictStar in strict mode.
+	// invalid are the dialects on which strict mode reports a violation.
+	escape, star             []byte
+	strictEscape, strictStar []byte
+	invalid                  []string
+}
+
+func (tc lineCase) run(t *testing.T) {
+	for _, d := range dialects {
+		for _, strict := range []bool{false, true} {
+			var buf bytes.Buffer
+			var opts []Options
+			if strict {
+				opts = append(opts, WithStrict())
+			}
+			cmd := d.new(48, 576, &buf, opts...)
+			tc.fn(cmd)
+			cmd.(Flusher).Flush()
+
+			want := map[string][]byte{"escape": tc.escape, "star": tc.star}[d.name]
+			if strict {
+				want = map[string][]byte{"escape": tc.strictEscape, "star": tc.strictStar}[d.name]
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("%s %s strict %v: wrote % x, want % x", tc.name, d.name, strict, buf.Bytes(), want)
+			}
+
+			violation := false
+			for _, name := range tc.invalid {
+				violation = violation || strict && name == d.name
+			}
+			var v *ValidationError
+			if invalid := errors.As(cmdErr(cmd), &v); invalid != violation {
+				t.Errorf("%s %s strict %v: error %v", tc.name, d.name, strict, cmdErr(cmd))
+			}
+		}
+	}
+}
+
+// both returns the cases of a command writing the same bytes on both dialects.
+func both(name string, fn func(Cmd), want, strict []byte, invalid bool) lineCase {
+	tc := lineCase{name: name, fn: fn, escape: want, star: want, strictEscape: strict, strictStar: strict}
+	if invalid {
+		tc.invalid = []string{"escape", "star"}
+	}
+	return tc
+}
+
+func TestLineTabPositions(t *testing.T) {
+	positions := func(n int) []byte {
+		bs := make([]byte, n)
+		for i := range bs {
+			bs[i] = byte(i + 1)
+		}
+		return bs
+	}
+	tabs := func(bs []byte) []byte {
+		return append(append([]byte{ESC, 'D'}, bs...), NUL)
+	}
+
+	for _, tc := range []lineCase{
+		both("ascending", func(c Cmd) { c.TabPositions(8, 16, 24) },
+			tabs([]byte{8, 16, 24}), tabs([]byte{8, 16, 24}), false),
+		both("unordered", func(c Cmd) { c.TabPositions(8, 4, 16) },
+			tabs([]byte{8, 16}), nil, true),
+		both("none", func(c Cmd) { c.TabPositions() }, nil, nil, false),
+		{
+			name:         "star maximum",
+			fn:           func(c Cmd) { c.TabPositions(positions(16)...) },
+			escape:       tabs(positions(16)),
+			star:         tabs(positions(16)),
+			strictEscape: tabs(positions(16)),
+			strictStar:   tabs(positions(16)),
+		},
+		{
+			name:         "escape maximum",
+			fn:           func(c Cmd) { c.TabPositions(positions(32)...) },
+			escape:       tabs(positions(32)),
+			star:         tabs(positions(16)),
+			strictEscape: tabs(positions(32)),
+			invalid:      []string{"star"},
+		},
+		{
+			name:    "too many",
+			fn:      func(c Cmd) { c.TabPositions(positions(33)...) },
+			escape:  tabs(positions(32)),
+			star:    tabs(positions(16)),
+			invalid: []string{"escape", "star"},
+		},
+	} {
+		tc.run(t)
+	}
+}
+
+func TestLineOpenCashDrawer(t *testing.T) {
+	profile := func(c Cmd) {
+		c.(DrawerOpener).OpenCashDrawerProfile(DrawerPin2, DrawerProfile{On: 200 * time.Millisecond, Off: 100 * time.Millisecond})
+	}
+	for _, tc := range []lineCase{
+		{
+			name:         "ordered",
+			fn:           func(c Cmd) { c.OpenCashDrawer(0, 5, 10) },
+			escape:       []byte{ESC, 'p', 0, 5, 10},
+			star:         []byte{ESC, GS, BEL, 1, 5, 10},
+			strictEscape: []byte{ESC, 'p', 0, 5, 10},
+			strictStar:   []byte{ESC, GS, BEL, 1, 5, 10},
+		},
+		{
+			// The escape command set swaps the times, the star command set sends them as they are.
+			name:    "on time exceeding off time",
+			fn:      func(c Cmd) { c.OpenCashDrawer(0, 10, 5) },
+			escape:  []byte{ESC, 'p', 0, 5, 10},
+			star:    []byte{ESC, GS, BEL, 1, 10, 5},
+			invalid: []string{"escape", "star"},
+		},
+		{
+			name:    "profile",
+			fn:      profile,
+			escape:  []byte{ESC, 'p', 0, 50, 100},
+			star:    []byte{ESC, GS, BEL, 1, 10, 5},
+			invalid: []string{"escape", "star"},
+		},
+		both("zero", func(c Cmd) { c.OpenCashDrawer(0, 0, 5) }, nil, nil, true),
+	} {
+		tc.run(t)
+	}
+}
+
+func TestLineTabStopsInit(t *testing.T) {
+	tabs := func(every, n int) []byte {
+		return append(append([]byte{ESC, '@', ESC, 'D'}, tabStopPositions(every, n)...), NUL)
+	}
+	for _, tc := range []struct {
+		name string
+		cmd  func(w io.Writer) Cmd
+		want []byte
+	}{
+		// The tab stops are set within the line of 48 characters.
+		{"escape", func(w io.Writer) Cmd { return NewEscape(48, 576, w, WithTabStops(4)) }, tabs(4, 11)},
+		{"star", func(w io.Writer) Cmd { return NewStar(48, 576, w, WithTabStops(4)) }, tabs(4, 11)},
+		// Up to 32 and 16 tab stops, the maximums of the printers.
+		{"escape maximum", func(w io.Writer) Cmd { return NewEscape(200, 1600, w, WithTabStops(2)) }, tabs(2, 32)},
+		{"star maximum", func(w io.Writer) Cmd { return NewStar(200, 1600, w, WithTabStops(2)) }, tabs(2, 16)},
+	} {
+		var buf bytes.Buffer
+		tc.cmd(&buf).Init()
+		if !bytes.Equal(buf.Bytes(), tc.want) {
+			t.Errorf("%s: Init wrote % x, want % x", tc.name, buf.Bytes(), tc.want)
+		}
+	}
+}
+
+func TestLineBarcodeWidth(t *testing.T) {
+	for _, tc := range []lineCase{
+		{
+			name:         "in range",
+			fn:           func(c Cmd) { c.BarcodeWidth(3) },
+			escape:       []byte{GS, 'w', 3},
+			strictEscape: []byte{GS, 'w', 3},
+		},
+		{
+			name:    "zero",
+			fn:      func(c Cmd) { c.BarcodeWidth(0) },
+			escape:  []byte{GS, 'w', 1},
+			invalid: []string{"escape", "star"},
+		},
+		{
+			name:    "escape maximum",
+			fn:      func(c Cmd) { c.BarcodeWidth(9) },
+			escape:  []byte{GS, 'w', 6},
+			invalid: []string{"escape"},
+		},
+	} {
+		tc.run(t)
+	}
+}
+
+func TestLineStarBarcodeWidth(t *testing.T) {
+	barcode := func(width byte) []byte {
+		return append([]byte{ESC, 'b', 52, 1, width, 100}, append([]byte("ABC"), RS)...)
+	}
+	for _, tc := range []struct {
+		width, want byte
+		invalid     bool
+	}{
+		{3, 3, false},
+		{9, 9, false},
+		{0, 1, true},
+		{10, 9, true},
+	} {
+		var buf bytes.Buffer
+		cmd := NewStar(48, 576, &buf)
+		cmd.BarcodeWidth(tc.width)
+		cmd.Barcode(Code39, "ABC")
+		if want := barcode(tc.want); !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("width %d: wrote % x, want % x", tc.width, buf.Bytes(), want)
+		}
+
+		buf.Reset()
+		cmd = NewStar(48, 576, &buf, WithStrict())
+		cmd.BarcodeWidth(tc.width)
+		if (cmdErr(cmd) != nil) != tc.invalid {
+			t.Errorf("width %d strict: error %v", tc.width, cmdErr(cmd))
+		}
+	}
+}
+
+func TestLineStarMargins(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := NewStar(48, 576, &buf)
+	// The margins are counted in the characters of the normal size, 12 dots, whatever CharSize selects.
+	cmd.CharSize(1, 1)
+	cmd.LeftMargin(4)
+	cmd.(TextFormatter).Indent(2)
+	if want := []byte{ESC, 'i', 1, 1, ESC, 'l', 4, ESC, 'l', 6}; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("wrote % x, want % x", buf.Bytes(), want)
+	}
+	if n := cmd.(LineMeasurer).RemainingWidth(); n != 576-6*12 {
+		t.Errorf("RemainingWidth = %d, want %d", n, 576-6*12)
+	}
+	cmd.WidthArea(20)
+	if n := cmd.(LineMeasurer).RemainingWidth(); n != 20*12 {
+		t.Errorf("RemainingWidth = %d, want %d", n, 20*12)
+	}
+}
+
+func TestLineBarcodeTypes(t *testing.T) {
+	escape := func(code byte, s string) []byte {
+		return append([]byte{GS, 'k', code, byte(len(s))}, s...)
+	}
+	star := func(code byte, s string) []byte {
+		return append(append([]byte{ESC, 'b', code, 1, 1, 100}, s...), RS)
+	}
+
+	for _, tc := range []lineCase{
+		{
+			name:         "UpcA",
+			fn:           func(c Cmd) { c.Barcode(UpcA, "01234567890") },
+			escape:       escape(65, "01234567890"),
+			star:         star(49, "01234567890"),
+			strictEscape: escape(65, "01234567890"),
+			strictStar:   star(49, "01234567890"),
+		},
+		{
+			name:         "Code39",
+			fn:           func(c Cmd) { c.Barcode(Code39, "ABC") },
+			escape:       escape(69, "ABC"),
+			star:         star(52, "ABC"),
+			strictEscape: escape(69, "ABC"),
+			strictStar:   star(52, "ABC"),
+		},
+		{
+			name:         "Code128",
+			fn:           func(c Cmd) { c.Barcode(Code128, "{BABC") },
+			escape:       escape(73, "{BABC"),
+			star:         star(54, "{BABC"),
+			strictEscape: escape(73, "{BABC"),
+			strictStar:   star(54, "{BABC"),
+		},
+		{
+			name:         "GS1Expanded",
+			fn:           func(c Cmd) { c.Barcode(GS1Expanded, "(01)12345678901231") },
+			escape:       escape(78, "(01)12345678901231"),
+			star:         star(68, "(01)12345678901231"),
+			strictEscape: escape(78, "(01)12345678901231"),
+			strictStar:   star(68, "(01)12345678901231"),
+		},
+		{
+			name:    "unknown",
+			fn:      func(c Cmd) { c.Barcode(GS1Expanded+1, "ABC") },
+			escape:  escape(69, "ABC"),
+			star:    star(52, "ABC"),
+			invalid: []string{"escape", "star"},
+		},
+		both("empty", func(c Cmd) { c.Barcode(Code39, "") }, nil, nil, true),
+	} {
+		tc.run(t)
+	}
+}
+
+func TestLinePrintDensity(t *testing.T) {
+	escape := func(n int8) []byte {
+		return []byte{GS, '(', 'K', 2, 0, 49, byte(n)}
+	}
+	star := func(n byte) []byte {
+		return []byte{ESC, RS, 'd', n}
+	}
+
+	for _, tc := range []lineCase{
+		{
+			name:         "standard",
+			fn:           func(c Cmd) { c.(DensityController).PrintDensity(0) },
+			escape:       escape(0),
+			star:         star(3),
+			strictEscape: escape(0),
+			strictStar:   star(3),
+		},
+		{
+			name:         "darker",
+			fn:           func(c Cmd) { c.(DensityController).PrintDensity(3) },
+			escape:       escape(3),
+			star:         star(0),
+			strictEscape: escape(3),
+			strictStar:   star(0),
+		},
+		{
+			name:         "lighter",
+			fn:           func(c Cmd) { c.(DensityController).PrintDensity(-3) },
+			escape:       escape(-3),
+			star:         star(6),
+			strictEscape: escape(-3),
+			strictStar:   star(6),
+		},
+		{
+			name:         "star maximum",
+			fn:           func(c Cmd) { c.(DensityController).PrintDensity(6) },
+			escape:       escape(6),
+			star:         star(0),
+			strictEscape: escape(6),
+			invalid:      []string{"star"},
+		},
+		{
+			name:    "escape maximum",
+			fn:      func(c Cmd) { c.(DensityController).PrintDensity(-7) },
+			escape:  escape(-6),
+			star:    star(6),
+			invalid: []string{"escape", "star"},
+		},
+	} {
+		tc.run(t)
+	}
+}
+
+// blackImage returns a black image of w by h dots, the zero value of the gray pixels.
+func blackImage(w, h int) image.Image {
+	return image.NewGray(image.Rect(0, 0, w, h))
+}
+
+// band is an image band written by writeBands: its first byte, its feed and its size.
+type band struct {
+	data byte
+	feed byte
+	size int
+}
+
+// splitBands splits the image bands preceded by the prefix and followed by [ESC J n], checking their bytes.
+func splitBands(t *testing.T, bs, prefix []byte, size int) []band {
+	t.Helper()
+	var got []band
+	for len(bs) > 0 {
+		if !bytes.HasPrefix(bs, prefix) {
+			t.Fatalf("band %d starts with % x, want % x", len(got), bs[:minByte(len(bs), len(prefix))], prefix)
+		}
+		bs = bs[len(prefix):]
+		i := size
+		if len(bs) < i+3 || bs[i] != ESC || bs[i+1] != 'J' {
+			t.Fatalf("band %d isn't followed by a feed", len(got))
+		}
+		got = append(got, band{data: bs[0], feed: bs[i+2], size: i})
+		bs = bs[i+3:]
+	}
+	return got
+}
+
+func TestLineImageBands(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		new    func(io.Writer, ...Options) Cmd
+		prefix []byte
+		rows   int
+		feed   byte
+		opts   []Options
+	}{
+		{
+			name:   "escape",
+			new:    func(w io.Writer, opts ...Options) Cmd { return NewEscape(48, 576, w, opts...) },
+			prefix: []byte{ESC, '*', BitImage24Double, 16, 0},
+			rows:   24,
+			feed:   24,
+			opts:   []Options{WithImageFuncVersion(2)},
+		},
+		{
+			name:   "escape 8-dot",
+			new:    func(w io.Writer, opts ...Options) Cmd { return NewEscape(48, 576, w, opts...) },
+			prefix: []byte{ESC, '*', BitImage8Double, 16, 0},
+			rows:   8,
+			feed:   8,
+			opts:   []Options{WithImageFuncVersion(2), WithBitImageMode(BitImage8Double)},
+		},
+		{
+			name:   "star",
+			new:    func(w io.Writer, opts ...Options) Cmd { return NewStar(48, 576, w, opts...) },
+			prefix: []byte{DC2, ESC, 'X', 16, 0},
+			rows:   24,
+			feed:   12,
+		},
+	} {
+		for _, height := range []int{tc.rows, 2*tc.rows + tc.rows/4} {
+			for _, strict := range []bool{false, true} {
+				var buf bytes.Buffer
+				opts := tc.opts
+				if strict {
+					opts = append(opts[:len(opts):len(opts)], WithStrict())
+				}
+				cmd := tc.new(&buf, opts...)
+				cmd.Image(blackImage(16, height), false)
+				cmd.(Flusher).Flush()
+
+				size := 16 * tc.rows / 8
+				got := splitBands(t, buf.Bytes(), tc.prefix, size)
+				if want := (height + tc.rows - 1) / tc.rows; len(got) != want {
+					t.Fatalf("%s height %d: %d bands, want %d", tc.name, height, len(got), want)
+				}
+				for i, b := range got {
+					feed := tc.feed
+					if i == len(got)-1 && height%tc.rows != 0 {
+						feed = byte((int(tc.feed)*(height%tc.rows) + tc.rows - 1) / tc.rows)
+					}
+					if b.size != size || b.feed != feed || i == 0 && b.data != 0xff {
+						t.Errorf("%s height %d band %d: %+v, want size %d feed %d", tc.name, height, i, b, size, feed)
+					}
+				}
+				if cmdErr(cmd) != nil {
+					t.Errorf("%s height %d strict %v: %v", tc.name, height, strict, cmdErr(cmd))
+				}
+			}
+		}
+	}
+}
+
+func TestLineCustomBarcodeType(t *testing.T) {
+	for _, d := range dialects {
+		var buf bytes.Buffer
+		cmd := d.new(48, 576, &buf, WithStrict(), WithBarcodeTypeMap(map[byte]byte{20: 80, Code39: 99}))
+		cmd.Barcode(20, "any data")
+		if cmdErr(cmd) != nil || !bytes.Contains(buf.Bytes(), []byte{80}) {
+			t.Errorf("%s: custom type wrote % x, error %v", d.name, buf.Bytes(), cmdErr(cmd))
+		}
+
+		cmd.Barcode(Code39, "lower case")
+		if cmdErr(cmd) == nil {
+			t.Errorf("%s: the invalid data of a mapped standard type isn't reported", d.name)
+		}
+
+		cmd = d.new(48, 576, &buf, WithStrict())
+		cmd.Barcode(20, "ABC")
+		if cmdErr(cmd) == nil {
+			t.Errorf("%s: the unmapped type isn't reported", d.name)
+		}
+	}
+}
+
+func TestBands(t *testing.T) {
+	for _, tc := range []struct {
+		l, block int
+		want     [][2]int
+	}{
+		{0, 4, nil},
+		{4, 4, [][2]int{{0, 4}}},
+		{10, 4, [][2]int{{0, 4}, {4, 8}, {8, 10}}},
+		{3, 0, [][2]int{{0, 1}, {1, 2}, {2, 3}}},
+	} {
+		var got [][2]int
+		bands(tc.l, tc.block, func(start, end int) { got = append(got, [2]int{start, end}) })
+		if len(got) != len(tc.want) {
+			t.Errorf("bands(%d, %d) = %v, want %v", tc.l, tc.block, got, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("bands(%d, %d) = %v, want %v", tc.l, tc.block, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLineImageWidth(t *testing.T) {
+	for _, d := range dialects {
+		var buf bytes.Buffer
+		cmd := d.new(48, 576, &buf, WithStrict())
+		cmd.Image(blackImage(600, 24), false)
+		if cmdErr(cmd) == nil || buf.Len() != 0 {
+			t.Errorf("%s: wrote %d bytes of an image wider than the line, error %v", d.name, buf.Len(), cmdErr(cmd))
+		}
+
+		buf.Reset()
+		cmd = d.new(48, 576, &buf)
+		cmd.Image(nil, false)
+		if buf.Len() != 0 {
+			t.Errorf("%s: wrote % x for a nil image", d.name, buf.Bytes())
+		}
+	}
+}
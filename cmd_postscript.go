@@ -1,16 +1,23 @@
 package thermalize
 
 import (
-	"fmt"
 	"image"
 	"io"
-	"strings"
+	"math"
+	"strconv"
 )
 
 const (
 	charWidth = 4.25
 	lineFeed  = 10.8
 
+	// fontAscent and fontDescent are the extents of the glyphs of the 9 point font above and below the baseline.
+	fontAscent  = 7.2
+	fontDescent = 2.25
+
+	// epsHeight is the height of the page of NewEPS, which is never broken.
+	epsHeight = 1e6
+
 	styleRegular = "Regular"
 	styleBold    = "Bold"
 )
@@ -28,8 +35,32 @@ const (
 // Options:
 // You can customize various aspects of the postscript command set using the following options:
 //   - WithBarCodeFunc(barCodeFunc): sets a function for generating barcodes.
+//   - WithBarcodeRenderer(fn): sets a function for generating barcodes following their settings.
+//   - WithQRCodeRenderer(fn): sets a function for generating QR codes following their settings.
 //   - WithQRCodeFunc(qrCodeFunc): sets a function for generating QR codes.
 //   - WithPageHeight(height): sets the page height to the specified value.
+//   - WithTallImages(policy): sets how images taller than the page are printed, by default they are split across pages.
+//   - WithFragments(fn): passes the laid out content of the pages to fn in EPS fragments for live previews.
+//   - WithPaperPreview(fade): draws the pages anti-aliased on a paper texture, fading the print by fade from 0 to 1.
+//   - WithLandscape(): prints the pages rotated on the sheets of the swapped size, for the layouts wider than high.
+//   - WithPageNumbers(): prints the "page X of Y" footers on the pages of the documents spanning several pages.
+//   - WithContinuationHeader(s): prints s at the top of the pages following the first one.
+//   - WithPrecision(n): writes the coordinates and the sizes with n decimal places, 2 by default.
+//   - WithStrict(): validates all parameters and reports violations through Err instead of clamping them.
+//   - WithBufferSize(n): buffers up to n bytes of output, which is flushed by Print and Flush.
+//   - WithBuffer(): accumulates the output in an internal buffer returned by Bytes (see Buffered).
+//   - WithImageCache(size): caches up to size bytes of converted images shared by all command sets.
+//   - WithGrayLevel(l): sets the level of gray printed as black by the command set.
+//   - WithAlphaBlending(bg): composites the translucent pixels of the images on the background.
+//   - WithCodePage(page, enc): encodes text with enc by default.
+//   - WithEncoder(enc): encodes text with the encoder by default.
+//   - WithCurrencySymbols(): substitutes the currency symbols the code page lacks with their ISO 4217 codes.
+//   - WithCatalog(c): translates the messages printed by the library.
+//   - WithMetrics(m): updates the metrics of the printed documents, writes and errors.
+//   - WithTabStops(every): sets a tab stop every n characters on Init.
+//   - WithResizeTabs(): sets the default tab stops within the new line on Sizing.
+//   - WithWideFormat(): sets the command set up for the 112 mm wide-format printers (see WideCPL).
+//   - WithResizeHook(fn): calls fn with the new sizing on Sizing.
 //
 // Example Usage:
 //
@@ -46,33 +77,58 @@ const (
 // If functions for generating barcodes and QR codes not provided, the call to print them will be skipped.
 func NewPostscript(cpl, ppl int, w io.Writer, opts ...Options) Cmd {
 	cmd := &postscript{
-		Cmd:          NewSkipper(cpl, ppl, w),
-		tabPositions: []float64{34, 68, 102, 136, 170, 204, 238, 272, 306, 340, 374, 408, 442, 476, 510, 544, 578, 612, 646, 680, 714, 748, 782, 816, 850, 884, 918, 952, 986, 1020, 1054},
-		width:        float64(cpl) * charWidth,
-		height:       400,
-		y:            400,
-		row:          row{pieces: make([]piece, 0)},
-		font:         defaultFont,
-		sizeX:        1,
-		sizeY:        1,
+		skipper: newSkipper(cpl, ppl, w),
+		width:   float64(cpl) * charWidth,
+		height:  400,
+		y:       400,
+		row:     row{pieces: make([]piece, 0)},
+		font:    defaultFont,
+		sizeX:   1,
+		sizeY:   1,
+		prec:    2,
 	}
+	cmd.onResize(cmd.resize)
 	for _, opt := range opts {
 		opt.apply(cmd)
 	}
+	cmd.resetTabs()
+	return cmd
+}
+
+// NewEPS returns the postscript set of printer commands drawing the receipt as a single EPS figure,
+// e.g. to embed the receipt previews into the reports and invoices.
+//
+// The receipt isn't broken into pages, each Print writes the content laid out since the previous one to w
+// as an EPS document whose BoundingBox is computed from the content, with the origin at its bottom left corner.
+// NewEPS accepts the options of NewPostscript, except WithPageHeight and WithTallImages, and the watermarks are not drawn.
+//
+// Example Usage:
+//
+// cmd := NewEPS(48, 576, writer, WithQRCodeFunc(qrCodeFunc))
+func NewEPS(cpl, ppl int, w io.Writer, opts ...Options) Cmd {
+	cmd := NewPostscript(cpl, ppl, w, opts...).(*postscript)
+	cmd.eps = true
+	cmd.height, cmd.y = epsHeight, epsHeight
+	cmd.resetBounds()
 	return cmd
 }
 
 type postscript struct {
-	Cmd
+	*skipper
 
 	tabPositions []float64
-	barCodeFunc  func(byte, string) image.Image
-	qrCodeFunc   func(string) image.Image
+	barCodeFunc  func(byte, string, BarcodeOptions) image.Image
+	qrCodeFunc   func(string, QRCodeOptions) image.Image
 
 	width  float64
 	height float64
-	x, y   float64
-	tab    float64
+
+	// margin and area are the left margin and the width of the layout area of the rows in points,
+	// if area is zero, the layout area extends to the right edge of the page.
+	margin, area float64
+
+	x, y float64
+	tab  float64
 
 	row   row
 	font  font
@@ -84,24 +140,91 @@ type postscript struct {
 	underling byte
 
 	openDrawer bool
+
+	// tallImages is the policy of the images taller than the page, see WithTallImages.
+	tallImages byte
+
+	// mark is drawn on each page, if it is set.
+	mark *watermark
+
+	// breaks counts the page breaks made by the layout of the rows.
+	breaks int
+
+	// fragments receives the fragments of the pages, if WithFragments is used.
+	// fragment holds the content of the page laid out since the last fragment, below the position fragmentTop.
+	fragments   func([]byte)
+	fragment    []byte
+	fragmentTop float64
+
+	// paper is set by WithPaperPreview, fade is the lightening of the black from 0 to 1.
+	paper bool
+	fade  float64
+
+	// landscape is set by WithLandscape.
+	landscape bool
+
+	// pageNumbers is set by WithPageNumbers, the pages of the document are held in pages until Print,
+	// when their number is known, page holds the content of the page being laid out.
+	pageNumbers bool
+	pages       [][]byte
+	page        []byte
+
+	// header is printed at the top of the pages of the document following the first one, pageNo is the current page.
+	header string
+	pageNo int
+
+	// eps is set by NewEPS, figure holds the content of the figure laid out since the last Print,
+	// bounds is its bounding box (x0, y0, x1, y1) in the coordinates of the page.
+	eps    bool
+	figure []byte
+	bounds [4]float64
+
+	// prec is the number of decimal places of the coordinates and the sizes, see WithPrecision.
+	prec int
+
+	// buf is reused to build the postscript commands.
+	buf []byte
 }
 
-func (c *postscript) Sizing(cpl, ppl int) {
-	c.Cmd.Sizing(cpl, ppl)
-	if cpl != 0 {
-		c.width = float64(cpl) * charWidth
+// resize updates the page width and keeps the layout area of the rows within it.
+// The tab stops are reset if WithResizeTabs is used, otherwise the last one is moved to the new width.
+func (c *postscript) resize(cpl, _ int) {
+	c.width = float64(cpl) * charWidth
+	c.margin = math.Min(c.margin, c.width-charWidth)
+	if w := c.width - c.margin; c.area > w {
+		c.area = w
+	}
+	if c.resizeTabs {
+		c.resetTabs()
+		return
 	}
+	for i := len(c.tabPositions) - 1; i >= 0 && c.tabPositions[i] >= c.width; i-- {
+		c.tabPositions = c.tabPositions[:i]
+	}
+	c.tabPositions = append(c.tabPositions, c.width)
 }
 
 func (c *postscript) Write(bs ...byte) {
 	c.Text(string(bs), nil)
 }
 
+func (c *postscript) WriteBytes(bs []byte) {
+	c.Text(string(bs), nil)
+}
+
+func (c *postscript) WriteString(s string) {
+	c.Text(s, nil)
+}
+
 func (c *postscript) Text(s string, enc func(string) []byte) {
 	if len(s) == 0 {
 		return
 	}
 
+	enc, ok := c.textEncoder("Text", s, enc)
+	if !ok {
+		return
+	}
 	if enc == nil {
 		enc = encoder
 	}
@@ -134,14 +257,129 @@ func (c *postscript) Text(s string, enc func(string) []byte) {
 	}
 }
 
+func (c *postscript) TextWrap(s string, enc func(string) []byte) {
+	c.lines(paragraph(s, c.wrapWidth(), 0, 0, c.justify), enc)
+}
+
+func (c *postscript) Paragraph(s string, indent, hang int) {
+	if (indent < 0 || hang < 0 || indent+hang >= c.wrapWidth()) &&
+		c.invalid("Paragraph", "indent %d and hang %d are out of range [0, %d)", indent, hang, c.wrapWidth()) {
+		return
+	}
+	c.lines(paragraph(s, c.wrapWidth(), indent, hang, c.justify), nil)
+}
+
+func (c *postscript) List(items []string, opts ...ListOption) {
+	c.lines(listLines(items, c.wrapWidth(), c.justify, opts), nil)
+}
+
+func (c *postscript) TriLine(left, center, right string) {
+	printTriLine(c, left, center, right)
+}
+
+// lines prints each line followed by a line feed.
+func (c *postscript) lines(ls []string, enc func(string) []byte) {
+	for _, l := range ls {
+		c.Text(l, enc)
+		c.LineFeed()
+	}
+}
+
 func (c *postscript) Init() {
+	c.justify = false
+	c.margin, c.area = 0, 0
+	c.indents = c.indents[:0]
 	c.align = Left
 	c.underling = NoUnderling
+	c.barcode, c.qrcode = defaultBarcode, defaultQRCode
 	c.font = defaultFont
+	if page, ok := c.defaultCodePage(); ok {
+		c.CodePage(page)
+	}
+	c.resetTabs()
 	c.setPage()
 }
 
+// LeftMargin shifts the layout area of the rows by n dots, converted to points the same way as by AbsolutePosition.
+func (c *postscript) LeftMargin(n int) {
+	if n < 0 || n >= c.PPL() {
+		c.invalid("LeftMargin", "%d is out of range [0, %d)", n, c.PPL())
+		return
+	}
+	c.margin = c.points(n)
+}
+
+// Indent shifts the layout area of the rows by the indentation, see TextFormatter.Indent.
+func (c *postscript) Indent(n int) {
+	c.pushIndent(n, int(c.areaWidth()/charWidth))
+}
+
+func (c *postscript) Outdent() {
+	c.popIndent()
+}
+
+// left returns the left edge of the layout area of the rows, the left margin plus the indentation.
+func (c *postscript) left() float64 {
+	return c.margin + float64(c.indentation())*charWidth
+}
+
+// WidthArea limits the width of the layout area of the rows to n dots.
+func (c *postscript) WidthArea(n int) {
+	if n < 0 || n > c.PPL() {
+		c.invalid("WidthArea", "%d is out of range [0, %d]", n, c.PPL())
+		return
+	}
+	c.area = c.points(n)
+}
+
+// areaWidth returns the width of the layout area of the rows.
+func (c *postscript) areaWidth() float64 {
+	w := c.width - c.left()
+	if c.area > 0 && c.area < w {
+		w = c.area
+	}
+	return w
+}
+
+// resolution returns the resolution of the images in dots per inch, the pixels per line on the width of the page.
+func (c *postscript) resolution() int {
+	return int(math.Round(float64(c.PPL()) * 72 / c.width))
+}
+
+// points converts dots to points.
+func (c *postscript) points(n int) float64 {
+	return float64(n) * c.width / float64(c.PPL())
+}
+
+// dots converts points to dots.
+func (c *postscript) dots(p float64) int {
+	return int(math.Round(p * float64(c.PPL()) / c.width))
+}
+
+// CurrentLineWidth returns the width of the pieces of the current row and the pending tab in dots.
+func (c *postscript) CurrentLineWidth() int {
+	return c.dots(c.row.width + c.tab)
+}
+
+// RemainingWidth returns the width left in the current row within the layout area in dots.
+func (c *postscript) RemainingWidth() int {
+	return maxByte(c.dots(c.areaWidth()-c.row.width-c.tab), 0)
+}
+
+// AbsolutePosition moves the next piece of the row to n dots from the beginning of the line,
+// where n is converted to points by the ratio of the page width to pixels per line.
+func (c *postscript) AbsolutePosition(n int) {
+	if n < 0 || n >= c.PPL() {
+		c.invalid("AbsolutePosition", "%d is out of range [0, %d)", n, c.PPL())
+		return
+	}
+	c.tab = c.points(n) - c.row.width
+}
+
 func (c *postscript) Align(b byte) {
+	if b > 2 && c.invalid("Align", "%d is out of range [0, 2]", b) {
+		return
+	}
 	c.align = minByte(b, 2)
 }
 
@@ -149,14 +387,20 @@ func (c *postscript) TabPositions(bs ...byte) {
 	l := len(bs)
 	if l == 0 {
 		return
-	} else if l > 16 {
-		bs = bs[:16]
+	} else if l > 32 {
+		if c.invalid("TabPositions", "%d positions exceed the maximum of 32", l) {
+			return
+		}
+		bs = bs[:32]
 	}
 
 	var previous byte
 	buf := make([]float64, 0)
 	for _, n := range bs {
 		if n <= previous {
+			if c.invalid("TabPositions", "positions must be in ascending order, got %d after %d", n, previous) {
+				return
+			}
 			continue
 		}
 		if tab := float64(n) * charWidth; tab < c.width {
@@ -172,11 +416,23 @@ func (c *postscript) TabPositions(bs ...byte) {
 	c.tabPositions = buf
 }
 
+// resetTabs sets the tab stops every 8 characters, the default of the printers, or as set by WithTabStops.
+func (c *postscript) resetTabs() {
+	every := c.tabStops
+	if every <= 0 {
+		every = 8
+	}
+	c.tabPositions = c.tabPositions[:0]
+	for _, p := range tabStopPositions(every, 32) {
+		c.tabPositions = append(c.tabPositions, float64(p)*charWidth)
+	}
+}
+
 func (c *postscript) Tab() {
 	for _, x := range c.tabPositions {
 		if c.row.width < x {
 			c.tab = x - c.row.width
-			if c.tab > c.width {
+			if c.tab > c.areaWidth() {
 				c.LineFeed()
 				c.tab = 0
 			}
@@ -186,6 +442,9 @@ func (c *postscript) Tab() {
 }
 
 func (c *postscript) CharSize(w, h byte) {
+	if (w > 5 || h > 5) && c.invalid("CharSize", "%dx%d is out of range [0, 5]", w, h) {
+		return
+	}
 	c.sizeX = minByte(w, 5) + 1
 	c.sizeY = minByte(h, 5) + 1
 }
@@ -195,6 +454,9 @@ func (c *postscript) Bold(b bool) {
 }
 
 func (c *postscript) Underling(b byte) {
+	if b > 2 && c.invalid("Underling", "%d is out of range [0, 2]", b) {
+		return
+	}
 	c.underling = minByte(b, 2)
 }
 
@@ -202,7 +464,12 @@ func (c *postscript) Barcode(m byte, s string) {
 	if c.barCodeFunc == nil || len(s) == 0 {
 		return
 	}
-	code := c.barCodeFunc(m, s)
+	if reason := validateBarcode(m, s); reason != "" && c.invalid("Barcode", "%s", reason) {
+		return
+	}
+	o := c.barcodeOptions()
+	o.DPI, o.MaxWidthPx = c.resolution(), c.dots(c.areaWidth())
+	code := c.barCodeFunc(m, s, o)
 	c.Image(code, false)
 }
 
@@ -210,16 +477,25 @@ func (c *postscript) QRCode(s string) {
 	if c.qrCodeFunc == nil || len(s) == 0 {
 		return
 	}
-	code := c.qrCodeFunc(s)
+	o := c.qrCodeOptions()
+	o.DPI, o.MaxWidthPx = c.resolution(), c.dots(c.areaWidth())
+	code := c.qrCodeFunc(s, o)
 	c.Image(code, false)
 }
 
 func (c *postscript) Image(img image.Image, invert bool) {
 	if img == nil {
+		c.invalid("Image", "nil image")
 		return
 	}
+	if w := img.Bounds().Dx(); w > c.PPL() && c.invalid("Image", "width %d exceeds %d pixels per line", w, c.PPL()) {
+		return
+	}
+
+	buf := rasterPool.Get().(*[]byte)
+	defer rasterPool.Put(buf)
 
-	w, bs := ImageToBytes(img, invert)
+	w, bs := c.convertImage(buf, img, invert, formatBytes)
 	h := img.Bounds().Size().Y
 
 	c.image(w, h, bs)
@@ -227,7 +503,7 @@ func (c *postscript) Image(img image.Image, invert bool) {
 
 func (c *postscript) LineFeed() {
 	c.y -= c.row.height
-	if c.y < lineFeed {
+	if c.y < c.bottom() {
 		c.newPage()
 		c.y -= c.row.height
 	}
@@ -235,13 +511,21 @@ func (c *postscript) LineFeed() {
 	offset := c.getOffset(c.row.width)
 
 	for _, p := range c.row.pieces {
+		if p.image {
+			offset += p.tab
+			c.drawImage(offset, c.inlineY(p), p.w, p.h, p.width, p.height, p.data)
+			offset += p.w
+			continue
+		}
+
 		c.font.setStyle(p.bold, p.sizeX, p.sizeY)
 		c.setFont()
 
 		offset += p.tab
 
 		c.moveTo(offset, c.y)
-		c.Cmd.Write([]byte(fmt.Sprintf("(%s) show\n", p.data))...)
+		c.show(p.data)
+		c.extend(offset, c.y-fontDescent*float64(p.sizeY), offset+p.w, c.y+fontAscent*float64(p.sizeY))
 		c.setLine(p.underling, offset, p.w)
 
 		offset += p.w
@@ -251,9 +535,107 @@ func (c *postscript) LineFeed() {
 	c.x = 0
 }
 
-func (c *postscript) Print() {
+// InlineImage adds the image as a piece of the current row, the row is as high as the highest piece.
+func (c *postscript) InlineImage(img image.Image, valign byte) {
+	if img == nil {
+		c.invalid("InlineImage", "nil image")
+		return
+	}
+	if valign > VAlignBottom && c.invalid("InlineImage", "vertical alignment %d is out of range [0, 2]", valign) {
+		return
+	}
+
+	buf := rasterPool.Get().(*[]byte)
+	defer rasterPool.Put(buf)
+
+	width, bs := c.convertImage(buf, img, false, formatBytes)
+	height := img.Bounds().Dy()
+
+	w := float64(width) / (float64(c.PPL()) / c.width)
+	h := w * float64(height) / float64(width)
+
+	if c.tab+c.row.width+w > c.areaWidth() {
+		c.LineFeed()
+	}
+
+	c.row.align = c.align
+	if h > c.row.height {
+		c.row.height = h
+	}
+
+	c.row.pieces = append(c.row.pieces, piece{
+		data:   append([]byte(nil), bs...),
+		w:      w,
+		tab:    c.tab,
+		image:  true,
+		width:  width,
+		height: height,
+		h:      h,
+		valign: minByte(valign, VAlignBottom),
+	})
+	c.row.width += c.tab + w
+	c.tab = 0
+}
+
+// inlineY returns the vertical position of the inline image in the row whose baseline is at c.y.
+func (c *postscript) inlineY(p piece) float64 {
+	// The descent of the font is about a quarter of the line height.
+	bottom := c.y - lineFeed/4
+	switch p.valign {
+	case VAlignTop:
+		return bottom + c.row.height - p.h
+	case VAlignMiddle:
+		return bottom + (c.row.height-p.h)/2
+	default:
+		return bottom
+	}
+}
+
+// KeepTogether prints the block written by fn on the current page,
+// or starts a new page if the block doesn't fit the rest of the current one,
+// so totals and signature blocks are not split across page breaks.
+// A block taller than a page is split anyway.
+//
+// The block is recorded and laid out on a measuring copy of the command set first, fn is called once.
+func (c *postscript) KeepTogether(fn func(Cmd)) {
+	d := NewDocument(c.CPL(), c.PPL())
+	fn(d)
+
+	if c.splits(d, c.y) && !c.splits(d, c.height) {
+		c.newPage()
+	}
+	d.Render(c)
+}
+
+// splits reports whether the document laid out from the position y makes a page break.
+// The document is rendered on a copy of the command set, which discards the output.
+func (c *postscript) splits(d *Document, y float64) bool {
+	s := *c.skipper
+	s.w, s.out, s.size, s.scratch = io.Discard, nil, 0, nil
+	s.strict, s.err, s.metrics = false, nil, nil
+
+	m := *c
+	m.skipper = &s
+	m.tabPositions = append([]float64(nil), c.tabPositions...)
+	m.row.pieces = append([]piece(nil), c.row.pieces...)
+	m.buf = nil
+	m.fragments, m.fragment = nil, nil
+	m.figure = nil
+	m.pages, m.page = nil, nil
+	m.y = y
+	m.breaks = 0
+	s.resizeHooks = []func(int, int){m.resize}
+
+	d.Render(&m)
+	return m.breaks > 0
+}
+
+func (c *postscript) Print() error {
 	c.LineFeed()
 	c.showPage()
+	c.emitPages()
+	c.pageNo = 0
+	return c.skipper.Print()
 }
 
 func (c *postscript) barcodeType(m byte) byte {
@@ -263,9 +645,101 @@ func (c *postscript) barcodeType(m byte) byte {
 	return m
 }
 
+// Watermark draws the watermark on the current page and each following page.
+// It is drawn behind the content that is added after the call.
+func (c *postscript) Watermark(s string, opts ...WatermarkOption) {
+	c.mark = nil
+	if s == "" {
+		return
+	}
+	c.mark = newWatermark(s, opts)
+	c.drawWatermark()
+}
+
+func (c *postscript) drawWatermark() {
+	if c.mark == nil || c.eps {
+		return
+	}
+
+	// The text is centered on the page and spans about 80% of the diagonal,
+	// a character of the monospaced font is 0.6 of the font size wide.
+	size := math.Hypot(c.width, c.height) * 0.8 / (0.6 * float64(maxByte(maxLineLen(c.mark.text), 1)))
+	size = math.Min(size, c.height/2)
+
+	bs := append(c.buf[:0], "gsave\n"...)
+	bs = appendFloat(bs, c.mark.gray, 2)
+	bs = append(bs, " setgray\n/NotoSansMono-Bold findfont "...)
+	bs = c.appendPoints(bs, size)
+	bs = append(bs, " scalefont setfont\n"...)
+	bs = c.appendPoints(bs, c.width/2)
+	bs = append(bs, ' ')
+	bs = c.appendPoints(bs, c.height/2)
+	bs = append(bs, " translate\n"...)
+	bs = strconv.AppendInt(bs, int64(c.mark.angle), 10)
+	bs = append(bs, " rotate\n("...)
+	bs = appendString(bs, c.mark.text)
+	bs = append(bs, ") dup stringwidth pop 2 div neg "...)
+	bs = c.appendPoints(bs, -size/3)
+	bs = append(bs, " moveto show\ngrestore\n"...)
+	c.write(bs)
+}
+
 func (c *postscript) setPage() {
-	s := fmt.Sprintf("%%!PS\n<< /PageSize [%.2f %.2f] >> setpagedevice\n", c.width, c.height)
-	c.Cmd.Write([]byte(s)...)
+	if c.eps {
+		c.fragment, c.fragmentTop = c.fragment[:0], c.height
+		return
+	}
+
+	w, h := c.width, c.height
+	if c.landscape {
+		w, h = h, w
+	}
+	bs := append(c.buf[:0], "%!PS\n<< /PageSize ["...)
+	bs = c.appendPoints(bs, w)
+	bs = append(bs, ' ')
+	bs = c.appendPoints(bs, h)
+	bs = append(bs, ']')
+	if c.paper {
+		// The renderers that support it, such as Ghostscript, smooth the edges of the text and the graphics.
+		bs = append(bs, " /TextAlphaBits 4 /GraphicsAlphaBits 4"...)
+	}
+	bs = append(bs, " >> setpagedevice\n"...)
+	if c.landscape {
+		// The top of the layout is at the left edge of the sheet.
+		bs = c.appendPoints(bs, c.height)
+		bs = append(bs, " 0 translate\n90 rotate\n"...)
+	}
+	bs = c.appendPaper(bs, c.width, c.height)
+	c.write(bs)
+	c.fragment, c.fragmentTop = c.fragment[:0], c.height
+	c.drawWatermark()
+
+	c.pageNo++
+	if c.header != "" && c.pageNo > 1 {
+		c.y -= lineFeed
+		c.write(c.appendLabel(c.buf[:0], c.header, c.y))
+	}
+}
+
+// bottom returns the lowest position of the baseline of the rows, above the footer of WithPageNumbers.
+func (c *postscript) bottom() float64 {
+	if c.pageNumbers {
+		return 2 * lineFeed
+	}
+	return lineFeed
+}
+
+// appendLabel appends the text centered on the page with its baseline at y, in the regular font of the normal size.
+// The font of the rows is restored.
+func (c *postscript) appendLabel(bs []byte, s string, y float64) []byte {
+	bs = append(bs, "gsave\n/NotoSansMono-Regular findfont 9 scalefont\ndup [0.79 0 0 1 0 0] makefont setfont\n"...)
+	bs = c.appendPoints(bs, math.Max((c.width-float64(len(s))*charWidth)/2, 0))
+	bs = append(bs, ' ')
+	bs = c.appendPoints(bs, y)
+	bs = append(bs, " moveto\n("...)
+	bs = appendString(bs, s)
+	bs = append(bs, ") show\ngrestore\n"...)
+	return bs
 }
 
 func (c *postscript) setFont() {
@@ -273,16 +747,31 @@ func (c *postscript) setFont() {
 		return
 	}
 
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("/NotoSansMono-%s findfont 9 scalefont\n", c.font.style))
-	sb.WriteString(fmt.Sprintf("dup [%.2f 0 0 %d 0 0] makefont setfont\n", float64(c.font.sizeX)*0.79, c.font.sizeY))
-	c.Cmd.Write([]byte(sb.String())...)
+	bs := append(c.buf[:0], "/NotoSansMono-"...)
+	bs = append(bs, c.font.style...)
+	bs = append(bs, " findfont 9 scalefont\ndup ["...)
+	bs = appendFloat(bs, float64(c.font.sizeX)*0.79, 2)
+	bs = append(bs, " 0 0 "...)
+	bs = strconv.AppendInt(bs, int64(c.font.sizeY), 10)
+	bs = append(bs, " 0 0] makefont setfont\n"...)
+	c.write(bs)
 
 	c.font.changed = false
 }
 
 func (c *postscript) moveTo(x, y float64) {
-	c.Cmd.Write([]byte(fmt.Sprintf("%.2f %.2f moveto\n", x, y))...)
+	bs := c.appendPoints(c.buf[:0], x)
+	bs = append(bs, ' ')
+	bs = c.appendPoints(bs, y)
+	bs = append(bs, " moveto\n"...)
+	c.write(bs)
+}
+
+func (c *postscript) show(data []byte) {
+	bs := append(c.buf[:0], '(')
+	bs = appendString(bs, data)
+	bs = append(bs, ") show\n"...)
+	c.write(bs)
 }
 
 func (c *postscript) setLine(underling byte, offset, width float64) {
@@ -294,8 +783,19 @@ func (c *postscript) setLine(underling byte, offset, width float64) {
 		weight = 0.75
 	}
 	y := c.y - 2
-
-	c.Cmd.Write([]byte(fmt.Sprintf("%.1f setlinewidth\n%.2f %.2f moveto\n%.2f %.2f lineto\nstroke\n", weight, offset, y, offset+width, y))...)
+	c.extend(offset, y-weight/2, offset+width, y+weight/2)
+
+	bs := appendFloat(c.buf[:0], weight, 1)
+	bs = append(bs, " setlinewidth\n"...)
+	bs = c.appendPoints(bs, offset)
+	bs = append(bs, ' ')
+	bs = c.appendPoints(bs, y)
+	bs = append(bs, " moveto\n"...)
+	bs = c.appendPoints(bs, offset+width)
+	bs = append(bs, ' ')
+	bs = c.appendPoints(bs, y)
+	bs = append(bs, " lineto\nstroke\n"...)
+	c.write(bs)
 }
 
 func (c *postscript) image(width, height int, bs []byte) {
@@ -303,35 +803,251 @@ func (c *postscript) image(width, height int, bs []byte) {
 	h := w / (float64(width) / float64(height))
 
 	if h > c.height {
-		c.Text("the height of the image is greater than the height of the page", nil)
-		return
+		if c.invalid("Image", "height %.2f exceeds the page height %.2f", h, c.height) {
+			return
+		}
+		switch c.tallImages {
+		case TallImageScale:
+			scale := (c.height - c.bottom() - 4) / h
+			w, h = w*scale, h*scale
+		case TallImageError:
+			c.fail("Image", "height %.2f exceeds the page height %.2f", h, c.height)
+			return
+		case TallImageMessage:
+			c.Text(Translate(c, MessageImageTooTall), nil)
+			return
+		default:
+			c.splitImage(w, h, width, height, bs)
+			return
+		}
 	}
 
 	c.y -= h
-	if c.y < lineFeed {
+	if c.y < c.bottom() {
 		c.newPage()
 		c.y -= h
 	}
 
 	c.y -= 4
 
-	var sb strings.Builder
-	sb.WriteString("gsave\n")
-	sb.WriteString(fmt.Sprintf("/picstr %d string def\n%.2f %.2f translate\n", width, c.getOffset(w), c.y))
-	sb.WriteString(fmt.Sprintf("%.2f %.2f scale\n%d %d 8\n", w, h, width, height))
-	sb.WriteString(fmt.Sprintf("[%d 0 0 %d neg 0 %d]\n{ currentfile picstr readhexstring pop }\nimage\n", width, height, height))
-	sb.WriteString(fmt.Sprintf("%X\n", bs))
-	sb.WriteString("grestore\n")
-	c.Cmd.Write([]byte(sb.String())...)
+	c.drawImage(c.getOffset(w), c.y, w, h, width, height, bs)
+}
+
+// splitImage draws the rows of the image of width x height pixels, scaled to w x h points,
+// filling the current page and continuing on the next pages.
+func (c *postscript) splitImage(w, h float64, width, height int, bs []byte) {
+	row := h / float64(height)
+	for y, fresh := 0, c.y >= c.height; y < height; fresh = false {
+		n := int((c.y - c.bottom() - 4) / row)
+		if n <= 0 && !fresh {
+			c.newPage()
+			fresh = true
+			n = int((c.y - c.bottom() - 4) / row)
+		}
+		// A row not fitting a fresh page is drawn on its own rather than starting a page for it again.
+		n = minByte(maxByte(n, 1), height-y)
+
+		c.y -= float64(n) * row
+		c.drawImage(c.getOffset(w), c.y, w, float64(n)*row, width, n, bs[y*width:(y+n)*width])
+		y += n
+	}
+	c.y -= 4
+}
+
+// drawImage draws the image of width x height pixels scaled to w x h points at x, y.
+func (c *postscript) drawImage(x, y, w, h float64, width, height int, bs []byte) {
+	c.extend(x, y, x+w, y+h)
+
+	buf := c.buf[:0]
+	if n := 256 + 2*len(bs); cap(buf) < n {
+		buf = make([]byte, 0, n)
+	}
+
+	buf = append(buf, "gsave\n/picstr "...)
+	buf = strconv.AppendInt(buf, int64(width), 10)
+	buf = append(buf, " string def\n"...)
+	buf = c.appendPoints(buf, x)
+	buf = append(buf, ' ')
+	buf = c.appendPoints(buf, y)
+	buf = append(buf, " translate\n"...)
+	buf = c.appendPoints(buf, w)
+	buf = append(buf, ' ')
+	buf = c.appendPoints(buf, h)
+	buf = append(buf, " scale\n"...)
+	if c.paper {
+		// The image dictionary asks the renderer to interpolate the dots of the scaled image rather than draw them as blocks.
+		buf = append(buf, "/DeviceGray setcolorspace\n<< /ImageType 1 /Width "...)
+		buf = strconv.AppendInt(buf, int64(width), 10)
+		buf = append(buf, " /Height "...)
+		buf = strconv.AppendInt(buf, int64(height), 10)
+		buf = append(buf, " /BitsPerComponent 8 /Decode [0 1] /Interpolate true\n/ImageMatrix ["...)
+	} else {
+		buf = strconv.AppendInt(buf, int64(width), 10)
+		buf = append(buf, ' ')
+		buf = strconv.AppendInt(buf, int64(height), 10)
+		buf = append(buf, " 8\n["...)
+	}
+	buf = strconv.AppendInt(buf, int64(width), 10)
+	buf = append(buf, " 0 0 "...)
+	buf = strconv.AppendInt(buf, int64(height), 10)
+	buf = append(buf, " neg 0 "...)
+	buf = strconv.AppendInt(buf, int64(height), 10)
+	if c.paper {
+		buf = append(buf, "]\n/DataSource { currentfile picstr readhexstring pop } >>\nimage\n"...)
+	} else {
+		buf = append(buf, "]\n{ currentfile picstr readhexstring pop }\nimage\n"...)
+	}
+	buf = appendHex(buf, bs)
+	buf = append(buf, "\ngrestore\n"...)
+	c.write(buf)
+}
+
+// write writes the content built in the reusable buffer and keeps the buffer for the next use.
+func (c *postscript) write(bs []byte) {
+	if c.eps {
+		c.figure = append(c.figure, bs...)
+	} else if c.pageNumbers {
+		c.page = append(c.page, bs...)
+	} else {
+		c.skipper.WriteBytes(bs)
+	}
+	if c.fragments != nil {
+		c.fragment = append(c.fragment, bs...)
+	}
+	c.buf = bs[:0]
+}
+
+// Flush emits the fragment of the page laid out since the last one, if WithFragments is used,
+// and writes the buffered data to the writer.
+func (c *postscript) Flush() error {
+	c.emitFragment()
+	return c.skipper.Flush()
+}
+
+// emitFragment passes the content laid out since the last fragment to the function set by WithFragments
+// as an EPS document, whose bounding box holds the rows below the previous fragment in the coordinates of the page.
+// The font is selected again by the next fragment, so each fragment can be rendered on its own.
+func (c *postscript) emitFragment() {
+	if c.fragments == nil || len(c.fragment) == 0 {
+		return
+	}
+
+	bottom := math.Max(math.Floor(c.y-lineFeed/3), 0)
+	bs := append([]byte(nil), "%!PS-Adobe-3.0 EPSF-3.0\n%%BoundingBox: 0 "...)
+	bs = appendFloat(bs, bottom, 0)
+	bs = append(bs, ' ')
+	bs = appendFloat(bs, math.Ceil(c.width), 0)
+	bs = append(bs, ' ')
+	bs = appendFloat(bs, math.Ceil(c.fragmentTop), 0)
+	bs = append(bs, '\n')
+	bs = append(bs, c.fragment...)
+	bs = append(bs, "%%EOF\n"...)
+
+	c.fragment, c.fragmentTop = c.fragment[:0], c.y
+	c.font.changed = true
+	c.fragments(bs)
 }
 
 func (c *postscript) showPage() {
+	c.emitFragment()
 	c.y = c.height
 	c.font.changed = true
-	c.Cmd.Write([]byte("showpage\n")...)
+	if c.eps {
+		c.emitFigure()
+		return
+	}
+	if c.pageNumbers {
+		c.pages = append(c.pages, c.page)
+		c.page = nil
+		return
+	}
+	c.skipper.WriteString("showpage\n")
+}
+
+// emitPages writes the pages held by WithPageNumbers with their footers.
+func (c *postscript) emitPages() {
+	for i, page := range c.pages {
+		c.skipper.WriteBytes(page)
+		bs := c.appendLabel(c.buf[:0], Translate(c, MessagePageOf, i+1, len(c.pages)), lineFeed/2)
+		c.skipper.WriteBytes(append(bs, "showpage\n"...))
+		c.buf = bs[:0]
+	}
+	c.pages = c.pages[:0]
+}
+
+// appendPaper appends the paper of WithPaperPreview filling the rectangle from the origin to w, h,
+// and the transfer function fading the print.
+// The texture of the paper is drawn as short fibers of a darker tone, about one per 100 square points,
+// spread by the random numbers of a fixed seed, so each preview of the same page looks the same.
+func (c *postscript) appendPaper(bs []byte, w, h float64) []byte {
+	if !c.paper {
+		return bs
+	}
+	bs = append(bs, "gsave\n0.98 0.97 0.93 setrgbcolor\n0 0 "...)
+	bs = c.appendPoints(bs, w)
+	bs = append(bs, ' ')
+	bs = c.appendPoints(bs, h)
+	bs = append(bs, " rectfill\n0.93 0.91 0.86 setrgbcolor\n0.2 setlinewidth\n1 srand\n"...)
+	bs = strconv.AppendInt(bs, int64(w*h/100), 10)
+	bs = append(bs, " {\nrand "...)
+	bs = strconv.AppendInt(bs, int64(math.Max(w, 1)), 10)
+	bs = append(bs, " mod rand "...)
+	bs = strconv.AppendInt(bs, int64(math.Max(h, 1)), 10)
+	bs = append(bs, " mod moveto\nrand 5 mod 2 sub rand 5 mod 2 sub rlineto stroke\n} repeat\ngrestore\n{ "...)
+	bs = appendFloat(bs, 1-c.fade, 2)
+	bs = append(bs, " mul "...)
+	bs = appendFloat(bs, c.fade, 2)
+	bs = append(bs, " add } settransfer\n"...)
+	return bs
+}
+
+// extend extends the bounding box of the figure of NewEPS by the rectangle x0, y0, x1, y1.
+func (c *postscript) extend(x0, y0, x1, y1 float64) {
+	c.bounds = [4]float64{
+		math.Min(c.bounds[0], x0), math.Min(c.bounds[1], y0),
+		math.Max(c.bounds[2], x1), math.Max(c.bounds[3], y1),
+	}
+}
+
+// resetBounds empties the bounding box of the figure.
+func (c *postscript) resetBounds() {
+	c.bounds = [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+// emitFigure writes the figure laid out since the last one as an EPS document,
+// the content is translated so that its bounding box starts at the origin.
+func (c *postscript) emitFigure() {
+	if len(c.figure) == 0 {
+		return
+	}
+
+	var x0, y0, x1, y1 float64
+	if c.bounds[0] <= c.bounds[2] {
+		x0, y0 = math.Floor(c.bounds[0]), math.Floor(c.bounds[1])
+		x1, y1 = math.Ceil(c.bounds[2]), math.Ceil(c.bounds[3])
+	}
+
+	bs := append(c.buf[:0], "%!PS-Adobe-3.0 EPSF-3.0\n%%BoundingBox: 0 0 "...)
+	bs = appendFloat(bs, x1-x0, 0)
+	bs = append(bs, ' ')
+	bs = appendFloat(bs, y1-y0, 0)
+	bs = append(bs, "\n%%EndComments\ngsave\n"...)
+	bs = c.appendPaper(bs, x1-x0, y1-y0)
+	bs = appendFloat(bs, -x0, 0)
+	bs = append(bs, ' ')
+	bs = appendFloat(bs, -y0, 0)
+	bs = append(bs, " translate\n"...)
+	c.skipper.WriteBytes(bs)
+	c.skipper.WriteBytes(c.figure)
+	c.skipper.WriteString("grestore\n%%EOF\n")
+
+	c.buf = bs[:0]
+	c.figure = c.figure[:0]
+	c.resetBounds()
 }
 
 func (c *postscript) newPage() {
+	c.breaks++
 	c.showPage()
 	c.setPage()
 }
@@ -339,21 +1055,21 @@ func (c *postscript) newPage() {
 func (c *postscript) getOffset(w float64) float64 {
 	switch c.align {
 	case Center:
-		return (c.width - c.x - w) / 2
+		return c.left() + (c.areaWidth()-c.x-w)/2
 	case Right:
-		return c.width - c.x - w
+		return c.left() + c.areaWidth() - c.x - w
 	default:
-		return 0
+		return c.left()
 	}
 }
 
 func (c *postscript) splitString(s string, offset, width float64) []string {
-	n := int(c.width / width)
+	n := int(c.areaWidth() / width)
 
 	start, end := 0, n
 
 	if offset > 0 {
-		end = int((c.width - offset) / width)
+		end = int((c.areaWidth() - offset) / width)
 	}
 
 	var chunks []string
@@ -384,6 +1100,12 @@ type piece struct {
 	sizeY     byte
 	underling byte
 	bold      bool
+
+	// image is set for an inline image of width x height pixels stored in data, h is its height in points.
+	image         bool
+	width, height int
+	h             float64
+	valign        byte
 }
 
 type row struct {
@@ -448,3 +1170,50 @@ func swap[T comparable](dst *T, src T) (b bool) {
 func encoder(s string) []byte {
 	return []byte(s)
 }
+
+// appendPoints appends the coordinate or the size in points with the precision of the command set.
+func (c *postscript) appendPoints(bs []byte, f float64) []byte {
+	return appendFloat(bs, f, c.prec)
+}
+
+// appendFloat appends the number with prec decimal places. The output doesn't depend on the platform or the locale,
+// the decimal point is always a period, a negative number rounded to zero is written without the sign,
+// and the numbers postscript can't represent, NaN and the infinities, are written as 0.
+func appendFloat(bs []byte, f float64, prec int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		f = 0
+	}
+	n := len(bs)
+	bs = strconv.AppendFloat(bs, f, 'f', prec, 64)
+	if bs[n] != '-' {
+		return bs
+	}
+	for _, b := range bs[n+1:] {
+		if b != '0' && b != '.' {
+			return bs
+		}
+	}
+	return append(bs[:n], bs[n+1:]...)
+}
+
+func appendHex(dst, src []byte) []byte {
+	const digits = "0123456789ABCDEF"
+	for _, b := range src {
+		dst = append(dst, digits[b>>4], digits[b&0x0F])
+	}
+	return dst
+}
+
+// appendString appends the text to a postscript string, escaping the parentheses and the backslashes,
+// which would otherwise end the string or start an escape sequence.
+func appendString[S ~string | ~[]byte](dst []byte, s S) []byte {
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '(', ')', '\\':
+			dst = append(dst, '\\', b)
+		default:
+			dst = append(dst, b)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,221 @@
+package thermalize
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendFloat(t *testing.T) {
+	tests := []struct {
+		f    float64
+		prec int
+		want string
+	}{
+		{0, 2, "0.00"},
+		{1.005, 2, strconv.FormatFloat(1.005, 'f', 2, 64)},
+		{12.5, 0, "12"},
+		{-3.25, 1, "-3.2"},
+		{-0.001, 2, "0.00"},
+		{math.Copysign(0, -1), 2, "0.00"},
+		{math.NaN(), 2, "0.00"},
+		{math.Inf(-1), 1, "0.0"},
+		{576, 3, "576.000"},
+	}
+	for _, tt := range tests {
+		if got := string(appendFloat([]byte("x"), tt.f, tt.prec)); got != "x"+tt.want {
+			t.Errorf("appendFloat(%v, %d) = %q, want %q", tt.f, tt.prec, got, "x"+tt.want)
+		}
+	}
+}
+
+func TestAppendHex(t *testing.T) {
+	src := []byte{0x00, 0x0F, 0xA5, 0xFF}
+	if got, want := string(appendHex(nil, src)), strings.ToUpper(hex.EncodeToString(src)); got != want {
+		t.Errorf("appendHex() = %q, want %q", got, want)
+	}
+}
+
+// longReceipt prints a receipt of 1000 lines of items.
+func longReceipt(cmd Cmd) {
+	cmd.Init()
+	for i := 0; i < 1000; i++ {
+		cmd.Text("Item "+strconv.Itoa(i), nil)
+		cmd.Tab()
+		cmd.Text("1.50", nil)
+		cmd.LineFeed()
+	}
+	cmd.FullCut()
+}
+
+func BenchmarkPostscriptReceipt1000(b *testing.B) {
+	cmd := NewPostscript(48, 576, io.Discard)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		longReceipt(cmd)
+		_ = cmd.Print()
+	}
+}
+
+func TestAppendString(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"TOTAL 3.50", "TOTAL 3.50"},
+		{"(COPY)", `\(COPY\)`},
+		{`C:\receipts`, `C:\\receipts`},
+		{`)\(`, `\)\\\(`},
+	} {
+		if got := string(appendString(nil, tc.in)); got != tc.want {
+			t.Errorf("appendString(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+		if got := string(appendString([]byte("x"), []byte(tc.in))); got != "x"+tc.want {
+			t.Errorf("appendString([]byte(%q)) = %q, want %q", tc.in, got, "x"+tc.want)
+		}
+	}
+}
+
+func TestPostscriptEscapesStrings(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := NewPostscript(48, 576, &buf, WithContinuationHeader("Order 42 (continued)"))
+	cmd.(Watermarker).Watermark("VOID (TEST)")
+	for i := 0; i < 200; i++ {
+		cmd.Text(`Item (large) \ 2`, nil)
+		cmd.LineFeed()
+	}
+	cmd.Print()
+
+	out := buf.String()
+	for _, want := range []string{`(VOID \(TEST\))`, `(Item \(large\) \\ 2)`, `(Order 42 \(continued\))`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the output lacks %s", want)
+		}
+	}
+	if strings.Contains(out, "(large)") {
+		t.Error("the output contains an unescaped parenthesis")
+	}
+}
+
+func TestPostscriptTallImages(t *testing.T) {
+	draw := func(height float64, rows int, opts ...Options) string {
+		var buf bytes.Buffer
+		cmd := NewPostscript(48, 576, &buf, append(opts, WithPageHeight(height))...)
+		cmd.Image(image.NewGray(image.Rect(0, 0, 576, rows)), false)
+		cmd.Print()
+		return buf.String()
+	}
+
+	// The message is wrapped at 48 characters.
+	message := Translate(NewPostscript(48, 576, io.Discard), MessageImageTooTall)[:48]
+	out := draw(100, 600)
+	if n := strings.Count(out, "\nimage\n"); n < 2 {
+		t.Errorf("the tall image is drawn in %d parts by default, want it split across pages", n)
+	}
+	if strings.Contains(out, message) {
+		t.Error("the tall image is replaced with the message by default")
+	}
+	if out := draw(100, 600, WithTallImages(TallImageMessage)); !strings.Contains(out, message) ||
+		strings.Contains(out, "\nimage\n") {
+		t.Error("TallImageMessage doesn't replace the image with the message")
+	}
+
+	// No row fits the page above the bottom margin, each row is drawn on a page of its own.
+	done := make(chan string)
+	go func() { done <- draw(12, 40) }()
+	select {
+	case out := <-done:
+		if n := strings.Count(out, "\nimage\n"); n != 40 {
+			t.Errorf("the rows are drawn in %d parts, want 40", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the rows not fitting the page are never drawn")
+	}
+}
+
+func TestPostscriptPaperPreview(t *testing.T) {
+	preview := []string{
+		"/TextAlphaBits 4 /GraphicsAlphaBits 4 >> setpagedevice",
+		"0.98 0.97 0.93 setrgbcolor",
+		"1 srand",
+		"/Interpolate true",
+		"{ 0.70 mul 0.30 add } settransfer",
+	}
+	for _, opts := range [][]Options{nil, {WithPaperPreview(0.3)}} {
+		var buf bytes.Buffer
+		cmd := NewPostscript(48, 576, &buf, opts...)
+		cmd.Init()
+		cmd.Text("TOTAL", nil)
+		cmd.Image(image.NewGray(image.Rect(0, 0, 16, 16)), false)
+		cmd.Print()
+
+		out := buf.String()
+		for _, want := range preview {
+			if strings.Contains(out, want) != (opts != nil) {
+				t.Errorf("with %d options, the output contains %q: %v", len(opts), want, opts == nil)
+			}
+		}
+		// The fibers of the texture are drawn before the content of the page.
+		if opts != nil && strings.Index(out, "repeat") > strings.Index(out, "(TOTAL)") {
+			t.Error("the paper texture is drawn over the text")
+		}
+	}
+}
+
+func TestPostscriptTabPositions(t *testing.T) {
+	positions := func(n int) []byte {
+		bs := make([]byte, n)
+		for i := range bs {
+			bs[i] = byte(i + 1)
+		}
+		return bs
+	}
+	// The limit is the 32 positions of the printers, the number of the tab stops set by Init.
+	for _, tc := range []struct {
+		n       int
+		invalid bool
+	}{{16, false}, {32, false}, {33, true}} {
+		cmd := NewPostscript(48, 576, io.Discard, WithStrict())
+		cmd.TabPositions(positions(tc.n)...)
+		if (cmdErr(cmd) != nil) != tc.invalid {
+			t.Errorf("%d positions: error %v", tc.n, cmdErr(cmd))
+		}
+	}
+
+	c := NewPostscript(200, 1600, io.Discard, WithTabStops(2)).(*postscript)
+	c.Init()
+	if n := len(c.tabPositions); n != 32 {
+		t.Errorf("Init sets %d tab stops, want 32", n)
+	}
+}
+
+func TestPostscriptNilImage(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fn   func(Cmd)
+	}{
+		{"Image", func(c Cmd) { c.Image(nil, false) }},
+		{"InlineImage", func(c Cmd) { c.(InlineImager).InlineImage(nil, VAlignTop) }},
+	} {
+		var buf bytes.Buffer
+		cmd := NewPostscript(48, 576, &buf, WithStrict())
+		tc.fn(cmd)
+		var v *ValidationError
+		if err := cmdErr(cmd); !errors.As(err, &v) || v.Command != tc.name {
+			t.Errorf("%s of a nil image: %v", tc.name, err)
+		}
+
+		cmd = NewPostscript(48, 576, &buf)
+		tc.fn(cmd)
+		if err := cmdErr(cmd); err != nil || buf.Len() != 0 {
+			t.Errorf("%s of a nil image: %v, wrote %q", tc.name, err, buf.String())
+		}
+	}
+}
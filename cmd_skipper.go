@@ -1,20 +1,131 @@
 package thermalize
 
 import (
+	"bytes"
+	"fmt"
 	"image"
+	"image/color"
 	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // NewSkipper returns a set of methods that skip the execution of unimplemented commands.
 // This writes raw bytes and text to a writer.
 func NewSkipper(cpl, ppl int, w io.Writer) Cmd {
-	return &skipper{cpl: cpl, ppl: ppl, w: w}
+	return newSkipper(cpl, ppl, w)
+}
+
+func newSkipper(cpl, ppl int, w io.Writer) *skipper {
+	return &skipper{cpl: cpl, ppl: ppl, w: w, barcode: defaultBarcode, qrcode: defaultQRCode}
 }
 
 type skipper struct {
 	cpl int
 	ppl int
 	w   io.Writer
+
+	strict bool
+	err    error
+
+	// writeErr is the error of the first failed write, the following writes are skipped.
+	writeErr error
+
+	// scratch holds a copy of the variadic Write arguments, so they don't escape to the heap.
+	scratch []byte
+
+	// out buffers the output until it exceeds size bytes or Flush is called.
+	// If size is zero, the output is not buffered.
+	out  []byte
+	size int
+
+	// buffer accumulates the output, if WithBuffer is used.
+	buffer *bytes.Buffer
+
+	// cache stores the converted images, if image caching is enabled.
+	cache *imageCache
+
+	// align is the alignment selected by Align, padImages is set if the images are padded to it.
+	align     byte
+	padImages bool
+
+	// bandFeed is the feed after each band of the images, if it is set.
+	bandFeed byte
+
+	// level is the gray level of the images, if hasLevel is set, otherwise the level set by SetGrayLevel is used.
+	level    uint8
+	hasLevel bool
+
+	// barcode and qrcode hold the settings passed to the functions set by WithBarcodeRenderer and WithQRCodeRenderer,
+	// dpi is the resolution of the printer set by WithDPI.
+	barcode BarcodeOptions
+	qrcode  QRCodeOptions
+	dpi     int
+
+	// background is the color the translucent pixels of the images are composited on, if it is set (see WithAlphaBlending).
+	background color.Color
+
+	// enc is the encoder of the selected code page, used by Text if no encoder is provided.
+	enc Encoder
+
+	// page is the code page selected by Init, if hasPage is set. pageEnc is its encoder.
+	page    byte
+	hasPage bool
+	pageEnc Encoder
+
+	// currency is set if Text substitutes the currency symbols, table is the code page whose symbols are used, if hasTable is set.
+	currency bool
+	table    byte
+	hasTable bool
+
+	// catalog translates the messages printed by the library, if it is set.
+	catalog Catalog
+
+	// justify is set if the word-wrapped text is justified.
+	justify bool
+
+	// tabStops is the distance between the tab stops set by Init in characters, if it is set.
+	// If resizeTabs is set, Sizing sets the tab stops the same way.
+	tabStops   int
+	resizeTabs bool
+
+	// resizeHooks are called by Sizing, the layout of the command set first.
+	resizeHooks []func(cpl, ppl int)
+
+	// col is the logical print position within the line in dots, sizeX is the character width multiplier.
+	// left and area are the left margin and the width of the printing area in dots, if area is zero,
+	// the printing area extends to the right edge of the line. tabs are the tab stops in characters,
+	// every 8 characters if they aren't set.
+	col, sizeX int
+	left, area int
+	tabs       []byte
+
+	// indents are the nested indentations set by Indent in characters of the standard size.
+	indents []int
+
+	// constrained is set if the command set behaves like a constrained device, see WithConstrainedDevice.
+	constrained bool
+
+	// quirks are the deviations of the printer the command set works around, see WithQuirks.
+	quirks Quirks
+
+	// barcodeTypes overrides the barcode type codes of the command set.
+	barcodeTypes map[byte]byte
+
+	// drawer is the pulse used by OpenBothDrawers, if it is set.
+	drawer DrawerProfile
+
+	// clock holds the commands of the real-time clock of the printer, if it is set.
+	clock *ClockProfile
+
+	// completion is how long Print waits for the printer to confirm the job, if it is positive.
+	completion time.Duration
+
+	// metrics are updated if they are set, jobBytes counts the bytes written since the last Print.
+	metrics  *Metrics
+	jobBytes int
 }
 
 func (c *skipper) Sizing(cpl, ppl int) {
@@ -24,6 +135,27 @@ func (c *skipper) Sizing(cpl, ppl int) {
 	if ppl != 0 {
 		c.ppl = ppl
 	}
+	for _, fn := range c.resizeHooks {
+		fn(c.cpl, c.ppl)
+	}
+}
+
+// onResize registers a function called by Sizing with the new number of characters and pixels per line.
+func (c *skipper) onResize(fn func(cpl, ppl int)) {
+	c.resizeHooks = append(c.resizeHooks, fn)
+}
+
+// defaultTabStops returns the tab stops every tabStops characters, or every 8 characters if it isn't set,
+// up to n positions within the line.
+func (c *skipper) defaultTabStops(n int) []byte {
+	every := c.tabStops
+	if every <= 0 {
+		every = 8
+	}
+	if max := (c.cpl - 1) / every; max < n {
+		n = maxByte(max, 0)
+	}
+	return tabStopPositions(every, n)
 }
 
 func (c *skipper) CPL() int {
@@ -35,20 +167,499 @@ func (c *skipper) PPL() int {
 }
 
 func (c *skipper) Write(bs ...byte) {
+	if c.size > 0 && len(c.out)+len(bs) <= c.size {
+		c.out = append(c.out, bs...)
+		return
+	}
+	c.scratch = append(c.scratch[:0], bs...)
+	c.WriteBytes(c.scratch)
+}
+
+func (c *skipper) WriteBytes(bs []byte) {
+	if c.size > 0 {
+		if len(c.out)+len(bs) > c.size {
+			c.Flush()
+		}
+		if len(bs) < c.size {
+			c.out = append(c.out, bs...)
+			return
+		}
+	}
+	c.write(bs)
+}
+
+func (c *skipper) WriteString(s string) {
+	if c.size > 0 {
+		if len(c.out)+len(s) > c.size {
+			c.Flush()
+		}
+		if len(s) < c.size {
+			c.out = append(c.out, s...)
+			return
+		}
+	}
+	if sw, ok := c.w.(io.StringWriter); ok && !c.constrained {
+		if c.writeErr != nil {
+			return
+		}
+		start := time.Now()
+		_, err := sw.WriteString(s)
+		c.observeWrite(start, len(s), err)
+		return
+	}
+	c.scratch = append(c.scratch[:0], s...)
+	c.write(c.scratch)
+}
+
+func (c *skipper) Raw(bs []byte) {
+	c.WriteBytes(bs)
+}
+
+func (c *skipper) Flush() error {
+	if len(c.out) > 0 {
+		c.write(c.out)
+		c.out = c.out[:0]
+	}
+	return c.writeErr
+}
+
+// write writes the data to the writer, unless an earlier write has failed.
+func (c *skipper) write(bs []byte) {
 	if c.w == nil {
 		panic("writer not specified")
 	}
-	if _, err := c.w.Write(bs); err != nil {
-		panic(err.Error())
+	if c.writeErr != nil {
+		return
+	}
+	// The constrained device receives the data in chunks of its buffer size, see WithConstrainedDevice.
+	for c.constrained && len(bs) > constrainedBufferSize {
+		c.write(bs[:constrainedBufferSize])
+		bs = bs[constrainedBufferSize:]
+	}
+	start := time.Now()
+	_, err := c.w.Write(bs)
+	c.observeWrite(start, len(bs), err)
+}
+
+// observeWrite updates the metrics of a write of n bytes and records the error if the write failed.
+func (c *skipper) observeWrite(start time.Time, n int, err error) {
+	if m := c.metrics; m != nil {
+		if m.WriteLatency != nil {
+			m.WriteLatency.Observe(time.Since(start).Seconds())
+		}
+		if err != nil {
+			m.error("write")
+		}
+	}
+	if err != nil {
+		c.writeErr = fmt.Errorf("thermalize: write: %w", err)
+		if c.err == nil {
+			c.err = c.writeErr
+		}
+		return
+	}
+	c.jobBytes += n
+}
+
+func (c *skipper) Err() error {
+	return c.err
+}
+
+// invalid reports a parameter violation of the command.
+// In strict mode the violation is recorded as a *ValidationError and true is returned,
+// which means that the command must not be emitted.
+// Otherwise, the violation is ignored and the command is expected to clamp its parameters.
+func (c *skipper) invalid(command, format string, args ...any) bool {
+	if !c.strict {
+		return false
+	}
+	c.fail(command, format, args...)
+	return true
+}
+
+// fail records the violation of the command as a *ValidationError regardless of strict mode,
+// for the commands that are skipped because their parameters can't be clamped, and counts it in the metrics.
+func (c *skipper) fail(command, format string, args ...any) {
+	if c.metrics != nil {
+		c.metrics.error("validation")
+	}
+	if c.err == nil {
+		c.err = &ValidationError{Command: command, Reason: fmt.Sprintf(format, args...)}
+	}
+}
+
+// convertImage converts the image to the format using the image cache, if it is enabled.
+// Otherwise, the converted data is stored in buf.
+func (c *skipper) convertImage(buf *[]byte, img image.Image, invert bool, format imageFormat) (int, []byte) {
+	img = c.blendImage(img)
+	if c.cache != nil {
+		return c.cache.convert(img, invert, format, c.imageLevel())
 	}
+	return format.convert(buf, img, invert, c.imageLevel())
+}
+
+// padImage pads the image narrower than the line with blank pixels according to the alignment, if WithImagePadding is used.
+func (c *skipper) padImage(img image.Image, invert bool) image.Image {
+	w := img.Bounds().Dx()
+	if !c.padImages || c.align == Left || w >= c.ppl {
+		return img
+	}
+	x := c.ppl - w
+	if c.align == Center {
+		x /= 2
+	}
+	var blank color.Color = color.White
+	if invert {
+		blank = color.Black
+	}
+	return paddedImage{Image: img, x: x, width: c.ppl, blank: blank}
+}
+
+// barcodeOptions returns the settings of the barcodes for the renderer, sized for the line.
+func (c *skipper) barcodeOptions() BarcodeOptions {
+	o := c.barcode
+	o.PPL, o.DPI, o.MaxWidthPx = c.ppl, c.resolution(), c.lineWidth()
+	return o
+}
+
+// qrCodeOptions returns the settings of the QR codes for the renderer, sized for the line.
+func (c *skipper) qrCodeOptions() QRCodeOptions {
+	o := c.qrcode
+	o.PPL, o.DPI, o.MaxWidthPx = c.ppl, c.resolution(), c.lineWidth()
+	return o
+}
+
+// resolution returns the resolution of the printer set by WithDPI, or 203 dpi.
+func (c *skipper) resolution() int {
+	if c.dpi > 0 {
+		return c.dpi
+	}
+	return defaultDPI
+}
+
+// blendImage composites the image on the background set by WithAlphaBlending, if it is set.
+func (c *skipper) blendImage(img image.Image) image.Image {
+	if c.background == nil {
+		return img
+	}
+	return BlendImage(img, c.background)
+}
+
+// imageLevel returns the gray level set by GrayLevel, or the level set by SetGrayLevel if there is none.
+func (c *skipper) imageLevel() uint8 {
+	if c.hasLevel {
+		return c.level
+	}
+	return uint8(grayLevel.Load())
+}
+
+func (c *skipper) GrayLevel(l uint8) {
+	c.level, c.hasLevel = l, true
+}
+
+// Bytes returns the output accumulated since the last WriteTo or Reset, or nil if WithBuffer is not used.
+// The buffered data is flushed first.
+func (c *skipper) Bytes() []byte {
+	if c.buffer == nil {
+		return nil
+	}
+	c.Flush()
+	return c.buffer.Bytes()
+}
+
+// WriteTo writes the accumulated output to w and empties the buffer. It implements io.WriterTo.
+func (c *skipper) WriteTo(w io.Writer) (int64, error) {
+	if c.buffer == nil {
+		return 0, nil
+	}
+	c.Flush()
+	return c.buffer.WriteTo(w)
+}
+
+// Reset discards the accumulated output.
+func (c *skipper) Reset() {
+	if c.buffer == nil {
+		return
+	}
+	c.out = c.out[:0]
+	c.buffer.Reset()
+}
+
+// bandFeeds returns the feed after each band of an image of the height, def units or as set by WithBandFeed,
+// and the feed after the last band, which is reduced to its rows, so the image isn't followed by a blank gap.
+// The bands are band dots high.
+func (c *skipper) bandFeeds(def byte, height, band int) (byte, byte) {
+	feed := def
+	if c.bandFeed > 0 {
+		feed = c.bandFeed
+	}
+	rows := height % band
+	if rows == 0 {
+		return feed, feed
+	}
+	return feed, byte(maxByte((int(feed)*rows+band-1)/band, 1))
+}
+
+// feedUnits converts mm millimeters to a feed of up to 255 units, perMM units per millimeter.
+// The second result is false if the command must be skipped.
+func (c *skipper) feedUnits(command string, mm, perMM float64) (byte, bool) {
+	n := math.Round(mm * perMM)
+	if (mm < 0 || n > 255) && c.invalid(command, "feed of %.2f mm is out of range [0, %.2f]", mm, 255/perMM) {
+		return 0, false
+	}
+	return byte(math.Max(0, math.Min(n, 255))), true
+}
+
+// tabStopPositions returns up to n tab positions every few characters.
+func tabStopPositions(every, n int) []byte {
+	var bs []byte
+	for p := every; p <= 255 && len(bs) < n; p += every {
+		bs = append(bs, byte(p))
+	}
+	return bs
+}
+
+// selectEncoder selects the encoder used by Text for the code page b.
+func (c *skipper) selectEncoder(b byte) {
+	if c.hasPage && b == c.page && c.pageEnc != nil {
+		c.enc = c.pageEnc
+		return
+	}
+	c.enc = LookupCodePage(b)
+}
+
+// defaultCodePage resets the selected encoder after initialization
+// and reports the code page that must be selected, if any.
+func (c *skipper) defaultCodePage() (byte, bool) {
+	c.enc, c.hasTable = nil, false
+	return c.page, c.hasPage
 }
 
 func (c *skipper) Text(str string, enc func(string) []byte) {
+	enc, ok := c.textEncoder("Text", str, enc)
+	if !ok {
+		return
+	}
+	c.advance(str)
 	if enc != nil {
-		c.Write(enc(str)...)
+		c.WriteBytes(enc(str))
 		return
 	}
-	c.Write([]byte(str)...)
+	c.WriteString(str)
+}
+
+// CurrentLineWidth returns the width of the current line in dots, which the byte command sets track
+// from the text, the tabs and the positions written since the last line feed.
+func (c *skipper) CurrentLineWidth() int {
+	return c.col
+}
+
+// RemainingWidth returns the width left in the current line within the printing area in dots.
+func (c *skipper) RemainingWidth() int {
+	return maxByte(c.lineWidth()-c.col, 0)
+}
+
+// lineWidth returns the width of the printing area in dots.
+func (c *skipper) lineWidth() int {
+	w := c.ppl - c.left - c.indentDots()
+	if c.area > 0 && c.area < w {
+		w = c.area
+	}
+	return w
+}
+
+// charDots returns the width of a character of the selected size in dots.
+func (c *skipper) charDots() int {
+	if c.cpl <= 0 {
+		return 0
+	}
+	return c.ppl / c.cpl * maxByte(c.sizeX, 1)
+}
+
+// advance moves the logical print position past the text, which wraps to the next line
+// where the printer does so.
+func (c *skipper) advance(s string) {
+	w := c.charDots()
+	for _, r := range s {
+		switch {
+		case r == '\n':
+			c.col = 0
+		case c.col+w > c.lineWidth():
+			c.col = w
+		default:
+			c.col += w
+		}
+	}
+}
+
+// tabTo moves the logical print position to the next tab stop and reports whether it moved,
+// the position doesn't move if there is none. The tab stops are counted in the characters of the normal size,
+// so the columns stay in place when CharSize widens the characters.
+func (c *skipper) tabTo() bool {
+	if c.cpl <= 0 || c.ppl < c.cpl {
+		return false
+	}
+	w := c.ppl / c.cpl
+	if c.tabs == nil {
+		if x := (c.col/(8*w) + 1) * 8 * w; x <= c.lineWidth() {
+			c.col = x
+			return true
+		}
+		return false
+	}
+	for _, n := range c.tabs {
+		if x := int(n) * w; x > c.col && x <= c.lineWidth() {
+			c.col = x
+			return true
+		}
+	}
+	return false
+}
+
+// rightAt returns the position in dots at which the text starts, so it ends at the column col
+// in the characters of the normal size (see ColumnPositioner). The second result is false if nothing is printed.
+func (c *skipper) rightAt(col int, s string) (int, bool) {
+	if s == "" || c.cpl <= 0 {
+		return 0, false
+	}
+	if (col < 1 || col > c.cpl) && c.invalid("RightAt", "column %d is out of range [1, %d]", col, c.cpl) {
+		return 0, false
+	}
+	col = minByte(maxByte(col, 1), c.cpl)
+
+	x := col*(c.ppl/c.cpl) - utf8.RuneCountInString(s)*c.charDots()
+	if x < 0 && c.invalid("RightAt", "%q doesn't fit before column %d", s, col) {
+		return 0, false
+	}
+	return maxByte(x, 0), true
+}
+
+// newLine resets the logical print position after the line has been printed.
+func (c *skipper) newLine() {
+	c.col = 0
+}
+
+// resetLine resets the logical print position and the layout of the line after initialization.
+func (c *skipper) resetLine() {
+	c.col, c.sizeX, c.left, c.area, c.tabs = 0, 1, 0, 0, nil
+	c.indents = c.indents[:0]
+}
+
+// pushIndent adds the indentation of n characters, which must leave at least one of the avail characters.
+// It reports whether the indentation has changed.
+func (c *skipper) pushIndent(n, avail int) bool {
+	if (n <= 0 || n >= avail) && c.invalid("Indent", "%d is out of range [1, %d)", n, avail) {
+		return false
+	}
+	if n = minByte(n, avail-1); n <= 0 {
+		return false
+	}
+	c.indents = append(c.indents, n)
+	return true
+}
+
+// popIndent removes the innermost indentation and reports whether there was one.
+func (c *skipper) popIndent() bool {
+	if len(c.indents) == 0 {
+		c.invalid("Outdent", "there is no indentation")
+		return false
+	}
+	c.indents = c.indents[:len(c.indents)-1]
+	return true
+}
+
+// indentation returns the sum of the indentations in characters.
+func (c *skipper) indentation() int {
+	n := 0
+	for _, i := range c.indents {
+		n += i
+	}
+	return n
+}
+
+// indentDots returns the sum of the indentations in dots.
+func (c *skipper) indentDots() int {
+	if c.cpl <= 0 {
+		return 0
+	}
+	return c.indentation() * (c.ppl / c.cpl)
+}
+
+// lineChars returns the width of the printing area in characters of the standard size.
+func (c *skipper) lineChars() int {
+	if c.cpl <= 0 || c.ppl < c.cpl {
+		return 0
+	}
+	return c.lineWidth() / (c.ppl / c.cpl)
+}
+
+// wrapWidth returns the number of characters the text is word-wrapped to, the characters per line less the indentation.
+func (c *skipper) wrapWidth() int {
+	return c.cpl - c.indentation()
+}
+
+// textEncoder returns the encoding function used by Text, enc if it is provided, otherwise the encoder
+// of the selected code page, or nil if there is none. The currency symbols are substituted if WithCurrencySymbols is used.
+func (c *skipper) textEncoder(command, s string, enc func(string) []byte) (func(string) []byte, bool) {
+	if enc != nil {
+		return c.currencyEncoder(EncoderFunc(enc)), true
+	}
+	if _, ok := c.encoder(command, s); !ok {
+		return nil, false
+	}
+	return c.currencyEncoder(c.enc), true
+}
+
+// encoder returns the encoding function of the selected code page, or nil if there is none.
+// In strict mode, the runes of s the encoder can't encode are reported, and the second result is false.
+func (c *skipper) encoder(command, s string) (func(string) []byte, bool) {
+	if c.enc == nil {
+		return nil, true
+	}
+	if c.strict {
+		for _, r := range s {
+			if !c.enc.CanEncode(r) && !(c.currency && isCurrency(r)) && c.invalid(command, "%q can't be encoded with %s", r, c.enc.Name()) {
+				return nil, false
+			}
+		}
+	}
+	return c.enc.Encode, true
+}
+
+func (c *skipper) TextWrap(s string, enc func(string) []byte) {
+	c.lines(paragraph(s, c.wrapWidth(), 0, 0, c.justify), enc)
+}
+
+// lines writes each line followed by a line break, since the skipper doesn't feed the lines.
+func (c *skipper) lines(ls []string, enc func(string) []byte) {
+	for _, l := range ls {
+		c.Text(l, enc)
+		c.newLine()
+		c.Write(LF)
+	}
+}
+
+func (c *skipper) Paragraph(s string, indent, hang int) {
+	c.lines(paragraph(s, c.wrapWidth(), indent, hang, c.justify), nil)
+}
+
+func (c *skipper) List(items []string, opts ...ListOption) {
+	c.lines(listLines(items, c.wrapWidth(), c.justify, opts), nil)
+}
+
+// TriLine writes the segments padded with spaces, since the skipper can't position the text.
+func (c *skipper) TriLine(left, center, right string) {
+	l, m, r, mcol, rcol := triLine(left, center, right, c.cpl)
+	ln := utf8.RuneCountInString(l)
+	mn := utf8.RuneCountInString(m)
+	if m == "" {
+		mcol, mn = ln, 0
+	}
+	c.Text(l+strings.Repeat(" ", mcol-ln)+m+strings.Repeat(" ", maxByte(rcol-mcol-mn, 0))+r, nil)
+}
+
+func (c *skipper) Justify(b bool) {
+	c.justify = b
 }
 
 func (c *skipper) Init() {}
@@ -57,6 +668,15 @@ func (c *skipper) LeftMargin(int) {}
 
 func (c *skipper) WidthArea(int) {}
 
+// Indent narrows the word-wrapped text, since the skipper can't position the text.
+func (c *skipper) Indent(n int) {
+	c.pushIndent(n, c.wrapWidth())
+}
+
+func (c *skipper) Outdent() {
+	c.popIndent()
+}
+
 func (c *skipper) AbsolutePosition(int) {}
 
 func (c *skipper) Align(byte) {}
@@ -67,7 +687,9 @@ func (c *skipper) TabPositions(...byte) {}
 
 func (c *skipper) Tab() {}
 
-func (c *skipper) CodePage(byte) {}
+func (c *skipper) CodePage(b byte) {
+	c.selectEncoder(b)
+}
 
 func (c *skipper) CharSize(byte, byte) {}
 
@@ -77,19 +699,34 @@ func (c *skipper) ClockwiseRotation(bool) {}
 
 func (c *skipper) Underling(byte) {}
 
-func (c *skipper) BarcodeWidth(byte) {}
+// BarcodeWidth, BarcodeHeight, HRIFont and HRIPosition record the settings passed to the function
+// set by WithBarcodeRenderer.
+func (c *skipper) BarcodeWidth(b byte) {
+	c.barcode.Width = b
+}
 
-func (c *skipper) BarcodeHeight(byte) {}
+func (c *skipper) BarcodeHeight(b byte) {
+	c.barcode.Height = b
+}
 
-func (c *skipper) HRIFont(byte) {}
+func (c *skipper) HRIFont(b byte) {
+	c.barcode.HRIFont = b
+}
 
-func (c *skipper) HRIPosition(byte) {}
+func (c *skipper) HRIPosition(b byte) {
+	c.barcode.HRIPosition = b
+}
 
 func (c *skipper) Barcode(byte, string) {}
 
-func (c *skipper) QRCodeSize(byte) {}
+// QRCodeSize and QRCodeCorrectionLevel record the settings passed to the function set by WithQRCodeRenderer.
+func (c *skipper) QRCodeSize(b byte) {
+	c.qrcode.Size = b
+}
 
-func (c *skipper) QRCodeCorrectionLevel(byte) {}
+func (c *skipper) QRCodeCorrectionLevel(b byte) {
+	c.qrcode.CorrectionLevel = b
+}
 
 func (c *skipper) QRCode(string) {}
 
@@ -99,13 +736,27 @@ func (c *skipper) Feed(byte) {}
 
 func (c *skipper) LineFeed() {}
 
-func (c *skipper) Cut(byte, byte) {}
+func (c *skipper) Cut(byte, byte) {
+	c.Flush()
+}
 
 func (c *skipper) FullCut() {}
 
 func (c *skipper) OpenCashDrawer(byte, byte, byte) {}
 
-func (c *skipper) Print() {}
+func (c *skipper) Print() error {
+	c.Flush()
+	if m := c.metrics; m != nil {
+		if m.Jobs != nil {
+			m.Jobs.Add(1)
+		}
+		if m.JobBytes != nil {
+			m.JobBytes.Observe(float64(c.jobBytes))
+		}
+	}
+	c.jobBytes = 0
+	return c.err
+}
 
 type number interface {
 	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~float32 | ~float64 |
@@ -125,3 +776,8 @@ func maxByte[T number](a, b T) T {
 	}
 	return b
 }
+
+// clamp limits v to the range [lo, hi].
+func clamp[T number](v, lo, hi T) T {
+	return minByte(maxByte(v, lo), hi)
+}
@@ -3,6 +3,7 @@ package thermalize
 import (
 	"image"
 	"io"
+	"time"
 )
 
 // NewStar returns the star set of printer commands for the given configuration.
@@ -18,7 +19,33 @@ import (
 // Options:
 // You can customize various aspects of the postscript command set using the following options:
 //   - WithBarCodeFunc(barCodeFunc): sets a custom function for generating barcodes.
+//   - WithBarcodeRenderer(fn): sets a custom function for generating barcodes following their settings.
+//   - WithQRCodeRenderer(fn): sets a custom function for generating QR codes following their settings.
+//   - WithDPI(n): sets the resolution of the printer passed to the barcode and QR code renderers.
 //   - WithQRCodeFunc(qrCodeFunc): sets a custom function for generating QR codes.
+//   - WithStrict(): validates all parameters and reports violations through Err instead of clamping them.
+//   - WithBufferSize(n): buffers up to n bytes of output, which is flushed by Print, Cut and Flush.
+//   - WithBuffer(): accumulates the output in an internal buffer returned by Bytes (see Buffered).
+//   - WithImageCache(size): caches up to size bytes of converted images shared by all command sets.
+//   - WithGrayLevel(l): sets the level of gray printed as black by the command set.
+//   - WithAlphaBlending(bg): composites the translucent pixels of the images on the background.
+//   - WithBandFeed(n): sets the paper feed after each 24-dot band of the images.
+//   - WithImagePadding(): pads narrow images according to the alignment.
+//   - WithCodePage(page, enc): selects the code page on Init and encodes text with enc by default.
+//   - WithEncoder(enc): selects the code page of the encoder on Init and encodes text with it by default.
+//   - WithCurrencySymbols(): substitutes the currency symbols the code page lacks with their ISO 4217 codes.
+//   - WithCatalog(c): translates the messages printed by the library.
+//   - WithMetrics(m): updates the metrics of the printed documents, writes and errors.
+//   - WithBarcodeTypeMap(types): overrides the barcode type codes sent to the printer.
+//   - WithTabStops(every): sets a tab stop every n characters on Init.
+//   - WithResizeTabs(): sets the default tab stops within the new line on Sizing.
+//   - WithWideFormat(): sets the command set up for the 112 mm wide-format printers (see WideCPL).
+//   - WithResizeHook(fn): calls fn with the new sizing on Sizing.
+//   - WithCompletion(timeout): makes Print wait until the printer confirms the job.
+//   - WithConstrainedDevice(): behaves like a constrained device, so the fallback paths are tested.
+//   - WithDrawerProfile(p): sets the pulse used by OpenBothDrawers.
+//   - WithClockProfile(p): sets the commands of the real-time clock of the printer (see ClockPrinter).
+//   - WithLinerFree(feed): configures the command set for liner-free (sticky) label paper.
 //
 // Example Usage:
 //
@@ -27,7 +54,9 @@ import (
 // In this example, a new star sequence command set is created with 48 characters per line,
 // 576 pixels per line.
 func NewStar(cpl, ppl int, w io.Writer, opts ...Options) Cmd {
-	cmd := &star{Cmd: NewSkipper(cpl, ppl, w), hriPosition: 1, barcodeWidth: 1, barcodeHeight: 100}
+	cmd := &star{lineMode: newLineMode(cpl, ppl, w, starDialect), hriPosition: 1, barcodeWidth: 1, barcodeHeight: 100}
+	cmd.barcode = BarcodeOptions{Width: 1, Height: 100}
+	cmd.onResize(cmd.resize)
 	for _, opt := range opts {
 		opt.apply(cmd)
 	}
@@ -35,37 +64,135 @@ func NewStar(cpl, ppl int, w io.Writer, opts ...Options) Cmd {
 }
 
 type star struct {
-	Cmd
-
-	barCodeFunc func(byte, string) image.Image
-	qrCodeFunc  func(string) image.Image
+	lineMode
 
 	hriPosition, barcodeWidth, barcodeHeight byte
+
+	// checkpoints is the expected ETB counter of the printer (see DocumentMarker), which is known
+	// once the command set has reset it, as reported by counted.
+	checkpoints byte
+	counted     bool
+
+	// linerFree is set for liner-free label paper, labelFeed is the distance to the peel position in dots.
+	linerFree bool
+	labelFeed byte
+
+	// peelAdjust and printAdjust are the adjustments of the peel and the print start positions in dots.
+	peelAdjust, printAdjust int
+}
+
+func (c *star) TextWrap(s string, enc func(string) []byte) {
+	c.lines(paragraph(s, c.wrapWidth(), 0, 0, c.justify), enc)
+}
+
+func (c *star) Paragraph(s string, indent, hang int) {
+	if (indent < 0 || hang < 0 || indent+hang >= c.wrapWidth()) &&
+		c.invalid("Paragraph", "indent %d and hang %d are out of range [0, %d)", indent, hang, c.wrapWidth()) {
+		return
+	}
+	c.lines(paragraph(s, c.wrapWidth(), indent, hang, c.justify), nil)
+}
+
+func (c *star) List(items []string, opts ...ListOption) {
+	c.lines(listLines(items, c.wrapWidth(), c.justify, opts), nil)
+}
+
+func (c *star) TriLine(left, center, right string) {
+	printTriLine(c, left, center, right)
+}
+
+// lines prints each line followed by a line feed.
+func (c *star) lines(ls []string, enc func(string) []byte) {
+	for _, l := range ls {
+		c.Text(l, enc)
+		c.LineFeed()
+	}
 }
 
 func (c *star) Init() {
+	c.justify, c.align = false, Left
+	c.resetLine()
 	c.Write(ESC, '@')
+	if page, ok := c.defaultCodePage(); ok {
+		c.CodePage(page)
+	}
+	if c.tabStops > 0 {
+		c.TabPositions(c.defaultTabStops(16)...)
+	}
+	if c.completion > 0 {
+		c.ResetCheckpoints()
+	}
+}
+
+// resize sets the default tab stops within the new line, if WithResizeTabs is used.
+func (c *star) resize(int, int) {
+	if c.resizeTabs {
+		c.TabPositions(c.defaultTabStops(16)...)
+	}
 }
 
 func (c *star) LeftMargin(n int) {
-	if n < c.CPL() {
-		c.Write(ESC, 'l', byte(n))
+	if n < 0 || n >= c.CPL() {
+		c.invalid("LeftMargin", "%d is out of range [0, %d)", n, c.CPL())
+		return
 	}
+	// The margin is counted in the characters of the normal size, as the printer does.
+	c.left = n * (c.PPL() / maxByte(c.CPL(), 1))
+	c.writeMargin()
+}
+
+// Indent shifts the left margin by the indentation, see TextFormatter.Indent.
+func (c *star) Indent(n int) {
+	if c.pushIndent(n, c.lineChars()) {
+		c.writeMargin()
+	}
+}
+
+func (c *star) Outdent() {
+	if c.popIndent() {
+		c.writeMargin()
+	}
+}
+
+// writeMargin sets the left margin of the printer to the left margin plus the indentation in characters.
+func (c *star) writeMargin() {
+	n := c.left + c.indentDots()
+	if w := c.PPL() / maxByte(c.CPL(), 1); w > 0 {
+		n /= w
+	}
+	c.Write(ESC, 'l', byte(n))
 }
 
 func (c *star) WidthArea(n int) {
-	if n <= c.CPL() {
-		c.Write(ESC, 'Q', byte(n))
+	if n < 0 || n > c.CPL() {
+		c.invalid("WidthArea", "%d is out of range [0, %d]", n, c.CPL())
+		return
 	}
+	c.area = n * (c.PPL() / maxByte(c.CPL(), 1))
+	c.Write(ESC, 'Q', byte(n))
 }
 
 func (c *star) AbsolutePosition(n int) {
-	if n < c.PPL() {
-		c.Write(ESC, GS, 'A', byte(n), byte(n>>8))
+	if n < 0 || n >= c.PPL() {
+		c.invalid("AbsolutePosition", "%d is out of range [0, %d)", n, c.PPL())
+		return
+	}
+	c.col = n
+	c.Write(ESC, GS, 'A', byte(n), byte(n>>8))
+}
+
+func (c *star) RightAt(col int, s string) {
+	if x, ok := c.rightAt(col, s); ok {
+		c.AbsolutePosition(x)
+		c.Text(s, nil)
 	}
 }
 
 func (c *star) Align(b byte) {
+	if b > 2 && c.invalid("Align", "%d is out of range [0, 2]", b) {
+		return
+	}
+	c.align = minByte(b, 2)
 	c.Write(ESC, GS, 'a', minByte(b, 2))
 }
 
@@ -77,37 +204,19 @@ func (c *star) UpsideDown(b bool) {
 	c.Write(DC2)
 }
 
-// TabPositions maximum of 16 horizontal tabs can be set.
-func (c *star) TabPositions(bs ...byte) {
-	l := len(bs)
-	if l == 0 {
+func (c *star) CodePage(b byte) {
+	b, ok := c.supportedCodePage(starCodePages, b)
+	if !ok {
 		return
-	} else if l > 16 {
-		bs = bs[:16]
 	}
-
-	buf := make([]byte, 0, 3+l)
-	buf = append(buf, ESC, 'D')
-
-	var previous byte
-	for _, n := range bs {
-		if n <= previous {
-			continue
-		}
-		buf = append(buf, n)
-		previous = n
+	n, ok := nativeCodePage(starCodePages, b)
+	if !ok {
+		c.invalid("CodePage", "symbolic code page %#x is not supported", b)
+		return
 	}
-
-	buf = append(buf, NUL)
-	c.Write(buf...)
-}
-
-func (c *star) Tab() {
-	c.Write(HT)
-}
-
-func (c *star) CodePage(b byte) {
-	c.Write(ESC, GS, 't', b)
+	c.Write(ESC, GS, 't', n)
+	c.selectEncoder(b)
+	c.selectTable(starCodePages, n)
 }
 
 // CharSize
@@ -115,6 +224,10 @@ func (c *star) CodePage(b byte) {
 //	h: character height (0 - x1 `normal`, 1 - x2, 2 - x3, 3 - x4, 5 - x5, 5 - x6)
 //	w: character width (0 - x1 `normal`, 1 - x2, 2 - x3, 3 - x4, 4 - x5, 5 - x6)
 func (c *star) CharSize(h, w byte) {
+	if (w > 5 || h > 5) && c.invalid("CharSize", "%dx%d is out of range [0, 5]", h, w) {
+		return
+	}
+	c.sizeX = int(minByte(w, 5)) + 1
 	c.Write(ESC, 'i', minByte(w, 5), minByte(h, 5))
 }
 
@@ -127,20 +240,32 @@ func (c *star) Bold(b bool) {
 }
 
 func (c *star) Underling(b byte) {
+	if b > 1 && c.invalid("Underling", "%d is out of range [0, 1]", b) {
+		return
+	}
 	c.Write(ESC, '-', minByte(b, 1))
 }
 
 // BarcodeWidth 1 <= b <= 9.
 func (c *star) BarcodeWidth(b byte) {
-	b = maxByte(b, 1)
-	c.barcodeWidth = minByte(b, 9)
+	if b, ok := c.checkBarcodeWidth(b); ok {
+		c.barcodeWidth, c.barcode.Width = b, b
+	}
 }
 
 func (c *star) BarcodeHeight(b byte) {
+	if b < 1 && c.invalid("BarcodeHeight", "%d is out of range [1, 255]", b) {
+		return
+	}
 	c.barcodeHeight = maxByte(b, 1)
+	c.barcode.Height = c.barcodeHeight
 }
 
 func (c *star) HRIPosition(b byte) {
+	if b > 3 && c.invalid("HRIPosition", "%d is out of range [0, 3]", b) {
+		return
+	}
+	c.barcode.HRIPosition = minByte(b, 3)
 	c.hriPosition = 1
 	if b > 1 {
 		c.hriPosition = 2
@@ -148,12 +273,10 @@ func (c *star) HRIPosition(b byte) {
 }
 
 func (c *star) Barcode(m byte, s string) {
-	if len(s) == 0 {
+	code, ok := c.checkBarcode(m, s)
+	if !ok {
 		return
-	}
-
-	if c.barCodeFunc != nil {
-		code := c.barCodeFunc(m, s)
+	} else if code != nil {
 		c.Image(code, false)
 		return
 	}
@@ -161,31 +284,72 @@ func (c *star) Barcode(m byte, s string) {
 	c.Write(ESC, 'b', c.barcodeType(m), c.hriPosition, c.barcodeWidth, c.barcodeHeight)
 	c.Text(s, nil)
 	c.Write(RS)
+	c.newLine()
+}
+
+// PrintDensity adjusts the print density by n steps [ESC RS d n], where n = 3 selects the standard density.
+//
+//	-3 <= n <= 3.
+func (c *star) PrintDensity(n int) {
+	if n, ok := c.checkDensity(n); ok {
+		c.Write(ESC, RS, 'd', byte(3-n))
+	}
+}
+
+// QRCodeModel selects QRModel1 or QRModel2 [ESC GS y S 0 n], star printers don't print Micro QR.
+func (c *star) QRCodeModel(b byte) {
+	if b > QRModel2 && c.invalid("QRCodeModel", "%d is out of range [0, 1]", b) {
+		return
+	}
+	if c.quirks&QuirkNoQRCode != 0 {
+		return
+	}
+	c.Write(ESC, GS, 'y', 'S', '0', minByte(b, QRModel2)+1)
 }
 
 // QRCodeSize
 //
 //	1 <= b <= 8.
 func (c *star) QRCodeSize(b byte) {
-	b = maxByte(b, 1)
-	c.Write(ESC, GS, 'y', 'S', '2', minByte(b, 8))
+	if (b < 1 || b > 8) && c.invalid("QRCodeSize", "%d is out of range [1, 8]", b) {
+		return
+	}
+	c.qrcode.Size = clamp(b, 1, 8)
+	if c.quirks&QuirkNoQRCode != 0 {
+		return
+	}
+	c.Write(ESC, GS, 'y', 'S', '2', clamp(b, 1, 8))
 }
 
 func (c *star) QRCodeCorrectionLevel(b byte) {
+	if b > 3 && c.invalid("QRCodeCorrectionLevel", "%d is out of range [0, 3]", b) {
+		return
+	}
+	c.qrcode.CorrectionLevel = minByte(b, 3)
+	if c.quirks&QuirkNoQRCode != 0 {
+		return
+	}
 	c.Write(ESC, GS, 'y', 'S', '1', minByte(b, 3))
 }
 
 func (c *star) QRCode(s string) {
 	l := len(s)
 	if l == 0 {
+		c.invalid("QRCode", "empty data")
+		return
+	} else if l > 7089 && c.invalid("QRCode", "%d bytes exceed the maximum of 7089", l) {
 		return
 	}
 
 	if c.qrCodeFunc != nil {
-		code := c.qrCodeFunc(s)
+		code := c.qrCodeFunc(s, c.qrCodeOptions())
 		c.Image(code, false)
 		return
 	}
+	if c.quirks&QuirkNoQRCode != 0 {
+		c.fail("QRCode", "QR codes are not supported by the printer, see WithQRCodeFunc")
+		return
+	}
 
 	h, w := byte(l), byte(l>>8)
 
@@ -195,36 +359,114 @@ func (c *star) QRCode(s string) {
 
 	// Print the symbol data in the symbol storage area.
 	c.Write(ESC, GS, 'y', 'P')
+	c.newLine()
+}
+
+// PDF417Size sets the number of rows and columns of the PDF417 symbol [ESC GS x S 0 1 p1 p2].
+//
+//	rows: 3 - 90, 0 - automatic;
+//	cols: 1 - 30, 0 - automatic.
+func (c *star) PDF417Size(rows, cols byte) {
+	if (rows > 90 || rows > 0 && rows < 3) && c.invalid("PDF417Size", "%d rows are out of range [3, 90]", rows) {
+		return
+	}
+	if cols > 30 && c.invalid("PDF417Size", "%d columns are out of range [1, 30]", cols) {
+		return
+	}
+	if rows > 0 {
+		rows = clamp(rows, 3, 90)
+	}
+	c.Write(ESC, GS, 'x', 'S', '0', 1, rows, minByte(cols, 30))
+}
+
+// PDF417CorrectionLevel sets the error correction level of the PDF417 symbol (0 - 8) [ESC GS x S 1 n].
+func (c *star) PDF417CorrectionLevel(b byte) {
+	if b > 8 && c.invalid("PDF417CorrectionLevel", "%d is out of range [0, 8]", b) {
+		return
+	}
+	c.Write(ESC, GS, 'x', 'S', '1', minByte(b, 8))
+}
+
+// PDF417Module sets the width of the module of the PDF417 symbol in dots (1 - 10) [ESC GS x S 2 n]
+// and the ratio of its height to its width (1 - 10) [ESC GS x S 3 n].
+func (c *star) PDF417Module(width, ratio byte) {
+	if (width < 1 || width > 10) && c.invalid("PDF417Module", "width %d is out of range [1, 10]", width) {
+		return
+	}
+	if (ratio < 1 || ratio > 10) && c.invalid("PDF417Module", "ratio %d is out of range [1, 10]", ratio) {
+		return
+	}
+	c.Write(ESC, GS, 'x', 'S', '2', clamp(width, 1, 10))
+	c.Write(ESC, GS, 'x', 'S', '3', clamp(ratio, 1, 10))
+}
+
+// PDF417 prints the PDF417 symbol encoding s.
+func (c *star) PDF417(s string) {
+	l := len(s)
+	if l == 0 {
+		c.invalid("PDF417", "empty data")
+		return
+	} else if l > 2710 && c.invalid("PDF417", "%d bytes exceed the maximum of 2710", l) {
+		return
+	}
+	l = minByte(l, 2710)
+
+	// Store the data in the symbol storage area, the data is sent as it is, so its length is exact.
+	c.Write(ESC, GS, 'x', 'D', byte(l), byte(l>>8))
+	c.WriteString(s[:l])
+
+	// Print the symbol data in the symbol storage area.
+	c.Write(ESC, GS, 'x', 'P')
 }
 
 func (c *star) Image(img image.Image, invert bool) {
-	w, bs := ImageToBin(img, invert)
+	if img == nil {
+		c.invalid("Image", "nil image")
+		return
+	}
+	if w := img.Bounds().Dx(); w > c.PPL() && c.invalid("Image", "width %d exceeds %d pixels per line", w, c.PPL()) {
+		return
+	}
 
-	xl, xh := byte(w), byte(w>>8)
+	img = c.padImage(img, invert)
 
-	l := len(bs)
-	block := w * 3
-	start := 0
+	buf := rasterPool.Get().(*[]byte)
+	defer rasterPool.Put(buf)
 
-	for end := block; start < l; end += block {
-		end = minByte(end, l)
+	w, bs := c.convertImage(buf, img, invert, formatBin)
 
-		c.Write(DC2)
-		c.Write(ESC, 'X', xl, xh)
-		c.Write(bs[start:end]...)
-		c.Write(ESC, 'J', 12)
+	// The bands of 24 rows are fed by 12 units of 1/4 mm.
+	feed, last := c.bandFeeds(12, img.Bounds().Dy(), 24)
+	c.writeBands(bs, w*3, []byte{DC2, ESC, 'X', byte(w), byte(w >> 8)}, feed, last)
+	c.newLine()
+}
 
-		start = end
+// InlineImage prints the image on its own line,
+// since the star command set can't print an image along with the text.
+func (c *star) InlineImage(img image.Image, _ byte) {
+	if c.invalid("InlineImage", "inline images are not supported") {
+		return
+	}
+	c.LineFeed()
+	c.Image(img, false)
+}
+
+// Watermark prints the watermark as a light raster band, if WithWatermarkBand is used.
+func (c *star) Watermark(s string, opts ...WatermarkOption) {
+	if w := newWatermark(s, opts); w.band && s != "" {
+		c.Image(w.raster(c.PPL()), false)
 	}
 }
 
 func (c *star) Feed(b byte) {
 	if b > 0 {
+		c.newLine()
 		c.Write(ESC, 'J', b)
 	}
 }
 
 func (c *star) LineFeed() {
+	c.newLine()
 	c.Write(LF)
 }
 
@@ -234,28 +476,232 @@ func (c *star) LineFeed() {
 //	m = 1, partial cut at the current position;
 //	m = 2, paper is fed to cutting position, then a full cut;
 //	m = 3, paper is fed to cutting position, then a partial cut;
+//
+// Liner-free paper can't be cut partially, so partial cuts are replaced with full cuts (see WithLinerFree).
 func (c *star) Cut(m, _ byte) {
-	c.Write(ESC, 'd', minByte(m, 3))
+	if m > 3 && c.invalid("Cut", "unknown mode %d", m) {
+		return
+	}
+	m = minByte(m, 3)
+	if c.linerFree {
+		m &^= 1
+	}
+	c.newLine()
+	c.Write(ESC, 'd', m)
+	c.Flush()
 }
 
+// FullCut feeds the paper to the cutting position, then cuts it [ESC d 2].
 func (c *star) FullCut() {
 	c.Cut(2, 0)
 }
 
+// FullCutAtPosition cuts the paper at the current position [ESC d 0].
+func (c *star) FullCutAtPosition() {
+	c.Cut(0, 0)
+}
+
+// PartialCutAtPosition cuts the paper partially at the current position [ESC d 1].
+func (c *star) PartialCutAtPosition() {
+	c.Cut(1, 0)
+}
+
+// FeedAndFullCut feeds the paper by p/4 mm [ESC J n], then feeds it to the cutting position and cuts it [ESC d 2].
+func (c *star) FeedAndFullCut(p byte) {
+	c.CutWithFeed(false, float64(p)/4)
+}
+
+// CutWithFeed feeds the paper by mm millimeters [ESC J n], in 1/4 mm up to 63.75 mm,
+// then feeds it to the cutting position and cuts it [ESC d 2|3].
+func (c *star) CutWithFeed(partial bool, mm float64) {
+	n, ok := c.feedUnits("CutWithFeed", mm, 4)
+	if !ok {
+		return
+	}
+	c.Feed(n)
+	if partial {
+		c.Cut(3, 0)
+		return
+	}
+	c.Cut(2, 0)
+}
+
+// feedMM feeds the paper mm millimeters in the vertical motion unit of CutWithFeed [ESC J n].
+func (c *star) feedMM(mm float64) {
+	if n, ok := c.feedUnits("Feed", mm, 4); ok {
+		c.Feed(n)
+	}
+}
+
 // OpenCashDrawer
 //
 //	1 <= t1 <= 255 - specifies the pulse on time (20 ms x t1).
 //	1 <= t2 <= 255 - specifies the pulse off time (20 ms x t2).
+//
+// In strict mode, t1 exceeding t2 is reported as with the ESC/POS command set,
+// otherwise the times are sent as they are, because the printer accepts them.
 func (c *star) OpenCashDrawer(m, t1, t2 byte) {
-	if t1 == 0 || t2 == 0 {
+	switch {
+	case t1 == 0 || t2 == 0:
+		c.invalid("OpenCashDrawer", "pulse times must be positive, got %d and %d", t1, t2)
+		return
+	case t1 > t2 && c.invalid("OpenCashDrawer", "pulse on time %d exceeds pulse off time %d", t1, t2):
+		return
+	}
+	if m > 1 && c.invalid("OpenCashDrawer", "pin %d is out of range [0, 1]", m) {
 		return
 	}
 	c.Write(ESC, GS, BEL, minByte(m, 1)+1, t1, t2)
 }
 
-func (c *star) barcodeType(m byte) byte {
-	if m > 13 {
-		m = 4
+// OpenCashDrawerProfile generates the pulse of the profile in units of 20 ms, up to 5.1 s.
+func (c *star) OpenCashDrawerProfile(m byte, p DrawerProfile) {
+	if t1, t2, ok := c.pulses("OpenCashDrawerProfile", p, 20*time.Millisecond); ok {
+		c.OpenCashDrawer(m, t1, t2)
+	}
+}
+
+// OpenBothDrawers generates the pulse on the first, then on the second drawer, by default with DrawerStar.
+func (c *star) OpenBothDrawers() {
+	p := c.drawerProfile(DrawerStar)
+	c.OpenCashDrawerProfile(DrawerPin2, p)
+	c.OpenCashDrawerProfile(DrawerPin5, p)
+}
+
+// SetClock sets the clock of the printer with the command of the clock profile.
+func (c *star) SetClock(t time.Time) {
+	c.setClock(t)
+}
+
+// RequestClock requests the time of the clock with the command of the clock profile.
+func (c *star) RequestClock() {
+	c.requestClock()
+}
+
+// PrintClock prints the time of the clock with the command of the clock profile.
+func (c *star) PrintClock() {
+	c.printClock()
+}
+
+// Reverse selects [ESC 4] or cancels [ESC 5] highlight (white/black reverse) printing.
+func (c *star) Reverse(b bool) {
+	if b {
+		c.Write(ESC, '4')
+		return
+	}
+	c.Write(ESC, '5')
+}
+
+// Beep drives the external buzzer [ESC GS EM DC1, ESC GS EM DC2].
+//
+//	1 <= n <= 255 - the number of beeps.
+//	1 <= t <= 255 - the on and off time of each beep (20 ms x t).
+func (c *star) Beep(n, t byte) {
+	if (n == 0 || t == 0) && c.invalid("Beep", "%d beeps of %d must be positive", n, t) {
+		return
+	}
+	t = maxByte(t, 1)
+	c.Write(ESC, GS, EM, DC1, 1, t, t)
+	c.Write(ESC, GS, EM, DC2, 1, maxByte(n, 1), 0)
+}
+
+// LabelAdjust stores the adjustment of the position, which is applied by LabelFeed.
+// The star command set can't feed the paper backwards, so the resulting distance is at least zero.
+func (c *star) LabelAdjust(p byte, n int) {
+	switch p {
+	case LabelPrintPosition:
+		c.printAdjust = n
+	case LabelPeelPosition, LabelCutPosition:
+		c.peelAdjust = n
+	default:
+		c.invalid("LabelAdjust", "unknown position %d", p)
 	}
-	return [14]byte{49, 48, 50, 51, 52, 55, 54, 53, 56, 57, 65, 66, 67, 68}[m]
+}
+
+// LabelFeed feeds the label [ESC J] to the peel or cut position by the distance set with WithLinerFree,
+// or to the print start position by the adjustment set with LabelAdjust.
+func (c *star) LabelFeed(p byte) {
+	var n int
+	switch p {
+	case LabelPeelPosition, LabelCutPosition:
+		n = int(c.labelFeed) + c.peelAdjust
+	case LabelPrintPosition:
+		n = c.printAdjust
+	default:
+		c.invalid("LabelFeed", "unknown position %d", p)
+		return
+	}
+	for ; n > 0; n -= 255 {
+		c.Write(ESC, 'J', byte(minByte(n, 255)))
+	}
+}
+
+// RequestLabelTaken requests the automatic status [ESC ACK SOH],
+// which reports whether the label has been taken on printers with a taken sensor.
+// Liner-free models don't cut the label while it is not taken, so the application waits
+// for the label to be taken before printing the next one.
+func (c *star) RequestLabelTaken() {
+	c.Write(ESC, ACK, SOH)
+	c.Flush()
+}
+
+// StartDocument marks the start of a document [ESC GS ETX 1 0 0].
+func (c *star) StartDocument() {
+	c.Write(ESC, GS, ETX, 1, 0, 0)
+}
+
+// EndDocument marks the end of the document [ESC GS ETX 2 0 0] and flushes the output.
+func (c *star) EndDocument() {
+	c.Write(ESC, GS, ETX, 2, 0, 0)
+	c.Flush()
+}
+
+// Checkpoint increments the ETB counter [ETB] once the data before it has been printed.
+func (c *star) Checkpoint() {
+	c.checkpoints = (c.checkpoints + 1) % 32
+	c.Write(ETB)
+}
+
+// ResetCheckpoints clears the ETB counter [ESC RS E 0].
+func (c *star) ResetCheckpoints() {
+	c.checkpoints, c.counted = 0, true
+	c.Write(ESC, RS, 'E', 0)
+}
+
+// Print flushes the output and, if WithCompletion is used, sends a checkpoint after the job
+// and requests the automatic status [ESC ACK SOH] until the ETB counter reports it.
+// The counter of the printer persists across the connections, so Init resets it with WithCompletion,
+// as does Print if the command set hasn't reset it yet.
+func (c *star) Print() error {
+	if c.completion <= 0 {
+		return c.skipper.Print()
+	}
+
+	if !c.counted {
+		c.ResetCheckpoints()
+	}
+	c.Checkpoint()
+	c.RequestLabelTaken()
+	if err := c.skipper.Print(); err != nil {
+		return err
+	}
+
+	var status []byte
+	return c.awaitReply(func(b byte) bool {
+		status = append(status, b)
+		s, n, err := ParseStarStatus(status)
+		switch {
+		case err == nil:
+			if s.Checkpoints == c.checkpoints {
+				return true
+			}
+			// The printer hasn't reached the checkpoint yet, it is polled again.
+			status = status[n:]
+			c.RequestLabelTaken()
+		case len(status) >= 16 || status[0]&0x91 != 0x01:
+			// The data isn't the beginning of a status, it is skipped.
+			status = status[1:]
+		}
+		return false
+	})
 }
@@ -0,0 +1,180 @@
+package thermalize
+
+import "sync"
+
+// Encoder converts a UTF-8 string to the bytes of a code page.
+type Encoder interface {
+	// Name returns the name of the code page, such as "CP866", used in error messages.
+	Name() string
+
+	// Encode converts the string to the bytes of the code page.
+	Encode(s string) []byte
+
+	// CodePage returns the code page selected with CodePage for the encoding,
+	// usually a symbolic code page, such as CodePageCP866, which is mapped to the code of each command set.
+	// It returns false if the code page is unknown.
+	CodePage() (byte, bool)
+
+	// CanEncode reports whether the rune is encoded without a replacement character.
+	CanEncode(r rune) bool
+}
+
+// EncoderFunc adapts an encoding function, such as the Bytes method of a charmap encoder, to the Encoder interface.
+// The code page of the function is unknown, a rune can be encoded if the function doesn't replace it with '?' or SUB.
+type EncoderFunc func(string) []byte
+
+func (fn EncoderFunc) Name() string {
+	return "unnamed encoder"
+}
+
+func (fn EncoderFunc) Encode(s string) []byte {
+	return fn(s)
+}
+
+func (fn EncoderFunc) CodePage() (byte, bool) {
+	return 0, false
+}
+
+func (fn EncoderFunc) CanEncode(r rune) bool {
+	if r < 0x80 {
+		return true
+	}
+	bs := fn(string(r))
+	return len(bs) > 0 && !(len(bs) == 1 && (bs[0] == '?' || bs[0] == SUB))
+}
+
+// NewEncoder returns the encoder of the code page, such as CodePageCP866, named for the error messages.
+func NewEncoder(name string, page byte, fn func(string) []byte) Encoder {
+	return namedEncoder{EncoderFunc: fn, name: name, page: page}
+}
+
+type namedEncoder struct {
+	EncoderFunc
+	name string
+	page byte
+}
+
+func (e namedEncoder) Name() string {
+	return e.name
+}
+
+func (e namedEncoder) CodePage() (byte, bool) {
+	return e.page, true
+}
+
+var codePages = struct {
+	sync.RWMutex
+	encoders map[byte]Encoder
+}{encoders: make(map[byte]Encoder)}
+
+// RegisterCodePage registers the encoder of the code page b.
+//
+// Once the code page is selected with CodePage or WithCodePage,
+// Text uses the registered encoder whenever no encoder is provided.
+// Registering a nil encoder removes the registration.
+//
+// Example Usage:
+//
+//	thermalize.RegisterCodePage(thermalize.CodePageCP866, thermalize.NewEncoder("CP866", thermalize.CodePageCP866, encodeCP866))
+func RegisterCodePage(b byte, enc Encoder) {
+	codePages.Lock()
+	defer codePages.Unlock()
+
+	if enc == nil {
+		delete(codePages.encoders, b)
+		return
+	}
+	codePages.encoders[b] = enc
+}
+
+// LookupCodePage returns the encoder registered for the code page b, or nil if there is none.
+func LookupCodePage(b byte) Encoder {
+	codePages.RLock()
+	defer codePages.RUnlock()
+
+	return codePages.encoders[b]
+}
+
+// Symbolic code pages, which each command set maps to its own code in CodePage,
+// since ESC/POS and star printers number the same code pages differently.
+// Encoders registered for a symbolic code page are used by all command sets.
+//
+// The symbolic code pages occupy the codes from 0xC0, which neither command set uses,
+// other codes are sent to the printer as they are.
+const (
+	CodePageCP437 byte = 0xC0 + iota
+	CodePageKatakana
+	CodePageCP737
+	CodePageCP850
+	CodePageCP852
+	CodePageCP855
+	CodePageCP857
+	CodePageCP858
+	CodePageCP860
+	CodePageCP861
+	CodePageCP862
+	CodePageCP863
+	CodePageCP864
+	CodePageCP865
+	CodePageCP866
+	CodePageCP869
+	CodePageCP1250
+	CodePageCP1251
+	CodePageCP1252
+	codePageEnd
+)
+
+// escapeCodePages maps the symbolic code pages to the codes of [ESC t n].
+var escapeCodePages = map[byte]byte{
+	CodePageCP437:    0,
+	CodePageKatakana: 1,
+	CodePageCP737:    14,
+	CodePageCP850:    2,
+	CodePageCP852:    18,
+	CodePageCP855:    34,
+	CodePageCP857:    13,
+	CodePageCP858:    19,
+	CodePageCP860:    3,
+	CodePageCP861:    35,
+	CodePageCP862:    36,
+	CodePageCP863:    4,
+	CodePageCP864:    37,
+	CodePageCP865:    5,
+	CodePageCP866:    17,
+	CodePageCP869:    38,
+	CodePageCP1250:   45,
+	CodePageCP1251:   46,
+	CodePageCP1252:   16,
+}
+
+// starCodePages maps the symbolic code pages to the codes of [ESC GS t n], star printers have no CP850.
+var starCodePages = map[byte]byte{
+	CodePageCP437:    1,
+	CodePageKatakana: 2,
+	CodePageCP737:    15,
+	CodePageCP852:    5,
+	CodePageCP855:    11,
+	CodePageCP857:    12,
+	CodePageCP858:    4,
+	CodePageCP860:    6,
+	CodePageCP861:    7,
+	CodePageCP862:    13,
+	CodePageCP863:    8,
+	CodePageCP864:    14,
+	CodePageCP865:    9,
+	CodePageCP866:    10,
+	CodePageCP869:    17,
+	CodePageCP1250:   33,
+	CodePageCP1251:   34,
+	CodePageCP1252:   32,
+}
+
+// nativeCodePage returns the code of the code page b in the table,
+// and false if b is a symbolic code page missing from the table.
+func nativeCodePage(codes map[byte]byte, b byte) (byte, bool) {
+	if b < CodePageCP437 || b >= codePageEnd {
+		return b, true
+	}
+	n, ok := codes[b]
+	return n, ok
+}
@@ -0,0 +1,178 @@
+package thermalize
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// encodeZhe encodes ASCII and 'Ж' as in CP866, other runes are replaced with '?'.
+func encodeZhe(s string) []byte {
+	var bs []byte
+	for _, r := range s {
+		switch {
+		case r < 0x80:
+			bs = append(bs, byte(r))
+		case r == 'Ж':
+			bs = append(bs, 0x86)
+		default:
+			bs = append(bs, '?')
+		}
+	}
+	return bs
+}
+
+func TestWithCodePage(t *testing.T) {
+	var buf bytes.Buffer
+	cmd := NewEscape(48, 576, &buf, WithCodePage(17, EncoderFunc(encodeZhe)))
+	cmd.Init()
+	cmd.Text("Ж", nil)
+	cmd.Text("Ж", func(string) []byte { return []byte("explicit") })
+	cmd.Print()
+
+	want := []byte{ESC, '@', ESC, 't', 17, 0x86}
+	if !bytes.HasPrefix(buf.Bytes(), want) || !bytes.Contains(buf.Bytes(), []byte("explicit")) {
+		t.Errorf("wrote % x, want the code page selected by Init and the text encoded", buf.Bytes())
+	}
+}
+
+func TestRegisterCodePage(t *testing.T) {
+	RegisterCodePage(17, EncoderFunc(encodeZhe))
+	defer RegisterCodePage(17, nil)
+	if LookupCodePage(17) == nil {
+		t.Fatal("the registered encoder isn't found")
+	}
+
+	var buf bytes.Buffer
+	cmd := NewEscape(48, 576, &buf)
+	cmd.Text("Ж", nil)
+	cmd.CodePage(17)
+	cmd.Text("Ж", nil)
+	cmd.Print()
+	// The text is encoded once the code page is selected.
+	if want := append([]byte("Ж"), ESC, 't', 17, 0x86); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("wrote % x, want % x", buf.Bytes(), want)
+	}
+
+	RegisterCodePage(17, nil)
+	if LookupCodePage(17) != nil {
+		t.Error("the encoder registered for nil isn't removed")
+	}
+	cmd = NewEscape(48, 576, io.Discard, WithCodePage(17, nil))
+	cmd.Init()
+	if c := skipperOf(cmd); c.enc != nil {
+		t.Error("the code page without a registered encoder encodes the text")
+	}
+}
+
+func TestSymbolicCodePages(t *testing.T) {
+	RegisterCodePage(CodePageCP866, EncoderFunc(encodeZhe))
+	defer RegisterCodePage(CodePageCP866, nil)
+
+	for _, tc := range []struct {
+		name string
+		new  func(io.Writer) Cmd
+		want []byte
+	}{
+		{"escape", func(w io.Writer) Cmd { return NewEscape(48, 576, w) }, []byte{ESC, 't', 17, 0x86}},
+		{"star", func(w io.Writer) Cmd { return NewStar(48, 576, w) }, []byte{ESC, GS, 't', 10, 0x86}},
+	} {
+		var buf bytes.Buffer
+		cmd := tc.new(&buf)
+		// The encoder registered for the symbolic code page is used by both command sets.
+		cmd.CodePage(CodePageCP866)
+		cmd.Text("Ж", nil)
+		cmd.Print()
+		if !bytes.Equal(buf.Bytes(), tc.want) {
+			t.Errorf("%s: wrote % x, want % x", tc.name, buf.Bytes(), tc.want)
+		}
+
+		// The other codes are sent as they are.
+		buf.Reset()
+		cmd.CodePage(3)
+		cmd.Print()
+		if got := buf.Bytes(); got[len(got)-1] != 3 {
+			t.Errorf("%s: the native code page is sent as % x", tc.name, got)
+		}
+	}
+
+	for name, codes := range map[string]map[byte]byte{"escape": escapeCodePages, "star": starCodePages} {
+		seen := make(map[byte]byte)
+		for b := CodePageCP437; b < codePageEnd; b++ {
+			n, ok := codes[b]
+			if !ok {
+				if name != "star" || b != CodePageCP850 {
+					t.Errorf("%s: the symbolic code page %#x isn't mapped", name, b)
+				}
+				continue
+			}
+			if p, ok := seen[n]; ok {
+				t.Errorf("%s: the code pages %#x and %#x are both mapped to %d", name, p, b, n)
+			}
+			seen[n] = b
+		}
+	}
+
+	// Star printers have no CP850.
+	var buf bytes.Buffer
+	cmd := NewStar(48, 576, &buf, WithStrict())
+	cmd.CodePage(CodePageCP850)
+	cmd.Print()
+	if err := cmdErr(cmd); err == nil || buf.Len() != 0 {
+		t.Errorf("the unsupported code page wrote % x: %v", buf.Bytes(), err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	fn := EncoderFunc(encodeZhe)
+	sub := EncoderFunc(func(string) []byte { return []byte{SUB} })
+	for _, tc := range []struct {
+		enc  Encoder
+		r    rune
+		want bool
+	}{
+		{fn, 'A', true},
+		{fn, 'Ж', true},
+		{fn, 'Я', false},
+		{sub, 'Я', false},
+		{sub, '?', true},
+	} {
+		if got := tc.enc.CanEncode(tc.r); got != tc.want {
+			t.Errorf("CanEncode(%q) = %v, want %v", tc.r, got, tc.want)
+		}
+	}
+	if _, ok := fn.CodePage(); ok {
+		t.Error("the code page of an EncoderFunc is known")
+	}
+
+	enc := NewEncoder("CP866", CodePageCP866, encodeZhe)
+	if page, ok := enc.CodePage(); enc.Name() != "CP866" || page != CodePageCP866 || !ok {
+		t.Errorf("the encoder is %s of %#x, %v", enc.Name(), page, ok)
+	}
+
+	// WithEncoder selects the code page of the encoder on Init.
+	var buf bytes.Buffer
+	cmd := NewEscape(48, 576, &buf, WithEncoder(enc), WithStrict())
+	cmd.Init()
+	cmd.Text("Ж", nil)
+	cmd.Text("Я", nil)
+	cmd.Print()
+	if want := []byte{ESC, '@', ESC, 't', 17, 0x86}; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("wrote % x, want % x", buf.Bytes(), want)
+	}
+	var v *ValidationError
+	if err := cmdErr(cmd); !errors.As(err, &v) || !strings.Contains(v.Reason, "CP866") {
+		t.Errorf("the rune the encoder can't encode: %v", err)
+	}
+
+	// The encoder of an unknown code page is skipped.
+	buf.Reset()
+	cmd = NewEscape(48, 576, &buf, WithEncoder(fn))
+	cmd.Init()
+	cmd.Print()
+	if want := []byte{ESC, '@'}; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("wrote % x, want % x", buf.Bytes(), want)
+	}
+}
@@ -0,0 +1,47 @@
+package thermalize
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotConfirmed is returned by Print if WithCompletion is used and the printer doesn't confirm the job in time.
+var ErrNotConfirmed = errors.New("thermalize: job not confirmed by the printer")
+
+// deadliner is implemented by connections with read deadlines, such as net.Conn and transport.TCP.
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// awaitReply reads the data transmitted by the printer until done reports the end of the reply,
+// or the completion timeout set by WithCompletion expires. The reply isn't awaited if the writer can't be read.
+// If the writer has read deadlines, a read waiting for the printer is interrupted by the timeout,
+// otherwise the timeout is checked after each byte.
+func (c *skipper) awaitReply(done func(b byte) bool) error {
+	r, ok := c.w.(io.Reader)
+	if !ok {
+		return nil
+	}
+
+	deadline := time.Now().Add(c.completion)
+	if d, ok := c.w.(deadliner); ok {
+		if err := d.SetReadDeadline(deadline); err == nil {
+			defer d.SetReadDeadline(time.Time{})
+		}
+	}
+
+	var b [1]byte
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return fmt.Errorf("%w: %v", ErrNotConfirmed, err)
+		}
+		if done(b[0]) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrNotConfirmed
+		}
+	}
+}
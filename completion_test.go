@@ -0,0 +1,83 @@
+package thermalize
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// starPrinter replies to each status request with the next of the statuses, and with nothing once they run out.
+type starPrinter struct {
+	statuses [][]byte
+	reply    bytes.Buffer
+	polls    int
+}
+
+func (p *starPrinter) Write(bs []byte) (int, error) {
+	for n := bytes.Count(bs, []byte{ESC, ACK, SOH}); n > 0 && len(p.statuses) > 0; n-- {
+		p.reply.Write(p.statuses[0])
+		p.statuses = p.statuses[1:]
+		p.polls++
+	}
+	return len(bs), nil
+}
+
+func (p *starPrinter) Read(bs []byte) (int, error) {
+	return p.reply.Read(bs)
+}
+
+// starStatus returns an automatic status of 9 bytes reporting the ETB counter.
+func starStatus(checkpoints byte) []byte {
+	return []byte{0x23, 0, 0, 0, 0, 0, 0, checkpoints&0x07<<1 | checkpoints&0x18<<2, 0}
+}
+
+func TestStarCompletionPollsAgain(t *testing.T) {
+	p := &starPrinter{statuses: [][]byte{starStatus(0), starStatus(1)}}
+	cmd := NewStar(48, 576, p, WithBufferSize(4096), WithCompletion(time.Second))
+	cmd.Text("Hello", nil)
+
+	if err := cmd.Print(); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if p.polls != 2 {
+		t.Errorf("the printer is polled %d times, want 2", p.polls)
+	}
+}
+
+func TestStarCompletionNotConfirmed(t *testing.T) {
+	p := &starPrinter{statuses: [][]byte{starStatus(0)}}
+	cmd := NewStar(48, 576, p, WithCompletion(time.Second))
+
+	if err := cmd.Print(); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("Print: %v, want %v", err, ErrNotConfirmed)
+	}
+}
+
+// silentPrinter accepts the data and never replies.
+type silentPrinter struct {
+	io.Writer
+}
+
+func (silentPrinter) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+func TestDocumentReplayNotConfirmed(t *testing.T) {
+	doc := NewDocument(48, 576)
+	doc.Init()
+	doc.Text("Hello", nil)
+	doc.Print()
+
+	cmd := NewEscape(48, 576, silentPrinter{io.Discard}, WithCompletion(time.Second))
+	if err := doc.Replay(cmd); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("Replay: %v, want %v", err, ErrNotConfirmed)
+	}
+	if err := doc.Replay(cmd); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("the copy: %v, want %v", err, ErrNotConfirmed)
+	}
+	if err := doc.Render(NewEscape(48, 576, io.Discard)); err != nil {
+		t.Errorf("Render without completion: %v", err)
+	}
+}
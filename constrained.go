@@ -0,0 +1,89 @@
+package thermalize
+
+import "unicode/utf8"
+
+const (
+	// constrainedBufferSize and constrainedRasterSize are the receive buffer and the raster buffer of the constrained device.
+	constrainedBufferSize = 256
+	constrainedRasterSize = 4096
+
+	// constrainedPPL is the print width of the constrained device, 72 mm at 203 dpi.
+	constrainedPPL = 576
+)
+
+// cp437 holds the runes of the bytes 0x80-0xFF of code page 437.
+const cp437 = "ÇüéâäàåçêëèïîìÄÅÉæÆôöòûùÿÖÜ¢£¥₧ƒáíóúñÑªº¿⌐¬½¼¡«»" +
+	"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐└┴┬├─┼╞╟╚╔╩╦╠═╬╧╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ "
+
+// cp437Bytes maps the runes of code page 437 above 0x7F to their bytes.
+var cp437Bytes = func() map[rune]byte {
+	m := make(map[rune]byte, 128)
+	b := 0x80
+	for _, r := range cp437 {
+		m[r] = byte(b)
+		b++
+	}
+	return m
+}()
+
+// transliterations holds the ASCII spellings of the common runes code page 437 lacks.
+var transliterations = func() map[rune]string {
+	m := map[rune]string{
+		'Œ': "OE", 'œ': "oe", 'Þ': "TH", 'þ': "th", 'Ð': "D", 'ð': "d",
+		'‘': "'", '’': "'", '‚': "'", '“': `"`, '”': `"`, '„': `"`,
+		'–': "-", '—': "-", '…': "...", '•': "*", '×': "x",
+	}
+	for base, runes := range map[string]string{
+		"A": "ÀÁÂÃĀĂĄ", "a": "ãāăą",
+		"C": "ĆĈČ", "c": "ćĉč",
+		"D": "Ď", "d": "ď",
+		"E": "ÈÊËĒĖĘĚ", "e": "ēėęě",
+		"G": "ĞĢ", "g": "ğģ",
+		"I": "ÌÍÎÏĪİ", "i": "īıį",
+		"L": "ĹĻĽŁ", "l": "ĺļľł",
+		"N": "ŃŅŇ", "n": "ńņň",
+		"O": "ÒÓÔÕØŌŐ", "o": "õøōő",
+		"R": "ŔŘ", "r": "ŕř",
+		"S": "ŚŞŠ", "s": "śşš",
+		"T": "ŢŤ", "t": "ţť",
+		"U": "ÙÚÛŪŮŰŲ", "u": "ūůűų",
+		"Y": "ÝŸ", "y": "ý",
+		"Z": "ŹŻŽ", "z": "źżž",
+	} {
+		for _, r := range runes {
+			m[r] = base
+		}
+	}
+	return m
+}()
+
+// cp437Encoder is the only code page of the constrained device and of the printers with QuirkCP437Only,
+// it transliterates the runes code page 437 lacks to ASCII, and replaces the other runes with '?'.
+var cp437Encoder Encoder = namedEncoder{EncoderFunc: encodeCP437, name: "CP437", page: CodePageCP437}
+
+func encodeCP437(s string) []byte {
+	bs := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			bs = append(bs, byte(r))
+		} else if b, ok := cp437Bytes[r]; ok {
+			bs = append(bs, b)
+		} else if t, ok := transliterations[r]; ok {
+			bs = append(bs, t...)
+		} else {
+			bs = append(bs, '?')
+		}
+	}
+	return bs
+}
+
+// constrain makes the command set behave like the constrained device of WithConstrainedDevice.
+func (c *skipper) constrain() {
+	c.constrained = true
+	if c.size == 0 || c.size > constrainedBufferSize {
+		c.size = constrainedBufferSize
+	}
+	c.ppl = minByte(c.ppl, constrainedPPL)
+	c.addQuirks(QuirkNoQRCode | QuirkCP437Only)
+}
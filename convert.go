@@ -0,0 +1,400 @@
+package thermalize
+
+import (
+	"image"
+	"image/color"
+)
+
+// ConvertOption customizes the conversion of ConvertEscape.
+type ConvertOption interface {
+	apply(*converter)
+}
+
+type convertOptionFunc func(*converter)
+
+func (fn convertOptionFunc) apply(c *converter) {
+	fn(c)
+}
+
+// WithTextDecoder decodes the text of the converted stream with fn, which receives the code page selected
+// by [ESC t n], a symbolic code page such as CodePageCP866 if the code is known, and the encoded text.
+// By default, the text is passed to the command set as it is encoded, which preserves it
+// for another ESC/POS or star command set, but only its ASCII characters for the postscript command set.
+func WithTextDecoder(fn func(page byte, data []byte) string) ConvertOption {
+	return convertOptionFunc(func(c *converter) { c.decode = fn })
+}
+
+// ConvertEscape re-emits the ESC/POS command stream, such as a job captured from the escape command set
+// or another application, through the command set cmd, e.g. to archive the receipts as postscript documents.
+// The stream is decoded with DecodeEscape, and the commands are mapped to the methods of cmd,
+// the optional commands to its capability interfaces. The images are decoded from their raster data,
+// and the bands of a bit image printed with [ESC * m ... ESC J] are joined into one image.
+//
+// The commands that can't be converted, such as unknown commands and the commands cmd doesn't support,
+// are returned in order. The document is not printed, so the conversion is usually followed by Print.
+//
+// Example Usage:
+//
+//	archive := thermalize.NewPostscript(48, 576, file)
+//	skipped := thermalize.ConvertEscape(archive, job)
+//	archive.Print()
+func ConvertEscape(cmd Cmd, bs []byte, opts ...ConvertOption) []Command {
+	c := &converter{cmd: cmd, page: CodePageCP437}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	cmds := DecodeEscape(bs)
+	for i := 0; i < len(cmds); i++ {
+		if cmds[i].Name == "ESC *" {
+			// A band followed by a feed is a part of an image, otherwise it is printed along with the text.
+			if i+1 < len(cmds) && cmds[i+1].Name == "ESC J" && len(cmds[i+1].Args) == 1 {
+				c.bands = append(c.bands, bitImage(cmds[i]))
+				c.lastFeed = int(cmds[i+1].Args[0])
+				i++
+				continue
+			}
+			c.flush()
+			if ii, ok := c.cmd.(InlineImager); ok {
+				ii.InlineImage(bitImage(cmds[i]), VAlignTop)
+			} else {
+				c.cmd.Image(bitImage(cmds[i]), false)
+			}
+			continue
+		}
+		c.flush()
+		if !c.convert(cmds[i]) {
+			c.skipped = append(c.skipped, cmds[i])
+		}
+	}
+	c.flush()
+
+	return c.skipped
+}
+
+type converter struct {
+	cmd    Cmd
+	decode func(byte, []byte) string
+
+	// page is the code page selected by [ESC t n].
+	page byte
+
+	// qr is the data stored in the QR code symbol storage area, graphics is the image stored by [GS 8 L].
+	qr       string
+	graphics image.Image
+
+	// bands are the bands of the bit image being joined, lastFeed is the feed after the last of them.
+	bands    []*image.Gray
+	lastFeed int
+
+	pageMode bool
+	skipped  []Command
+}
+
+// convert calls the method of the command set for the command and reports whether the command was converted.
+func (c *converter) convert(in Command) bool {
+	cmd := c.cmd
+	arg := func(i int) byte {
+		if i < len(in.Args) {
+			return in.Args[i]
+		}
+		return 0
+	}
+	// Some parameters are accepted both as a number and as an ASCII digit.
+	digit := func(i int) byte {
+		if b := arg(i); b >= '0' {
+			return b - '0'
+		}
+		return arg(i)
+	}
+	word := func(i int) int {
+		return int(arg(i)) | int(arg(i+1))<<8
+	}
+
+	switch in.Name {
+	case textCommand:
+		if c.decode != nil {
+			cmd.Text(c.decode(c.page, in.Data), nil)
+			break
+		}
+		cmd.Text(string(in.Data), func(s string) []byte { return []byte(s) })
+	case "LF":
+		cmd.LineFeed()
+	case "HT":
+		cmd.Tab()
+	case "CR":
+	case "ESC @":
+		cmd.Init()
+		c.page, c.pageMode = CodePageCP437, false
+	case "ESC a":
+		cmd.Align(digit(0))
+	case "ESC E":
+		cmd.Bold(arg(0)&1 == 1)
+	case "ESC -":
+		cmd.Underling(digit(0))
+	case "ESC {":
+		cmd.UpsideDown(arg(0)&1 == 1)
+	case "ESC V":
+		cmd.ClockwiseRotation(digit(0) == 1)
+	case "ESC !":
+		n := arg(0)
+		cmd.Bold(n&0x08 != 0)
+		cmd.CharSize(n>>5&1, n>>4&1)
+		cmd.Underling(n >> 7)
+	case "ESC $":
+		cmd.AbsolutePosition(word(0))
+	case "ESC D":
+		cmd.TabPositions(in.Data...)
+	case "ESC t":
+		c.page = symbolicCodePageOf(escapeCodePages, arg(0))
+		cmd.CodePage(c.page)
+	case "ESC J":
+		cmd.Feed(arg(0))
+	case "ESC d":
+		for n := arg(0); n > 0; n-- {
+			cmd.LineFeed()
+		}
+	case "ESC p":
+		cmd.OpenCashDrawer(digit(0), arg(1), arg(2))
+	case "GS !":
+		cmd.CharSize(arg(0)>>4&7, arg(0)&7)
+	case "GS L":
+		cmd.LeftMargin(word(0))
+	case "GS W":
+		cmd.WidthArea(word(0))
+	case "GS w":
+		cmd.BarcodeWidth(arg(0))
+	case "GS h":
+		cmd.BarcodeHeight(arg(0))
+	case "GS f":
+		cmd.HRIFont(digit(0))
+	case "GS H":
+		cmd.HRIPosition(digit(0))
+	case "GS k":
+		m := arg(0)
+		if m <= 6 {
+			m += 65
+		}
+		b, ok := barcodeOfType(m)
+		if !ok {
+			return false
+		}
+		cmd.Barcode(b, string(in.Data))
+	case "GS ( k":
+		return c.qrCode(in.Data)
+	case "GS ( K":
+		d, ok := cmd.(DensityController)
+		if !ok || len(in.Data) < 2 || in.Data[0] != 49 {
+			return false
+		}
+		d.PrintDensity(int(int8(in.Data[1])))
+	case "GS ( H":
+		p, ok := cmd.(ProcessIDResponder)
+		if !ok || len(in.Data) != 6 || in.Data[0] != 48 {
+			return false
+		}
+		p.RequestProcessID(string(in.Data[2:]))
+	case "GS v":
+		w, h := word(2), word(4)
+		if len(in.Data) < w*h {
+			return false
+		}
+		cmd.Image(rasterImage(w, h, in.Data), false)
+	case "GS 8 L":
+		// The graphics data follows the parameters [48 112 48 bx by c xL xH yL yH].
+		bs := in.Data
+		if len(bs) < 10 || bs[1] != 112 {
+			return false
+		}
+		x, y := int(bs[6])|int(bs[7])<<8, int(bs[8])|int(bs[9])<<8
+		w := (x + 7) / 8
+		if len(bs)-10 < w*y {
+			return false
+		}
+		c.graphics = rasterImage(w, y, bs[10:])
+	case "GS ( L":
+		if len(in.Data) < 2 || in.Data[1] != 2 && in.Data[1] != 50 || c.graphics == nil {
+			return false
+		}
+		cmd.Image(c.graphics, false)
+	case "GS V":
+		m := arg(0)
+		if m == 48 || m == 49 {
+			m -= 48
+		}
+		cmd.Cut(m, arg(1))
+	case "GS B":
+		r, ok := cmd.(Reverser)
+		if !ok {
+			return false
+		}
+		r.Reverse(arg(0)&1 == 1)
+	case "ESC r":
+		p, ok := cmd.(ColorPrinter)
+		if !ok {
+			return false
+		}
+		p.Color(digit(0))
+	case "ESC B":
+		b, ok := cmd.(Beeper)
+		if !ok {
+			return false
+		}
+		b.Beep(arg(0), arg(1))
+	case "ESC L", "ESC S", "ESC W", "FF":
+		return c.pageCommand(in.Name, word)
+	default:
+		return false
+	}
+	return true
+}
+
+// pageCommand converts the page mode commands, FF is converted only in page mode, where it prints the page.
+func (c *converter) pageCommand(name string, word func(int) int) bool {
+	p, ok := c.cmd.(PageModer)
+	if !ok {
+		return false
+	}
+	switch name {
+	case "ESC L":
+		p.PageMode(true)
+		c.pageMode = true
+	case "ESC S":
+		p.PageMode(false)
+		c.pageMode = false
+	case "ESC W":
+		p.PrintArea(word(0), word(2), word(4), word(6))
+	case "FF":
+		if !c.pageMode {
+			return false
+		}
+		p.PrintPage()
+		c.pageMode = false
+	}
+	return true
+}
+
+// qrCode converts the QR code functions [GS ( k cn = 49].
+func (c *converter) qrCode(bs []byte) bool {
+	if len(bs) < 3 || bs[0] != 49 {
+		return false
+	}
+	switch bs[1] {
+	case 65:
+		m, ok := c.cmd.(QRModeler)
+		if !ok {
+			return false
+		}
+		m.QRCodeModel(bs[2] - 49)
+	case 67:
+		c.cmd.QRCodeSize(bs[2])
+	case 69:
+		c.cmd.QRCodeCorrectionLevel(bs[2] - 48)
+	case 80:
+		c.qr = string(bs[3:])
+	case 81:
+		if c.qr == "" {
+			return false
+		}
+		c.cmd.QRCode(c.qr)
+	default:
+		return false
+	}
+	return true
+}
+
+// flush prints the joined bands of the bit image, if there are any.
+// The last band is cropped to the feed after it, which is reduced to its rows (see WithBandFeed).
+func (c *converter) flush() {
+	if len(c.bands) == 0 {
+		return
+	}
+	last := c.bands[len(c.bands)-1]
+	if c.lastFeed > 0 && c.lastFeed < last.Rect.Dy() {
+		c.bands[len(c.bands)-1] = last.SubImage(image.Rect(0, 0, last.Rect.Dx(), c.lastFeed)).(*image.Gray)
+	}
+
+	w, h := 0, 0
+	for _, b := range c.bands {
+		w, h = maxByte(w, b.Rect.Dx()), h+b.Rect.Dy()
+	}
+	img := blankImage(w, h)
+	y := 0
+	for _, b := range c.bands {
+		for row := 0; row < b.Rect.Dy(); row++ {
+			copy(img.Pix[(y+row)*img.Stride:], b.Pix[row*b.Stride:row*b.Stride+b.Rect.Dx()])
+		}
+		y += b.Rect.Dy()
+	}
+	c.bands = c.bands[:0]
+
+	c.cmd.Image(img, false)
+}
+
+// symbolicCodePageOf returns the symbolic code page of the native code n, or n if it isn't mapped.
+func symbolicCodePageOf(codes map[byte]byte, n byte) byte {
+	for b, code := range codes {
+		if code == n {
+			return b
+		}
+	}
+	return n
+}
+
+// barcodeOfType returns the barcode system of the [GS k m] type code of the escape command set.
+func barcodeOfType(m byte) (byte, bool) {
+	for b, t := range [14]byte{65, 66, 68, 67, 69, 72, 73, 70, 71, 74, 75, 76, 77, 78} {
+		if t == m {
+			return byte(b), true
+		}
+	}
+	return 0, false
+}
+
+// blankImage returns a white image of the size.
+func blankImage(w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	return img
+}
+
+// rasterImage decodes the raster data of h rows of w bytes, the leftmost dot in the most significant bit.
+func rasterImage(w, h int, bs []byte) *image.Gray {
+	img := blankImage(w*8, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w*8; x++ {
+			if bs[y*w+x/8]&(0x80>>uint(x%8)) != 0 {
+				img.SetGray(x, y, color.Gray{})
+			}
+		}
+	}
+	return img
+}
+
+// bitImage decodes the band of the bit image [ESC * m nL nH d1...dk], the single density dots are doubled.
+func bitImage(in Command) *image.Gray {
+	if len(in.Args) < 3 {
+		return blankImage(0, 0)
+	}
+	m := in.Args[0]
+	n := int(in.Args[1]) | int(in.Args[2])<<8
+	k := 1
+	if m >= BitImage24Single {
+		k = 3
+	}
+	scale := 2 - int(m&1)
+
+	img := blankImage(n*scale, 8*k)
+	for x := 0; x < n && (x+1)*k <= len(in.Data); x++ {
+		for y := 0; y < 8*k; y++ {
+			if in.Data[x*k+y/8]&(0x80>>uint(y%8)) != 0 {
+				for i := 0; i < scale; i++ {
+					img.SetGray(x*scale+i, y, color.Gray{})
+				}
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,112 @@
+package thermalize
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"testing"
+)
+
+// opStrings returns the names and the arguments of the recorded commands, the images as their sizes.
+func opStrings(doc *Document) []string {
+	var out []string
+	for _, op := range doc.Ops() {
+		args := make([]string, len(op.Args))
+		for i, a := range op.Args {
+			if img, ok := a.(image.Image); ok {
+				a = img.Bounds().Size()
+			}
+			args[i] = fmt.Sprint(a)
+		}
+		out = append(out, strings.TrimSpace(op.Name+" "+strings.Join(args, " ")))
+	}
+	return out
+}
+
+func TestConvertEscape(t *testing.T) {
+	bs := []byte{ESC, '@', ESC, 'a', '1', ESC, 'E', 1, ESC, '!', 0x38}
+	bs = append(bs, "Hi"...)
+	bs = append(bs, LF, ESC, 'd', 2, GS, 'V', '1', ESC, 'B', 2, 3)
+
+	doc := NewDocument(48, 576)
+	if skipped := ConvertEscape(doc, bs); len(skipped) != 0 {
+		t.Errorf("the skipped commands are %v", skipped)
+	}
+	want := []string{
+		"Init", "Align 1", "Bold true",
+		// [ESC ! n] selects the emphasized mode and the double width and height at once.
+		"Bold true", "CharSize 1 1", "Underling 0",
+		"Text Hi", "LineFeed", "LineFeed", "LineFeed", "Cut 1 0", "Beep 2 3",
+	}
+	if got := opStrings(doc); strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("the commands are\n%q\nwant\n%q", got, want)
+	}
+
+	// The postscript command set doesn't beep.
+	skipped := ConvertEscape(NewPostscript(48, 576, io.Discard), append(bs, ESC, 0xFF))
+	if len(skipped) != 2 || skipped[0].Name != "ESC B" {
+		t.Errorf("the skipped commands are %v, want ESC B and the unknown command", skipped)
+	}
+}
+
+func TestConvertEscapeImages(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bs   []byte
+		want []string
+	}{
+		{"raster", []byte{GS, 'v', '0', 0, 1, 0, 2, 0, 0x80, 0x01}, []string{"Image (8,2) false"}},
+		{"short raster", []byte{GS, 'v', '0', 0, 1, 0, 2, 0, 0x80}, nil},
+		{
+			"bands",
+			append(append(bitImageBand(2), ESC, 'J', 24), append(bitImageBand(2), ESC, 'J', 10)...),
+			// The last band is cropped to the feed after it.
+			[]string{"Image (2,34) false"},
+		},
+		{"inline band", append(bitImageBand(3), 'x'), []string{"InlineImage (3,24) 0", "Text x"}},
+	} {
+		doc := NewDocument(48, 576)
+		ConvertEscape(doc, tc.bs)
+		if got := opStrings(doc); strings.Join(got, ", ") != strings.Join(tc.want, ", ") {
+			t.Errorf("%s: the commands are %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	doc := NewDocument(48, 576)
+	ConvertEscape(doc, []byte{GS, 'v', '0', 0, 1, 0, 2, 0, 0x80, 0x01})
+	img := doc.Ops()[0].Args[0].(image.Image)
+	for _, p := range []image.Point{{0, 0}, {7, 1}} {
+		if !black(img, p.X, p.Y) {
+			t.Errorf("the dot %v of the raster image is white", p)
+		}
+	}
+	if black(img, 1, 0) || black(img, 0, 1) {
+		t.Error("the raster image has extra dots")
+	}
+}
+
+// bitImageBand returns a band of the 24-dot double density bit image of n black columns.
+func bitImageBand(n int) []byte {
+	bs := []byte{ESC, '*', BitImage24Double, byte(n), 0}
+	for i := 0; i < 3*n; i++ {
+		bs = append(bs, 0xFF)
+	}
+	return bs
+}
+
+func TestConvertEscapeTextDecoder(t *testing.T) {
+	var page byte
+	decode := func(p byte, data []byte) string {
+		page = p
+		return strings.ToUpper(string(data))
+	}
+	doc := NewDocument(48, 576)
+	ConvertEscape(doc, []byte{ESC, 't', 17, 'a', 'b'}, WithTextDecoder(decode))
+	if page != CodePageCP866 {
+		t.Errorf("the decoder received the code page %d, want %d", page, CodePageCP866)
+	}
+	if got := opStrings(doc); len(got) != 2 || got[1] != "Text AB" {
+		t.Errorf("the commands are %q", got)
+	}
+}
@@ -0,0 +1,98 @@
+package thermalize
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Coupon is a coupon or a voucher printed by CouponBlock.
+type Coupon struct {
+	// Title is printed in double size, such as "10% OFF".
+	Title string
+
+	// Text is the description of the offer, word-wrapped to the width of the paper.
+	Text string
+
+	// Code is printed as a barcode of the Type (see Cmd.Barcode), such as Code128, with the readable text below it.
+	// The barcode is omitted if the code is empty.
+	Code string
+	Type byte
+
+	// Expires is printed as "Valid until" with ExpiryLayout, by default "2006-01-02", unless it is zero.
+	Expires      time.Time
+	ExpiryLayout string
+
+	// NoCut disables the partial cut after the coupon.
+	NoCut bool
+}
+
+// CouponBlock prints the coupon framed by dashed cut-here rules: the centered title, text, barcode and expiry date,
+// followed by a partial cut below the bottom rule, so the coupon can be torn off the receipt.
+//
+// Example Usage:
+//
+//	thermalize.CouponBlock(cmd, thermalize.Coupon{
+//		Title:   "10% OFF",
+//		Text:    "on your next purchase",
+//		Code:    "SAVE10-2931",
+//		Type:    thermalize.Code128,
+//		Expires: time.Now().AddDate(0, 1, 0),
+//	})
+func CouponBlock(cmd Cmd, c Coupon) {
+	rule := cutRule(cmd.CPL(), Translate(cmd, MessageCutHere))
+
+	cmd.Align(Left)
+	cmd.Text(rule, nil)
+	cmd.LineFeed()
+	cmd.Align(Center)
+
+	if c.Title != "" {
+		cmd.Bold(true)
+		cmd.CharSize(1, 1)
+		cmd.Text(c.Title, nil)
+		cmd.LineFeed()
+		cmd.CharSize(0, 0)
+		cmd.Bold(false)
+	}
+
+	if c.Text != "" {
+		textWrap(cmd, c.Text, nil)
+	}
+
+	if c.Code != "" {
+		cmd.HRIPosition(HRIBelow)
+		cmd.Barcode(c.Type, c.Code)
+		cmd.LineFeed()
+	}
+
+	if !c.Expires.IsZero() {
+		layout := c.ExpiryLayout
+		if layout == "" {
+			layout = "2006-01-02"
+		}
+		cmd.Text(Translate(cmd, MessageValidUntil)+" "+c.Expires.Format(layout), nil)
+		cmd.LineFeed()
+	}
+
+	cmd.Align(Left)
+	cmd.Text(rule, nil)
+	cmd.LineFeed()
+
+	if !c.NoCut {
+		cutWithFeed(cmd, true, 0)
+	}
+}
+
+// cutRule returns a dashed line of n characters with the label, such as "cut here", in the middle.
+func cutRule(n int, label string) string {
+	if label != "" {
+		label = " " + label + " "
+	}
+	l := utf8.RuneCountInString(label)
+	if n < l+2 {
+		return strings.Repeat("-", maxByte(n, 0))
+	}
+	left := (n - l) / 2
+	return strings.Repeat("-", left) + label + strings.Repeat("-", n-left-l)
+}
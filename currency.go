@@ -0,0 +1,116 @@
+package thermalize
+
+import "unicode/utf8"
+
+// currencyCodes holds the ISO 4217 codes printed instead of the currency symbols the code page lacks.
+var currencyCodes = map[rune]string{
+	'€': "EUR",
+	'£': "GBP",
+	'¥': "JPY",
+	'₽': "RUB",
+	'₴': "UAH",
+	'₹': "INR",
+	'₪': "ILS",
+	'₩': "KRW",
+	'₺': "TRY",
+	'₸': "KZT",
+	'₦': "NGN",
+	'₱': "PHP",
+	'฿': "THB",
+	'₫': "VND",
+	'₼': "AZN",
+	'₾': "GEL",
+	'₧': "ESP",
+}
+
+// currencyBytes holds the bytes of the currency symbols in the symbolic code pages.
+var currencyBytes = map[byte]map[rune]byte{
+	CodePageCP437:  {'¢': 0x9B, '£': 0x9C, '¥': 0x9D, '₧': 0x9E},
+	CodePageCP850:  {'¢': 0xBD, '£': 0x9C, '¥': 0xBE},
+	CodePageCP857:  {'¢': 0xBD, '£': 0x9C, '¥': 0xBE},
+	CodePageCP858:  {'€': 0xD5, '¢': 0xBD, '£': 0x9C, '¥': 0xBE},
+	CodePageCP860:  {'¢': 0x9B, '£': 0x9C},
+	CodePageCP862:  {'¢': 0x9B, '£': 0x9C, '¥': 0x9D},
+	CodePageCP863:  {'¢': 0x9B, '£': 0x9C},
+	CodePageCP865:  {'£': 0x9C},
+	CodePageCP1250: {'€': 0x80},
+	CodePageCP1251: {'€': 0x88},
+	CodePageCP1252: {'€': 0x80, '¢': 0xA2, '£': 0xA3, '¥': 0xA5},
+}
+
+// CurrencyCode returns the ISO 4217 code of the currency symbol, such as "EUR" for '€'.
+func CurrencyCode(r rune) (string, bool) {
+	code, ok := currencyCodes[r]
+	return code, ok
+}
+
+// isCurrency reports whether the rune is a currency symbol substituted by WithCurrencySymbols.
+func isCurrency(r rune) bool {
+	_, ok := currencyCodes[r]
+	return ok || r == '¢'
+}
+
+// symbolicCodePage returns the symbolic code page of the code b in the table, if the table has one.
+func symbolicCodePage(codes map[byte]byte, b byte) (byte, bool) {
+	if b >= CodePageCP437 && b < codePageEnd {
+		_, ok := codes[b]
+		return b, ok
+	}
+	for page, n := range codes {
+		if n == b {
+			return page, true
+		}
+	}
+	return 0, false
+}
+
+// selectTable sets the code page whose currency symbols are substituted, see WithCurrencySymbols.
+func (c *skipper) selectTable(codes map[byte]byte, b byte) {
+	c.table, c.hasTable = symbolicCodePage(codes, b)
+}
+
+// currencyEncoder returns the encoding function of enc, which may be nil, so the currency symbols it can't encode
+// are replaced with their bytes in the selected code page, or with their ISO 4217 codes if the code page lacks them.
+// The substitution is done only if WithCurrencySymbols is used.
+func (c *skipper) currencyEncoder(enc Encoder) func(string) []byte {
+	if !c.currency {
+		if enc == nil {
+			return nil
+		}
+		return enc.Encode
+	}
+
+	encode, canEncode := encoder, func(rune) bool { return false }
+	if enc != nil {
+		encode, canEncode = enc.Encode, enc.CanEncode
+	}
+
+	var table map[rune]byte
+	if c.hasTable {
+		table = currencyBytes[c.table]
+	}
+
+	return func(s string) []byte {
+		var bs []byte
+		start := 0
+		for i, r := range s {
+			if !isCurrency(r) || canEncode(r) {
+				continue
+			}
+			bs = append(bs, encode(s[start:i])...)
+			start = i + utf8.RuneLen(r)
+
+			if b, ok := table[r]; ok {
+				bs = append(bs, b)
+			} else if code, ok := currencyCodes[r]; ok {
+				bs = append(bs, code...)
+			} else {
+				bs = append(bs, 'c')
+			}
+		}
+		if start == 0 {
+			return encode(s)
+		}
+		return append(bs, encode(s[start:])...)
+	}
+}
@@ -0,0 +1,432 @@
+package thermalize
+
+import (
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+)
+
+// Command is a single printer command decoded from a command stream.
+type Command struct {
+	// Name is the mnemonic of the command, e.g. "ESC a" or "GS ( k".
+	// Printable text is reported as "TEXT".
+	Name string
+
+	// Args holds the fixed parameters of the command.
+	Args []byte
+
+	// Data holds the variable-length payload of the command, e.g. text, barcode or graphics data.
+	Data []byte
+}
+
+// String returns a normalized, human-readable representation of the command.
+// Text is quoted and large payloads are reduced to their length and checksum,
+// so the result is stable and suitable for comparison.
+func (c Command) String() string {
+	var sb strings.Builder
+	sb.WriteString(c.Name)
+
+	for _, b := range c.Args {
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.Itoa(int(b)))
+	}
+
+	switch {
+	case len(c.Data) == 0:
+	case c.Name == textCommand:
+		sb.WriteByte(' ')
+		sb.WriteString(strconv.Quote(string(c.Data)))
+	case len(c.Data) <= 16:
+		sb.WriteString(fmt.Sprintf(" [% X]", c.Data))
+	default:
+		sb.WriteString(fmt.Sprintf(" [%d bytes, crc32 %08X]", len(c.Data), crc32.ChecksumIEEE(c.Data)))
+	}
+
+	return sb.String()
+}
+
+const textCommand = "TEXT"
+
+// DecodeEscape decodes an ESC/POS command stream, such as the one produced by the escape command set.
+//
+// Unknown commands are reported by their prefix only, and an incomplete command
+// at the end of the stream is reported with the parameters that are available.
+func DecodeEscape(bs []byte) []Command {
+	d := decoder{bs: bs}
+	for d.pos < len(d.bs) {
+		d.next()
+	}
+	return d.cmds
+}
+
+// EscapeBoundaries returns the offsets at which the complete ESC/POS commands of bs end, in ascending order.
+// A command cut off at the end of bs has no offset, so data written after the last offset continues a command.
+func EscapeBoundaries(bs []byte) []int {
+	d := decoder{bs: bs}
+	var offsets []int
+	for d.pos < len(d.bs) {
+		d.next()
+		if d.short {
+			break
+		}
+		offsets = append(offsets, d.pos)
+	}
+	return offsets
+}
+
+// escapeModes are the commands selecting the print modes, which stay in effect until the printer is initialized.
+var escapeModes = map[string]bool{
+	"ESC !": true, "ESC E": true, "ESC -": true, "ESC G": true, "ESC M": true, "ESC a": true, "ESC {": true,
+	"ESC V": true, "ESC t": true, "ESC R": true, "ESC 2": true, "ESC 3": true, "ESC D": true,
+	"GS !": true, "GS B": true, "GS b": true, "GS L": true, "GS W": true, "GS P": true,
+	"GS h": true, "GS w": true, "GS f": true, "GS H": true,
+}
+
+// EscapeModes returns the complete commands of bs selecting the print modes since its last [ESC @],
+// such as the character size, the alignment, the line spacing or the code table, in the order of bs.
+// Sent after [ESC @], they restore the modes in effect at the end of bs, e.g. to continue a suspended job.
+func EscapeModes(bs []byte) []byte {
+	d := decoder{bs: bs}
+	var modes []byte
+	for d.pos < len(d.bs) {
+		start, n := d.pos, len(d.cmds)
+		d.next()
+		if d.short {
+			break
+		}
+		if name := d.cmds[n].Name; name == "ESC @" {
+			modes = modes[:0]
+		} else if escapeModes[name] {
+			modes = append(modes, bs[start:d.pos]...)
+		}
+	}
+	return modes
+}
+
+type decoder struct {
+	bs   []byte
+	pos  int
+	cmds []Command
+
+	// short is set when the end of the stream cuts off a command,
+	// incomplete is the name of that command.
+	short      bool
+	incomplete string
+}
+
+// checkEscape reports why the ESC/POS command stream would leave the printer in an inconsistent state:
+// a command cut off at the end of the stream, or page mode selected but not left.
+func checkEscape(bs []byte) (string, bool) {
+	d := decoder{bs: bs}
+	for d.pos < len(d.bs) {
+		d.next()
+	}
+	if d.incomplete != "" {
+		return fmt.Sprintf("%s is incomplete", d.incomplete), false
+	}
+
+	pageMode := false
+	for _, cmd := range d.cmds {
+		switch cmd.Name {
+		case "ESC L":
+			pageMode = true
+		case "ESC S", "FF", "ESC @":
+			pageMode = false
+		}
+	}
+	if pageMode {
+		return "page mode is not left", false
+	}
+
+	return "", true
+}
+
+func (d *decoder) next() {
+	b := d.bs[d.pos]
+
+	switch b {
+	case ESC:
+		d.pos++
+		d.escape()
+	case GS:
+		d.pos++
+		d.groupSeparator()
+	case DLE:
+		d.pos++
+		d.dataLinkEscape()
+	case FS:
+		d.pos++
+		d.fileSeparator()
+	default:
+		if isPrintable(b) {
+			d.text()
+			return
+		}
+		d.pos++
+		d.emit(controlName(b), nil, nil)
+	}
+}
+
+func (d *decoder) escape() {
+	c, ok := d.read()
+	if !ok {
+		d.emit("ESC", nil, nil)
+		return
+	}
+
+	name := "ESC " + controlName(c)
+
+	switch c {
+	case '@', '2', 'L', 'S', 'i', 'm', FF:
+		d.emit(name, nil, nil)
+	case 'a', '{', 't', 'E', 'V', '-', 'J', 'd', '=', 'M', '!', 'r', 'G', '3', 'T', 'U', 'R':
+		d.emit(name, d.take(1), nil)
+	case '$', '\\', 'B', 'c', 'f':
+		d.emit(name, d.take(2), nil)
+	case 'p':
+		d.emit(name, d.take(3), nil)
+	case 'W':
+		d.emit(name, d.take(8), nil)
+	case 'D':
+		d.emit(name, nil, d.terminated())
+	case '*':
+		args := d.take(3)
+		n := 0
+		if len(args) == 3 {
+			n = int(args[1]) | int(args[2])<<8
+			if args[0] >= 32 {
+				n *= 3
+			}
+		}
+		d.emit(name, args, d.take(n))
+	case '(':
+		d.extended(name)
+	default:
+		d.emit(name, nil, nil)
+	}
+}
+
+func (d *decoder) groupSeparator() {
+	c, ok := d.read()
+	if !ok {
+		d.emit("GS", nil, nil)
+		return
+	}
+
+	name := "GS " + controlName(c)
+
+	switch c {
+	case '!', 'w', 'h', 'f', 'H', 'B', 'b', 'I', 'r', 'a', '/':
+		d.emit(name, d.take(1), nil)
+	case 'L', 'W', '$', '\\', 'P':
+		d.emit(name, d.take(2), nil)
+	case 'V':
+		n := 1
+		if m, ok := d.peek(0); ok && m >= 65 {
+			n = 2
+		}
+		d.emit(name, d.take(n), nil)
+	case 'k':
+		m, ok := d.peek(0)
+		switch {
+		case !ok:
+			d.emit(name, nil, nil)
+		case m <= 6:
+			d.emit(name, d.take(1), d.terminated())
+		default:
+			args := d.take(2)
+			n := 0
+			if len(args) == 2 {
+				n = int(args[1])
+			}
+			d.emit(name, args, d.take(n))
+		}
+	case 'v':
+		args := d.take(6)
+		n := 0
+		if len(args) == 6 {
+			n = (int(args[2]) | int(args[3])<<8) * (int(args[4]) | int(args[5])<<8)
+		}
+		d.emit(name, args, d.take(n))
+	case '*':
+		args := d.take(2)
+		n := 0
+		if len(args) == 2 {
+			n = int(args[0]) * int(args[1]) * 8
+		}
+		d.emit(name, args, d.take(n))
+	case '8':
+		sub, ok := d.read()
+		if !ok {
+			d.emit(name, nil, nil)
+			return
+		}
+		name += " " + controlName(sub)
+		args := d.take(4)
+		n := 0
+		if len(args) == 4 {
+			n = int(args[0]) | int(args[1])<<8 | int(args[2])<<16 | int(args[3])<<24
+		}
+		d.emit(name, args, d.take(n))
+	case '(':
+		d.extended(name)
+	default:
+		d.emit(name, nil, nil)
+	}
+}
+
+func (d *decoder) dataLinkEscape() {
+	c, ok := d.read()
+	if !ok {
+		d.emit("DLE", nil, nil)
+		return
+	}
+
+	name := "DLE " + controlName(c)
+
+	switch c {
+	case EOT:
+		n := 1
+		if m, ok := d.peek(0); ok && (m == 7 || m == 8) {
+			n = 2
+		}
+		d.emit(name, d.take(n), nil)
+	case ENQ:
+		d.emit(name, d.take(1), nil)
+	case DC4:
+		n := 3
+		if fn, ok := d.peek(0); ok && fn == 8 {
+			n = 8
+		}
+		d.emit(name, d.take(n), nil)
+	default:
+		d.emit(name, nil, nil)
+	}
+}
+
+func (d *decoder) fileSeparator() {
+	c, ok := d.read()
+	if !ok {
+		d.emit("FS", nil, nil)
+		return
+	}
+
+	name := "FS " + controlName(c)
+
+	switch c {
+	case 'p':
+		d.emit(name, d.take(2), nil)
+	case 'a':
+		n := 1
+		if m, ok := d.peek(0); ok && m == '0' {
+			n = 2
+		}
+		d.emit(name, d.take(n), nil)
+	case 'b':
+		d.emit(name, d.take(1), nil)
+	case '(':
+		d.extended(name)
+	default:
+		d.emit(name, nil, nil)
+	}
+}
+
+// extended decodes the "( fn pL pH data" family of commands.
+func (d *decoder) extended(name string) {
+	fn, ok := d.read()
+	if !ok {
+		d.emit(name, nil, nil)
+		return
+	}
+
+	name += " " + controlName(fn)
+
+	args := d.take(2)
+	n := 0
+	if len(args) == 2 {
+		n = int(args[0]) | int(args[1])<<8
+	}
+	d.emit(name, args, d.take(n))
+}
+
+func (d *decoder) text() {
+	start := d.pos
+	for d.pos < len(d.bs) && isPrintable(d.bs[d.pos]) {
+		d.pos++
+	}
+	d.emit(textCommand, nil, d.bs[start:d.pos])
+}
+
+func (d *decoder) read() (byte, bool) {
+	if d.pos >= len(d.bs) {
+		d.short = true
+		return 0, false
+	}
+	b := d.bs[d.pos]
+	d.pos++
+	return b, true
+}
+
+// peek returns the byte at offset i from the current position without consuming it.
+func (d *decoder) peek(i int) (byte, bool) {
+	if d.pos+i >= len(d.bs) {
+		return 0, false
+	}
+	return d.bs[d.pos+i], true
+}
+
+// take consumes up to n bytes.
+func (d *decoder) take(n int) []byte {
+	end := d.pos + n
+	if end > len(d.bs) {
+		end, d.short = len(d.bs), true
+	}
+	bs := d.bs[d.pos:end]
+	d.pos = end
+	return bs
+}
+
+// terminated returns the data up to the next NUL, consuming the terminator.
+func (d *decoder) terminated() []byte {
+	start := d.pos
+	for d.pos < len(d.bs) && d.bs[d.pos] != NUL {
+		d.pos++
+	}
+	bs := d.bs[start:d.pos]
+	if d.pos < len(d.bs) {
+		d.pos++
+	} else {
+		d.short = true
+	}
+	return bs
+}
+
+func (d *decoder) emit(name string, args, data []byte) {
+	if d.short && d.incomplete == "" {
+		d.incomplete = name
+	}
+	d.cmds = append(d.cmds, Command{Name: name, Args: args, Data: data})
+}
+
+func isPrintable(b byte) bool {
+	return b >= SP && b != 0x7F
+}
+
+var controlNames = [...]string{
+	"NUL", "SOH", "STX", "ETX", "EOT", "ENQ", "ACK", "BEL",
+	"BS", "HT", "LF", "VT", "FF", "CR", "SO", "SI",
+	"DLE", "DC1", "DC2", "DC3", "DC4", "NAK", "SYN", "ETB",
+	"CAN", "EM", "SUB", "ESC", "FS", "GS", "RS", "US",
+	"SP",
+}
+
+func controlName(b byte) string {
+	if int(b) < len(controlNames) {
+		return controlNames[b]
+	}
+	if b < 0x7F {
+		return string(rune(b))
+	}
+	return fmt.Sprintf("0x%02X", b)
+}
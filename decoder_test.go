@@ -0,0 +1,73 @@
+package thermalize
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecodeEscapeArgs(t *testing.T) {
+	for _, tc := range []struct {
+		bs   []byte
+		want string
+	}{
+		{[]byte{ESC, 'R', 3}, "ESC R 3"},
+		{[]byte{ESC, 'a', 1}, "ESC a 1"},
+		{[]byte{ESC, 'D', 8, 16, NUL}, "ESC D [08 10]"},
+		{[]byte{GS, 'L', 10, 0}, "GS L 10 0"},
+		{[]byte{GS, 'k', 69, 3, 'A', 'B', 'C'}, "GS k 69 3 [41 42 43]"},
+	} {
+		cmds := DecodeEscape(tc.bs)
+		if len(cmds) != 1 || cmds[0].String() != tc.want {
+			t.Errorf("DecodeEscape(% x) = %v, want %s", tc.bs, cmds, tc.want)
+		}
+	}
+}
+
+func TestEscapeModes(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		bs     []byte
+		want   []byte
+		bounds []int
+	}{
+		{
+			name:   "modes",
+			bs:     []byte{ESC, 'a', 1, 'H', 'i', LF, ESC, 'R', 3, GS, '!', 0x11, ESC, 'J', 10},
+			want:   []byte{ESC, 'a', 1, ESC, 'R', 3, GS, '!', 0x11},
+			bounds: []int{3, 5, 6, 9, 12, 15},
+		},
+		{
+			name:   "initialized",
+			bs:     []byte{ESC, 'E', 1, ESC, '@', ESC, 't', 17, 'A'},
+			want:   []byte{ESC, 't', 17},
+			bounds: []int{3, 5, 8, 9},
+		},
+		{
+			name:   "cut off",
+			bs:     []byte{ESC, 'a', 1, ESC, '3'},
+			want:   []byte{ESC, 'a', 1},
+			bounds: []int{3},
+		},
+		{
+			name:   "tabs",
+			bs:     []byte{ESC, 'D', 4, 8, NUL, 'x', HT},
+			want:   []byte{ESC, 'D', 4, 8, NUL},
+			bounds: []int{5, 6, 7},
+		},
+	} {
+		if got := EscapeModes(tc.bs); !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: EscapeModes = % x, want % x", tc.name, got, tc.want)
+		}
+		got := EscapeBoundaries(tc.bs)
+		if len(got) != len(tc.bounds) {
+			t.Errorf("%s: EscapeBoundaries = %v, want %v", tc.name, got, tc.bounds)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.bounds[i] {
+				t.Errorf("%s: EscapeBoundaries = %v, want %v", tc.name, got, tc.bounds)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package thermalize
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ChangeKind is the kind of difference between two command streams.
+type ChangeKind byte
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+// Change is a semantic difference between two command streams.
+type Change struct {
+	Kind ChangeKind
+
+	// OldIndex and NewIndex are the positions of the commands in the decoded streams,
+	// or -1 if the command is absent from the stream.
+	OldIndex, NewIndex int
+
+	Old, New Command
+}
+
+// String describes the change, e.g. `alignment changed: ESC a 0 -> ESC a 1`.
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("%s added: %s", describe(c.New.Name), c.New)
+	case Removed:
+		return fmt.Sprintf("%s removed: %s", describe(c.Old.Name), c.Old)
+	}
+
+	if len(c.Old.Data) > 16 && bytes.Equal(c.Old.Args, c.New.Args) {
+		return fmt.Sprintf("%s data differs: %d bytes -> %d bytes", describe(c.Old.Name), len(c.Old.Data), len(c.New.Data))
+	}
+	return fmt.Sprintf("%s changed: %s -> %s", describe(c.Old.Name), c.Old, c.New)
+}
+
+var commandDescriptions = map[string]string{
+	textCommand: "text",
+	"LF":        "line feed",
+	"HT":        "tab",
+	"ESC @":     "initialization",
+	"ESC a":     "alignment",
+	"ESC E":     "bold",
+	"ESC -":     "underline",
+	"ESC {":     "upside-down mode",
+	"ESC V":     "rotation",
+	"ESC t":     "code page",
+	"ESC D":     "tab positions",
+	"ESC $":     "absolute position",
+	"ESC J":     "paper feed",
+	"ESC p":     "cash drawer pulse",
+	"ESC =":     "peripheral device",
+	"ESC c":     "print station",
+	"ESC f":     "slip wait time",
+	"DLE ENQ":   "error recovery",
+	"DLE DC4":   "real-time request",
+	"FS a":      "MICR reading",
+	"FS b":      "MICR transmission",
+	"ESC *":     "image",
+	"GS !":      "character size",
+	"GS L":      "left margin",
+	"GS W":      "print area width",
+	"GS w":      "barcode width",
+	"GS h":      "barcode height",
+	"GS f":      "HRI font",
+	"GS H":      "HRI position",
+	"GS k":      "barcode",
+	"GS ( k":    "2D symbol",
+	"GS v":      "image",
+	"GS 8 L":    "image",
+	"GS ( L":    "graphics",
+	"GS V":      "cut",
+	"GS ( F":    "label position",
+	"GS ( K":    "print density",
+	"GS ( H":    "process ID response",
+	"FS ( L":    "label feed",
+}
+
+func describe(name string) string {
+	if d, ok := commandDescriptions[name]; ok {
+		return d
+	}
+	return name
+}
+
+// Diff decodes two ESC/POS command streams and reports their semantic differences,
+// such as changed text, changed alignment or different image data.
+// Commands that differ only in their parameters or data are reported as Modified.
+func Diff(a, b []byte) []Change {
+	return diffCommands(DecodeEscape(a), DecodeEscape(b))
+}
+
+// DiffCommands reports the semantic differences between two decoded command streams, as Diff does.
+func DiffCommands(a, b []Command) []Change {
+	return diffCommands(a, b)
+}
+
+func diffCommands(a, b []Command) []Change {
+	// lcs[i][j] holds the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if equalCommands(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = maxByte(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []Change
+	var removed, added []int
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && equalCommands(a[i], b[j]):
+			changes = appendHunk(changes, a, b, removed, added)
+			removed, added = removed[:0], added[:0]
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, i)
+			i++
+		default:
+			added = append(added, j)
+			j++
+		}
+	}
+
+	return appendHunk(changes, a, b, removed, added)
+}
+
+// appendHunk pairs the removed and added commands of the same name between two common commands as modifications.
+func appendHunk(changes []Change, a, b []Command, removed, added []int) []Change {
+	paired := make([]bool, len(added))
+
+	for _, i := range removed {
+		change := Change{Kind: Removed, OldIndex: i, NewIndex: -1, Old: a[i]}
+		for k, j := range added {
+			if !paired[k] && a[i].Name == b[j].Name {
+				paired[k] = true
+				change = Change{Kind: Modified, OldIndex: i, NewIndex: j, Old: a[i], New: b[j]}
+				break
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	for k, j := range added {
+		if !paired[k] {
+			changes = append(changes, Change{Kind: Added, OldIndex: -1, NewIndex: j, New: b[j]})
+		}
+	}
+
+	return changes
+}
+
+func equalCommands(a, b Command) bool {
+	return a.Name == b.Name && bytes.Equal(a.Args, b.Args) && bytes.Equal(a.Data, b.Data)
+}
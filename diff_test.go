@@ -0,0 +1,69 @@
+package thermalize
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	image := func(fill byte) []byte {
+		return append([]byte{GS, 'v', '0', 0, 2, 0, 16, 0}, bytes.Repeat([]byte{fill}, 32)...)
+	}
+	receipt := func(align byte, total string, img []byte) []byte {
+		bs := []byte{ESC, '@', ESC, 'a', align, ESC, 'E', 1}
+		bs = append(bs, "Total "+total...)
+		bs = append(bs, LF)
+		return append(bs, img...)
+	}
+
+	old := receipt(0, "10.00", image(0xF0))
+	if changes := Diff(old, old); len(changes) != 0 {
+		t.Errorf("the same streams differ: %v", changes)
+	}
+
+	for _, tc := range []struct {
+		name string
+		new  []byte
+		want []string
+	}{
+		{"alignment", receipt(1, "10.00", image(0xF0)), []string{"alignment changed: ESC a 0 -> ESC a 1"}},
+		{"image", receipt(0, "10.00", image(0x0F)), []string{"image data differs: 32 bytes -> 32 bytes"}},
+		{"added", append(receipt(0, "10.00", image(0xF0)), GS, 'V', 0), []string{"cut added: GS V 0"}},
+		{"removed", bytes.Replace(old, []byte{ESC, 'E', 1}, nil, 1), []string{"bold removed: ESC E 1"}},
+		{"two changes", receipt(2, "12.00", image(0xF0)), []string{
+			"alignment changed: ESC a 0 -> ESC a 2",
+			`text changed: TEXT "Total 10.00" -> TEXT "Total 12.00"`,
+		}},
+	} {
+		changes := Diff(old, tc.new)
+		if len(changes) != len(tc.want) {
+			t.Errorf("%s: the changes are %v, want %q", tc.name, changes, tc.want)
+			continue
+		}
+		for i, c := range changes {
+			if c.String() != tc.want[i] {
+				t.Errorf("%s: the change is %q, want %q", tc.name, c, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestDiffIndices(t *testing.T) {
+	old := []byte{ESC, 'a', 0, 'A', LF, 'B', LF}
+	new := []byte{ESC, 'a', 0, 'B', LF, 'C', LF}
+	changes := Diff(old, new)
+	// The line "A" is removed before the common line "B", and the line "C" added after it.
+	want := []struct {
+		kind     ChangeKind
+		old, new int
+	}{{Removed, 1, -1}, {Removed, 2, -1}, {Added, -1, 3}, {Added, -1, 4}}
+	if len(changes) != len(want) {
+		t.Fatalf("the changes are %v", changes)
+	}
+	for i, c := range changes {
+		if c.Kind != want[i].kind || c.OldIndex != want[i].old || c.NewIndex != want[i].new {
+			t.Errorf("change %d is %v at %d, %d, want %v at %d, %d", i, c, c.OldIndex, c.NewIndex,
+				want[i].kind, want[i].old, want[i].new)
+		}
+	}
+}
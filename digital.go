@@ -0,0 +1,109 @@
+package thermalize
+
+import "bytes"
+
+// ReceiptOption customizes the digital receipt of DigitalReceipt.
+type ReceiptOption interface {
+	apply(*receipt)
+}
+
+type receipt struct {
+	postscript bool
+	psOpts     []Options
+	caption    *string
+}
+
+type receiptOptionFunc func(*receipt)
+
+func (fn receiptOptionFunc) apply(r *receipt) {
+	fn(r)
+}
+
+// WithReceiptPostscript uploads the document rendered by the postscript command set configured with the options
+// (see NewPostscript), instead of the accessible HTML.
+func WithReceiptPostscript(opts ...Options) ReceiptOption {
+	return receiptOptionFunc(func(r *receipt) { r.postscript, r.psOpts = true, opts })
+}
+
+// WithReceiptCaption sets the text printed above the QR code, by default MessageDigitalReceipt.
+// An empty caption prints nothing.
+func WithReceiptCaption(s string) ReceiptOption {
+	return receiptOptionFunc(func(r *receipt) { r.caption = &s })
+}
+
+// finishingOps are the commands finishing a document, the QR code of DigitalReceipt is printed before them.
+var finishingOps = map[string]bool{
+	"Feed": true, "Cut": true, "FullCut": true, "CutWithFeed": true, "FullCutAtPosition": true,
+	"PartialCutAtPosition": true, "FeedAndFullCut": true, "OpenCashDrawer": true, "OpenCashDrawerProfile": true,
+	"OpenBothDrawers": true, "Print": true, "Flush": true, "EndDocument": true,
+}
+
+// DigitalReceipt hands the receipt off to the customer's phone: it uploads the digital receipt with the function,
+// which stores the data with the content type and returns the URL of it, then prints the document with the command set
+// followed by the centered caption and a QR code of the URL, before the cuts, feeds and Print the document ends with.
+//
+// The digital receipt is the accessible HTML of the document (see Document.Accessible and AccessibleHTML) by default,
+// or the postscript document with WithReceiptPostscript. If the upload fails, nothing is printed and its error is returned,
+// otherwise DigitalReceipt returns the error of the command set.
+//
+// Example Usage:
+//
+//	err := thermalize.DigitalReceipt(cmd, doc, func(data []byte, contentType string) (string, error) {
+//		return store.Put(ctx, orderID, data, contentType)
+//	})
+func DigitalReceipt(cmd Cmd, d *Document, upload func(data []byte, contentType string) (string, error), opts ...ReceiptOption) error {
+	var r receipt
+	for _, opt := range opts {
+		opt.apply(&r)
+	}
+
+	data, contentType := []byte(AccessibleHTML(d.Accessible())), "text/html; charset=utf-8"
+	if r.postscript {
+		data, contentType = d.postscript(r.psOpts), "application/postscript"
+	}
+	url, err := upload(data, contentType)
+	if err != nil {
+		return err
+	}
+
+	d.printErr = nil
+	end := len(d.ops)
+	for end > 0 && finishingOps[d.ops[end-1].Name] {
+		end--
+	}
+	for _, op := range d.ops[:end] {
+		op.play(cmd)
+	}
+
+	caption := Translate(cmd, MessageDigitalReceipt)
+	if r.caption != nil {
+		caption = *r.caption
+	}
+	cmd.Align(Center)
+	if caption != "" {
+		textWrap(cmd, caption, nil)
+	}
+	cmd.QRCode(url)
+	cmd.Align(Left)
+
+	for _, op := range d.ops[end:] {
+		op.play(cmd)
+	}
+	return d.result(cmd)
+}
+
+// postscript renders the document with the postscript command set, which is initialized and printed
+// if the document doesn't do it.
+func (d *Document) postscript(opts []Options) []byte {
+	var buf bytes.Buffer
+	ps := NewPostscript(d.CPL(), d.PPL(), &buf, opts...)
+
+	if len(d.ops) == 0 || d.ops[0].Name != "Init" {
+		ps.Init()
+	}
+	d.Render(ps)
+	if len(d.ops) == 0 || d.ops[len(d.ops)-1].Name != "Print" {
+		ps.Print()
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,759 @@
+package thermalize
+
+import (
+	"image"
+	"time"
+)
+
+// Document records the commands of a receipt, so it can be printed several times,
+// printed by several command sets, or inspected before printing.
+//
+// Document implements Cmd and the optional capability interfaces, such as Beeper and Reverser.
+// The calls of the capability interfaces are skipped when the document is replayed on a command set
+// that doesn't implement them.
+//
+// Example Usage:
+//
+//	doc := thermalize.NewDocument(48, 576)
+//	doc.Init()
+//	doc.Text("Hello world!", nil)
+//	doc.LineFeed()
+//	doc.FullCut()
+//
+//	err := doc.Replay(cmd) // the original
+//	err = doc.Replay(cmd)  // the copy, marked with CopyBanner
+type Document struct {
+	cpl, ppl int
+	ops      []Op
+	replays  int
+
+	// printErr is the first error returned by the recorded Print while the document is played.
+	printErr error
+
+	// CopyLabel is the label of the banner printed on the copies,
+	// by default MessageCopy translated with the catalog of the command set (see WithCatalog), "COPY" in English.
+	CopyLabel string
+}
+
+// Op is a recorded command.
+type Op struct {
+	// Name is the name of the method of Cmd or of a capability interface, e.g. "Text" or "Beep".
+	Name string
+
+	// Args holds the arguments of the call, except for the encoders and the options.
+	Args []any
+
+	play func(Cmd)
+}
+
+// Play executes the command on the command set.
+func (op Op) Play(cmd Cmd) {
+	op.play(cmd)
+}
+
+// NewDocument returns an empty document for a printer with cpl characters per line and ppl pixels per line.
+func NewDocument(cpl, ppl int) *Document {
+	return &Document{cpl: cpl, ppl: ppl}
+}
+
+// Ops returns the recorded commands.
+func (d *Document) Ops() []Op {
+	return d.ops
+}
+
+// Render plays the recorded commands on the command set and returns the error returned by the recorded Print,
+// such as ErrNotConfirmed if WithCompletion is used, or the error recorded by the command set.
+func (d *Document) Render(cmd Cmd) error {
+	d.printErr = nil
+	for _, op := range d.ops {
+		op.play(cmd)
+	}
+	return d.result(cmd)
+}
+
+// result returns the first error returned by the recorded Print, or the error recorded by the command set.
+func (d *Document) result(cmd Cmd) error {
+	if d.printErr != nil {
+		return d.printErr
+	}
+	return cmdErr(cmd)
+}
+
+// Replay prints a copy of the document with the command set and returns the error reported by it, the same way as Render.
+// Every replay but the first one prints CopyBanner with CopyLabel after the initialization of the printer,
+// so the copies can't be mistaken for the original.
+func (d *Document) Replay(cmd Cmd) error {
+	d.replays++
+	if d.replays == 1 {
+		return d.Render(cmd)
+	}
+
+	// The banner follows the first initialization, which would clear it.
+	d.printErr = nil
+	start := 0
+	for i, op := range d.ops {
+		if op.Name == "Init" {
+			start = i + 1
+			break
+		}
+	}
+
+	for _, op := range d.ops[:start] {
+		op.play(cmd)
+	}
+	label := d.CopyLabel
+	if label == "" {
+		label = Translate(cmd, MessageCopy)
+	}
+	CopyBanner(cmd, label)
+	for _, op := range d.ops[start:] {
+		op.play(cmd)
+	}
+	return d.result(cmd)
+}
+
+func (d *Document) record(name string, play func(Cmd), args ...any) {
+	d.ops = append(d.ops, Op{Name: name, Args: args, play: play})
+}
+
+func (d *Document) Sizing(cpl, ppl int) {
+	if cpl != 0 {
+		d.cpl = cpl
+	}
+	if ppl != 0 {
+		d.ppl = ppl
+	}
+	d.record("Sizing", func(c Cmd) { c.Sizing(cpl, ppl) }, cpl, ppl)
+}
+
+func (d *Document) CPL() int {
+	return d.cpl
+}
+
+func (d *Document) PPL() int {
+	return d.ppl
+}
+
+// Err returns nil, the errors are reported by the command set the document is replayed on.
+func (d *Document) Err() error {
+	return nil
+}
+
+func (d *Document) Write(bs ...byte) {
+	bs = append([]byte(nil), bs...)
+	d.record("Write", func(c Cmd) { c.Write(bs...) }, bs)
+}
+
+func (d *Document) WriteBytes(bs []byte) {
+	bs = append([]byte(nil), bs...)
+	d.record("WriteBytes", func(c Cmd) {
+		if c, ok := c.(RawWriter); ok {
+			c.WriteBytes(bs)
+			return
+		}
+		c.Write(bs...)
+	}, bs)
+}
+
+func (d *Document) WriteString(s string) {
+	d.record("WriteString", func(c Cmd) {
+		if c, ok := c.(RawWriter); ok {
+			c.WriteString(s)
+			return
+		}
+		c.Write([]byte(s)...)
+	}, s)
+}
+
+func (d *Document) Raw(bs []byte) {
+	bs = append([]byte(nil), bs...)
+	d.record("Raw", func(c Cmd) {
+		if c, ok := c.(RawWriter); ok {
+			c.Raw(bs)
+			return
+		}
+		c.Write(bs...)
+	}, bs)
+}
+
+func (d *Document) Text(s string, enc func(string) []byte) {
+	d.record("Text", func(c Cmd) { c.Text(s, enc) }, s)
+}
+
+func (d *Document) TextWrap(s string, enc func(string) []byte) {
+	d.record("TextWrap", func(c Cmd) { textWrap(c, s, enc) }, s)
+}
+
+func (d *Document) Paragraph(s string, indent, hang int) {
+	d.record("Paragraph", func(c Cmd) {
+		if c, ok := c.(TextFormatter); ok {
+			c.Paragraph(s, indent, hang)
+			return
+		}
+		writeLines(c, paragraph(s, c.CPL(), indent, hang, false), nil)
+	}, s, indent, hang)
+}
+
+func (d *Document) List(items []string, opts ...ListOption) {
+	items = append([]string(nil), items...)
+	d.record("List", func(c Cmd) {
+		if c, ok := c.(TextFormatter); ok {
+			c.List(items, opts...)
+			return
+		}
+		writeLines(c, listLines(items, c.CPL(), false, opts), nil)
+	}, items)
+}
+
+func (d *Document) GrayLevel(l uint8) {
+	d.record("GrayLevel", func(c Cmd) {
+		if c, ok := c.(GrayLeveler); ok {
+			c.GrayLevel(l)
+		}
+	}, l)
+}
+
+func (d *Document) PrintDensity(n int) {
+	d.record("PrintDensity", func(c Cmd) {
+		if c, ok := c.(DensityController); ok {
+			c.PrintDensity(n)
+		}
+	}, n)
+}
+
+func (d *Document) RecoverError(mode byte) {
+	d.record("RecoverError", func(c Cmd) {
+		if c, ok := c.(Recoverer); ok {
+			c.RecoverError(mode)
+		}
+	}, mode)
+}
+
+func (d *Document) SoftReset() {
+	d.record("SoftReset", func(c Cmd) {
+		if c, ok := c.(Recoverer); ok {
+			c.SoftReset()
+		}
+	})
+}
+
+func (d *Document) RequestProcessID(id string) {
+	d.record("RequestProcessID", func(c Cmd) {
+		if c, ok := c.(ProcessIDResponder); ok {
+			c.RequestProcessID(id)
+		}
+	}, id)
+}
+
+func (d *Document) RightAt(col int, s string) {
+	d.record("RightAt", func(c Cmd) {
+		if c, ok := c.(ColumnPositioner); ok {
+			c.RightAt(col, s)
+		}
+	}, col, s)
+}
+
+func (d *Document) Smoothing(b bool) {
+	d.record("Smoothing", func(c Cmd) {
+		if c, ok := c.(Smoother); ok {
+			c.Smoothing(b)
+		}
+	}, b)
+}
+
+func (d *Document) Glyph(g byte) {
+	d.record("Glyph", func(c Cmd) {
+		if c, ok := c.(GlyphPrinter); ok {
+			c.Glyph(g)
+		}
+	}, g)
+}
+
+func (d *Document) SetClock(t time.Time) {
+	d.record("SetClock", func(c Cmd) {
+		if c, ok := c.(ClockPrinter); ok {
+			c.SetClock(t)
+		}
+	}, t)
+}
+
+func (d *Document) RequestClock() {
+	d.record("RequestClock", func(c Cmd) {
+		if c, ok := c.(ClockPrinter); ok {
+			c.RequestClock()
+		}
+	})
+}
+
+func (d *Document) PrintClock() {
+	d.record("PrintClock", func(c Cmd) {
+		if c, ok := c.(ClockPrinter); ok {
+			c.PrintClock()
+		}
+	})
+}
+
+func (d *Document) StartDocument() {
+	d.record("StartDocument", func(c Cmd) {
+		if c, ok := c.(DocumentMarker); ok {
+			c.StartDocument()
+		}
+	})
+}
+
+func (d *Document) EndDocument() {
+	d.record("EndDocument", func(c Cmd) {
+		if c, ok := c.(DocumentMarker); ok {
+			c.EndDocument()
+		}
+	})
+}
+
+func (d *Document) Checkpoint() {
+	d.record("Checkpoint", func(c Cmd) {
+		if c, ok := c.(DocumentMarker); ok {
+			c.Checkpoint()
+		}
+	})
+}
+
+func (d *Document) ResetCheckpoints() {
+	d.record("ResetCheckpoints", func(c Cmd) {
+		if c, ok := c.(DocumentMarker); ok {
+			c.ResetCheckpoints()
+		}
+	})
+}
+
+func (d *Document) TriLine(left, center, right string) {
+	d.record("TriLine", func(c Cmd) {
+		if c, ok := c.(TriLiner); ok {
+			c.TriLine(left, center, right)
+		}
+	}, left, center, right)
+}
+
+func (d *Document) Justify(b bool) {
+	d.record("Justify", func(c Cmd) {
+		if c, ok := c.(TextFormatter); ok {
+			c.Justify(b)
+		}
+	}, b)
+}
+
+func (d *Document) Init() {
+	d.record("Init", func(c Cmd) { c.Init() })
+}
+
+func (d *Document) LeftMargin(n int) {
+	d.record("LeftMargin", func(c Cmd) { c.LeftMargin(n) }, n)
+}
+
+func (d *Document) WidthArea(n int) {
+	d.record("WidthArea", func(c Cmd) { c.WidthArea(n) }, n)
+}
+
+func (d *Document) Indent(n int) {
+	d.record("Indent", func(c Cmd) {
+		if c, ok := c.(TextFormatter); ok {
+			c.Indent(n)
+		}
+	}, n)
+}
+
+func (d *Document) Outdent() {
+	d.record("Outdent", func(c Cmd) {
+		if c, ok := c.(TextFormatter); ok {
+			c.Outdent()
+		}
+	})
+}
+
+func (d *Document) AbsolutePosition(n int) {
+	d.record("AbsolutePosition", func(c Cmd) { c.AbsolutePosition(n) }, n)
+}
+
+func (d *Document) Align(b byte) {
+	d.record("Align", func(c Cmd) { c.Align(b) }, b)
+}
+
+func (d *Document) UpsideDown(b bool) {
+	d.record("UpsideDown", func(c Cmd) { c.UpsideDown(b) }, b)
+}
+
+func (d *Document) TabPositions(bs ...byte) {
+	bs = append([]byte(nil), bs...)
+	d.record("TabPositions", func(c Cmd) { c.TabPositions(bs...) }, bs)
+}
+
+func (d *Document) Tab() {
+	d.record("Tab", func(c Cmd) { c.Tab() })
+}
+
+func (d *Document) CodePage(b byte) {
+	d.record("CodePage", func(c Cmd) { c.CodePage(b) }, b)
+}
+
+func (d *Document) CharSize(w, h byte) {
+	d.record("CharSize", func(c Cmd) { c.CharSize(w, h) }, w, h)
+}
+
+func (d *Document) Bold(b bool) {
+	d.record("Bold", func(c Cmd) { c.Bold(b) }, b)
+}
+
+func (d *Document) ClockwiseRotation(b bool) {
+	d.record("ClockwiseRotation", func(c Cmd) { c.ClockwiseRotation(b) }, b)
+}
+
+func (d *Document) Underling(b byte) {
+	d.record("Underling", func(c Cmd) { c.Underling(b) }, b)
+}
+
+func (d *Document) BarcodeWidth(b byte) {
+	d.record("BarcodeWidth", func(c Cmd) { c.BarcodeWidth(b) }, b)
+}
+
+func (d *Document) BarcodeHeight(b byte) {
+	d.record("BarcodeHeight", func(c Cmd) { c.BarcodeHeight(b) }, b)
+}
+
+func (d *Document) HRIFont(b byte) {
+	d.record("HRIFont", func(c Cmd) { c.HRIFont(b) }, b)
+}
+
+func (d *Document) HRIPosition(b byte) {
+	d.record("HRIPosition", func(c Cmd) { c.HRIPosition(b) }, b)
+}
+
+func (d *Document) Barcode(m byte, s string) {
+	d.record("Barcode", func(c Cmd) { c.Barcode(m, s) }, m, s)
+}
+
+func (d *Document) QRCodeSize(b byte) {
+	d.record("QRCodeSize", func(c Cmd) { c.QRCodeSize(b) }, b)
+}
+
+func (d *Document) QRCodeCorrectionLevel(b byte) {
+	d.record("QRCodeCorrectionLevel", func(c Cmd) { c.QRCodeCorrectionLevel(b) }, b)
+}
+
+func (d *Document) QRCode(s string) {
+	d.record("QRCode", func(c Cmd) { c.QRCode(s) }, s)
+}
+
+func (d *Document) Image(img image.Image, invert bool) {
+	d.record("Image", func(c Cmd) { c.Image(img, invert) }, img, invert)
+}
+
+func (d *Document) InlineImage(img image.Image, valign byte) {
+	d.record("InlineImage", func(c Cmd) {
+		if c, ok := c.(InlineImager); ok {
+			c.InlineImage(img, valign)
+		}
+	}, img, valign)
+}
+
+func (d *Document) Watermark(s string, opts ...WatermarkOption) {
+	d.record("Watermark", func(c Cmd) {
+		if c, ok := c.(Watermarker); ok {
+			c.Watermark(s, opts...)
+		}
+	}, s)
+}
+
+func (d *Document) Feed(b byte) {
+	d.record("Feed", func(c Cmd) { c.Feed(b) }, b)
+}
+
+func (d *Document) LineFeed() {
+	d.record("LineFeed", func(c Cmd) { c.LineFeed() })
+}
+
+func (d *Document) Cut(m, p byte) {
+	d.record("Cut", func(c Cmd) { c.Cut(m, p) }, m, p)
+}
+
+func (d *Document) FullCut() {
+	d.record("FullCut", func(c Cmd) { c.FullCut() })
+}
+
+func (d *Document) CutWithFeed(partial bool, mm float64) {
+	d.record("CutWithFeed", func(c Cmd) {
+		if c, ok := c.(Cutter); ok {
+			c.CutWithFeed(partial, mm)
+		}
+	}, partial, mm)
+}
+
+func (d *Document) FullCutAtPosition() {
+	d.record("FullCutAtPosition", func(c Cmd) {
+		if c, ok := c.(Cutter); ok {
+			c.FullCutAtPosition()
+		}
+	})
+}
+
+func (d *Document) PartialCutAtPosition() {
+	d.record("PartialCutAtPosition", func(c Cmd) {
+		if c, ok := c.(Cutter); ok {
+			c.PartialCutAtPosition()
+		}
+	})
+}
+
+func (d *Document) FeedAndFullCut(p byte) {
+	d.record("FeedAndFullCut", func(c Cmd) {
+		if c, ok := c.(Cutter); ok {
+			c.FeedAndFullCut(p)
+		}
+	}, p)
+}
+
+func (d *Document) OpenCashDrawer(m, t1, t2 byte) {
+	d.record("OpenCashDrawer", func(c Cmd) { c.OpenCashDrawer(m, t1, t2) }, m, t1, t2)
+}
+
+func (d *Document) OpenCashDrawerProfile(m byte, p DrawerProfile) {
+	d.record("OpenCashDrawerProfile", func(c Cmd) {
+		if c, ok := c.(DrawerOpener); ok {
+			c.OpenCashDrawerProfile(m, p)
+		}
+	}, m, p)
+}
+
+func (d *Document) OpenBothDrawers() {
+	d.record("OpenBothDrawers", func(c Cmd) {
+		if c, ok := c.(DrawerOpener); ok {
+			c.OpenBothDrawers()
+		}
+	})
+}
+
+// Print records the call and returns nil, the error returned by the command set the document is played on
+// is returned by Render and Replay.
+func (d *Document) Print() error {
+	d.record("Print", func(c Cmd) {
+		if err := c.Print(); err != nil && d.printErr == nil {
+			d.printErr = err
+		}
+	})
+	return nil
+}
+
+// Flush records the call and returns nil.
+func (d *Document) Flush() error {
+	d.record("Flush", func(c Cmd) {
+		if c, ok := c.(Flusher); ok {
+			c.Flush()
+		}
+	})
+	return nil
+}
+
+func (d *Document) PageMode(b bool) {
+	d.record("PageMode", func(c Cmd) {
+		if c, ok := c.(PageModer); ok {
+			c.PageMode(b)
+		}
+	}, b)
+}
+
+func (d *Document) PrintArea(x, y, w, h int) {
+	d.record("PrintArea", func(c Cmd) {
+		if c, ok := c.(PageModer); ok {
+			c.PrintArea(x, y, w, h)
+		}
+	}, x, y, w, h)
+}
+
+func (d *Document) PrintPage() {
+	d.record("PrintPage", func(c Cmd) {
+		if c, ok := c.(PageModer); ok {
+			c.PrintPage()
+		}
+	})
+}
+
+func (d *Document) Beep(n, t byte) {
+	d.record("Beep", func(c Cmd) {
+		if c, ok := c.(Beeper); ok {
+			c.Beep(n, t)
+		}
+	}, n, t)
+}
+
+func (d *Document) Color(b byte) {
+	d.record("Color", func(c Cmd) {
+		if c, ok := c.(ColorPrinter); ok {
+			c.Color(b)
+		}
+	}, b)
+}
+
+func (d *Document) Reverse(b bool) {
+	d.record("Reverse", func(c Cmd) {
+		if c, ok := c.(Reverser); ok {
+			c.Reverse(b)
+		}
+	}, b)
+}
+
+func (d *Document) RequestStatus(n byte) {
+	d.record("RequestStatus", func(c Cmd) {
+		if c, ok := c.(StatusQuerier); ok {
+			c.RequestStatus(n)
+		}
+	}, n)
+}
+
+func (d *Document) LabelAdjust(p byte, n int) {
+	d.record("LabelAdjust", func(c Cmd) {
+		if c, ok := c.(Labeler); ok {
+			c.LabelAdjust(p, n)
+		}
+	}, p, n)
+}
+
+func (d *Document) LabelFeed(p byte) {
+	d.record("LabelFeed", func(c Cmd) {
+		if c, ok := c.(Labeler); ok {
+			c.LabelFeed(p)
+		}
+	}, p)
+}
+
+func (d *Document) RequestLabelTaken() {
+	d.record("RequestLabelTaken", func(c Cmd) {
+		if c, ok := c.(Labeler); ok {
+			c.RequestLabelTaken()
+		}
+	})
+}
+
+func (d *Document) QRCodeModel(b byte) {
+	d.record("QRCodeModel", func(c Cmd) {
+		if c, ok := c.(QRModeler); ok {
+			c.QRCodeModel(b)
+		}
+	}, b)
+}
+
+func (d *Document) QRCodeVersion(v byte) {
+	d.record("QRCodeVersion", func(c Cmd) {
+		if c, ok := c.(QRVersioner); ok {
+			c.QRCodeVersion(v)
+		}
+	}, v)
+}
+
+func (d *Document) QRCodeMask(m byte) {
+	d.record("QRCodeMask", func(c Cmd) {
+		if c, ok := c.(QRVersioner); ok {
+			c.QRCodeMask(m)
+		}
+	}, m)
+}
+
+func (d *Document) SelectStation(s byte) {
+	d.record("SelectStation", func(c Cmd) {
+		if c, ok := c.(SlipPrinter); ok {
+			c.SelectStation(s)
+		}
+	}, s)
+}
+
+func (d *Document) SelectSheet(mask byte) {
+	d.record("SelectSheet", func(c Cmd) {
+		if c, ok := c.(SlipPrinter); ok {
+			c.SelectSheet(mask)
+		}
+	}, mask)
+}
+
+func (d *Document) SheetSettings(mask byte) {
+	d.record("SheetSettings", func(c Cmd) {
+		if c, ok := c.(SlipPrinter); ok {
+			c.SheetSettings(mask)
+		}
+	}, mask)
+}
+
+func (d *Document) SlipWait(insert, delay byte) {
+	d.record("SlipWait", func(c Cmd) {
+		if c, ok := c.(SlipPrinter); ok {
+			c.SlipWait(insert, delay)
+		}
+	}, insert, delay)
+}
+
+func (d *Document) EjectSlip() {
+	d.record("EjectSlip", func(c Cmd) {
+		if c, ok := c.(SlipPrinter); ok {
+			c.EjectSlip()
+		}
+	})
+}
+
+func (d *Document) ReadMICR(font byte) {
+	d.record("ReadMICR", func(c Cmd) {
+		if c, ok := c.(SlipPrinter); ok {
+			c.ReadMICR(font)
+		}
+	}, font)
+}
+
+func (d *Document) SelectDevice(printer, display bool) {
+	d.record("SelectDevice", func(c Cmd) {
+		if c, ok := c.(DeviceSelector); ok {
+			c.SelectDevice(printer, display)
+		}
+	}, printer, display)
+}
+
+func (d *Document) PDF417Size(rows, cols byte) {
+	d.record("PDF417Size", func(c Cmd) {
+		if c, ok := c.(PDF417Printer); ok {
+			c.PDF417Size(rows, cols)
+		}
+	}, rows, cols)
+}
+
+func (d *Document) PDF417CorrectionLevel(b byte) {
+	d.record("PDF417CorrectionLevel", func(c Cmd) {
+		if c, ok := c.(PDF417Printer); ok {
+			c.PDF417CorrectionLevel(b)
+		}
+	}, b)
+}
+
+func (d *Document) PDF417Module(width, ratio byte) {
+	d.record("PDF417Module", func(c Cmd) {
+		if c, ok := c.(PDF417Printer); ok {
+			c.PDF417Module(width, ratio)
+		}
+	}, width, ratio)
+}
+
+func (d *Document) PDF417(s string) {
+	d.record("PDF417", func(c Cmd) {
+		if c, ok := c.(PDF417Printer); ok {
+			c.PDF417(s)
+		}
+	}, s)
+}
+
+// KeepTogether records the block written by fn, its commands are the argument of the recorded call.
+// Unlike the calls of other capability interfaces, the block is printed by all command sets,
+// those that don't implement Paginator print it as it is.
+func (d *Document) KeepTogether(fn func(Cmd)) {
+	block := NewDocument(d.cpl, d.ppl)
+	fn(block)
+	d.record("KeepTogether", func(c Cmd) {
+		if c, ok := c.(Paginator); ok {
+			c.KeepTogether(func(c Cmd) { block.Render(c) })
+			return
+		}
+		block.Render(c)
+	}, block.Ops())
+}
@@ -0,0 +1,69 @@
+package thermalize
+
+import (
+	"math"
+	"time"
+)
+
+// DrawerProfile is the pulse kicking a cash drawer, which is converted to the pulse units of each command set.
+// The pulse on time must not exceed the pulse off time, which the command sets require.
+type DrawerProfile struct {
+	On  time.Duration
+	Off time.Duration
+}
+
+// Pulse profiles of common drawers.
+var (
+	// DrawerEpson is the pulse sent by the Epson drivers [ESC p 0 25 250], suitable for most drawers.
+	DrawerEpson = DrawerProfile{On: 50 * time.Millisecond, Off: 500 * time.Millisecond}
+
+	// DrawerStar is the default pulse of star printers.
+	DrawerStar = DrawerProfile{On: 200 * time.Millisecond, Off: 200 * time.Millisecond}
+
+	// DrawerHeavy is a longer pulse for drawers with stronger solenoids, which don't open with a short one.
+	DrawerHeavy = DrawerProfile{On: 100 * time.Millisecond, Off: 500 * time.Millisecond}
+)
+
+type drawerProfileOption DrawerProfile
+
+func (dpo drawerProfileOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.drawer = DrawerProfile(dpo)
+	}
+}
+
+// WithDrawerProfile sets the pulse used by OpenBothDrawers.
+// By default, DrawerEpson is used by the ESC/POS command set and DrawerStar by the star command set.
+func WithDrawerProfile(p DrawerProfile) Options {
+	return drawerProfileOption(p)
+}
+
+// drawerProfile returns the profile set by WithDrawerProfile, or def if there is none.
+func (c *skipper) drawerProfile(def DrawerProfile) DrawerProfile {
+	if c.drawer == (DrawerProfile{}) {
+		return def
+	}
+	return c.drawer
+}
+
+// pulses converts the profile to the pulse on and off times in units.
+// The third result is false if the command must be skipped.
+func (c *skipper) pulses(command string, p DrawerProfile, unit time.Duration) (byte, byte, bool) {
+	switch {
+	case p.On <= 0 || p.Off <= 0:
+		c.invalid(command, "pulse times must be positive, got %v and %v", p.On, p.Off)
+		return 0, 0, false
+	case p.On > p.Off && c.invalid(command, "pulse on time %v exceeds pulse off time %v", p.On, p.Off):
+		return 0, 0, false
+	}
+
+	max := 255 * unit
+	if p.Off > max && c.invalid(command, "pulse off time %v exceeds %v", p.Off, max) {
+		return 0, 0, false
+	}
+
+	units := func(d time.Duration) byte {
+		return byte(math.Max(1, math.Min(255, math.Round(float64(d)/float64(unit)))))
+	}
+	return units(p.On), units(p.Off), true
+}
@@ -0,0 +1,89 @@
+package thermalize
+
+import "image"
+
+// The glyphs printed by GlyphPrinter.
+const (
+	GlyphBox        byte = iota // GlyphBox is an empty box
+	GlyphBoxChecked             // GlyphBoxChecked is a box with a cross
+	GlyphBoxFilled              // GlyphBoxFilled is a filled box
+)
+
+// glyphCode is the code of the first glyph among the user-defined characters of the escape command set.
+const glyphCode = 0x7C
+
+// Checkbox prints an empty or a checked box followed by a space, e.g. in front of the items of the pick lists
+// and the delivery confirmations. The box is printed as a glyph if the command set implements GlyphPrinter,
+// otherwise as "[ ]" or "[x]". A Document records the checkbox, which is printed the same way when it is replayed.
+func Checkbox(cmd Cmd, checked bool) {
+	if d, ok := cmd.(*Document); ok {
+		d.record("Checkbox", func(c Cmd) { Checkbox(c, checked) }, checked)
+		return
+	}
+	if g, ok := cmd.(GlyphPrinter); ok {
+		if checked {
+			g.Glyph(GlyphBoxChecked)
+		} else {
+			g.Glyph(GlyphBox)
+		}
+		cmd.Text(" ", nil)
+		return
+	}
+	if checked {
+		cmd.Text("[x] ", nil)
+	} else {
+		cmd.Text("[ ] ", nil)
+	}
+}
+
+// glyphImage draws the glyph in a cell of w x h dots, the box is a square as wide as the cell less a dot on each side,
+// centered on the middle of the cell.
+func glyphImage(g byte, w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	s := maxByte(w-2, 1)
+	x0, y0 := (w-s)/2, (h-s)/2
+	for y := 0; y < s; y++ {
+		for x := 0; x < s; x++ {
+			edge := x == 0 || y == 0 || x == s-1 || y == s-1
+			cross := x == y || x == s-1-y
+			if edge || g == GlyphBoxFilled || g == GlyphBoxChecked && cross {
+				img.Pix[(y0+y)*img.Stride+x0+x] = 0
+			}
+		}
+	}
+	return img
+}
+
+// Glyph prints the glyph as a user-defined character [ESC &] of the font A, which is defined after each Init.
+func (c *escape) Glyph(g byte) {
+	if g > GlyphBoxFilled && c.invalid("Glyph", "%d is out of range [0, 2]", g) {
+		return
+	}
+	g = minByte(g, GlyphBoxFilled)
+
+	if !c.glyphs {
+		// The characters of the font A are 12 x 24 dots, 3 bytes of each column.
+		bs := []byte{ESC, '&', 3, glyphCode, glyphCode + GlyphBoxFilled}
+		for n := GlyphBox; n <= GlyphBoxFilled; n++ {
+			_, data := imageToColumns(nil, glyphImage(n, 12, 24), false, defaultGrayLevel, 3)
+			bs = append(append(bs, 12), data...)
+		}
+		c.WriteBytes(bs)
+		c.glyphs = true
+	}
+	c.Write(ESC, '%', 1, glyphCode+g, ESC, '%', 0)
+	c.advance(" ")
+}
+
+// Glyph prints the glyph as an inline image of the size of a character.
+func (c *postscript) Glyph(g byte) {
+	if g > GlyphBoxFilled && c.invalid("Glyph", "%d is out of range [0, 2]", g) {
+		return
+	}
+	w := c.PPL() / maxByte(c.CPL(), 1)
+	c.InlineImage(glyphImage(minByte(g, GlyphBoxFilled), w*int(c.sizeX), 2*w*int(c.sizeY)), VAlignBottom)
+}
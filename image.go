@@ -1,11 +1,9 @@
 package thermalize
 
 import (
-	"bytes"
-	"encoding/base64"
 	"image"
 	"image/color"
-	"image/png"
+	"sync"
 	"sync/atomic"
 )
 
@@ -27,57 +25,118 @@ func ResetGrayLevel() {
 	grayLevel.Store(uint32(defaultGrayLevel))
 }
 
+// gray reports whether the pixel should be printed, the pixels printed inverted are exactly the others.
+// The alpha and luminance are computed the same way as color.AlphaModel and color.GrayModel do,
+// but without converting the color to an intermediate interface value.
 func gray(c color.Color, level uint8, invert bool) bool {
-	if color.AlphaModel.Convert(c).(color.Alpha).A < level {
+	r, g, b, a := c.RGBA()
+	if uint8(a>>8) < level {
 		return invert
 	}
-	if invert {
-		return color.GrayModel.Convert(c).(color.Gray).Y > level
+	y := uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+	return (y < level) != invert
+}
+
+// rasterPool holds the buffers reused by the command sets to convert images.
+var rasterPool = sync.Pool{New: func() any { return new([]byte) }}
+
+// rasterBuffer returns a zeroed slice of length n.
+// If buf is not nil, its capacity is reused and the resulting slice is stored back into it.
+func rasterBuffer(buf *[]byte, n int) []byte {
+	if buf == nil {
+		return make([]byte, n)
+	}
+	if cap(*buf) < n {
+		*buf = make([]byte, n)
+		return *buf
+	}
+	data := (*buf)[:n]
+	for i := range data {
+		data[i] = 0
 	}
-	return color.GrayModel.Convert(c).(color.Gray).Y < level
+	*buf = data
+	return data
+}
+
+// ImageToBinSize returns the width in dots and the length of the data ImageToBin returns for an image of w x h dots:
+// the height is padded to a multiple of 24 dots.
+func ImageToBinSize(w, h int) (int, int) {
+	return columnsSize(w, h, 3)
+}
+
+// columnsSize returns the width and the length of the data of imageToColumns.
+func columnsSize(w, h, k int) (int, int) {
+	w, h = maxByte(w, 0), maxByte(h, 0)
+	return w, (h + 8*k - 1) / (8 * k) * k * w
 }
 
+// ImageToBitSize returns the width in bytes and the length of the data ImageToBit returns for an image of w x h dots:
+// the width is padded to a multiple of 8 dots.
+func ImageToBitSize(w, h int) (int, int) {
+	w, h = (maxByte(w, 0)+7)/8, maxByte(h, 0)
+	return w, w * h
+}
+
+// ImageToBytesSize returns the width in dots and the length of the data ImageToBytes returns for an image of w x h dots.
+func ImageToBytesSize(w, h int) (int, int) {
+	w, h = maxByte(w, 0), maxByte(h, 0)
+	return w, w * h
+}
+
+// ImageToBin converts the image to bands of 24 rows, in which each column is 3 bytes with the top dot
+// in the most significant bit, as printed by [ESC * 33], see ImageToBinSize.
+//
+// The converters ImageToBin, ImageToBit and ImageToBytes share these invariants for the images of any bounds,
+// including the bounds not starting at (0, 0):
+//   - the width and the length of the data are those of ImageToBinSize, ImageToBitSize and ImageToBytesSize;
+//   - the padding of the widths or the heights that aren't a multiple of 8 (or 24) dots is never printed;
+//   - the dots printed with invert are exactly the dots of the image not printed without it.
 func ImageToBin(img image.Image, invert bool) (int, []byte) {
-	sz := img.Bounds().Size()
+	return imageToBin(nil, img, invert, uint8(grayLevel.Load()))
+}
 
-	rows := sz.Y / 24
-	if sz.Y%24 != 0 {
-		rows += 1
-	}
-	rows *= 3
+func imageToBin(buf *[]byte, img image.Image, invert bool, lvl uint8) (int, []byte) {
+	return imageToColumns(buf, img, invert, lvl, 3)
+}
 
-	data := make([]byte, rows*sz.X)
-	shift := 3 * (sz.X - 1)
+// imageToColumns converts the image to bands of k × 8 rows, in which each column is k bytes
+// with the top dot in the most significant bit, as printed by [ESC * m].
+func imageToColumns(buf *[]byte, img image.Image, invert bool, lvl uint8, k int) (int, []byte) {
+	b := img.Bounds()
+	sz := b.Size()
 
-	lvl := uint8(grayLevel.Load())
+	w, n := columnsSize(sz.X, sz.Y, k)
+	data := rasterBuffer(buf, n)
+	shift := k * (sz.X - 1)
 
 	for y := 0; y < sz.Y; y++ {
-		n := y/8 + y/24*shift
+		n := y/8 + y/(8*k)*shift
 		for x := 0; x < sz.X; x++ {
-			if gray(img.At(x, y), lvl, invert) {
-				data[n+x*3] |= 0x80 >> uint(y%8)
+			if gray(img.At(b.Min.X+x, b.Min.Y+y), lvl, invert) {
+				data[n+x*k] |= 0x80 >> uint(y%8)
 			}
 		}
 	}
 
-	return sz.X, data
+	return w, data
 }
 
+// ImageToBit converts the image to raster rows with the leftmost dot in the most significant bit,
+// as printed by [GS v 0], see ImageToBitSize.
 func ImageToBit(img image.Image, invert bool) (int, []byte) {
-	sz := img.Bounds().Size()
-
-	w := sz.X / 8
-	if sz.X%8 != 0 {
-		w += 1
-	}
+	return imageToBit(nil, img, invert, uint8(grayLevel.Load()))
+}
 
-	data := make([]byte, w*sz.Y)
+func imageToBit(buf *[]byte, img image.Image, invert bool, lvl uint8) (int, []byte) {
+	b := img.Bounds()
+	sz := b.Size()
 
-	lvl := uint8(grayLevel.Load())
+	w, n := ImageToBitSize(sz.X, sz.Y)
+	data := rasterBuffer(buf, n)
 
 	for y := 0; y < sz.Y; y++ {
 		for x := 0; x < sz.X; x++ {
-			if gray(img.At(x, y), lvl, invert) {
+			if gray(img.At(b.Min.X+x, b.Min.Y+y), lvl, invert) {
 				data[y*w+x/8] |= 0x80 >> uint(x%8)
 			}
 		}
@@ -86,34 +145,131 @@ func ImageToBit(img image.Image, invert bool) (int, []byte) {
 	return w, data
 }
 
+// ImageToBytes converts the image to rows of a byte per dot, 0 for the printed dots and 255 for the others,
+// see ImageToBytesSize.
 func ImageToBytes(img image.Image, invert bool) (int, []byte) {
-	sz := img.Bounds().Size()
+	return imageToBytes(nil, img, invert, uint8(grayLevel.Load()))
+}
 
-	data := make([]byte, sz.X*sz.Y)
+func imageToBytes(buf *[]byte, img image.Image, invert bool, lvl uint8) (int, []byte) {
+	b := img.Bounds()
+	sz := b.Size()
 
-	lvl := uint8(grayLevel.Load())
+	w, n := ImageToBytesSize(sz.X, sz.Y)
+	data := rasterBuffer(buf, n)
 
 	for y := 0; y < sz.Y; y++ {
 		for x := 0; x < sz.X; x++ {
-			if !gray(img.At(x, y), lvl, invert) {
+			if !gray(img.At(b.Min.X+x, b.Min.Y+y), lvl, invert) {
 				data[y*sz.X+x] = 255
 			}
 		}
 	}
 
-	return sz.X, data
+	return w, data
 }
 
-// Logo returns the library logo.
-func Logo() (img image.Image) {
-	file, err := base64.StdEncoding.DecodeString(logo)
-	if err != nil {
-		panic(err)
+// InvertBand returns the image with the rows from y0 to y1 (exclusive) inverted, the other rows are unchanged,
+// e.g. for a bold section header knocked out of a graphic on the printers that print only images.
+// The transparent pixels of the band are inverted as the white paper, so they are printed.
+// The result composes with the conversions, such as ImageToBytes, and with Cmd.Image.
+//
+// Example Usage:
+//
+//	w, data := thermalize.ImageToBytes(thermalize.InvertBand(img, 0, 48), false)
+func InvertBand(img image.Image, y0, y1 int) image.Image {
+	b := img.Bounds()
+	return invertedBand{Image: img, band: image.Rect(b.Min.X, b.Min.Y+y0, b.Max.X, b.Min.Y+y1).Intersect(b)}
+}
+
+// invertedBand is an image whose pixels within the band are inverted.
+type invertedBand struct {
+	image.Image
+	band image.Rectangle
+}
+
+func (img invertedBand) At(x, y int) color.Color {
+	c := img.Image.At(x, y)
+	if !(image.Point{X: x, Y: y}).In(img.band) {
+		return c
 	}
-	if img, err = png.Decode(bytes.NewReader(file)); err != nil {
+	// The premultiplied color is composed on white before it is inverted.
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{R: uint16(a - r), G: uint16(a - g), B: uint16(a - b), A: 0xFFFF}
+}
+
+// BlendImage returns the image composited on the opaque background, so the translucent pixels, such as the
+// anti-aliased edges of a logo, are printed by their blended gray level instead of being dropped as the background
+// when their alpha is below the gray level. The result composes with the conversions, such as ImageToBit,
+// and with Cmd.Image, see also WithAlphaBlending.
+//
+// Example Usage:
+//
+//	w, data := thermalize.ImageToBit(thermalize.BlendImage(logo, color.White), false)
+func BlendImage(img image.Image, background color.Color) image.Image {
+	r, g, b, _ := background.RGBA()
+	return blendedImage{Image: img, background: color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: 0xFFFF}}
+}
+
+// blendedImage is an image composited on the opaque background.
+type blendedImage struct {
+	image.Image
+	background color.RGBA64
+}
+
+func (img blendedImage) At(x, y int) color.Color {
+	r, g, b, a := img.Image.At(x, y).RGBA()
+	if a == 0xFFFF {
+		return color.RGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: 0xFFFF}
+	}
+	// The color is premultiplied, so the background shows through by the remaining alpha.
+	t := 0xFFFF - a
+	bg := img.background
+	return color.RGBA64{
+		R: uint16(r + uint32(bg.R)*t/0xFFFF),
+		G: uint16(g + uint32(bg.G)*t/0xFFFF),
+		B: uint16(b + uint32(bg.B)*t/0xFFFF),
+		A: 0xFFFF,
+	}
+}
+
+// CropImage returns the part of the image within the rectangle, in the coordinates of the image, without copying it,
+// e.g. to print a region of a scanned form or one label of a sheet. Unlike SubImage, it works for any image,
+// and the crops of the same rectangle of an image are equal, so they share the converted data in the image cache
+// (see WithImageCache). The result composes with the conversions, such as ImageToBit, and with Cmd.Image.
+//
+// Example Usage:
+//
+//	w, data := thermalize.ImageToBit(thermalize.CropImage(sheet, image.Rect(0, 200, 576, 400)), false)
+func CropImage(img image.Image, r image.Rectangle) image.Image {
+	return croppedImage{Image: img, rect: r.Intersect(img.Bounds())}
+}
+
+// croppedImage is the part of an image within the rectangle.
+type croppedImage struct {
+	image.Image
+	rect image.Rectangle
+}
+
+func (img croppedImage) Bounds() image.Rectangle {
+	return img.rect
+}
+
+func (img croppedImage) At(x, y int) color.Color {
+	if !(image.Point{X: x, Y: y}).In(img.rect) {
+		return color.Transparent
+	}
+	return img.Image.At(x, y)
+}
+
+// Logo returns the library logo, which is registered as LogoThermalize (see RegisterLogo).
+// The image is shared and must not be modified.
+func Logo() image.Image {
+	img, err := LookupLogo(LogoThermalize)
+	if err != nil {
 		panic(err)
 	}
-	return
+	return img
 }
 
 const logo = `iVBORw0KGgoAAAANSUhEUgAAAf4AAADfCAYAAAAX6LECAAAACXBIWXMAAAsTAAALEwEAmpwYAAAAAXNSR0IArs4c6QAAAARnQU1BAACxjw
@@ -227,3 +383,54 @@ zrPhFdlZJ+8tc//cGpJ/zkP3/7F5kQJ/gG78+6TZqmIr/ky1zZubf0nzvb+Q5Z2v4s3/nHb/yrCpDP0H
 3jVPa1yh83vS4/Nb1eCvtZu5vxVNVf/vQHrcDWRlv3l//738+e+uZ3jvMjf56Ofj5bUz2WDz6/88GPydBfv/jDmNtM1f6qNlMdMhO0zWR3q89RTgAAAAAAAA
 AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAbft/SxHU7C9i4ZoAAA
 AASUVORK5CYII=`
+
+// inlineBand returns the image placed in a band of 24 rows, the height of a line of font A,
+// according to the vertical alignment. Taller images are scaled down to the height of the band.
+func inlineBand(img image.Image, valign byte) image.Image {
+	const height = 24
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if h > height {
+		w, h = maxByte(w*height/h, 1), height
+	}
+
+	top := 0
+	switch valign {
+	case VAlignMiddle:
+		top = (height - h) / 2
+	case VAlignBottom:
+		top = height - h
+	}
+
+	band := image.NewRGBA(image.Rect(0, 0, w, height))
+	for i := range band.Pix {
+		band.Pix[i] = 0xFF
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			band.Set(x, top+y, img.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h))
+		}
+	}
+	return band
+}
+
+// paddedImage places the image x pixels from the left edge of a blank canvas of the width.
+// It is comparable if the image is, so the padded image can be cached.
+type paddedImage struct {
+	image.Image
+	x, width int
+	blank    color.Color
+}
+
+func (p paddedImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, p.width, p.Image.Bounds().Dy())
+}
+
+func (p paddedImage) At(x, y int) color.Color {
+	b := p.Image.Bounds()
+	if x < p.x || x >= p.x+b.Dx() {
+		return p.blank
+	}
+	return p.Image.At(b.Min.X+x-p.x, b.Min.Y+y)
+}
@@ -0,0 +1,116 @@
+package thermalize
+
+import (
+	"container/list"
+	"image"
+	"reflect"
+	"sync"
+)
+
+type imageFormat byte
+
+const (
+	formatBit imageFormat = iota
+	formatBin
+	formatBytes
+	formatBin8
+)
+
+func (f imageFormat) convert(buf *[]byte, img image.Image, invert bool, lvl uint8) (int, []byte) {
+	switch f {
+	case formatBin:
+		return imageToBin(buf, img, invert, lvl)
+	case formatBytes:
+		return imageToBytes(buf, img, invert, lvl)
+	case formatBin8:
+		return imageToColumns(buf, img, invert, lvl, 1)
+	default:
+		return imageToBit(buf, img, invert, lvl)
+	}
+}
+
+// imageCache is an LRU cache of converted images shared by all command sets of the process.
+// Its capacity is the total size of the converted data in bytes.
+type imageCache struct {
+	mu    sync.Mutex
+	size  int
+	used  int
+	items map[imageKey]*list.Element
+	lru   *list.List
+}
+
+type imageKey struct {
+	img    image.Image
+	format imageFormat
+	invert bool
+	level  uint8
+}
+
+type imageEntry struct {
+	key  imageKey
+	w    int
+	data []byte
+}
+
+var images = &imageCache{items: make(map[imageKey]*list.Element), lru: list.New()}
+
+// resize sets the capacity of the cache and evicts the least recently used images that no longer fit.
+func (ic *imageCache) resize(size int) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	ic.size = size
+	ic.evict()
+}
+
+// convert returns the converted image from the cache, converting and storing it on a cache miss.
+// The returned data is shared and must not be modified.
+func (ic *imageCache) convert(img image.Image, invert bool, format imageFormat, lvl uint8) (int, []byte) {
+	if !cacheable(img) {
+		return format.convert(nil, img, invert, lvl)
+	}
+
+	key := imageKey{img: img, format: format, invert: invert, level: lvl}
+
+	ic.mu.Lock()
+	if el, ok := ic.items[key]; ok {
+		ic.lru.MoveToFront(el)
+		e := el.Value.(*imageEntry)
+		ic.mu.Unlock()
+		return e.w, e.data
+	}
+	ic.mu.Unlock()
+
+	w, data := format.convert(nil, img, invert, lvl)
+
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	if _, ok := ic.items[key]; !ok && len(data) <= ic.size {
+		ic.items[key] = ic.lru.PushFront(&imageEntry{key: key, w: w, data: data})
+		ic.used += len(data)
+		ic.evict()
+	}
+
+	return w, data
+}
+
+// cacheable reports whether the image can be used as a key of the cache, including the image of a padded image.
+func cacheable(img image.Image) bool {
+	if b, ok := img.(blendedImage); ok {
+		img = b.Image
+	}
+	if p, ok := img.(paddedImage); ok {
+		img = p.Image
+	}
+	return reflect.TypeOf(img).Comparable()
+}
+
+func (ic *imageCache) evict() {
+	for ic.used > ic.size {
+		el := ic.lru.Back()
+		e := ic.lru.Remove(el).(*imageEntry)
+		delete(ic.items, e.key)
+		ic.used -= len(e.data)
+	}
+}
@@ -0,0 +1,197 @@
+package thermalize
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/bits"
+	"math/rand"
+	"testing"
+)
+
+// converterCase is a converter with the function telling whether it prints the dot (x, y) of an image w dots wide.
+type converterCase struct {
+	name    string
+	size    func(w, h int) (int, int)
+	convert func(buf *[]byte, img image.Image, invert bool, lvl uint8) (int, []byte)
+	dot     func(data []byte, w, x, y int) bool
+	printed func(data []byte) int
+}
+
+// countBits returns the number of the bits set in the data, the dots printed by the formats of bits.
+func countBits(data []byte) int {
+	n := 0
+	for _, b := range data {
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
+
+var converterCases = []converterCase{
+	{
+		name:    "ImageToBin",
+		size:    ImageToBinSize,
+		convert: imageToBin,
+		dot: func(data []byte, w, x, y int) bool {
+			return data[y/24*3*w+x*3+y%24/8]&(0x80>>uint(y%8)) != 0
+		},
+		printed: countBits,
+	},
+	{
+		name:    "ImageToBit",
+		size:    ImageToBitSize,
+		convert: imageToBit,
+		dot: func(data []byte, w, x, y int) bool {
+			return data[y*((w+7)/8)+x/8]&(0x80>>uint(x%8)) != 0
+		},
+		printed: countBits,
+	},
+	{
+		name:    "ImageToBytes",
+		size:    ImageToBytesSize,
+		convert: imageToBytes,
+		dot: func(data []byte, w, x, y int) bool {
+			return data[y*w+x] == 0
+		},
+		printed: func(data []byte) int {
+			return bytes.Count(data, []byte{0})
+		},
+	},
+}
+
+// randomImage returns an image of w x h dots of random gray and transparent pixels with the bounds at (x, y).
+func randomImage(r *rand.Rand, x, y, w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(x, y, x+w, y+h))
+	for i := range img.Pix {
+		img.Pix[i] = byte(r.Intn(256))
+	}
+	// Some pixels at the gray level.
+	for i := 0; i < len(img.Pix); i += 4 * 7 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 127, 127, 127, 255
+	}
+	return img
+}
+
+// translate returns a copy of the image with the bounds at (0, 0).
+func translate(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			out.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return out
+}
+
+// imageSizes are the sizes of the images converted, multiples of 8 and 24 dots and the others.
+var imageSizes = [][2]int{{1, 1}, {7, 9}, {8, 8}, {9, 23}, {16, 24}, {17, 25}, {33, 48}, {5, 50}}
+
+func TestImageSizes(t *testing.T) {
+	for _, tc := range []struct {
+		size             func(w, h int) (int, int)
+		w, h, width, len int
+	}{
+		{ImageToBinSize, 10, 24, 10, 30},
+		{ImageToBinSize, 10, 25, 10, 60},
+		{ImageToBinSize, 10, 0, 10, 0},
+		{ImageToBitSize, 8, 3, 1, 3},
+		{ImageToBitSize, 9, 3, 2, 6},
+		{ImageToBitSize, -1, 3, 0, 0},
+		{ImageToBytesSize, 9, 3, 9, 27},
+		{ImageToBytesSize, 9, -3, 9, 0},
+	} {
+		if w, l := tc.size(tc.w, tc.h); w != tc.width || l != tc.len {
+			t.Errorf("size of %d x %d = %d, %d, want %d, %d", tc.w, tc.h, w, l, tc.width, tc.len)
+		}
+	}
+}
+
+func TestImageConverters(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const lvl = defaultGrayLevel
+
+	for _, f := range converterCases {
+		for _, sz := range imageSizes {
+			for _, origin := range []image.Point{{}, {5, -3}, {-11, 40}} {
+				img := randomImage(r, origin.X, origin.Y, sz[0], sz[1])
+				b := img.Bounds()
+
+				w, data := f.convert(nil, img, false, lvl)
+				iw, inverted := f.convert(nil, img, true, lvl)
+
+				width, n := f.size(sz[0], sz[1])
+				if w != width || len(data) != n || iw != width || len(inverted) != n {
+					t.Fatalf("%s %v: %d, %d bytes, inverted %d, %d bytes, want %d, %d bytes",
+						f.name, b, w, len(data), iw, len(inverted), width, n)
+				}
+
+				dots := 0
+				for y := 0; y < sz[1]; y++ {
+					for x := 0; x < sz[0]; x++ {
+						want := gray(img.At(b.Min.X+x, b.Min.Y+y), lvl, false)
+						if got := f.dot(data, sz[0], x, y); got != want {
+							t.Fatalf("%s %v: dot (%d, %d) printed %v, want %v", f.name, b, x, y, got, want)
+						}
+						if f.dot(inverted, sz[0], x, y) == want {
+							t.Fatalf("%s %v: inverted dot (%d, %d) printed %v", f.name, b, x, y, want)
+						}
+						if want {
+							dots++
+						}
+					}
+				}
+
+				// The bits of the padding are never set, with or without invert.
+				if got := f.printed(data); got != dots {
+					t.Errorf("%s %v: %d dots printed, want %d", f.name, b, got, dots)
+				}
+				if got, want := f.printed(inverted), sz[0]*sz[1]-dots; got != want {
+					t.Errorf("%s %v: %d inverted dots printed, want %d", f.name, b, got, want)
+				}
+
+				if _, moved := f.convert(nil, translate(img), false, lvl); !bytes.Equal(moved, data) {
+					t.Errorf("%s %v: the image at (0, 0) is converted differently", f.name, b)
+				}
+			}
+		}
+	}
+}
+
+func TestImageConvertersSubImage(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	img := randomImage(r, 0, 0, 64, 64).(*image.NRGBA)
+	sub := img.SubImage(image.Rect(13, 21, 42, 60))
+
+	for _, f := range converterCases {
+		_, got := f.convert(nil, sub, false, defaultGrayLevel)
+		_, want := f.convert(nil, translate(sub), false, defaultGrayLevel)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: the sub-image is converted differently from its copy", f.name)
+		}
+	}
+}
+
+func TestImageConvertersReuseBuffer(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	img := randomImage(r, 0, 0, 17, 25)
+
+	for _, f := range converterCases {
+		dirty := bytes.Repeat([]byte{0xA5}, 4096)
+		_, got := f.convert(&dirty, img, false, defaultGrayLevel)
+		_, want := f.convert(nil, img, false, defaultGrayLevel)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: the reused buffer isn't cleared", f.name)
+		}
+	}
+}
+
+func TestGrayTransparent(t *testing.T) {
+	transparent := color.NRGBA{A: 0}
+	if gray(transparent, defaultGrayLevel, false) || !gray(transparent, defaultGrayLevel, true) {
+		t.Error("the transparent pixels are printed as the paper")
+	}
+	if !gray(color.Black, defaultGrayLevel, false) || gray(color.White, defaultGrayLevel, false) {
+		t.Error("black isn't printed or white is")
+	}
+}
@@ -0,0 +1,122 @@
+package thermalize
+
+import "image"
+
+// JobOption customizes the framing of a job.
+type JobOption interface {
+	apply(*job)
+}
+
+type job struct {
+	logo   image.Image
+	feed   byte
+	cut    bool
+	mode   bool
+	m, p   byte
+	drawer bool
+	pin    byte
+	t1, t2 byte
+}
+
+type jobOptionFunc func(*job)
+
+func (fn jobOptionFunc) apply(j *job) {
+	fn(j)
+}
+
+// WithJobLogo prints the logo centered above the document.
+func WithJobLogo(img image.Image) JobOption {
+	return jobOptionFunc(func(j *job) { j.logo = img })
+}
+
+// WithJobFeed feeds the paper by n/4 mm after the document.
+func WithJobFeed(n byte) JobOption {
+	return jobOptionFunc(func(j *job) { j.feed = n })
+}
+
+// WithJobCut sets the mode of the cut after the document, see Cmd.Cut. By default, FullCut is used.
+func WithJobCut(m, p byte) JobOption {
+	return jobOptionFunc(func(j *job) { j.mode, j.m, j.p = true, m, p })
+}
+
+// WithoutJobCut disables the cut after the document.
+func WithoutJobCut() JobOption {
+	return jobOptionFunc(func(j *job) { j.cut = false })
+}
+
+// WithJobDrawer opens the cash drawer after the document has been printed successfully, see Cmd.OpenCashDrawer.
+func WithJobDrawer(m, t1, t2 byte) JobOption {
+	return jobOptionFunc(func(j *job) { j.drawer, j.pin, j.t1, j.t2 = true, m, t1, t2 })
+}
+
+// Job frames the document printed by fn: it initializes the printer, prints the logo,
+// calls fn, then feeds and cuts the paper, opens the cash drawer and prints the data.
+//
+// If fn returns an error or panics, the printer is initialized again, which resets the print modes
+// and clears the unprinted data, and the paper is fed and cut, but the cash drawer is not opened.
+// This leaves the printer in a clean state for the next job.
+//
+// Job returns the error returned by fn, or the error returned by Print, which reports the error of the command set.
+//
+// Example Usage:
+//
+//	err := thermalize.Job(cmd, func(cmd thermalize.Cmd) error {
+//		cmd.Text("Hello", nil)
+//		cmd.LineFeed()
+//		return nil
+//	}, thermalize.WithJobFeed(100), thermalize.WithJobDrawer(thermalize.DrawerPin2, 50, 100))
+func Job(cmd Cmd, fn func(Cmd) error, opts ...JobOption) (err error) {
+	j := job{cut: true}
+	for _, opt := range opts {
+		opt.apply(&j)
+	}
+
+	finished := false
+	defer func() {
+		if !finished {
+			j.end(cmd, false)
+		}
+	}()
+
+	cmd.Init()
+	if j.logo != nil {
+		cmd.Align(Center)
+		cmd.Image(j.logo, false)
+		cmd.Align(Left)
+	}
+
+	err = fn(cmd)
+	finished = true
+
+	perr := j.end(cmd, err == nil)
+	if err != nil {
+		return err
+	}
+	return perr
+}
+
+// end finishes the job, ok reports whether the document has been printed without an error.
+// It returns the error of Print.
+func (j *job) end(cmd Cmd, ok bool) error {
+	if !ok {
+		cmd.Init()
+	}
+
+	if j.feed > 0 {
+		cmd.Feed(j.feed)
+	}
+
+	switch {
+	case !j.cut:
+	case j.mode:
+		cmd.Cut(j.m, j.p)
+	default:
+		cmd.FullCut()
+	}
+
+	if ok && j.drawer {
+		cmd.OpenCashDrawer(j.pin, j.t1, j.t2)
+	}
+
+	return cmd.Print()
+}
@@ -0,0 +1,389 @@
+// Package journal keeps an electronic journal of the printed documents,
+// as required for the receipts by the fiscal regulations of some jurisdictions.
+//
+// Each document is appended to the journal as one JSON line holding its sequence number, the time,
+// the metadata of the application, the rendered text and the raw bytes sent to the printer.
+// The entries are chained by the SHA-256 hash of the previous entry, so removed or modified entries
+// are detected by Verify. The journal is rotated to a new file once the current one reaches its maximum size.
+//
+// Example Usage:
+//
+//	j, err := journal.Open("/var/lib/pos/journal")
+//	tee := j.Tee(conn)
+//	cmd := thermalize.NewEscape(48, 576, tee)
+//	// ... build and print the receipt
+//	entry, err := tee.Commit(map[string]string{"receipt": "0042"})
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+var (
+	// ErrClosed is returned by the methods of a closed journal.
+	ErrClosed = errors.New("journal: closed")
+
+	// ErrBroken is returned by Verify if an entry doesn't follow the previous one.
+	ErrBroken = errors.New("journal: broken chain")
+)
+
+// Entry is a journaled document.
+type Entry struct {
+	// Seq is the sequence number of the entry, starting from 1.
+	Seq uint64 `json:"seq"`
+
+	Time time.Time         `json:"time"`
+	Meta map[string]string `json:"meta,omitempty"`
+
+	// Text is the document rendered as plain text, Raw is the data sent to the printer.
+	Text string `json:"text"`
+	Raw  []byte `json:"raw"`
+
+	// Prev is the hex SHA-256 hash of the JSON line of the previous entry, empty for the first entry.
+	Prev string `json:"prev"`
+}
+
+// Journal is an append-only journal stored in a directory, in the files journal-000001.jsonl, journal-000002.jsonl, etc.
+//
+// Journal is safe for concurrent use.
+type Journal struct {
+	dir string
+
+	// maxSize is the size at which the file is rotated, render renders the text of the entries.
+	maxSize int64
+	render  func([]byte) string
+	sync    bool
+
+	mu   sync.Mutex
+	file *os.File
+	num  int
+	size int64
+	seq  uint64
+	prev string
+
+	// err is set if a failed entry couldn't be removed, the journal can't be appended to.
+	err error
+}
+
+// Option customizes the journal.
+type Option interface {
+	apply(*Journal)
+}
+
+type optionFunc func(*Journal)
+
+func (fn optionFunc) apply(j *Journal) {
+	fn(j)
+}
+
+// WithMaxSize rotates the journal to a new file once the current one reaches n bytes, by default 16 MiB.
+func WithMaxSize(n int64) Option {
+	return optionFunc(func(j *Journal) { j.maxSize = n })
+}
+
+// WithTextRenderer renders the text of the entries with fn, by default PlainText,
+// e.g. for the documents printed with the postscript command set.
+func WithTextRenderer(fn func(raw []byte) string) Option {
+	return optionFunc(func(j *Journal) { j.render = fn })
+}
+
+// WithSync commits each entry to stable storage before Append returns.
+func WithSync() Option {
+	return optionFunc(func(j *Journal) { j.sync = true })
+}
+
+// Open opens the journal in the directory, creating it if it doesn't exist.
+// The entries are appended to the last file of the journal, continuing the sequence and the chain
+// of the last entry, which may be in an earlier file if the last one is empty.
+// A torn entry at the end of the last file, written partly before a crash, is removed.
+func Open(dir string, opts ...Option) (*Journal, error) {
+	j := &Journal{dir: dir, maxSize: 16 << 20, render: PlainText}
+	for _, opt := range opts {
+		opt.apply(j)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := journalFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	j.num = 1
+	if len(files) > 0 {
+		last := files[len(files)-1]
+		if j.num, err = fileNumber(last); err != nil {
+			return nil, err
+		}
+		if err = repairTail(last); err != nil {
+			return nil, err
+		}
+		for i := len(files) - 1; i >= 0; i-- {
+			found, err := j.resume(files[i])
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				break
+			}
+		}
+	}
+	if err = j.openFile(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Append appends the document to the journal, the text is rendered from the raw data.
+func (j *Journal) Append(raw []byte, meta map[string]string) (Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return Entry{}, ErrClosed
+	}
+	if j.err != nil {
+		return Entry{}, j.err
+	}
+
+	e := Entry{Seq: j.seq + 1, Time: time.Now().UTC(), Meta: meta, Text: j.render(raw), Raw: raw, Prev: j.prev}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if j.size > 0 && j.size+int64(len(line))+1 > j.maxSize {
+		if err = j.rotate(); err != nil {
+			return Entry{}, err
+		}
+	}
+	_, err = j.file.Write(append(line, '\n'))
+	if err == nil && j.sync {
+		err = j.file.Sync()
+	}
+	if err != nil {
+		j.discard()
+		return Entry{}, err
+	}
+	j.size += int64(len(line)) + 1
+
+	j.seq, j.prev = e.Seq, hash(line)
+	return e, nil
+}
+
+// discard removes the entry that failed to be written from the end of the file, so the chain stays intact.
+// If it can't be removed, the next appends fail.
+func (j *Journal) discard() {
+	if err := j.file.Truncate(j.size); err != nil {
+		j.err = fmt.Errorf("journal: removing the failed entry: %w", err)
+	}
+}
+
+// Close closes the journal.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return ErrClosed
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// Tee returns a writer passing the data to w and collecting it for the journal until Commit.
+func (j *Journal) Tee(w io.Writer) *Tee {
+	return &Tee{w: w, j: j}
+}
+
+// Tee writes the data to the printer and collects it, every document printed through it is appended
+// to the journal by Commit. The data the printer didn't accept is not journaled.
+//
+// Tee is not safe for concurrent use.
+type Tee struct {
+	w   io.Writer
+	j   *Journal
+	buf bytes.Buffer
+}
+
+func (t *Tee) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.buf.Write(p[:n])
+	return n, err
+}
+
+// Commit appends the data written since the last Commit or Discard to the journal as a document.
+func (t *Tee) Commit(meta map[string]string) (Entry, error) {
+	raw := append([]byte(nil), t.buf.Bytes()...)
+	t.buf.Reset()
+	return t.j.Append(raw, meta)
+}
+
+// Discard discards the data written since the last Commit, such as the status requests.
+func (t *Tee) Discard() {
+	t.buf.Reset()
+}
+
+// PlainText renders the ESC/POS command stream as plain text:
+// the text and the line breaks, the tabs as spaces and the cuts as blank lines.
+func PlainText(raw []byte) string {
+	var sb strings.Builder
+	for _, cmd := range thermalize.DecodeEscape(raw) {
+		switch cmd.Name {
+		case "TEXT":
+			sb.Write(cmd.Data)
+		case "LF":
+			sb.WriteByte('\n')
+		case "HT":
+			sb.WriteByte(' ')
+		case "GS V":
+			sb.WriteString("\n\n")
+		}
+	}
+	return sb.String()
+}
+
+// Verify checks the chain of the entries in all files of the journal in the directory
+// and returns the number of entries.
+func Verify(dir string) (uint64, error) {
+	files, err := journalFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var seq uint64
+	prev := ""
+	for _, name := range files {
+		err = readLines(name, func(line []byte) error {
+			var e Entry
+			if err := json.Unmarshal(line, &e); err != nil {
+				return fmt.Errorf("journal: %s: entry %d: %w", filepath.Base(name), seq+1, err)
+			}
+			if e.Seq != seq+1 || e.Prev != prev {
+				return fmt.Errorf("%w: %s: entry %d", ErrBroken, filepath.Base(name), e.Seq)
+			}
+			seq, prev = e.Seq, hash(line)
+			return nil
+		})
+		if err != nil {
+			return seq, err
+		}
+	}
+	return seq, nil
+}
+
+// resume continues the sequence and the chain of the last entry of the file
+// and reports whether the file holds any entry.
+func (j *Journal) resume(name string) (bool, error) {
+	found := false
+	err := readLines(name, func(line []byte) error {
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return fmt.Errorf("journal: %s: %w", filepath.Base(name), err)
+		}
+		j.seq, j.prev, found = e.Seq, hash(line), true
+		return nil
+	})
+	return found, err
+}
+
+// repairTail removes the data following the last complete line of the file, the rest of an entry torn by a crash.
+func repairTail(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+		return os.Truncate(name, int64(end))
+	}
+	return nil
+}
+
+func (j *Journal) rotate() error {
+	if err := j.file.Close(); err != nil {
+		return err
+	}
+	j.num++
+	return j.openFile()
+}
+
+func (j *Journal) openFile() error {
+	f, err := os.OpenFile(filepath.Join(j.dir, fileName(j.num)), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	j.file, j.size = f, info.Size()
+	return nil
+}
+
+// journalFiles returns the files of the journal in the directory in order.
+func journalFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "journal-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func fileName(n int) string {
+	return fmt.Sprintf("journal-%06d.jsonl", n)
+}
+
+func fileNumber(name string) (int, error) {
+	var n int
+	if _, err := fmt.Sscanf(filepath.Base(name), "journal-%06d.jsonl", &n); err != nil {
+		return 0, fmt.Errorf("journal: %s: %w", filepath.Base(name), err)
+	}
+	return n, nil
+}
+
+// readLines calls fn with each line of the file.
+func readLines(name string, fn func([]byte) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSuffix(line, []byte("\n")); len(line) > 0 {
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func hash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gromey/thermalize"
+)
+
+// limited accepts n bytes and fails the writes of the rest.
+type limited struct {
+	n int
+}
+
+var errFull = errors.New("full")
+
+func (l *limited) Write(p []byte) (int, error) {
+	if len(p) > l.n {
+		n := l.n
+		l.n = 0
+		return n, errFull
+	}
+	l.n -= len(p)
+	return len(p), nil
+}
+
+func TestTeeCommit(t *testing.T) {
+	j, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	tee := j.Tee(&limited{n: 1 << 10})
+	cmd := thermalize.NewEscape(48, 576, tee)
+	cmd.Text("Total\t12.50", nil)
+	cmd.LineFeed()
+	cmd.FullCut()
+	if err := cmd.Print(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := tee.Commit(map[string]string{"receipt": "0042"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Seq != 1 || e.Prev != "" || e.Meta["receipt"] != "0042" {
+		t.Errorf("the first entry is %d %q %v", e.Seq, e.Prev, e.Meta)
+	}
+	if want := "Total 12.50\n\n\n"; e.Text != want {
+		t.Errorf("the text is %q, want %q", e.Text, want)
+	}
+
+	tee.Write([]byte{thermalize.DLE, thermalize.EOT, 1})
+	tee.Discard()
+	e, err = tee.Commit(nil)
+	if err != nil || len(e.Raw) != 0 || e.Seq != 2 {
+		t.Errorf("the discarded data is journaled: %d % x %v", e.Seq, e.Raw, err)
+	}
+}
+
+func TestTeeRejected(t *testing.T) {
+	j, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	tee := j.Tee(&limited{n: 3})
+	if _, err := tee.Write([]byte("accepted")); !errors.Is(err, errFull) {
+		t.Fatalf("Write: %v", err)
+	}
+	e, err := tee.Commit(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(e.Raw) != "acc" {
+		t.Errorf("the journaled data is %q, want the accepted %q", e.Raw, "acc")
+	}
+}
+
+func TestRotateAndResume(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, WithMaxSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := j.Append([]byte(strings.Repeat("x", 100)), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+	if _, err := j.Append(nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Append after Close: %v, want %v", err, ErrClosed)
+	}
+
+	files, _ := journalFiles(dir)
+	if len(files) != 5 {
+		t.Errorf("the journal is rotated into %d files, want 5", len(files))
+	}
+	// The last file is empty after a rotation, the chain is resumed from the entry of the previous file.
+	if err := os.WriteFile(filepath.Join(dir, fileName(6)), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = Open(dir, WithMaxSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := j.Append([]byte("resumed"), nil)
+	j.Close()
+	if err != nil || e.Seq != 6 {
+		t.Errorf("the resumed entry is %d, %v, want 6", e.Seq, err)
+	}
+	if n, err := Verify(dir); n != 6 || err != nil {
+		t.Errorf("Verify = %d, %v, want 6 entries", n, err)
+	}
+}
+
+func TestTornAndTampered(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"first", "second", "third"} {
+		if _, err := j.Append([]byte(s), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Close()
+
+	// An entry torn by a crash is removed on Open.
+	name := filepath.Join(dir, fileName(1))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":4,"ti`)
+	f.Close()
+	if j, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := j.Append([]byte("fourth"), nil); err != nil || e.Seq != 4 {
+		t.Errorf("the entry after the torn one is %d, %v, want 4", e.Seq, err)
+	}
+	j.Close()
+	if n, err := Verify(dir); n != 4 || err != nil {
+		t.Errorf("Verify = %d, %v, want 4 entries", n, err)
+	}
+
+	// A modified entry breaks the chain of the next one.
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if err := os.WriteFile(name, []byte(lines[0]+lines[1]+lines[3]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Verify(dir); !errors.Is(err, ErrBroken) || n != 2 {
+		t.Errorf("Verify of the removed entry = %d, %v, want 2 and %v", n, err, ErrBroken)
+	}
+}
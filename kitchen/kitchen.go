@@ -0,0 +1,327 @@
+// Package kitchen prints kitchen order tickets.
+//
+// The ticket layout follows common restaurant practice: a large order number and table,
+// order and print times, items with indented modifiers, highlighted allergen lines,
+// per-station filtering or the stations side by side on the wide-format printers,
+// and a beep and a cut at the end to get the attention of the staff.
+package kitchen
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+// ErrNoItems is returned by Print if the ticket has no items for the selected station, and by PrintSummary
+// if it has no items.
+var ErrNoItems = errors.New("kitchen: no items to print")
+
+// Ticket is a kitchen order ticket.
+type Ticket struct {
+	Number  string
+	Table   string
+	Server  string
+	Ordered time.Time
+	Items   []Item
+	Note    string
+}
+
+// Item is an ordered item.
+type Item struct {
+	Quantity  int
+	Name      string
+	Modifiers []string
+	Allergens []string
+
+	// Station is the kitchen station preparing the item, such as "grill" or "bar".
+	Station string
+}
+
+// Option customizes the printed ticket.
+type Option interface {
+	apply(*config)
+}
+
+type config struct {
+	station    string
+	timeLayout string
+	enc        thermalize.Encoder
+	now        func() time.Time
+	beep       bool
+	cut        bool
+}
+
+type optionFunc func(*config)
+
+func (fn optionFunc) apply(c *config) {
+	fn(c)
+}
+
+// WithStation prints only the items of the station.
+func WithStation(station string) Option {
+	return optionFunc(func(c *config) { c.station = station })
+}
+
+// WithTimeLayout sets the layout of the printed times, by default "15:04".
+func WithTimeLayout(layout string) Option {
+	return optionFunc(func(c *config) { c.timeLayout = layout })
+}
+
+// WithEncoder sets the encoder of the ticket text.
+func WithEncoder(enc thermalize.Encoder) Option {
+	return optionFunc(func(c *config) { c.enc = enc })
+}
+
+// WithClock sets the function returning the print time, by default time.Now.
+func WithClock(now func() time.Time) Option {
+	return optionFunc(func(c *config) { c.now = now })
+}
+
+// WithoutBeep disables the beep at the end of the ticket.
+func WithoutBeep() Option {
+	return optionFunc(func(c *config) { c.beep = false })
+}
+
+// WithoutCut disables the cut at the end of the ticket.
+func WithoutCut() Option {
+	return optionFunc(func(c *config) { c.cut = false })
+}
+
+// Print prints the ticket and returns the error reported by the command set, if any.
+//
+// Allergens are printed in the second color on two-color printers (thermalize.ColorPrinter),
+// in reverse mode on printers supporting it (thermalize.Reverser), or marked with exclamation marks otherwise.
+func Print(cmd thermalize.Cmd, t Ticket, opts ...Option) error {
+	cfg := newConfig(opts)
+
+	items := make([]Item, 0, len(t.Items))
+	for _, item := range t.Items {
+		if cfg.station == "" || strings.EqualFold(item.Station, cfg.station) {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return ErrNoItems
+	}
+
+	p := newPrinter(cmd, cfg)
+	cmd.Init()
+	p.header(t, cfg)
+
+	for _, item := range items {
+		p.item(item)
+	}
+	return p.footer(t, cfg)
+}
+
+// maxLanes is the number of stations printed side by side by PrintSummary.
+const maxLanes = 3
+
+// PrintSummary prints the ticket with the items of the stations side by side, e.g. on the 112 mm printer
+// of the pass (see thermalize.WideCPL), and returns the error reported by the command set, if any.
+// The header is printed once, followed by a lane per station (see thermalize.Lanes) in the order of their first item,
+// in rows of up to three stations. The items without a station share a lane, WithStation is ignored.
+func PrintSummary(cmd thermalize.Cmd, t Ticket, opts ...Option) error {
+	cfg := newConfig(opts)
+	cfg.station = ""
+	if len(t.Items) == 0 {
+		return ErrNoItems
+	}
+
+	var stations []string
+	items := make(map[string][]Item)
+	for _, item := range t.Items {
+		key := strings.ToLower(item.Station)
+		if _, ok := items[key]; !ok {
+			stations = append(stations, item.Station)
+		}
+		items[key] = append(items[key], item)
+	}
+
+	p := newPrinter(cmd, cfg)
+	cmd.Init()
+	p.header(t, cfg)
+
+	for start := 0; start < len(stations); start += maxLanes {
+		end := start + maxLanes
+		if end > len(stations) {
+			end = len(stations)
+		}
+
+		lanes := make([]func(thermalize.Cmd), 0, end-start)
+		for _, station := range stations[start:end] {
+			station, items := station, items[strings.ToLower(station)]
+			lanes = append(lanes, func(cmd thermalize.Cmd) {
+				lp := printer{cmd: cmd, enc: p.enc}
+				lp.station(station)
+				for _, item := range items {
+					lp.item(item)
+				}
+			})
+		}
+		thermalize.Lanes(cmd, lanes)
+	}
+	return p.footer(t, cfg)
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{timeLayout: "15:04", now: time.Now, beep: true, cut: true}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	return cfg
+}
+
+type printer struct {
+	cmd thermalize.Cmd
+	enc func(string) []byte
+}
+
+func newPrinter(cmd thermalize.Cmd, cfg config) printer {
+	p := printer{cmd: cmd}
+	if cfg.enc != nil {
+		p.enc = cfg.enc.Encode
+	}
+	return p
+}
+
+func (p printer) header(t Ticket, cfg config) {
+	p.cmd.Align(thermalize.Center)
+	p.cmd.Bold(true)
+
+	if t.Number != "" {
+		p.cmd.CharSize(2, 2)
+		p.line("#" + t.Number)
+	}
+
+	if t.Table != "" {
+		p.cmd.CharSize(1, 1)
+		p.line("Table " + t.Table)
+	}
+
+	p.cmd.CharSize(0, 0)
+	p.cmd.Bold(false)
+
+	if cfg.station != "" {
+		p.line(strings.ToUpper(cfg.station))
+	}
+
+	p.cmd.Align(thermalize.Left)
+
+	if t.Server != "" {
+		p.line("Server: " + t.Server)
+	}
+	if !t.Ordered.IsZero() {
+		p.line("Ordered: " + t.Ordered.Format(cfg.timeLayout))
+	}
+	p.line("Printed: " + cfg.now().Format(cfg.timeLayout))
+
+	p.rule('=')
+}
+
+// footer prints the note of the ticket, then beeps, cuts and prints the ticket.
+func (p printer) footer(t Ticket, cfg config) error {
+	if t.Note != "" {
+		p.rule('-')
+		p.wrapped("", t.Note)
+	}
+
+	p.rule('=')
+	p.cmd.Feed(100)
+
+	if b, ok := p.cmd.(thermalize.Beeper); ok && cfg.beep {
+		b.Beep(2, 3)
+	}
+	if cfg.cut {
+		p.cmd.FullCut()
+	}
+	return p.cmd.Print()
+}
+
+// station prints the heading of the lane of the station, "OTHER" for the items without a station.
+func (p printer) station(station string) {
+	if station == "" {
+		station = "other"
+	}
+	p.cmd.Bold(true)
+	p.line(strings.ToUpper(station))
+	p.cmd.Bold(false)
+	p.rule('-')
+}
+
+func (p printer) item(item Item) {
+	qty := strconv.Itoa(maxInt(item.Quantity, 1)) + " x "
+
+	p.cmd.Bold(true)
+	p.cmd.CharSize(0, 1)
+	p.wrapped(qty, item.Name)
+	p.cmd.CharSize(0, 0)
+	p.cmd.Bold(false)
+
+	for _, m := range item.Modifiers {
+		p.wrapped("   - ", m)
+	}
+
+	if len(item.Allergens) > 0 {
+		p.allergens(item.Allergens)
+	}
+}
+
+func (p printer) allergens(allergens []string) {
+	text := "ALLERGY: " + strings.ToUpper(strings.Join(allergens, ", "))
+
+	switch cmd := p.cmd.(type) {
+	case thermalize.ColorPrinter:
+		cmd.Color(1)
+		p.cmd.Bold(true)
+		p.wrapped("   ", text)
+		p.cmd.Bold(false)
+		cmd.Color(0)
+	case thermalize.Reverser:
+		p.cmd.Bold(true)
+		for _, l := range thermalize.Wrap(text, p.cmd.CPL()-5) {
+			p.cmd.Text("   ", p.enc)
+			cmd.Reverse(true)
+			p.cmd.Text(" "+l+" ", p.enc)
+			cmd.Reverse(false)
+			p.cmd.LineFeed()
+		}
+		p.cmd.Bold(false)
+	default:
+		p.cmd.Bold(true)
+		p.wrapped(" !! ", text+" !!")
+		p.cmd.Bold(false)
+	}
+}
+
+// wrapped prints the prefix followed by the text wrapped to the line width,
+// indenting the continuation lines by the width of the prefix.
+func (p printer) wrapped(prefix, s string) {
+	indent := strings.Repeat(" ", len(prefix))
+	for i, l := range thermalize.Wrap(s, p.cmd.CPL()-len(prefix)) {
+		if i == 0 {
+			p.line(prefix + l)
+			continue
+		}
+		p.line(indent + l)
+	}
+}
+
+func (p printer) rule(c byte) {
+	p.line(strings.Repeat(string(c), p.cmd.CPL()))
+}
+
+func (p printer) line(s string) {
+	p.cmd.Text(s, p.enc)
+	p.cmd.LineFeed()
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
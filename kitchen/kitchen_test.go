@@ -0,0 +1,144 @@
+package kitchen
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+var ticket = Ticket{
+	Number:  "42",
+	Table:   "7",
+	Ordered: time.Date(2024, 5, 1, 12, 25, 0, 0, time.UTC),
+	Items: []Item{
+		{Quantity: 2, Name: "Burger", Modifiers: []string{"no onions"}, Allergens: []string{"gluten", "sesame"}, Station: "grill"},
+		{Name: "Lemonade", Station: "bar"},
+		{Quantity: 1, Name: "Steak with a pepper sauce, grilled vegetables and potatoes", Station: "Grill"},
+	},
+	Note: "Birthday",
+}
+
+var clock = WithClock(func() time.Time { return time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC) })
+
+// lines returns the lines of text of the ESC/POS command stream.
+func lines(bs []byte) []string {
+	var sb strings.Builder
+	for _, cmd := range thermalize.DecodeEscape(bs) {
+		switch cmd.Name {
+		case "TEXT":
+			sb.Write(cmd.Data)
+		case "LF":
+			sb.WriteByte('\n')
+		}
+	}
+	return strings.Split(sb.String(), "\n")
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPrintStation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Print(thermalize.NewEscape(32, 384, &buf), ticket, WithStation("GRILL"), clock); err != nil {
+		t.Fatal(err)
+	}
+
+	got := lines(buf.Bytes())
+	for _, want := range []string{
+		"#42", "Table 7", "GRILL", "Ordered: 12:25", "Printed: 12:30",
+		"2 x Burger", "   - no onions",
+		"1 x Steak with a pepper sauce,", "    grilled vegetables and", "    potatoes",
+		"Birthday",
+	} {
+		if !contains(got, want) {
+			t.Errorf("the ticket lacks %q:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+	if contains(got, "1 x Lemonade") {
+		t.Error("the ticket of the grill includes the item of the bar")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte{thermalize.ESC, 'B', 2, 3}) {
+		t.Error("the ticket doesn't beep")
+	}
+
+	if err := Print(thermalize.NewEscape(32, 384, io.Discard), ticket, WithStation("pastry")); !errors.Is(err, ErrNoItems) {
+		t.Errorf("Print of a station without items = %v, want %v", err, ErrNoItems)
+	}
+}
+
+func TestPrintOptions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Print(thermalize.NewEscape(32, 384, &buf), ticket, WithoutBeep(), WithoutCut(), clock); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte{thermalize.ESC, 'B', 2, 3}) {
+		t.Error("the ticket beeps WithoutBeep")
+	}
+	for _, cmd := range thermalize.DecodeEscape(buf.Bytes()) {
+		if cmd.Name == "GS V" {
+			t.Error("the ticket is cut WithoutCut")
+		}
+	}
+}
+
+func TestAllergens(t *testing.T) {
+	const allergy = "ALLERGY: GLUTEN, SESAME"
+	for _, tc := range []struct {
+		name string
+		new  func(io.Writer) thermalize.Cmd
+		want []byte
+	}{
+		// The second color of the two-color printers.
+		{"escape", func(w io.Writer) thermalize.Cmd { return thermalize.NewEscape(32, 384, w) },
+			append([]byte{thermalize.ESC, 'r', 1, thermalize.ESC, 'E', 1}, "   "+allergy...)},
+		// The reverse mode.
+		{"star", func(w io.Writer) thermalize.Cmd { return thermalize.NewStar(32, 384, w) },
+			append([]byte{thermalize.ESC, '4'}, " "+allergy+" "...)},
+		// The exclamation marks.
+		{"postscript", func(w io.Writer) thermalize.Cmd { return thermalize.NewPostscript(32, 384, w) },
+			[]byte("( !! " + allergy + " !!)")},
+	} {
+		var buf bytes.Buffer
+		if err := Print(tc.new(&buf), ticket, clock); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !bytes.Contains(buf.Bytes(), tc.want) {
+			t.Errorf("%s: the allergens aren't highlighted with % x", tc.name, tc.want)
+		}
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	tk := ticket
+	tk.Items = append(tk.Items, Item{Name: "Bread"})
+
+	var buf bytes.Buffer
+	if err := PrintSummary(thermalize.NewEscape(thermalize.WideCPL, 832, &buf), tk, WithStation("bar"), clock); err != nil {
+		t.Fatal(err)
+	}
+	got := lines(buf.Bytes())
+	for _, want := range []string{"GRILL", "BAR", "OTHER", "2 x Burger", "1 x Lemonade", "1 x Bread"} {
+		if !contains(got, want) {
+			t.Errorf("the summary lacks %q:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+	// The stations are printed side by side in page mode.
+	if !bytes.Contains(buf.Bytes(), []byte{thermalize.ESC, 'L'}) {
+		t.Error("the lanes aren't printed in page mode")
+	}
+
+	if err := PrintSummary(thermalize.NewEscape(32, 384, io.Discard), Ticket{}); !errors.Is(err, ErrNoItems) {
+		t.Errorf("PrintSummary without items = %v, want %v", err, ErrNoItems)
+	}
+}
@@ -0,0 +1,231 @@
+package thermalize
+
+import (
+	"fmt"
+	"image"
+)
+
+// Problem is an issue found by Lint in a document.
+type Problem struct {
+	// Op is the index of the command in Ops, the commands of a KeepTogether block or of Lanes have the index of the block.
+	Op int
+
+	// Name is the name of the command, e.g. "Text".
+	Name string
+
+	Reason string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%d %s: %s", p.Op, p.Name, p.Reason)
+}
+
+// Lint reports the problems the document would have when printed with the command set:
+//   - lines of text exceeding the characters per line, taking CharSize into account;
+//   - images and estimated barcode widths exceeding the pixels per line;
+//   - images exceeding the page height of the postscript command set, unless they are split or scaled (see WithTallImages);
+//   - calls of capability interfaces the command set doesn't implement, which are skipped.
+//
+// Lint doesn't print anything, the checks are estimates and the printer may still reject the document.
+func (d *Document) Lint(cmd Cmd) []Problem {
+	l := linter{cmd: cmd, caps: Capabilities(cmd), module: 3, charWidth: 1}
+	l.lint(d.ops, -1)
+	return l.problems
+}
+
+type linter struct {
+	cmd  Cmd
+	caps Capability
+
+	// col is the width of the current line in characters of the standard size, long is set once it is reported.
+	col       int
+	long      bool
+	charWidth int
+
+	// indents are the indentations of the lines in characters, which narrow the line.
+	indents []int
+
+	// module is the width of the barcode module in dots.
+	module int
+
+	problems []Problem
+}
+
+func (l *linter) lint(ops []Op, block int) {
+	for i, op := range ops {
+		n := i
+		if block >= 0 {
+			n = block
+		}
+
+		if supported, ok := capabilityOps[op.Name]; ok && !supported(l.caps) {
+			l.report(n, op.Name, "the command set doesn't support it, the command is skipped")
+			continue
+		}
+
+		switch op.Name {
+		case "Init":
+			l.newLine()
+			l.charWidth, l.module = 1, 3
+			l.indents = l.indents[:0]
+		case "Indent":
+			l.indents = append(l.indents, op.Args[0].(int))
+		case "Outdent":
+			if len(l.indents) > 0 {
+				l.indents = l.indents[:len(l.indents)-1]
+			}
+		case "LineFeed", "Feed", "Print", "Image", "Barcode", "QRCode", "TextWrap", "Paragraph", "List", "TriLine":
+			l.newLine()
+		case "CharSize":
+			l.charWidth = int(op.Args[0].(byte)) + 1
+		case "BarcodeWidth":
+			l.module = int(op.Args[0].(byte))
+		case "Tab":
+			l.col += 8 - l.col%8
+			l.checkLine(n, op.Name)
+		case "Text":
+			l.text(n, op.Name, op.Args[0].(string))
+		case "RightAt":
+			l.col = op.Args[0].(int)
+			l.checkLine(n, op.Name)
+		case "KeepTogether":
+			l.lint(op.Args[0].([]Op), n)
+		case "Lanes":
+			for _, ops := range op.Args[0].([][]Op) {
+				l.lint(ops, n)
+				l.newLine()
+			}
+		}
+
+		switch op.Name {
+		case "Image", "InlineImage":
+			img, _ := op.Args[0].(image.Image)
+			l.image(n, op.Name, img)
+		case "Barcode":
+			l.barcode(n, op.Args[0].(byte), op.Args[1].(string))
+		}
+	}
+}
+
+func (l *linter) report(op int, name, format string, args ...any) {
+	l.problems = append(l.problems, Problem{Op: op, Name: name, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) newLine() {
+	l.col, l.long = 0, false
+}
+
+func (l *linter) text(op int, name, s string) {
+	for _, r := range s {
+		if r == '\n' {
+			l.newLine()
+			continue
+		}
+		l.col += l.charWidth
+	}
+	l.checkLine(op, name)
+}
+
+func (l *linter) checkLine(op int, name string) {
+	cpl := l.cmd.CPL()
+	for _, n := range l.indents {
+		cpl -= n
+	}
+	if l.col > cpl && !l.long {
+		l.long = true
+		l.report(op, name, "the line of %d characters exceeds %d characters per line", l.col, cpl)
+	}
+}
+
+func (l *linter) image(op int, name string, img image.Image) {
+	if img == nil {
+		l.report(op, name, "nil image")
+		return
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w > l.cmd.PPL() {
+		l.report(op, name, "width %d exceeds %d pixels per line", w, l.cmd.PPL())
+	}
+
+	if c, ok := l.cmd.(*postscript); ok && w > 0 && (c.strict || c.tallImages == TallImageMessage || c.tallImages == TallImageError) {
+		if pts := c.points(w) * float64(h) / float64(w); pts > c.height {
+			l.report(op, name, "height %.2f exceeds the page height %.2f", pts, c.height)
+		}
+	}
+}
+
+func (l *linter) barcode(op int, m byte, s string) {
+	if w := barcodeModules(m, len(s)) * l.module; w > l.cmd.PPL() {
+		l.report(op, "Barcode", "the barcode of about %d dots exceeds %d pixels per line", w, l.cmd.PPL())
+	}
+}
+
+// barcodeModules estimates the number of modules of a barcode of n characters, or 0 if it can't be estimated.
+func barcodeModules(m byte, n int) int {
+	switch m {
+	case UpcA, JanEAN13:
+		return 95
+	case UpcE:
+		return 51
+	case JanEAN8:
+		return 67
+	case Code39:
+		return (n + 2) * 16
+	case Code93:
+		return (n+4)*9 + 1
+	case Code128:
+		return (n+3)*11 + 2
+	}
+	return 0
+}
+
+// capabilityOps reports whether the commands of the capability interfaces are supported.
+var capabilityOps = map[string]func(Capability) bool{
+	"PageMode":              func(c Capability) bool { return c.PageMode },
+	"PrintArea":             func(c Capability) bool { return c.PageMode },
+	"PrintPage":             func(c Capability) bool { return c.PageMode },
+	"Beep":                  func(c Capability) bool { return c.Beep },
+	"Color":                 func(c Capability) bool { return c.Color },
+	"Reverse":               func(c Capability) bool { return c.Reverse },
+	"RequestStatus":         func(c Capability) bool { return c.Status },
+	"LabelAdjust":           func(c Capability) bool { return c.Label },
+	"LabelFeed":             func(c Capability) bool { return c.Label },
+	"RequestLabelTaken":     func(c Capability) bool { return c.Label },
+	"PDF417Size":            func(c Capability) bool { return c.PDF417 },
+	"PDF417CorrectionLevel": func(c Capability) bool { return c.PDF417 },
+	"PDF417Module":          func(c Capability) bool { return c.PDF417 },
+	"PDF417":                func(c Capability) bool { return c.PDF417 },
+	"QRCodeModel":           func(c Capability) bool { return c.QRModel },
+	"QRCodeVersion":         func(c Capability) bool { return c.QRVersion },
+	"QRCodeMask":            func(c Capability) bool { return c.QRVersion },
+	"SelectDevice":          func(c Capability) bool { return c.Device },
+	"SelectStation":         func(c Capability) bool { return c.Slip },
+	"SelectSheet":           func(c Capability) bool { return c.Slip },
+	"SheetSettings":         func(c Capability) bool { return c.Slip },
+	"SlipWait":              func(c Capability) bool { return c.Slip },
+	"EjectSlip":             func(c Capability) bool { return c.Slip },
+	"ReadMICR":              func(c Capability) bool { return c.Slip },
+	"PrintDensity":          func(c Capability) bool { return c.Density },
+	"RecoverError":          func(c Capability) bool { return c.Recover },
+	"SoftReset":             func(c Capability) bool { return c.Recover },
+	"StartDocument":         func(c Capability) bool { return c.Markers },
+	"EndDocument":           func(c Capability) bool { return c.Markers },
+	"Checkpoint":            func(c Capability) bool { return c.Markers },
+	"ResetCheckpoints":      func(c Capability) bool { return c.Markers },
+	"RequestProcessID":      func(c Capability) bool { return c.ProcessID },
+	"RightAt":               func(c Capability) bool { return c.Columns },
+	"Smoothing":             func(c Capability) bool { return c.Smoothing },
+	"Glyph":                 func(c Capability) bool { return c.Glyphs },
+	"InlineImage":           func(c Capability) bool { return c.Inline },
+	"Watermark":             func(c Capability) bool { return c.Watermark },
+	"FullCutAtPosition":     func(c Capability) bool { return c.Cut },
+	"PartialCutAtPosition":  func(c Capability) bool { return c.Cut },
+	"FeedAndFullCut":        func(c Capability) bool { return c.Cut },
+	"CutWithFeed":           func(c Capability) bool { return c.Cut },
+	"SetClock":              func(c Capability) bool { return c.Clock },
+	"RequestClock":          func(c Capability) bool { return c.Clock },
+	"PrintClock":            func(c Capability) bool { return c.Clock },
+	"OpenCashDrawerProfile": func(c Capability) bool { return c.Drawers },
+	"OpenBothDrawers":       func(c Capability) bool { return c.Drawers },
+}
@@ -0,0 +1,85 @@
+package thermalize
+
+import (
+	"image"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	escape := NewEscape(32, 384, io.Discard)
+	for _, tc := range []struct {
+		name  string
+		cmd   Cmd
+		build func(d *Document)
+		want  []string
+	}{
+		{"fitting", escape, func(d *Document) {
+			d.Text(strings.Repeat("x", 32), nil)
+			d.LineFeed()
+			d.Text(strings.Repeat("x", 32), nil)
+		}, nil},
+		{"long line", escape, func(d *Document) {
+			d.Text(strings.Repeat("x", 20), nil)
+			d.Text(strings.Repeat("x", 20), nil)
+			d.Text("reported once", nil)
+		}, []string{"1 Text: the line of 40 characters exceeds 32 characters per line"}},
+		{"char size", escape, func(d *Document) {
+			d.CharSize(1, 1)
+			d.Text(strings.Repeat("x", 17), nil)
+			d.Init()
+			d.Text(strings.Repeat("x", 17), nil)
+		}, []string{"1 Text: the line of 34 characters exceeds 32 characters per line"}},
+		{"indent", escape, func(d *Document) {
+			d.Indent(4)
+			d.Text(strings.Repeat("x", 30), nil)
+		}, []string{"1 Text: the line of 30 characters exceeds 28 characters per line"}},
+		{"image", escape, func(d *Document) {
+			d.Image(image.NewGray(image.Rect(0, 0, 400, 8)), false)
+			d.Image(nil, false)
+		}, []string{"0 Image: width 400 exceeds 384 pixels per line", "1 Image: nil image"}},
+		{"barcode", escape, func(d *Document) {
+			// 10 characters of Code 128 are 145 modules, of 3 dots by default.
+			d.Barcode(Code128, "0123456789")
+			d.BarcodeWidth(2)
+			d.Barcode(Code128, "0123456789")
+		}, []string{"0 Barcode: the barcode of about 435 dots exceeds 384 pixels per line"}},
+		{"unsupported", NewPostscript(32, 384, io.Discard), func(d *Document) {
+			d.Text("x", nil)
+			d.Beep(1, 1)
+		}, []string{"1 Beep: the command set doesn't support it, the command is skipped"}},
+		{"block", escape, func(d *Document) {
+			d.LineFeed()
+			d.KeepTogether(func(cmd Cmd) {
+				cmd.LineFeed()
+				cmd.Text(strings.Repeat("x", 33), nil)
+			})
+		}, []string{"1 Text: the line of 33 characters exceeds 32 characters per line"}},
+		{"tall image", NewPostscript(32, 384, io.Discard, WithTallImages(TallImageError)), func(d *Document) {
+			d.Image(image.NewGray(image.Rect(0, 0, 384, 384)), false)
+			d.Image(image.NewGray(image.Rect(0, 0, 384, 100000)), false)
+		}, []string{"1 Image: height"}},
+	} {
+		doc := NewDocument(32, 384)
+		tc.build(doc)
+		got := doc.Lint(tc.cmd)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: the problems are %v, want %q", tc.name, got, tc.want)
+			continue
+		}
+		for i, p := range got {
+			if !strings.HasPrefix(p.String(), tc.want[i]) {
+				t.Errorf("%s: the problem is %q, want %q", tc.name, p, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestLintTallImagesSplit(t *testing.T) {
+	doc := NewDocument(32, 384)
+	doc.Image(image.NewGray(image.Rect(0, 0, 384, 100000)), false)
+	if got := doc.Lint(NewPostscript(32, 384, io.Discard)); len(got) != 0 {
+		t.Errorf("the image split across the pages: %v", got)
+	}
+}
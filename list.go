@@ -0,0 +1,98 @@
+package thermalize
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ListOption customizes a list printed by List.
+type ListOption interface {
+	apply(*listStyle)
+}
+
+type listStyle struct {
+	bullet  string
+	numbers bool
+	letters bool
+	start   int
+	indent  int
+}
+
+type listOptionFunc func(*listStyle)
+
+func (fn listOptionFunc) apply(l *listStyle) {
+	fn(l)
+}
+
+// WithBullet marks the items with the bullet, by default "-".
+func WithBullet(b string) ListOption {
+	return listOptionFunc(func(l *listStyle) { l.bullet, l.numbers, l.letters = b, false, false })
+}
+
+// WithNumbers numbers the items starting from start, e.g. "1.", "2.".
+func WithNumbers(start int) ListOption {
+	return listOptionFunc(func(l *listStyle) { l.numbers, l.letters, l.start = true, false, start })
+}
+
+// WithLetters marks the items with lowercase letters, e.g. "a)", "b)".
+func WithLetters() ListOption {
+	return listOptionFunc(func(l *listStyle) { l.letters, l.numbers = true, false })
+}
+
+// WithListIndent indents the list by n characters.
+func WithListIndent(n int) ListOption {
+	return listOptionFunc(func(l *listStyle) { l.indent = n })
+}
+
+// listLines wraps the items to lines of at most width characters.
+// The continuation lines are aligned with the text of the first line of the item.
+func listLines(items []string, width int, justify bool, opts []ListOption) []string {
+	l := listStyle{bullet: "-", start: 1}
+	for _, opt := range opts {
+		opt.apply(&l)
+	}
+
+	markers := make([]string, len(items))
+	n := 0
+	for i := range items {
+		markers[i] = l.marker(i)
+		n = maxByte(n, utf8.RuneCountInString(markers[i]))
+	}
+
+	var lines []string
+	indent := maxByte(l.indent, 0)
+	for i, item := range items {
+		ls := paragraph(item, width, indent+n+1, 0, justify)
+
+		// Numbers are aligned to the right, so the texts start in the same column.
+		marker := markers[i]
+		pad := strings.Repeat(" ", n-utf8.RuneCountInString(marker))
+		if l.numbers || l.letters {
+			marker = pad + marker
+		} else {
+			marker += pad
+		}
+
+		ls[0] = strings.Repeat(" ", indent) + marker + " " + strings.TrimLeft(ls[0], " ")
+		lines = append(lines, ls...)
+	}
+	return lines
+}
+
+func (l listStyle) marker(i int) string {
+	switch {
+	case l.numbers:
+		return strconv.Itoa(l.start+i) + "."
+	case l.letters:
+		var bs []byte
+		for n := i; ; n = n/26 - 1 {
+			bs = append([]byte{byte('a' + n%26)}, bs...)
+			if n < 26 {
+				break
+			}
+		}
+		return string(bs) + ")"
+	}
+	return l.bullet
+}
@@ -0,0 +1,181 @@
+package thermalize
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale describes how numbers and dates are formatted for a language.
+type Locale struct {
+	// Digits holds the characters of the digits 0 to 9.
+	Digits [10]rune
+
+	// DecimalSeparator separates the integer and the fractional part of a number.
+	DecimalSeparator rune
+
+	// GroupSeparator separates the thousands of the integer part of a number. Zero disables the grouping.
+	GroupSeparator rune
+
+	// DateLayout and TimeLayout are the numeric layouts of dates and times, as accepted by time.Time.Format.
+	DateLayout string
+	TimeLayout string
+
+	// Months and Weekdays hold the names of the months, starting with January,
+	// and the names of the days of the week, starting with Sunday.
+	Months   [12]string
+	Weekdays [7]string
+
+	// RTL reports whether the language is written from right to left.
+	RTL bool
+}
+
+var (
+	asciiDigits   = [10]rune{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9'}
+	easternDigits = [10]rune{'٠', '١', '٢', '٣', '٤', '٥', '٦', '٧', '٨', '٩'}
+)
+
+// LocaleEnglish formats numbers and dates in English.
+var LocaleEnglish = Locale{
+	Digits:           asciiDigits,
+	DecimalSeparator: '.',
+	GroupSeparator:   ',',
+	DateLayout:       "01/02/2006",
+	TimeLayout:       "15:04",
+	Months:           [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	Weekdays:         [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+}
+
+// LocaleHebrew formats numbers and dates in Hebrew.
+var LocaleHebrew = Locale{
+	Digits:           asciiDigits,
+	DecimalSeparator: '.',
+	GroupSeparator:   ',',
+	DateLayout:       "02.01.2006",
+	TimeLayout:       "15:04",
+	Months:           [12]string{"ינואר", "פברואר", "מרץ", "אפריל", "מאי", "יוני", "יולי", "אוגוסט", "ספטמבר", "אוקטובר", "נובמבר", "דצמבר"},
+	Weekdays:         [7]string{"ראשון", "שני", "שלישי", "רביעי", "חמישי", "שישי", "שבת"},
+	RTL:              true,
+}
+
+// LocaleArabic formats numbers and dates in Arabic with Eastern Arabic digits.
+var LocaleArabic = Locale{
+	Digits:           easternDigits,
+	DecimalSeparator: '٫',
+	GroupSeparator:   '٬',
+	DateLayout:       "02/01/2006",
+	TimeLayout:       "15:04",
+	Months:           [12]string{"يناير", "فبراير", "مارس", "أبريل", "مايو", "يونيو", "يوليو", "أغسطس", "سبتمبر", "أكتوبر", "نوفمبر", "ديسمبر"},
+	Weekdays:         [7]string{"الأحد", "الاثنين", "الثلاثاء", "الأربعاء", "الخميس", "الجمعة", "السبت"},
+	RTL:              true,
+}
+
+// Safe returns a copy of the locale that can be printed with the encoder.
+// If the encoder can't encode the digits or the separators of the locale, they are replaced with ASCII ones.
+func (l Locale) Safe(enc Encoder) Locale {
+	if enc == nil {
+		return l
+	}
+
+	for _, r := range l.Digits {
+		if !enc.CanEncode(r) {
+			l.Digits = asciiDigits
+			break
+		}
+	}
+	if !enc.CanEncode(l.DecimalSeparator) {
+		l.DecimalSeparator = '.'
+	}
+	if l.GroupSeparator != 0 && !enc.CanEncode(l.GroupSeparator) {
+		l.GroupSeparator = ','
+	}
+
+	return l
+}
+
+// FormatDigits replaces the ASCII digits of s with the digits of the locale.
+func (l Locale) FormatDigits(s string) string {
+	if l.Digits == asciiDigits {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return l.Digits[r-'0']
+		}
+		return r
+	}, s)
+}
+
+// FormatInt formats the integer with the digits and the group separator of the locale.
+func (l Locale) FormatInt(n int64) string {
+	return l.formatNumber(strconv.FormatInt(n, 10))
+}
+
+// FormatFloat formats the number with prec decimal places using the digits and separators of the locale.
+func (l Locale) FormatFloat(f float64, prec int) string {
+	return l.formatNumber(strconv.FormatFloat(f, 'f', prec, 64))
+}
+
+func (l Locale) formatNumber(s string) string {
+	var sign string
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	integer, fraction, hasFraction := strings.Cut(s, ".")
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, r := range integer {
+		if i > 0 && l.GroupSeparator != 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteRune(l.GroupSeparator)
+		}
+		sb.WriteRune(r)
+	}
+	if hasFraction {
+		sb.WriteRune(l.DecimalSeparator)
+		sb.WriteString(fraction)
+	}
+
+	return l.FormatDigits(sb.String())
+}
+
+// FormatDate formats the date using the date layout and the digits of the locale.
+func (l Locale) FormatDate(t time.Time) string {
+	return l.FormatDigits(t.Format(l.DateLayout))
+}
+
+// FormatTime formats the time of day using the time layout and the digits of the locale.
+func (l Locale) FormatTime(t time.Time) string {
+	return l.FormatDigits(t.Format(l.TimeLayout))
+}
+
+// FormatLongDate formats the date with the name of the month, e.g. "14 October 2026".
+func (l Locale) FormatLongDate(t time.Time) string {
+	return l.FormatDigits(strconv.Itoa(t.Day())) + " " + l.Month(t.Month()) + " " + l.FormatDigits(strconv.Itoa(t.Year()))
+}
+
+// Month returns the name of the month.
+func (l Locale) Month(m time.Month) string {
+	if m < time.January || m > time.December {
+		return ""
+	}
+	return l.Months[m-1]
+}
+
+// Weekday returns the name of the day of the week.
+func (l Locale) Weekday(d time.Weekday) string {
+	if d < time.Sunday || d > time.Saturday {
+		return ""
+	}
+	return l.Weekdays[d]
+}
+
+// Visual returns the string in the order it must be sent to a printer that prints from left to right.
+// For right-to-left locales the string is reordered with Visual, otherwise it is returned as is.
+func (l Locale) Visual(s string) string {
+	if !l.RTL {
+		return s
+	}
+	return Visual(s)
+}
@@ -0,0 +1,97 @@
+package thermalize
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"English int", LocaleEnglish.FormatInt(1234567), "1,234,567"},
+		{"English negative int", LocaleEnglish.FormatInt(-1234), "-1,234"},
+		{"English short int", LocaleEnglish.FormatInt(123), "123"},
+		{"English float", LocaleEnglish.FormatFloat(1234.5, 2), "1,234.50"},
+		{"Arabic float", LocaleArabic.FormatFloat(1234.5, 2), "١٬٢٣٤٫٥٠"},
+		{"Arabic digits", LocaleArabic.FormatDigits("No. 42"), "No. ٤٢"},
+		{"ungrouped", Locale{Digits: asciiDigits, DecimalSeparator: ','}.FormatFloat(-1234.5, 1), "-1234,5"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLocaleDates(t *testing.T) {
+	d := time.Date(2026, time.October, 14, 9, 5, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"English date", LocaleEnglish.FormatDate(d), "10/14/2026"},
+		{"Hebrew date", LocaleHebrew.FormatDate(d), "14.10.2026"},
+		{"Arabic date", LocaleArabic.FormatDate(d), "١٤/١٠/٢٠٢٦"},
+		{"Arabic time", LocaleArabic.FormatTime(d), "٠٩:٠٥"},
+		{"English long date", LocaleEnglish.FormatLongDate(d), "14 October 2026"},
+		{"Hebrew weekday", LocaleHebrew.Weekday(d.Weekday()), "רביעי"},
+		{"invalid month", LocaleEnglish.Month(13), ""},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLocaleSafe(t *testing.T) {
+	// The encoder can encode ASCII only, like CP437 for Arabic text.
+	ascii := EncoderFunc(func(s string) []byte {
+		bs := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r >= 0x80 {
+				r = '?'
+			}
+			bs = append(bs, byte(r))
+		}
+		return bs
+	})
+
+	l := LocaleArabic.Safe(ascii)
+	if got, want := l.FormatFloat(1234.5, 2), "1,234.50"; got != want {
+		t.Errorf("the safe Arabic float = %q, want %q", got, want)
+	}
+	if l.Months != LocaleArabic.Months || !l.RTL {
+		t.Error("the names and the direction of the safe locale are changed")
+	}
+	if got := LocaleArabic.Safe(nil).FormatInt(7); got != "٧" {
+		t.Errorf("the locale without an encoder formats %q", got)
+	}
+}
+
+func TestVisual(t *testing.T) {
+	for _, tc := range []struct {
+		s, want string
+	}{
+		{"abc 123", "abc 123"},
+		{"שלום", "םולש"},
+		{"שלום world", "world םולש"},
+		{"שלום 123", "123 םולש"},
+		{"12:30 ש", "ש 12:30"},
+		{"(שלום)", "(םולש)"},
+		{"סה״כ 1,234.50 ₪", "₪ 1,234.50 כ״הס"},
+	} {
+		if got := Visual(tc.s); got != tc.want {
+			t.Errorf("Visual(%q) = %q, want %q", tc.s, got, tc.want)
+		}
+	}
+
+	if got := LocaleEnglish.Visual("(abc)"); got != "(abc)" {
+		t.Errorf("the left-to-right locale reorders %q", got)
+	}
+	if got := LocaleHebrew.Visual("שלום"); got != "םולש" {
+		t.Errorf("the right-to-left locale doesn't reorder %q", got)
+	}
+}
@@ -0,0 +1,184 @@
+package thermalize
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"sync"
+)
+
+// LogoThermalize is the name of the library logo, which is registered by default.
+const LogoThermalize = "thermalize"
+
+// ErrUnknownLogo is returned by PrintLogo and LookupLogo if no logo is registered with the name.
+var ErrUnknownLogo = errors.New("thermalize: unknown logo")
+
+// logoEntry is a registered logo, which is decoded once on first use.
+// The scaled copies are cached by width, so the image cache (see WithImageCache) hits when the logo is printed again.
+type logoEntry struct {
+	data []byte
+
+	once sync.Once
+	img  image.Image
+	err  error
+
+	mu     sync.Mutex
+	scaled map[int]image.Image
+}
+
+var logos = struct {
+	sync.RWMutex
+	m map[string]*logoEntry
+}{m: make(map[string]*logoEntry)}
+
+func init() {
+	data, err := base64.StdEncoding.DecodeString(logo)
+	if err != nil {
+		panic(err)
+	}
+	RegisterLogo(LogoThermalize, data)
+}
+
+// RegisterLogo registers the PNG image, such as a file embedded with go:embed, as the logo with the name,
+// replacing the logo registered with the same name. The data must not be modified after the registration.
+//
+// Example Usage:
+//
+//	//go:embed store.png
+//	var storeLogo []byte
+//
+//	func init() {
+//		thermalize.RegisterLogo("store", storeLogo)
+//	}
+func RegisterLogo(name string, data []byte) {
+	logos.Lock()
+	logos.m[name] = &logoEntry{data: data}
+	logos.Unlock()
+}
+
+// LookupLogo returns the decoded logo registered with the name. The image is shared and must not be modified.
+func LookupLogo(name string) (image.Image, error) {
+	e, err := lookupLogo(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.image()
+}
+
+func lookupLogo(name string) (*logoEntry, error) {
+	logos.RLock()
+	e, ok := logos.m[name]
+	logos.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownLogo, name)
+	}
+	return e, nil
+}
+
+// image decodes the logo on first use.
+func (e *logoEntry) image() (image.Image, error) {
+	e.once.Do(func() {
+		e.img, e.err = png.Decode(bytes.NewReader(e.data))
+	})
+	return e.img, e.err
+}
+
+// scale returns the logo scaled to the width, keeping its aspect ratio.
+func (e *logoEntry) scale(width int) (image.Image, error) {
+	img, err := e.image()
+	if err != nil || width <= 0 || width == img.Bounds().Dx() {
+		return img, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if s, ok := e.scaled[width]; ok {
+		return s, nil
+	}
+	if e.scaled == nil {
+		e.scaled = make(map[int]image.Image)
+	}
+	s := scaleImage(img, width)
+	e.scaled[width] = s
+	return s, nil
+}
+
+// scaleImage scales the image to the width with the nearest neighbor, keeping its aspect ratio.
+func scaleImage(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := maxByte(b.Dy()*width/b.Dx(), 1)
+
+	s := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			s.Set(x, y, img.At(b.Min.X+x*b.Dx()/width, b.Min.Y+y*b.Dy()/height))
+		}
+	}
+	return s
+}
+
+// LogoOption customizes a logo printed by PrintLogo.
+type LogoOption interface {
+	apply(*logoStyle)
+}
+
+type logoStyle struct {
+	width  int
+	align  byte
+	invert bool
+}
+
+type logoOptionFunc func(*logoStyle)
+
+func (fn logoOptionFunc) apply(l *logoStyle) {
+	fn(l)
+}
+
+// WithLogoWidth scales the logo to the width in dots, by default the logo is printed in its own size.
+func WithLogoWidth(n int) LogoOption {
+	return logoOptionFunc(func(l *logoStyle) { l.width = n })
+}
+
+// WithLogoAlign aligns the logo, by default it is centered.
+func WithLogoAlign(b byte) LogoOption {
+	return logoOptionFunc(func(l *logoStyle) { l.align = b })
+}
+
+// WithLogoInvert prints the logo inverted.
+func WithLogoInvert() LogoOption {
+	return logoOptionFunc(func(l *logoStyle) { l.invert = true })
+}
+
+// PrintLogo prints the logo registered with the name (see RegisterLogo).
+// Logos wider than the line are scaled down to fit it, and the alignment is reset to Left afterwards.
+func PrintLogo(cmd Cmd, name string, opts ...LogoOption) error {
+	l := logoStyle{align: Center}
+	for _, opt := range opts {
+		opt.apply(&l)
+	}
+
+	e, err := lookupLogo(name)
+	if err != nil {
+		return err
+	}
+	img, err := e.image()
+	if err != nil {
+		return fmt.Errorf("thermalize: logo %q: %w", name, err)
+	}
+
+	width := l.width
+	if width <= 0 {
+		width = img.Bounds().Dx()
+	}
+	if img, err = e.scale(minByte(width, cmd.PPL())); err != nil {
+		return err
+	}
+
+	cmd.Align(l.align)
+	cmd.Image(img, l.invert)
+	cmd.Align(Left)
+	return nil
+}
@@ -0,0 +1,60 @@
+package thermalize
+
+// Counter is a metric that only increases, such as prometheus.Counter.
+type Counter interface {
+	Add(float64)
+}
+
+// Observer records the observations of a metric, such as prometheus.Histogram or prometheus.Summary.
+type Observer interface {
+	Observe(float64)
+}
+
+// Metrics holds the metrics updated by the command sets (see WithMetrics).
+// The metrics that are nil are not updated.
+//
+// Example Usage:
+//
+//	errors := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "printer_errors_total"}, []string{"kind"})
+//	m := &thermalize.Metrics{
+//		Jobs:         prometheus.NewCounter(prometheus.CounterOpts{Name: "printer_jobs_total"}),
+//		WriteLatency: prometheus.NewHistogram(prometheus.HistogramOpts{Name: "printer_write_seconds"}),
+//		Errors:       func(kind string) thermalize.Counter { return errors.WithLabelValues(kind) },
+//	}
+type Metrics struct {
+	// Jobs counts the printed documents, a document ends with Print.
+	Jobs Counter
+
+	// JobBytes observes the number of bytes written per document.
+	JobBytes Observer
+
+	// WriteLatency observes the duration of each write to the writer in seconds.
+	WriteLatency Observer
+
+	// Errors returns the counter of the errors of the kind:
+	//   - "validation": a parameter violation reported through Err, in strict mode (see WithStrict)
+	//     or by a command skipped because its parameters can't be clamped;
+	//   - "write": the writer failed.
+	Errors func(kind string) Counter
+
+	// PaperOut counts the paper-out events reported to ObserveStatus.
+	PaperOut Counter
+}
+
+// ObserveStatus updates the metrics with the status transmitted by the printer
+// in response to RequestStatus(n) (see StatusQuerier).
+func (m *Metrics) ObserveStatus(n, status byte) {
+	// Bits 5 and 6 of the roll paper sensor status report that the paper end has been detected.
+	if n == 4 && status&0x60 != 0 && m.PaperOut != nil {
+		m.PaperOut.Add(1)
+	}
+}
+
+func (m *Metrics) error(kind string) {
+	if m.Errors == nil {
+		return
+	}
+	if c := m.Errors(kind); c != nil {
+		c.Add(1)
+	}
+}
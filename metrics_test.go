@@ -0,0 +1,46 @@
+package thermalize
+
+import (
+	"io"
+	"testing"
+)
+
+// counter counts the additions.
+type counter float64
+
+func (c *counter) Add(n float64) {
+	*c += counter(n)
+}
+
+func TestValidationMetric(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		strict bool
+		fn     func(Cmd)
+		want   counter
+	}{
+		{"lenient clamped", false, func(c Cmd) { c.Underling(3) }, 0},
+		{"strict rejected", true, func(c Cmd) { c.Underling(3) }, 1},
+		{"lenient empty barcode", false, func(c Cmd) { c.Barcode(Code39, "") }, 0},
+		{"lenient skipped", false, func(c Cmd) { c.QRCode("https://example.com") }, 1},
+		{"strict valid", true, func(c Cmd) { c.Underling(1) }, 0},
+		{"strict twice", true, func(c Cmd) { c.Underling(3); c.Bold(true); c.HRIFont(2) }, 2},
+	} {
+		var validation counter
+		opts := []Options{WithMetrics(&Metrics{Errors: func(kind string) Counter {
+			if kind == "validation" {
+				return &validation
+			}
+			return nil
+		}})}
+		if tc.strict {
+			opts = append(opts, WithStrict())
+		}
+		opts = append(opts, WithQuirks(QuirkNoQRCode))
+		cmd := NewEscape(48, 576, io.Discard, opts...)
+		tc.fn(cmd)
+		if validation != tc.want {
+			t.Errorf("%s: %v violations counted, want %v", tc.name, validation, tc.want)
+		}
+	}
+}
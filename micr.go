@@ -0,0 +1,94 @@
+package thermalize
+
+import (
+	"errors"
+	"strings"
+)
+
+// Print stations of hybrid printers, see SlipPrinter.
+const (
+	StationRoll = iota
+	StationSlip
+	StationValidation
+)
+
+// Sheets of hybrid printers, which SelectSheet and SheetSettings combine, see SlipPrinter.
+const (
+	SheetJournal    byte = 1 << iota // SheetJournal is the journal roll
+	SheetRoll                        // SheetRoll is the receipt roll
+	SheetSlip                        // SheetSlip is the face of the slip
+	SheetValidation                  // SheetValidation is the validation paper
+)
+
+// MICR fonts, see SlipPrinter.
+const (
+	MICRFontE13B = iota
+	MICRFontCMC7
+)
+
+// MICR is the magnetic ink line of a check read by ReadMICR.
+//
+// For checks printed in the E13B font, the symbols are transmitted as letters:
+// 'A' (transit), 'B' (amount), 'C' (on-us) and 'D' (dash). Unreadable characters are transmitted as '?'.
+type MICR struct {
+	// Line is the whole line as it has been read.
+	Line string
+
+	// Routing is the routing number between the transit symbols, Account the on-us field following it,
+	// with the dashes kept, Check the check number following the on-us field or preceding the routing number,
+	// and Amount the field between the amount symbols, if there is one.
+	Routing string
+	Account string
+	Check   string
+	Amount  string
+
+	// Complete is set if all characters have been read.
+	Complete bool
+}
+
+// ErrMICR is returned by ParseMICR if the data is not a MICR reply.
+var ErrMICR = errors.New("thermalize: invalid MICR reply")
+
+// ParseMICR parses the reply of the printer to ReadMICR: the header 0x5F, the status, the characters and NUL.
+// The fields are parsed for the E13B font, the other fonts only set Line and Complete.
+func ParseMICR(bs []byte) (MICR, error) {
+	if len(bs) < 3 || bs[0] != 0x5F || bs[len(bs)-1] != NUL {
+		return MICR{}, ErrMICR
+	}
+
+	line := strings.TrimSpace(string(bs[2 : len(bs)-1]))
+	m := MICR{Line: line, Complete: !strings.Contains(line, "?")}
+
+	line = strings.ReplaceAll(line, " ", "")
+
+	// The routing number is enclosed in transit symbols, the auxiliary on-us field,
+	// which holds the check number of business checks, precedes it.
+	if i := strings.IndexByte(line, 'A'); i >= 0 {
+		if j := strings.IndexByte(line[i+1:], 'A'); j >= 0 {
+			m.Routing = line[i+1 : i+1+j]
+			if aux := strings.Trim(line[:i], "C"); aux != "" {
+				m.Check = aux
+			}
+			line = line[i+j+2:]
+		}
+	}
+
+	if i := strings.IndexByte(line, 'B'); i >= 0 {
+		if j := strings.IndexByte(line[i+1:], 'B'); j >= 0 {
+			m.Amount = line[i+1 : i+1+j]
+			line = line[:i] + line[i+j+2:]
+		}
+	}
+
+	// The on-us field ends with the on-us symbol, the check number of personal checks follows it.
+	if i := strings.IndexByte(line, 'C'); i >= 0 {
+		m.Account = strings.ReplaceAll(line[:i], "D", "-")
+		if check := strings.Trim(line[i+1:], "C"); check != "" && m.Check == "" {
+			m.Check = check
+		}
+	} else {
+		m.Account = strings.ReplaceAll(line, "D", "-")
+	}
+
+	return m, nil
+}
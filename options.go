@@ -1,6 +1,12 @@
 package thermalize
 
-import "image"
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"time"
+)
 
 const (
 	Left = iota
@@ -8,6 +14,12 @@ const (
 	Right
 )
 
+const (
+	VAlignTop = iota
+	VAlignMiddle
+	VAlignBottom
+)
+
 const (
 	NoUnderling = iota
 	OneDotUnderling
@@ -50,15 +62,62 @@ const (
 	H        // H recovers 30% of data
 )
 
+const (
+	QRModel1 = iota // QRModel1 is the original QR code model
+	QRModel2        // QRModel2 is the common QR code model, the default of the printers
+	QRMicro         // QRMicro is Micro QR, a smaller symbol with a single position detection pattern
+)
+
+// QRMaskAuto selects the mask pattern of QR codes for the data, see QRVersioner.
+const QRMaskAuto = 8
+
+const (
+	TallImageSplit   = iota // TallImageSplit splits the image across pages, the default
+	TallImageScale          // TallImageScale scales the image down to the height of the page
+	TallImageError          // TallImageError skips the image and reports the error through Err
+	TallImageMessage        // TallImageMessage replaces the image with MessageImageTooTall
+)
+
+const (
+	BitImage8Single  = 0  // BitImage8Single prints 8-dot bands at single horizontal density
+	BitImage8Double  = 1  // BitImage8Double prints 8-dot bands at double horizontal density
+	BitImage24Single = 32 // BitImage24Single prints 24-dot bands at single horizontal density
+	BitImage24Double = 33 // BitImage24Double prints 24-dot bands at double horizontal density, the default
+)
+
 const (
 	DrawerPin2 = iota
 	DrawerPin5
 )
 
+const (
+	RecoverRestart = 1 + iota // RecoverRestart recovers from the error and restarts printing from the line where it occurred
+	RecoverClear              // RecoverClear recovers from the error after clearing the receive and print buffers
+)
+
+const (
+	LabelPeelPosition = iota
+	LabelCutPosition
+	LabelPrintPosition
+)
+
 type Options interface {
 	apply(Cmd)
 }
 
+// skipperOf returns the skipper shared by the command sets, or nil if cmd isn't one of them.
+func skipperOf(cmd Cmd) *skipper {
+	switch cmd.(type) {
+	case *escape:
+		return cmd.(*escape).skipper
+	case *postscript:
+		return cmd.(*postscript).skipper
+	case *star:
+		return cmd.(*star).skipper
+	}
+	return nil
+}
+
 type imageFuncVersionOption byte
 
 func (ifv imageFuncVersionOption) apply(cmd Cmd) {
@@ -78,6 +137,219 @@ func WithImageFuncVersion(v byte) Options {
 	return imageFuncVersionOption(v)
 }
 
+type bitImageModeOption byte
+
+func (bimo bitImageModeOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*escape); ok {
+		switch bimo {
+		case BitImage8Single, BitImage8Double, BitImage24Single, BitImage24Double:
+			c.bitImageMode = byte(bimo)
+		}
+	}
+}
+
+// WithBitImageMode sets the density of the images printed with [ESC * m ... ESC J] (see WithImageFuncVersion):
+// BitImage8Single, BitImage8Double, BitImage24Single or BitImage24Double, the default.
+// The inline images are printed at the horizontal density of the mode in 24-dot bands.
+// Narrow and older printers, which print the images stretched in the default mode, need one of the other modes.
+// The bands of the 8-dot modes are fed 8 units by default, printers printing them at a lower vertical density
+// need another feed (see WithBandFeed).
+func WithBitImageMode(m byte) Options {
+	return bitImageModeOption(m)
+}
+
+type completionOption time.Duration
+
+func (co completionOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.completion = time.Duration(co)
+	}
+}
+
+// WithCompletion makes Print wait until the printer confirms that the job has been printed,
+// for up to the timeout, and return ErrNotConfirmed otherwise. The escape command set requests the process ID
+// of the job [GS ( H], the star command set polls the ETB counter of the automatic status after a checkpoint.
+// The confirmation is read from the writer, it isn't awaited if the writer can't be read
+// and by the postscript command set.
+func WithCompletion(timeout time.Duration) Options {
+	return completionOption(timeout)
+}
+
+type fragmentsOption func([]byte)
+
+func (fo fragmentsOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*postscript); ok {
+		c.fragments = fo
+	}
+}
+
+// WithFragments passes the content of the postscript pages to fn in fragments while they are laid out,
+// e.g. to stream a live preview of a long document. A fragment is emitted by each Flush and at the end of each page,
+// it is an EPS document holding the rows laid out since the previous fragment, without the page setup
+// and showpage, positioned by its bounding box in the coordinates of the page.
+// The output written to the writer doesn't change.
+func WithFragments(fn func(fragment []byte)) Options {
+	return fragmentsOption(fn)
+}
+
+type paperPreviewOption float64
+
+func (ppo paperPreviewOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*postscript); ok {
+		c.paper = true
+		c.fade = math.Min(math.Max(float64(ppo), 0), 1)
+	}
+}
+
+// WithPaperPreview draws the postscript pages on the warm white tone and the fibers of the thermal paper,
+// so the previews of the receipt designs look closer to the printed receipts.
+// The fade from 0 to 1 simulates the thermal print fading over time by lightening the black,
+// 0 keeps the fresh print and 1 fades it out completely.
+//
+// The preview is anti-aliased: the images are interpolated when scaled, and the pages ask the renderer
+// to smooth the text and the graphics, which Ghostscript does and the other renderers ignore.
+func WithPaperPreview(fade float64) Options {
+	return paperPreviewOption(fade)
+}
+
+type slipWaitOption [2]byte
+
+func (swo slipWaitOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*escape); ok {
+		c.slipWait, c.slipInsert, c.slipDelay = true, swo[0], swo[1]
+	}
+}
+
+// WithSlipWait sets the wait for the slip insertion (see SlipPrinter.SlipWait) each time SelectStation or SelectSheet
+// selects the slip or the validation paper, so the printer waits up to insert minutes for the slip to be inserted
+// and prints it delay x 100 ms after it has been detected.
+func WithSlipWait(insert, delay byte) Options {
+	return slipWaitOption{insert, delay}
+}
+
+type precisionOption int
+
+func (po precisionOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*postscript); ok {
+		c.prec = minByte(maxByte(int(po), 0), 6)
+	}
+}
+
+// WithPrecision writes the coordinates and the sizes of the postscript output with n decimal places from 0 to 6,
+// by default 2. The numbers are formatted the same way on all platforms and in all locales,
+// so the output of a document is byte-identical, e.g. for the golden-file tests of the layouts.
+func WithPrecision(n int) Options {
+	return precisionOption(n)
+}
+
+type landscapeOption struct{}
+
+func (landscapeOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*postscript); ok {
+		c.landscape = true
+	}
+}
+
+// WithLandscape prints the postscript pages in the landscape orientation, e.g. the previews of the wide tables
+// on the 112 mm stock. The layout keeps the width set by the characters per line and the height set by WithPageHeight,
+// the sheets have the swapped size and the coordinate system is rotated, so the top of the layout is at their left edge.
+// It doesn't apply to NewEPS, whose figures can be rotated by the document embedding them.
+func WithLandscape() Options {
+	return landscapeOption{}
+}
+
+type pageNumbersOption struct{}
+
+func (pageNumbersOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*postscript); ok {
+		c.pageNumbers = true
+	}
+}
+
+// WithPageNumbers prints the footer "page X of Y" (see MessagePageOf) at the bottom of each postscript page,
+// the rows end a line higher to leave room for it. Since the number of the pages is known only once the document
+// is laid out, the pages are written to the writer by Print. The pages of NewEPS are not numbered.
+func WithPageNumbers() Options {
+	return pageNumbersOption{}
+}
+
+type continuationHeaderOption string
+
+func (cho continuationHeaderOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*postscript); ok {
+		c.header = string(cho)
+	}
+}
+
+// WithContinuationHeader prints s centered at the top of each postscript page of a document following the first one,
+// e.g. the number of the order followed by "(continued)", and the rows start a line lower.
+func WithContinuationHeader(s string) Options {
+	return continuationHeaderOption(s)
+}
+
+type constrainedOption struct{}
+
+func (constrainedOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*escape); ok && (c.rasterLimit == 0 || c.rasterLimit > constrainedRasterSize) {
+		c.rasterLimit = constrainedRasterSize
+	}
+	switch cmd.(type) {
+	case *escape, *star:
+		skipperOf(cmd).constrain()
+	}
+}
+
+// WithConstrainedDevice makes the byte command sets behave like a constrained device for testing,
+// so the fallback paths of the receipts are executed in CI:
+//   - QR codes are printed as images with the function set by WithQRCodeFunc, or reported through Err if it isn't set.
+//   - The output is written in chunks of up to 256 bytes, and the escape raster images are split by 4 KB.
+//   - The pixels per line are limited to 576, the 72 mm print width at 203 dpi.
+//   - Only code page 437 is supported, other code pages are reported and the runes it lacks are transliterated to ASCII.
+//
+// It has no effect on the postscript command set.
+func WithConstrainedDevice() Options {
+	return constrainedOption{}
+}
+
+type quirksOption Quirks
+
+func (qo quirksOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*escape); ok {
+		c.addQuirks(Quirks(qo))
+	}
+}
+
+// WithQuirks works around the quirks of an ESC/POS clone with the escape command set, such as QuirkRasterNUL.
+func WithQuirks(q Quirks) Options {
+	return quirksOption(q)
+}
+
+type profileOption string
+
+func (po profileOption) apply(cmd Cmd) {
+	c, ok := cmd.(*escape)
+	if !ok {
+		return
+	}
+	q, ok := LookupProfile(string(po))
+	p, hasClock := LookupClockProfile(string(po))
+	if !ok && !hasClock {
+		c.invalid("WithProfile", "unknown profile %q", string(po))
+		return
+	}
+	c.addQuirks(q)
+	if hasClock {
+		c.clock = &p
+	}
+}
+
+// WithProfile works around the quirks of the printer model registered with RegisterProfile
+// and selects its clock commands registered with RegisterClockProfile.
+// It is skipped if no profile is registered with the name.
+func WithProfile(name string) Options {
+	return profileOption(name)
+}
+
 type pageHeight float64
 
 func (ph pageHeight) apply(cmd Cmd) {
@@ -91,8 +363,77 @@ func WithPageHeight(height float64) Options {
 	return pageHeight(height)
 }
 
+type tallImageOption byte
+
+func (tio tallImageOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*postscript); ok {
+		c.tallImages = byte(tio)
+	}
+}
+
+// WithTallImages sets how the postscript command set prints the images taller than the page:
+// TallImageSplit (the default), TallImageScale, TallImageError or TallImageMessage.
+// In strict mode, the tall images are always skipped and reported through Err.
+func WithTallImages(policy byte) Options {
+	return tallImageOption(policy)
+}
+
+// BarcodeOptions are the settings of the barcodes passed to the function set by WithBarcodeRenderer, so the image
+// of the barcode follows them like the barcodes the printer draws.
+type BarcodeOptions struct {
+	// Width is the module width set by BarcodeWidth, Height is the height of the bars in dots set by BarcodeHeight.
+	Width, Height byte
+
+	// HRIPosition is the position of the human readable interpretation set by HRIPosition (HRINotPrinted, HRIAbove,
+	// HRIBelow or HRIAboveAndBelow), HRIFont is its font set by HRIFont (HRIFontA or HRIFontB).
+	HRIPosition, HRIFont byte
+
+	// PPL is the number of pixels per line of the printer, DPI its resolution in dots per inch (see WithDPI),
+	// and MaxWidthPx the width of the image fitting the line within the margins and the indentation.
+	PPL, DPI, MaxWidthPx int
+}
+
+// QRCodeOptions are the settings of the QR codes passed to the function set by WithQRCodeRenderer.
+type QRCodeOptions struct {
+	// Size is the module size in dots set by QRCodeSize, CorrectionLevel is the error correction level (L, M, Q or H)
+	// set by QRCodeCorrectionLevel.
+	Size, CorrectionLevel byte
+
+	// Version is the version locked by QRCodeVersion, 0 if the smallest version holding the data is to be used,
+	// Mask is the mask pattern locked by QRCodeMask (0 - 7), QRMaskAuto if it is chosen for the data.
+	Version, Mask byte
+
+	// PPL is the number of pixels per line of the printer, DPI its resolution in dots per inch (see WithDPI),
+	// and MaxWidthPx the width of the image fitting the line within the margins and the indentation.
+	PPL, DPI, MaxWidthPx int
+}
+
+// defaultBarcode and defaultQRCode are the settings of the barcodes and the QR codes on Init.
+var (
+	defaultBarcode = BarcodeOptions{Width: 3, Height: 162}
+	defaultQRCode  = QRCodeOptions{Size: 3, CorrectionLevel: L, Mask: QRMaskAuto}
+)
+
+// defaultDPI is the resolution of the printers, unless it is set by WithDPI.
+const defaultDPI = 203
+
+type dpiOption int
+
+func (do dpiOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.dpi = int(do)
+	}
+}
+
+// WithDPI sets the resolution of the printer in dots per inch passed to the functions set by WithBarcodeRenderer
+// and WithQRCodeRenderer, by default 203. The postscript command set derives it from the pixels per line
+// and the width of the page instead.
+func WithDPI(n int) Options {
+	return dpiOption(n)
+}
+
 type barCodeFuncOption struct {
-	fn func(byte, string) image.Image
+	fn func(byte, string, BarcodeOptions) image.Image
 }
 
 func (cfo barCodeFuncOption) apply(cmd Cmd) {
@@ -106,12 +447,20 @@ func (cfo barCodeFuncOption) apply(cmd Cmd) {
 	}
 }
 
+// WithBarCodeFunc prints the barcodes as the images drawn by fn, see WithBarcodeRenderer.
 func WithBarCodeFunc(fn func(byte, string) image.Image) Options {
+	return barCodeFuncOption{fn: func(m byte, s string, _ BarcodeOptions) image.Image { return fn(m, s) }}
+}
+
+// WithBarcodeRenderer prints the barcodes as the images drawn by fn, which receives the system (such as Code128),
+// the data and the settings of the barcode, e.g. to draw the human readable interpretation at the HRIPosition.
+// By default, the printer draws the barcodes, except the postscript command set, which skips them.
+func WithBarcodeRenderer(fn func(m byte, s string, o BarcodeOptions) image.Image) Options {
 	return barCodeFuncOption{fn: fn}
 }
 
 type qrCodeFuncOption struct {
-	fn func(string) image.Image
+	fn func(string, QRCodeOptions) image.Image
 }
 
 func (cfo qrCodeFuncOption) apply(cmd Cmd) {
@@ -125,6 +474,333 @@ func (cfo qrCodeFuncOption) apply(cmd Cmd) {
 	}
 }
 
+// WithQRCodeFunc prints the QR codes as the images drawn by fn, see WithQRCodeRenderer.
 func WithQRCodeFunc(fn func(string) image.Image) Options {
+	return qrCodeFuncOption{fn: func(s string, _ QRCodeOptions) image.Image { return fn(s) }}
+}
+
+// WithQRCodeRenderer prints the QR codes as the images drawn by fn, which receives the data and the settings
+// of the QR code, e.g. to size the modules for the resolution of the printer within MaxWidthPx.
+// By default, the printer draws the QR codes, except the postscript command set, which skips them.
+func WithQRCodeRenderer(fn func(s string, o QRCodeOptions) image.Image) Options {
 	return qrCodeFuncOption{fn: fn}
 }
+
+type strictOption struct{}
+
+func (strictOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.strict = true
+	}
+}
+
+// WithStrict makes the command set validate all parameters.
+// Invalid commands are not emitted, and the first violation is reported by Err as a *ValidationError.
+func WithStrict() Options {
+	return strictOption{}
+}
+
+type bufferSize int
+
+func (bs bufferSize) apply(cmd Cmd) {
+	c := skipperOf(cmd)
+	if c == nil {
+		return
+	}
+	c.size = maxByte(int(bs), 0)
+	c.out = make([]byte, 0, c.size)
+}
+
+// WithBufferSize buffers up to n bytes of output before writing it to the writer,
+// so the printer receives a few large writes instead of many small ones.
+// The buffered data is written by Print, Cut and Flush.
+func WithBufferSize(n int) Options {
+	return bufferSize(n)
+}
+
+type bufferOption struct{}
+
+func (bufferOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.buffer = new(bytes.Buffer)
+		c.w = c.buffer
+	}
+}
+
+// WithBuffer accumulates the output in an internal buffer instead of writing it to the writer,
+// which may be nil. The output is returned by Bytes or written by WriteTo, see Buffered.
+func WithBuffer() Options {
+	return bufferOption{}
+}
+
+type imageCacheOption int
+
+func (ico imageCacheOption) apply(cmd Cmd) {
+	c := skipperOf(cmd)
+	if c == nil {
+		return
+	}
+	if ico <= 0 {
+		c.cache = nil
+		return
+	}
+	images.resize(int(ico))
+	c.cache = images
+}
+
+// WithImageCache enables caching of converted images, so the same image printed on every receipt,
+// such as a logo, is converted only once per process.
+//
+// The cache is shared by all command sets and holds up to size bytes of converted data,
+// the least recently used images are evicted first. Images are identified by their pointer,
+// so an image must not be modified after it has been printed with caching enabled.
+func WithImageCache(size int) Options {
+	return imageCacheOption(size)
+}
+
+type imagePaddingOption struct{}
+
+func (imagePaddingOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.padImages = true
+	}
+}
+
+// WithImagePadding pads the images narrower than the line with blank pixels according to the alignment set by Align,
+// so they are placed the same way as by the postscript command set on printers that ignore the alignment of images.
+func WithImagePadding() Options {
+	return imagePaddingOption{}
+}
+
+type bandFeedOption byte
+
+func (bfo bandFeedOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.bandFeed = byte(bfo)
+	}
+}
+
+// WithBandFeed sets the paper feed after each band of the images printed with [ESC * m ... ESC J]
+// (see WithImageFuncVersion) and by the star command set, by default 24 and 12 feed units for the 24-dot bands.
+// The feed must match the height of the band, printers with another dot pitch or feed unit need
+// another value to print the bands without seams or gaps.
+func WithBandFeed(n byte) Options {
+	return bandFeedOption(n)
+}
+
+type rasterBufferSize int
+
+func (rbs rasterBufferSize) apply(cmd Cmd) {
+	if c, ok := cmd.(*escape); ok {
+		c.rasterLimit = maxByte(int(rbs), 0)
+	}
+}
+
+// WithRasterBufferSize limits the size of the graphics data stored in the printer buffer
+// by the [GS 8 L ... GS ( L] print image command (see WithImageFuncVersion) to n bytes.
+// Taller images are split into several store and print cycles,
+// which prevents the buffer overflow of printers with small buffers, such as 64 KB.
+func WithRasterBufferSize(n int) Options {
+	return rasterBufferSize(n)
+}
+
+type linerFreeOption byte
+
+func (lfo linerFreeOption) apply(cmd Cmd) {
+	if c, ok := cmd.(*star); ok {
+		c.linerFree, c.labelFeed = true, byte(lfo)
+	}
+}
+
+// WithLinerFree configures the star command set for liner-free (sticky) label paper,
+// as used by Star's SK1 and TSP100IV SK models.
+// LabelFeed feeds the label by feed dots to the peel position, and partial cuts are replaced with full cuts,
+// since liner-free paper can't be cut partially.
+func WithLinerFree(feed byte) Options {
+	return linerFreeOption(feed)
+}
+
+type metricsOption struct {
+	m *Metrics
+}
+
+func (mo metricsOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.metrics = mo.m
+	}
+}
+
+// WithMetrics updates the metrics while printing. The metrics can be shared by several command sets.
+func WithMetrics(m *Metrics) Options {
+	return metricsOption{m: m}
+}
+
+type barcodeTypeMapOption map[byte]byte
+
+func (btm barcodeTypeMapOption) apply(cmd Cmd) {
+	c := skipperOf(cmd)
+	if c == nil {
+		return
+	}
+	c.barcodeTypes = make(map[byte]byte, len(btm))
+	for k, v := range btm {
+		c.barcodeTypes[k] = v
+	}
+}
+
+// WithBarcodeTypeMap overrides the codes of the barcode types sent to the printer by Barcode,
+// for clone printers that use other codes, e.g. for the GS1 DataBar variants.
+// The keys are the barcode types, such as GS1Expanded, the values are the codes of the device.
+// The keys above GS1Expanded add the custom types of the device, their data isn't validated in strict mode.
+//
+// Example Usage:
+//
+//	cmd := NewEscape(48, 576, writer, WithBarcodeTypeMap(map[byte]byte{GS1Expanded: 75}))
+func WithBarcodeTypeMap(types map[byte]byte) Options {
+	return barcodeTypeMapOption(types)
+}
+
+type tabStopsOption int
+
+func (tso tabStopsOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.tabStops = maxByte(int(tso), 0)
+	}
+}
+
+// WithTabStops sets a tab stop every n characters on Init, so Tab behaves the same way with all command sets.
+// By default, the printers set a tab stop every 8 characters, which is also the default of the postscript command set.
+func WithTabStops(every int) Options {
+	return tabStopsOption(every)
+}
+
+type grayLevelOption uint8
+
+func (glo grayLevelOption) apply(cmd Cmd) {
+	if c, ok := cmd.(GrayLeveler); ok {
+		c.GrayLevel(uint8(glo))
+	}
+}
+
+// WithGrayLevel sets the level of gray printed as black by the command set, overriding SetGrayLevel.
+func WithGrayLevel(l uint8) Options {
+	return grayLevelOption(l)
+}
+
+type alphaBlendingOption struct {
+	background color.Color
+}
+
+func (abo alphaBlendingOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.background = abo.background
+	}
+}
+
+// WithAlphaBlending composites the translucent pixels of the images on the background, usually color.White,
+// before they are converted, see BlendImage. By default, the pixels whose alpha is below the gray level
+// are treated as the background and the others as opaque.
+func WithAlphaBlending(background color.Color) Options {
+	return alphaBlendingOption{background: background}
+}
+
+type catalogOption Catalog
+
+func (co catalogOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.catalog = Catalog(co)
+	}
+}
+
+// WithCatalog translates the messages printed by the library, such as the labels of TestPage, see Translate.
+func WithCatalog(c Catalog) Options {
+	return catalogOption(c)
+}
+
+type currencyOption struct{}
+
+func (currencyOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.currency = true
+	}
+}
+
+// WithCurrencySymbols makes Text substitute the currency symbols the encoder can't encode, such as '€',
+// with their bytes in the code page selected with CodePage, e.g. 0xD5 in CodePageCP858,
+// or with their ISO 4217 codes, e.g. "EUR", if the code page lacks them or none is selected.
+// The postscript command set always uses the ISO 4217 codes.
+func WithCurrencySymbols() Options {
+	return currencyOption{}
+}
+
+type resizeTabsOption struct{}
+
+func (resizeTabsOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		c.resizeTabs = true
+	}
+}
+
+// WithResizeTabs makes Sizing set the default tab stops within the new line, every 8 characters
+// or as set by WithTabStops, so the tab stops set for a wider line don't extend past its end.
+func WithResizeTabs() Options {
+	return resizeTabsOption{}
+}
+
+type resizeHookOption struct {
+	fn func(cpl, ppl int)
+}
+
+func (rho resizeHookOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil && rho.fn != nil {
+		c.onResize(rho.fn)
+	}
+}
+
+// WithResizeHook calls fn with the new number of characters and pixels per line whenever Sizing is called,
+// after the layout of the command set has been updated, e.g. to recompute the column widths of a table.
+func WithResizeHook(fn func(cpl, ppl int)) Options {
+	return resizeHookOption{fn: fn}
+}
+
+type codePageOption struct {
+	page byte
+	enc  Encoder
+}
+
+func (cpo codePageOption) apply(cmd Cmd) {
+	c := skipperOf(cmd)
+	if c == nil {
+		return
+	}
+	c.page, c.hasPage, c.pageEnc = cpo.page, true, cpo.enc
+}
+
+type encoderOption struct {
+	enc Encoder
+}
+
+func (eo encoderOption) apply(cmd Cmd) {
+	page, ok := eo.enc.CodePage()
+	if !ok {
+		if c := skipperOf(cmd); c != nil {
+			c.invalid("WithEncoder", "the code page of %s is unknown", eo.enc.Name())
+		}
+		return
+	}
+	codePageOption{page: page, enc: eo.enc}.apply(cmd)
+}
+
+// WithEncoder selects the code page of the encoder on Init, such as one returned by NewEncoder,
+// and encodes text with it by default, the same way as WithCodePage does.
+// It is skipped if the code page of the encoder is unknown.
+func WithEncoder(enc Encoder) Options {
+	return encoderOption{enc: enc}
+}
+
+// WithCodePage sets the default code page, which is selected by Init.
+// Text encodes strings with enc whenever no encoder is provided and the code page is selected.
+// If enc is nil, the encoder registered for the code page is used (see RegisterCodePage).
+func WithCodePage(page byte, enc Encoder) Options {
+	return codePageOption{page: page, enc: enc}
+}
@@ -0,0 +1,332 @@
+package payload
+
+import (
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/gromey/thermalize"
+)
+
+// EPC is a SEPA credit transfer of the EPC quick response code guideline, also known as GiroCode,
+// read by the banking apps of the euro area. The amount is in euros.
+type EPC struct {
+	Name string
+	IBAN string
+
+	// BIC is the bank of the beneficiary, it may be omitted within the European Economic Area.
+	BIC string
+
+	// Amount is the amount in euros, such as "12.50". If it is empty, the payer enters the amount.
+	Amount string
+
+	// Purpose is the four-letter ISO 20022 purpose code, such as "GDDS".
+	Purpose string
+
+	// Reference is the structured creditor reference (RF...), Text is the unstructured remittance information.
+	// At most one of them may be set.
+	Reference string
+	Text      string
+
+	// Info is shown to the payer by the banking app.
+	Info string
+}
+
+func (e EPC) Payload() (string, error) {
+	if e.Name == "" || len(e.Name) > 70 {
+		return "", invalid("Name", "must have 1 to 70 characters, got %d", len(e.Name))
+	}
+	iban := compact(e.IBAN)
+	if !validIBAN(iban) {
+		return "", invalid("IBAN", "%q is not a valid IBAN", e.IBAN)
+	}
+	if n := len(e.BIC); n != 0 && n != 8 && n != 11 {
+		return "", invalid("BIC", "%q must have 8 or 11 characters", e.BIC)
+	}
+	amt := ""
+	if e.Amount != "" {
+		if err := amount("Amount", e.Amount, 12); err != nil {
+			return "", err
+		}
+		amt = "EUR" + e.Amount
+	}
+	if e.Purpose != "" && !letters(e.Purpose, 4) {
+		return "", invalid("Purpose", "%q is not a four-letter code", e.Purpose)
+	}
+	if e.Reference != "" && e.Text != "" {
+		return "", invalid("Text", "is set together with the reference")
+	}
+	if e.Reference != "" && !validCreditorReference(compact(e.Reference)) {
+		return "", invalid("Reference", "%q is not a valid creditor reference", e.Reference)
+	}
+	if len(e.Text) > 140 {
+		return "", invalid("Text", "exceeds 140 characters")
+	}
+	if len(e.Info) > 70 {
+		return "", invalid("Info", "exceeds 70 characters")
+	}
+
+	s := strings.Join([]string{
+		"BCD", "002", "1", "SCT", e.BIC, e.Name, iban, amt, e.Purpose, compact(e.Reference), e.Text, e.Info,
+	}, "\n")
+	s = strings.TrimRight(s, "\n")
+	if len(s) > 331 {
+		return "", invalid("Name", "the payload exceeds 331 bytes")
+	}
+	return s, nil
+}
+
+// SwissQR is the payment part of a Swiss QR-bill, read by the banking apps of Switzerland and Liechtenstein.
+type SwissQR struct {
+	// IBAN is the account of the creditor, a QR-IBAN requires a QR reference.
+	IBAN     string
+	Creditor Address
+
+	// Amount is the amount, such as "12.50". If it is empty, the payer enters the amount.
+	Amount string
+
+	// Currency is "CHF" or "EUR", by default "CHF".
+	Currency string
+
+	// Debtor is the payer, it is omitted if the name is empty.
+	Debtor Address
+
+	// Reference is the 27-digit QR reference, required by a QR-IBAN, or a creditor reference (RF...).
+	Reference string
+
+	// Message is the unstructured message, BillInfo is the structured information of the creditor.
+	Message  string
+	BillInfo string
+}
+
+// Address is the structured address of a party of a Swiss QR-bill.
+type Address struct {
+	Name       string
+	Street     string
+	Number     string
+	PostalCode string
+	City       string
+
+	// Country is the two-letter ISO 3166-1 code, such as "CH".
+	Country string
+}
+
+func (s SwissQR) Payload() (string, error) {
+	iban := compact(s.IBAN)
+	if !validIBAN(iban) || !strings.HasPrefix(iban, "CH") && !strings.HasPrefix(iban, "LI") {
+		return "", invalid("IBAN", "%q is not a valid Swiss or Liechtenstein IBAN", s.IBAN)
+	}
+	if err := s.Creditor.validate("Creditor"); err != nil {
+		return "", err
+	}
+	if s.Debtor.Name != "" {
+		if err := s.Debtor.validate("Debtor"); err != nil {
+			return "", err
+		}
+	}
+	if s.Amount != "" {
+		if err := amount("Amount", s.Amount, 12); err != nil {
+			return "", err
+		}
+	}
+
+	currency := s.Currency
+	if currency == "" {
+		currency = "CHF"
+	}
+	if currency != "CHF" && currency != "EUR" {
+		return "", invalid("Currency", "%q is not CHF or EUR", s.Currency)
+	}
+
+	// The institution ID of a QR-IBAN is in the range from 30000 to 31999.
+	ref, refType := compact(s.Reference), "NON"
+	qrIBAN := iban[4] == '3' && (iban[5] == '0' || iban[5] == '1')
+	switch {
+	case qrIBAN:
+		if !validQRReference(ref) {
+			return "", invalid("Reference", "%q is not a valid QR reference, which is required by a QR-IBAN", s.Reference)
+		}
+		refType = "QRR"
+	case ref == "":
+	case validCreditorReference(ref):
+		refType = "SCOR"
+	default:
+		return "", invalid("Reference", "%q is not a valid creditor reference", s.Reference)
+	}
+
+	if len(s.Message)+len(s.BillInfo) > 140 {
+		return "", invalid("Message", "the message and the bill information exceed 140 characters")
+	}
+
+	lines := []string{"SPC", "0200", "1", iban}
+	lines = append(lines, s.Creditor.lines()...)
+	lines = append(lines, "", "", "", "", "", "", "") // the ultimate creditor is reserved for future use
+	lines = append(lines, s.Amount, currency)
+	if s.Debtor.Name != "" {
+		lines = append(lines, s.Debtor.lines()...)
+	} else {
+		lines = append(lines, "", "", "", "", "", "", "")
+	}
+	lines = append(lines, refType, ref, s.Message, "EPD")
+	if s.BillInfo != "" {
+		lines = append(lines, s.BillInfo)
+	}
+	return strings.Join(lines, "\r\n"), nil
+}
+
+func (a Address) validate(field string) error {
+	switch {
+	case a.Name == "" || len(a.Name) > 70:
+		return invalid(field, "the name must have 1 to 70 characters, got %d", len(a.Name))
+	case len(a.Street) > 70:
+		return invalid(field, "the street exceeds 70 characters")
+	case len(a.Number) > 16:
+		return invalid(field, "the building number exceeds 16 characters")
+	case a.PostalCode == "" || len(a.PostalCode) > 16:
+		return invalid(field, "the postal code must have 1 to 16 characters")
+	case a.City == "" || len(a.City) > 35:
+		return invalid(field, "the city must have 1 to 35 characters")
+	case !letters(a.Country, 2):
+		return invalid(field, "%q is not a two-letter country code", a.Country)
+	}
+	return nil
+}
+
+func (a Address) lines() []string {
+	return []string{"S", a.Name, a.Street, a.Number, a.PostalCode, a.City, strings.ToUpper(a.Country)}
+}
+
+// EPCQRCode prints the EPC code with the correction level M required by the guideline, 30 mm wide.
+func EPCQRCode(cmd thermalize.Cmd, e EPC, dpi int) error {
+	return SizedQRCode(cmd, e, thermalize.M, 30, dpi)
+}
+
+// SwissQRCode prints the Swiss QR code with the correction level M and the size of 46×46 mm required by the standard.
+//
+// The Swiss cross in the middle of the code is not printed, since the size of the QR code modules is chosen by the printer.
+func SwissQRCode(cmd thermalize.Cmd, s SwissQR, dpi int) error {
+	return SizedQRCode(cmd, s, thermalize.M, 46, dpi)
+}
+
+// SizedQRCode prints the payload with the correction level (L, M, Q or H), choosing the size of the modules,
+// so the symbol, without the quiet zone, is as wide as possible but not wider than mm millimeters
+// on a printer with the resolution of dpi dots per inch, such as 203.
+//
+// The size of the modules is limited to 8 dots, which is supported by all command sets.
+func SizedQRCode(cmd thermalize.Cmd, p Payload, level byte, mm float64, dpi int) error {
+	s, err := p.Payload()
+	if err != nil {
+		return err
+	}
+	if level > thermalize.H {
+		return invalid("level", "%d is out of range [0, 3]", level)
+	}
+
+	version := qrVersion(len(s), level)
+	if version == 0 {
+		return invalid("level", "the payload of %d bytes doesn't fit a QR code with the correction level", len(s))
+	}
+
+	modules := 17 + 4*version
+	size := int(math.Floor(mm / 25.4 * float64(dpi) / float64(modules)))
+	if size < 1 {
+		size = 1
+	}
+	if size > 8 {
+		size = 8
+	}
+
+	cmd.QRCodeCorrectionLevel(level)
+	cmd.QRCodeSize(byte(size))
+	cmd.QRCode(s)
+	return nil
+}
+
+// qrVersion returns the smallest QR code version holding n bytes in the byte mode, or 0 if there is none.
+// Printers may choose a denser mode and a smaller version, which makes the symbol smaller, never larger.
+func qrVersion(n int, level byte) int {
+	for i, c := range qrCapacity[level] {
+		if n <= c {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// qrCapacity holds the capacity of the QR code versions in the byte mode by correction level.
+var qrCapacity = [4][40]int{
+	{17, 32, 53, 78, 106, 134, 154, 192, 230, 271, 321, 367, 425, 458, 520, 586, 644, 718, 792, 858,
+		929, 1003, 1091, 1171, 1273, 1367, 1465, 1528, 1628, 1732, 1840, 1952, 2068, 2188, 2303, 2431, 2563, 2699, 2809, 2953},
+	{14, 26, 42, 62, 84, 106, 122, 152, 180, 213, 251, 287, 331, 362, 412, 450, 504, 560, 624, 666,
+		711, 779, 857, 911, 997, 1059, 1125, 1190, 1264, 1370, 1452, 1538, 1628, 1722, 1809, 1911, 1989, 2099, 2213, 2331},
+	{11, 20, 32, 46, 60, 74, 86, 108, 130, 151, 177, 203, 241, 258, 292, 322, 364, 394, 442, 482,
+		509, 565, 611, 661, 715, 751, 805, 868, 908, 982, 1030, 1112, 1168, 1228, 1283, 1351, 1423, 1499, 1579, 1663},
+	{7, 14, 24, 34, 44, 58, 64, 84, 98, 119, 137, 155, 177, 194, 220, 250, 280, 310, 338, 382,
+		403, 439, 461, 511, 535, 593, 625, 658, 698, 742, 790, 842, 898, 958, 983, 1051, 1093, 1139, 1219, 1273},
+}
+
+// compact removes the spaces used to group the characters of IBANs and references.
+func compact(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+}
+
+// validIBAN checks the length and the ISO 7064 MOD 97-10 check digits of the IBAN.
+func validIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 || !letters(iban[:2], 2) {
+		return false
+	}
+	return mod97(iban[4:] + iban[:4])
+}
+
+// validCreditorReference checks the ISO 11649 creditor reference, such as "RF18539007547034".
+func validCreditorReference(ref string) bool {
+	if len(ref) < 5 || len(ref) > 25 || !strings.HasPrefix(ref, "RF") {
+		return false
+	}
+	return mod97(ref[4:] + ref[:4])
+}
+
+// mod97 reports whether the letters and digits, with the letters replaced by numbers from 10 to 35, are 1 modulo 97.
+func mod97(s string) bool {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case c >= 'A' && c <= 'Z':
+			b.WriteString(big.NewInt(int64(c-'A') + 10).String())
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validQRReference checks the 27 digits of the QR reference, the last of which is the recursive modulo 10 check digit.
+func validQRReference(ref string) bool {
+	if !digits(ref, 27) {
+		return false
+	}
+	table := [10]int{0, 9, 4, 6, 8, 2, 7, 1, 3, 5}
+	carry := 0
+	for i := 0; i < 26; i++ {
+		carry = table[(carry+int(ref[i]-'0'))%10]
+	}
+	return (10-carry)%10 == int(ref[26]-'0')
+}
+
+// letters reports whether s consists of n ASCII letters.
+func letters(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i] | 0x20
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
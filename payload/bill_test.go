@@ -0,0 +1,144 @@
+package payload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gromey/thermalize"
+)
+
+func TestReferences(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		valid func(string) bool
+		s     string
+		want  bool
+	}{
+		{"IBAN", validIBAN, "GB82WEST12345698765432", true},
+		{"IBAN", validIBAN, "DE89370400440532013000", true},
+		{"IBAN", validIBAN, "CH4431999123000889012", true},
+		{"IBAN", validIBAN, "DE89370400440532013001", false},
+		{"IBAN", validIBAN, "1289370400440532013000", false},
+		{"IBAN", validIBAN, "DE8937", false},
+		// The example of ISO 11649.
+		{"creditor reference", validCreditorReference, "RF18539007547034", true},
+		{"creditor reference", validCreditorReference, "RF18539007547035", false},
+		{"creditor reference", validCreditorReference, "RF19539007547034", false},
+		{"creditor reference", validCreditorReference, "XX18539007547034", false},
+		// The example of the Swiss implementation guidelines.
+		{"QR reference", validQRReference, "210000000003139471430009017", true},
+		{"QR reference", validQRReference, "210000000003139471430009018", false},
+		{"QR reference", validQRReference, "21000000000313947143000901", false},
+		{"QR reference", validQRReference, "000000000000000000000000000", true},
+	} {
+		if got := tc.valid(tc.s); got != tc.want {
+			t.Errorf("the %s %q is valid: %v, want %v", tc.name, tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestQRVersion(t *testing.T) {
+	for _, tc := range []struct {
+		n     int
+		level byte
+		want  int
+	}{
+		{1, thermalize.L, 1},
+		{17, thermalize.L, 1},
+		{18, thermalize.L, 2},
+		{14, thermalize.M, 1},
+		{15, thermalize.M, 2},
+		{7, thermalize.H, 1},
+		{331, thermalize.M, 13},
+		{2953, thermalize.L, 40},
+		{2954, thermalize.L, 0},
+		{1274, thermalize.H, 0},
+	} {
+		if got := qrVersion(tc.n, tc.level); got != tc.want {
+			t.Errorf("qrVersion(%d, %d) = %d, want %d", tc.n, tc.level, got, tc.want)
+		}
+	}
+}
+
+func TestEPC(t *testing.T) {
+	got, err := EPC{
+		Name:      "Red Cross",
+		IBAN:      "DE89 3704 0044 0532 0130 00",
+		BIC:       "COBADEFFXXX",
+		Amount:    "12.50",
+		Reference: "RF18 5390 0754 7034",
+	}.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "BCD\n002\n1\nSCT\nCOBADEFFXXX\nRed Cross\nDE89370400440532013000\nEUR12.50\n\nRF18539007547034"
+	if got != want {
+		t.Errorf("Payload() = %q, want %q", got, want)
+	}
+
+	_, err = EPC{Name: "Red Cross", IBAN: "DE89370400440532013000", Reference: "RF18539007547034", Text: "gift"}.Payload()
+	var f *FieldError
+	if !errors.As(err, &f) || f.Field != "Text" {
+		t.Errorf("the reference and the text: %v", err)
+	}
+}
+
+func TestSwissQR(t *testing.T) {
+	creditor := Address{Name: "Robert Schneider AG", Street: "Rue du Lac", Number: "1268", PostalCode: "2501", City: "Biel", Country: "CH"}
+	got, err := SwissQR{
+		IBAN:      "CH44 3199 9123 0008 8901 2",
+		Creditor:  creditor,
+		Amount:    "1949.75",
+		Reference: "21 00000 00003 13947 14300 09017",
+	}.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(got, "\r\n")
+	if len(lines) != 31 || lines[3] != "CH4431999123000889012" || lines[18] != "1949.75" || lines[19] != "CHF" ||
+		lines[27] != "QRR" || lines[28] != "210000000003139471430009017" || lines[30] != "EPD" {
+		t.Errorf("Payload() = %q", lines)
+	}
+
+	// A QR-IBAN requires a QR reference.
+	_, err = SwissQR{IBAN: "CH4431999123000889012", Creditor: creditor, Reference: "RF18539007547034"}.Payload()
+	var f *FieldError
+	if !errors.As(err, &f) || f.Field != "Reference" {
+		t.Errorf("the QR-IBAN with a creditor reference: %v", err)
+	}
+}
+
+func TestSizedQRCode(t *testing.T) {
+	size := func(p Payload, mm float64, dpi int) byte {
+		var buf bytes.Buffer
+		cmd := thermalize.NewEscape(48, 576, &buf)
+		if err := SizedQRCode(cmd, p, thermalize.M, mm, dpi); err != nil {
+			t.Fatal(err)
+		}
+		cmd.Print()
+		i := bytes.Index(buf.Bytes(), []byte{thermalize.GS, '(', 'k', 3, 0, 49, 67})
+		if i < 0 {
+			t.Fatalf("the module size isn't set: % x", buf.Bytes())
+		}
+		return buf.Bytes()[i+7]
+	}
+
+	// 20 bytes fit the version 2 of 25 modules with the correction level M.
+	url := URL("https://example.com/")
+	if got := size(url, 30, 100); got != 4 {
+		t.Errorf("the module size of 30 mm at 100 dpi is %d, want 4", got)
+	}
+	if got := size(url, 30, 203); got != 8 {
+		t.Errorf("the module size of 30 mm at 203 dpi is %d, want the maximum of 8", got)
+	}
+	if got := size(url, 1, 203); got != 1 {
+		t.Errorf("the module size of 1 mm is %d, want 1", got)
+	}
+
+	if err := SizedQRCode(thermalize.NewEscape(48, 576, io.Discard), URL(strings.Repeat("x", 8)), thermalize.M, 30, 203); err == nil {
+		t.Error("the relative URL is printed")
+	}
+}
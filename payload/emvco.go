@@ -0,0 +1,240 @@
+package payload
+
+import (
+	"strconv"
+	"strings"
+)
+
+// EMVCo is a merchant-presented payment code of the EMVCo QR code specification,
+// read by the payment apps of many countries. The checksum is appended by Payload.
+type EMVCo struct {
+	// Dynamic is set for a code printed for a single payment, such as on a receipt.
+	Dynamic bool
+
+	// Accounts holds the merchant account information, with IDs from 2 to 51 assigned by the payment networks.
+	Accounts []Template
+
+	// CategoryCode is the four-digit merchant category code, by default "0000".
+	CategoryCode string
+
+	// Currency is the three-digit numeric ISO 4217 code, such as "986".
+	Currency string
+
+	// Amount is the transaction amount, such as "12.50". If it is empty, the payer enters the amount.
+	Amount string
+
+	// CountryCode is the two-letter ISO 3166-1 code, such as "BR".
+	CountryCode string
+
+	MerchantName string
+	MerchantCity string
+	PostalCode   string
+
+	// Additional holds the fields of the additional data template, such as the bill number (ID 1) or the reference (ID 5).
+	Additional []Field
+}
+
+// Field is a data object of an EMVCo payload.
+type Field struct {
+	ID    int
+	Value string
+}
+
+// Template is a data object of an EMVCo payload containing fields.
+type Template struct {
+	ID     int
+	Fields []Field
+}
+
+func (e EMVCo) Payload() (string, error) {
+	if len(e.Accounts) == 0 {
+		return "", invalid("Accounts", "no merchant account is set")
+	}
+
+	category := e.CategoryCode
+	if category == "" {
+		category = "0000"
+	}
+	if !digits(category, 4) {
+		return "", invalid("CategoryCode", "%q is not a four-digit code", category)
+	}
+	if !digits(e.Currency, 3) {
+		return "", invalid("Currency", "%q is not a three-digit code", e.Currency)
+	}
+	if e.Amount != "" {
+		if err := amount("Amount", e.Amount, 13); err != nil {
+			return "", err
+		}
+	}
+	if len(e.CountryCode) != 2 || strings.ToUpper(e.CountryCode) != e.CountryCode {
+		return "", invalid("CountryCode", "%q is not a two-letter code", e.CountryCode)
+	}
+	if e.MerchantName == "" || len(e.MerchantName) > 25 {
+		return "", invalid("MerchantName", "must have 1 to 25 characters, got %d", len(e.MerchantName))
+	}
+	if e.MerchantCity == "" || len(e.MerchantCity) > 15 {
+		return "", invalid("MerchantCity", "must have 1 to 15 characters, got %d", len(e.MerchantCity))
+	}
+
+	var t tlv
+	t.add("PayloadFormat", 0, "01")
+	if e.Dynamic {
+		t.add("Dynamic", 1, "12")
+	} else {
+		t.add("Dynamic", 1, "11")
+	}
+	for _, a := range e.Accounts {
+		if a.ID < 2 || a.ID > 51 {
+			t.fail(invalid("Accounts", "ID %d is out of range [2, 51]", a.ID))
+		}
+		t.add("Accounts", a.ID, fields("Accounts", a.Fields, &t))
+	}
+	t.add("CategoryCode", 52, category)
+	t.add("Currency", 53, e.Currency)
+	if e.Amount != "" {
+		t.add("Amount", 54, e.Amount)
+	}
+	t.add("CountryCode", 58, e.CountryCode)
+	t.add("MerchantName", 59, e.MerchantName)
+	t.add("MerchantCity", 60, e.MerchantCity)
+	if e.PostalCode != "" {
+		t.add("PostalCode", 61, e.PostalCode)
+	}
+	if len(e.Additional) > 0 {
+		t.add("Additional", 62, fields("Additional", e.Additional, &t))
+	}
+	if t.err != nil {
+		return "", t.err
+	}
+
+	t.b.WriteString("6304")
+	return t.b.String() + crc16(t.b.String()), nil
+}
+
+// PIX is a payment code of the Brazilian instant payment system, an EMVCo payload in Brazilian reais.
+type PIX struct {
+	// Key is the PIX key of the payee: a CPF/CNPJ number, a phone number, an email address or a random key.
+	Key  string
+	Name string
+	City string
+
+	// Amount is the amount in reais, such as "12.50". If it is empty, the payer enters the amount.
+	Amount string
+
+	// TxID identifies the payment with up to 25 letters and digits, by default "***", which means no identifier.
+	TxID string
+
+	Description string
+}
+
+func (p PIX) Payload() (string, error) {
+	if p.Key == "" {
+		return "", invalid("Key", "is empty")
+	}
+
+	txID := p.TxID
+	if txID == "" {
+		txID = "***"
+	}
+	if txID != "***" && !alnum(txID, 25) {
+		return "", invalid("TxID", "%q must have 1 to 25 letters and digits", txID)
+	}
+
+	account := Template{ID: 26, Fields: []Field{{ID: 0, Value: "br.gov.bcb.pix"}, {ID: 1, Value: p.Key}}}
+	if p.Description != "" {
+		account.Fields = append(account.Fields, Field{ID: 2, Value: p.Description})
+	}
+
+	return EMVCo{
+		Accounts:     []Template{account},
+		Currency:     "986",
+		Amount:       p.Amount,
+		CountryCode:  "BR",
+		MerchantName: p.Name,
+		MerchantCity: p.City,
+		Additional:   []Field{{ID: 5, Value: txID}},
+	}.Payload()
+}
+
+// tlv builds the ID, length and value data objects, keeping the first error.
+type tlv struct {
+	b   strings.Builder
+	err error
+}
+
+func (t *tlv) add(field string, id int, v string) {
+	switch {
+	case id < 0 || id > 99:
+		t.fail(invalid(field, "ID %d is out of range [0, 99]", id))
+	case len(v) > 99:
+		t.fail(invalid(field, "the value of ID %d exceeds 99 characters", id))
+	case !printable(v):
+		t.fail(invalid(field, "the value of ID %d contains characters other than printable ASCII", id))
+	}
+
+	if id < 10 {
+		t.b.WriteByte('0')
+	}
+	t.b.WriteString(strconv.Itoa(id))
+	if len(v) < 10 {
+		t.b.WriteByte('0')
+	}
+	t.b.WriteString(strconv.Itoa(len(v)))
+	t.b.WriteString(v)
+}
+
+func (t *tlv) fail(err error) {
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// fields returns the encoded fields of a template, an error is kept by parent.
+func fields(field string, fs []Field, parent *tlv) string {
+	var t tlv
+	for _, f := range fs {
+		t.add(field, f.ID, f.Value)
+	}
+	parent.fail(t.err)
+	return t.b.String()
+}
+
+// crc16 returns the CRC-16/CCITT-FALSE checksum of s as four hexadecimal digits.
+func crc16(s string) string {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	h := strings.ToUpper(strconv.FormatUint(uint64(crc), 16))
+	return strings.Repeat("0", 4-len(h)) + h
+}
+
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// alnum reports whether s consists of 1 to n letters and digits.
+func alnum(s string, n int) bool {
+	if s == "" || len(s) > n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
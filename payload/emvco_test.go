@@ -0,0 +1,83 @@
+package payload
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// pixSample is the static PIX code of the manual of the Central Bank of Brazil, with its published checksum.
+const pixSample = "00020126580014br.gov.bcb.pix0136123e4567-e12b-12d1-a456-4266554400005204000053039865802BR" +
+	"5913Fulano de Tal6008BRASILIA62070503***63041D3D"
+
+func TestCRC16(t *testing.T) {
+	for _, tc := range []struct {
+		s, want string
+	}{
+		{"", "FFFF"},
+		{"123456789", "29B1"}, // the check value of CRC-16/CCITT-FALSE
+		{pixSample[:len(pixSample)-4], "1D3D"},
+		{"A", "B915"},
+	} {
+		if got := crc16(tc.s); got != tc.want {
+			t.Errorf("crc16(%q) = %s, want %s", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestPIX(t *testing.T) {
+	got, err := PIX{Key: "123e4567-e12b-12d1-a456-426655440000", Name: "Fulano de Tal", City: "BRASILIA"}.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The payload is the published sample with the optional point of initiation method (ID 1) of a static code.
+	want := pixSample[:6] + "010211" + pixSample[6:len(pixSample)-4]
+	if got[:len(got)-4] != want {
+		t.Errorf("Payload() = %q, want %q and the checksum", got, want)
+	}
+	if sum := got[len(got)-4:]; sum != crc16(want) {
+		t.Errorf("the checksum is %s, want %s", sum, crc16(want))
+	}
+}
+
+func TestEMVCoInvalid(t *testing.T) {
+	valid := EMVCo{
+		Accounts:     []Template{{ID: 26, Fields: []Field{{ID: 0, Value: "br.gov.bcb.pix"}}}},
+		Currency:     "986",
+		CountryCode:  "BR",
+		MerchantName: "Fulano de Tal",
+		MerchantCity: "BRASILIA",
+	}
+	if _, err := valid.Payload(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		field  string
+		modify func(*EMVCo)
+	}{
+		{"Accounts", func(e *EMVCo) { e.Accounts = nil }},
+		{"Accounts", func(e *EMVCo) { e.Accounts[0].ID = 52 }},
+		{"Accounts", func(e *EMVCo) { e.Accounts[0].Fields[0].Value = "pix\n" }},
+		{"CategoryCode", func(e *EMVCo) { e.CategoryCode = "12" }},
+		{"Currency", func(e *EMVCo) { e.Currency = "BRL" }},
+		{"Amount", func(e *EMVCo) { e.Amount = "12,50" }},
+		{"CountryCode", func(e *EMVCo) { e.CountryCode = "br" }},
+		{"MerchantName", func(e *EMVCo) { e.MerchantName = strings.Repeat("x", 26) }},
+		{"MerchantCity", func(e *EMVCo) { e.MerchantCity = "" }},
+		{"Additional", func(e *EMVCo) { e.Additional = []Field{{ID: 5, Value: strings.Repeat("x", 100)}} }},
+	} {
+		e := valid
+		e.Accounts = []Template{{ID: 26, Fields: []Field{{ID: 0, Value: "br.gov.bcb.pix"}}}}
+		tc.modify(&e)
+		_, err := e.Payload()
+		var f *FieldError
+		if !errors.As(err, &f) || f.Field != tc.field {
+			t.Errorf("the invalid %s: %v", tc.field, err)
+		}
+	}
+
+	if _, err := (PIX{Key: "key", Name: "Fulano", City: "BRASILIA", TxID: "order-42"}).Payload(); err == nil {
+		t.Error("the TxID with a dash is accepted")
+	}
+}
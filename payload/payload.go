@@ -0,0 +1,271 @@
+// Package payload builds the contents of QR codes in the formats understood by phones and payment apps,
+// such as EMVCo merchant payment codes, Wi-Fi join codes and vCards.
+//
+// Malformed contents are scanned as plain text or rejected by the apps,
+// so the builders validate the fields and return an error instead of a payload that can't be used.
+//
+// Example Usage:
+//
+//	err := payload.QRCode(cmd, payload.WiFi{SSID: "Guests", Password: "welcome123"})
+package payload
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/gromey/thermalize"
+)
+
+// Payload is the content of a QR code.
+type Payload interface {
+	// Payload returns the formatted content, or an error if a field is invalid.
+	Payload() (string, error)
+}
+
+// QRCode prints the payload with the QRCode command. Nothing is printed if the payload is invalid.
+func QRCode(cmd thermalize.Cmd, p Payload) error {
+	s, err := p.Payload()
+	if err != nil {
+		return err
+	}
+	cmd.QRCode(s)
+	return nil
+}
+
+// FieldError reports an invalid field of a payload.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return "payload: " + e.Field + ": " + e.Reason
+}
+
+func invalid(field, format string, args ...any) error {
+	return &FieldError{Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// URL is a link opened by the QR code, it must be an absolute URL, such as "https://example.com/menu".
+type URL string
+
+func (u URL) Payload() (string, error) {
+	p, err := url.Parse(string(u))
+	if err != nil {
+		return "", invalid("URL", "%v", err)
+	}
+	if p.Scheme == "" || p.Host == "" && p.Opaque == "" {
+		return "", invalid("URL", "%q is not an absolute URL", string(u))
+	}
+	return string(u), nil
+}
+
+// WiFi is a code joining a Wi-Fi network.
+type WiFi struct {
+	SSID     string
+	Password string
+
+	// Security is "WPA", "WEP" or "nopass". By default, WPA is used if the password is set, nopass otherwise.
+	Security string
+
+	// Hidden is set if the network doesn't broadcast the SSID.
+	Hidden bool
+}
+
+func (w WiFi) Payload() (string, error) {
+	if w.SSID == "" {
+		return "", invalid("SSID", "is empty")
+	}
+
+	security := w.Security
+	if security == "" {
+		security = "nopass"
+		if w.Password != "" {
+			security = "WPA"
+		}
+	}
+
+	switch security {
+	case "WPA":
+		if n := len(w.Password); n < 8 || n > 63 {
+			return "", invalid("Password", "WPA requires 8 to 63 characters, got %d", n)
+		}
+	case "WEP":
+		if n := len(w.Password); n != 5 && n != 10 && n != 13 && n != 26 {
+			return "", invalid("Password", "WEP requires 5, 10, 13 or 26 characters, got %d", n)
+		}
+	case "nopass":
+		if w.Password != "" {
+			return "", invalid("Password", "is set for an open network")
+		}
+	default:
+		return "", invalid("Security", "%q is not one of WPA, WEP and nopass", w.Security)
+	}
+
+	var b strings.Builder
+	b.WriteString("WIFI:T:")
+	b.WriteString(security)
+	b.WriteString(";S:")
+	b.WriteString(wifiEscaper.Replace(w.SSID))
+	if w.Password != "" {
+		b.WriteString(";P:")
+		b.WriteString(wifiEscaper.Replace(w.Password))
+	}
+	if w.Hidden {
+		b.WriteString(";H:true")
+	}
+	b.WriteString(";;")
+	return b.String(), nil
+}
+
+var wifiEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+
+// VCard is a contact card in the vCard 3.0 format, at least one of the names or the organization must be set.
+type VCard struct {
+	FirstName    string
+	LastName     string
+	Organization string
+	Title        string
+	Phone        string
+	Email        string
+	URL          string
+	Address      string
+	Note         string
+}
+
+func (v VCard) Payload() (string, error) {
+	name := strings.TrimSpace(v.FirstName + " " + v.LastName)
+	if name == "" {
+		name = v.Organization
+	}
+	if name == "" {
+		return "", invalid("FirstName", "no name or organization is set")
+	}
+	if v.Email != "" {
+		if _, err := mail.ParseAddress(v.Email); err != nil {
+			return "", invalid("Email", "%q is not an email address", v.Email)
+		}
+	}
+	if v.URL != "" {
+		if _, err := URL(v.URL).Payload(); err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	line := func(name, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteString(name)
+		b.WriteByte(':')
+		b.WriteString(value)
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN", "VCARD")
+	line("VERSION", "3.0")
+	line("N", vcardEscaper.Replace(v.LastName)+";"+vcardEscaper.Replace(v.FirstName)+";;;")
+	line("FN", vcardEscaper.Replace(name))
+	line("ORG", vcardEscaper.Replace(v.Organization))
+	line("TITLE", vcardEscaper.Replace(v.Title))
+	line("TEL", vcardEscaper.Replace(v.Phone))
+	line("EMAIL", vcardEscaper.Replace(v.Email))
+	line("URL", v.URL)
+	if v.Address != "" {
+		line("ADR", ";;"+vcardEscaper.Replace(v.Address)+";;;;")
+	}
+	line("NOTE", vcardEscaper.Replace(v.Note))
+	line("END", "VCARD")
+	return b.String(), nil
+}
+
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, `,`, `\,`, `;`, `\;`, "\r\n", `\n`, "\n", `\n`)
+
+// UPI is a payment request of the Indian Unified Payments Interface.
+type UPI struct {
+	// VPA is the virtual payment address of the payee, such as "shop@bank".
+	VPA  string
+	Name string
+
+	// Amount is the amount in rupees, such as "12.50". If it is empty, the payer enters the amount.
+	Amount string
+
+	// MerchantCode is the four-digit merchant category code.
+	MerchantCode string
+
+	// Reference is the transaction reference, such as the receipt number.
+	Reference string
+	Note      string
+}
+
+func (u UPI) Payload() (string, error) {
+	if at := strings.IndexByte(u.VPA, '@'); at < 1 || at == len(u.VPA)-1 {
+		return "", invalid("VPA", "%q is not a virtual payment address", u.VPA)
+	}
+	if u.Name == "" {
+		return "", invalid("Name", "is empty")
+	}
+	if u.MerchantCode != "" && !digits(u.MerchantCode, 4) {
+		return "", invalid("MerchantCode", "%q is not a four-digit code", u.MerchantCode)
+	}
+	if u.Amount != "" {
+		if err := amount("Amount", u.Amount, 13); err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("upi://pay")
+	sep := byte('?')
+	param := func(name, value string) {
+		if value == "" {
+			return
+		}
+		b.WriteByte(sep)
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(upiEscaper.Replace(url.QueryEscape(value)))
+		sep = '&'
+	}
+
+	param("pa", u.VPA)
+	param("pn", u.Name)
+	param("mc", u.MerchantCode)
+	param("tr", u.Reference)
+	param("tn", u.Note)
+	param("am", u.Amount)
+	param("cu", "INR")
+	return b.String(), nil
+}
+
+// upiEscaper keeps the escaping of the query compatible with the payment apps,
+// which don't decode '+' as a space and expect '@' in the addresses as it is.
+var upiEscaper = strings.NewReplacer("+", "%20", "%40", "@")
+
+// amount validates the decimal amount with up to two decimal places, such as "12.50".
+func amount(field, s string, max int) error {
+	whole, frac, dot := strings.Cut(s, ".")
+	if whole == "" || !digits(whole, len(whole)) || dot && (frac == "" || len(frac) > 2 || !digits(frac, len(frac))) {
+		return invalid(field, "%q is not an amount, such as 12.50", s)
+	}
+	if len(s) > max {
+		return invalid(field, "%q exceeds %d characters", s, max)
+	}
+	return nil
+}
+
+// digits reports whether s consists of n decimal digits.
+func digits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
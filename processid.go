@@ -0,0 +1,38 @@
+package thermalize
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrProcessID is returned by ParseProcessID if the data is not a process ID response.
+var ErrProcessID = errors.New("thermalize: invalid process ID response")
+
+// ParseProcessID parses the response of the printer to RequestProcessID: the header 0x37 0x22, the ID and NUL.
+//
+// Example Usage:
+//
+//	cmd.(thermalize.ProcessIDResponder).RequestProcessID("1234")
+//	// ... read the 7 bytes of the response from the connection
+//	if id, err := thermalize.ParseProcessID(reply); err == nil {
+//		log.Printf("job %s fully printed", id)
+//	}
+func ParseProcessID(bs []byte) (string, error) {
+	if len(bs) != 7 || bs[0] != 0x37 || bs[1] != 0x22 || bs[6] != NUL {
+		return "", ErrProcessID
+	}
+	return string(bs[2:6]), nil
+}
+
+// processIDReason reports why the ID can't be sent by RequestProcessID, or returns an empty string.
+func processIDReason(id string) string {
+	if len(id) != 4 {
+		return fmt.Sprintf("ID %q must be 4 characters", id)
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 32 || id[i] > 126 {
+			return fmt.Sprintf("ID %q must consist of the characters from 32 to 126", id)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoPrinter is the error of the jobs submitted to a pool without a healthy printer.
+var ErrNoPrinter = errors.New("queue: no healthy printer")
+
+// Pool routes the jobs to several printers of the same class, such as the ticket printers of the counters,
+// each printing with its own queue. A job is routed to the healthy printer with the fewest jobs (see Queue.Load),
+// and fails over to the next one if its printer didn't accept any of its data. A printer whose job failed
+// isn't used until the cooldown has passed, see WithCooldown.
+//
+// Pool is safe for concurrent use.
+type Pool struct {
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	printers []*poolPrinter
+}
+
+type poolPrinter struct {
+	name  string
+	queue *Queue
+
+	// failed is the time of the last failure of the printer.
+	failed time.Time
+}
+
+// PoolOption customizes the pool.
+type PoolOption interface {
+	apply(*Pool)
+}
+
+type poolOptionFunc func(*Pool)
+
+func (fn poolOptionFunc) apply(p *Pool) {
+	fn(p)
+}
+
+// WithCooldown sets how long a printer isn't used after a failed job, by default 30 seconds.
+func WithCooldown(d time.Duration) PoolOption {
+	return poolOptionFunc(func(p *Pool) { p.cooldown = d })
+}
+
+// NewPool returns an empty pool.
+func NewPool(opts ...PoolOption) *Pool {
+	p := &Pool{cooldown: 30 * time.Second}
+	for _, opt := range opts {
+		opt.apply(p)
+	}
+	return p
+}
+
+// Add adds the printer with the queue to the pool, the name identifies it in PoolJob.Printer.
+func (p *Pool) Add(name string, q *Queue) {
+	p.mu.Lock()
+	p.printers = append(p.printers, &poolPrinter{name: name, queue: q})
+	p.mu.Unlock()
+}
+
+// PoolJob is a job submitted to a pool.
+type PoolJob struct {
+	// ID identifies the job for the application, the pool doesn't interpret it.
+	ID string
+
+	done chan struct{}
+	err  error
+
+	mu       sync.Mutex
+	printer  string
+	job      *Job
+	canceled bool
+}
+
+// Printer returns the name of the printer the job is routed to.
+func (j *PoolJob) Printer() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.printer
+}
+
+// Done returns a channel closed once the job has been printed or has failed on all printers.
+func (j *PoolJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Err returns the error of the job once it is done, nil if it has been printed.
+func (j *PoolJob) Err() error {
+	select {
+	case <-j.done:
+		return j.err
+	default:
+		return nil
+	}
+}
+
+// Wait waits until the job is done and returns its error, or the error of the context.
+func (j *PoolJob) Wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return j.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel cancels the job waiting in the queue of its printer, see Job.Cancel.
+func (j *PoolJob) Cancel() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.job == nil || !j.job.Cancel() {
+		return false
+	}
+	j.canceled = true
+	return true
+}
+
+// Submit routes the job to the least busy healthy printer. It returns ErrNoPrinter if no printer is healthy.
+func (p *Pool) Submit(id string, data []byte, opts ...JobOption) (*PoolJob, error) {
+	pj := &PoolJob{ID: id, done: make(chan struct{})}
+	tried := make(map[*poolPrinter]bool)
+	if err := p.route(pj, tried, data, opts); err != nil {
+		return nil, err
+	}
+	go p.watch(pj, tried, data, opts)
+	return pj, nil
+}
+
+// route submits the job to the least busy healthy printer it hasn't been tried on.
+func (p *Pool) route(pj *PoolJob, tried map[*poolPrinter]bool, data []byte, opts []JobOption) error {
+	for {
+		pr := p.pick(tried)
+		if pr == nil {
+			return ErrNoPrinter
+		}
+		tried[pr] = true
+
+		job, err := pr.queue.Submit(pj.ID, data, opts...)
+		if err != nil {
+			continue
+		}
+		pj.mu.Lock()
+		pj.printer, pj.job = pr.name, job
+		pj.mu.Unlock()
+		return nil
+	}
+}
+
+// pick returns the healthy printer with the fewest jobs, which hasn't been tried, or nil if there is none.
+func (p *Pool) pick(tried map[*poolPrinter]bool) *poolPrinter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *poolPrinter
+	load := 0
+	for _, pr := range p.printers {
+		if tried[pr] || time.Since(pr.failed) < p.cooldown || !pr.queue.Healthy() {
+			continue
+		}
+		if l := pr.queue.Load(); best == nil || l < load {
+			best, load = pr, l
+		}
+	}
+	return best
+}
+
+// watch waits for the job and fails it over to the next printer if its printer didn't accept any of its data.
+func (p *Pool) watch(pj *PoolJob, tried map[*poolPrinter]bool, data []byte, opts []JobOption) {
+	for {
+		pj.mu.Lock()
+		job := pj.job
+		pj.mu.Unlock()
+
+		<-job.Done()
+		err := job.Err()
+		// The job is sent to another printer only if none of it was accepted, otherwise it would be printed twice.
+		if err == nil || job.sent > 0 || errors.Is(err, ErrPreempted) {
+			pj.err = err
+			break
+		}
+
+		pj.mu.Lock()
+		canceled := pj.canceled
+		pj.mu.Unlock()
+		if canceled {
+			pj.err = err
+			break
+		}
+
+		p.mu.Lock()
+		for pr := range tried {
+			if pr.queue == job.q {
+				pr.failed = time.Now()
+			}
+		}
+		p.mu.Unlock()
+
+		if p.route(pj, tried, data, opts) != nil {
+			pj.err = err
+			break
+		}
+	}
+	close(pj.done)
+}
@@ -0,0 +1,126 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errOffline = errors.New("offline")
+
+// partial accepts the first n bytes of each write and fails the write.
+type partial struct {
+	printer
+	n int
+}
+
+func (p *partial) Write(bs []byte) (int, error) {
+	n, _ := p.printer.Write(bs[:p.n])
+	return n, errOffline
+}
+
+// unhealthy is a printer reporting it is unhealthy, like a transport whose health check failed.
+type unhealthy struct {
+	printer
+}
+
+func (*unhealthy) Healthy() bool {
+	return false
+}
+
+func TestPoolLeastBusy(t *testing.T) {
+	a, b := &printer{}, &printer{}
+	started, release := blocked(a)
+	qa, qb := New(a), New(b)
+	defer qa.Close()
+	defer qb.Close()
+	pool := NewPool()
+	pool.Add("a", qa)
+	pool.Add("b", qb)
+
+	first, err := pool.Submit("first", []byte("first;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	second, err := pool.Submit("second", []byte("second;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-first.Done()
+	<-second.Done()
+
+	if first.Printer() != "a" || second.Printer() != "b" {
+		t.Errorf("the jobs are routed to %s and %s, want a and b", first.Printer(), second.Printer())
+	}
+	if got := string(b.bytes()); got != "second;" {
+		t.Errorf("b printed %q", got)
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	a, b := &printer{}, &printer{}
+	a.hook = func(int) error { return errOffline }
+	qa, qb := New(a), New(b)
+	defer qa.Close()
+	defer qb.Close()
+	pool := NewPool(WithCooldown(time.Hour))
+	pool.Add("a", qa)
+	pool.Add("b", qb)
+
+	for _, id := range []string{"first", "second"} {
+		j, err := pool.Submit(id, []byte(id+";"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-j.Done()
+		if err := j.Err(); err != nil || j.Printer() != "b" {
+			t.Errorf("the job %s is printed by %s: %v, want b", id, j.Printer(), err)
+		}
+	}
+	// The failed printer is tried once, it isn't used during the cooldown.
+	if a.writes != 1 {
+		t.Errorf("the failed printer is written %d times, want 1", a.writes)
+	}
+	if got := string(b.bytes()); got != "first;second;" {
+		t.Errorf("b printed %q", got)
+	}
+}
+
+func TestPoolPartlyAccepted(t *testing.T) {
+	a, b := &partial{n: 3}, &printer{}
+	qa, qb := New(a), New(b)
+	defer qa.Close()
+	defer qb.Close()
+	pool := NewPool()
+	pool.Add("a", qa)
+	pool.Add("b", qb)
+
+	j, err := pool.Submit("job", []byte("printed once"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-j.Done()
+	// The job accepted partly isn't sent to the other printer, which would print it twice.
+	if err := j.Err(); !errors.Is(err, errOffline) || j.Printer() != "a" {
+		t.Errorf("the job is printed by %s: %v, want a and %v", j.Printer(), err, errOffline)
+	}
+	if got := b.bytes(); len(got) != 0 {
+		t.Errorf("b printed %q", got)
+	}
+}
+
+func TestPoolNoPrinter(t *testing.T) {
+	if _, err := NewPool().Submit("job", nil); !errors.Is(err, ErrNoPrinter) {
+		t.Errorf("Submit to an empty pool: %v, want %v", err, ErrNoPrinter)
+	}
+
+	q := New(&unhealthy{})
+	defer q.Close()
+	pool := NewPool()
+	pool.Add("unhealthy", q)
+	if _, err := pool.Submit("job", nil); !errors.Is(err, ErrNoPrinter) {
+		t.Errorf("Submit to an unhealthy printer: %v, want %v", err, ErrNoPrinter)
+	}
+}
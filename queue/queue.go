@@ -0,0 +1,396 @@
+// Package queue prints the jobs on a printer one at a time, so the jobs of several sources,
+// such as the POS terminal and the online orders, never interleave.
+//
+// The jobs are printed by priority, in the order they are submitted within a priority, e.g. a fiscal receipt
+// before a kitchen reprint. The jobs waiting in the queue can be canceled, and WithPreemption sets what happens
+// to the job being transmitted when a job of a higher priority arrives.
+//
+// Example Usage:
+//
+//	q := queue.New(conn)
+//	job, err := q.Submit("order-42", data, queue.WithPriority(queue.PriorityHigh))
+//	// ...
+//	err = job.Wait(ctx)
+package queue
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+var (
+	// ErrClosed is returned by Submit once the queue is closed.
+	ErrClosed = errors.New("queue: closed")
+
+	// ErrCanceled is the error of the jobs canceled before they have been printed.
+	ErrCanceled = errors.New("queue: job canceled")
+
+	// ErrPreempted is the error of the jobs aborted by a job of a higher priority, see PreemptAbort.
+	ErrPreempted = errors.New("queue: job preempted")
+)
+
+// The priorities of the common jobs, any other priority may be used, the higher ones are printed first.
+const (
+	PriorityLow    = -10
+	PriorityNormal = 0
+	PriorityHigh   = 10
+)
+
+// The preemption policies of the job being transmitted when a job of a higher priority arrives.
+const (
+	// PreemptNever completes the transmission of the job first.
+	PreemptNever byte = iota
+
+	// PreemptSuspend suspends the transmission at the next command boundary, prints the job of the higher priority
+	// and continues the suspended job afterwards. The printed part of the suspended job precedes the other job on the paper.
+	// The printer prints the pending line of the suspended job and is initialized with [ESC @] before the other job,
+	// so it prints in the default modes, and again before the suspended job continues, followed by the print modes
+	// the suspended job had selected (see thermalize.EscapeModes).
+	PreemptSuspend
+
+	// PreemptAbort aborts the transmission at the next command boundary, initializes the printer with [ESC @],
+	// so the print modes of the aborted job don't apply to the next one, and fails the job with ErrPreempted.
+	PreemptAbort
+)
+
+// Job is a submitted job.
+type Job struct {
+	// ID identifies the job for the application, the queue doesn't interpret it.
+	ID string
+
+	priority int
+	data     []byte
+	done     chan struct{}
+	err      error
+
+	// q is the queue of the job, sent is the number of bytes transmitted, started is set once the transmission starts.
+	// bounds are the command boundaries of the data, which is transmitted in chunks ending at them.
+	// suspended is set while the job is suspended, until its print modes are restored.
+	q         *Queue
+	sent      int
+	started   bool
+	bounds    []int
+	suspended bool
+}
+
+// Done returns a channel closed once the job has been printed or has failed.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Err returns the error of the job once it is done, nil if it has been printed.
+func (j *Job) Err() error {
+	select {
+	case <-j.done:
+		return j.err
+	default:
+		return nil
+	}
+}
+
+// Wait waits until the job is done and returns its error, or the error of the context.
+func (j *Job) Wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return j.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cancel removes the job from the queue and fails it with ErrCanceled.
+// It reports false if the transmission of the job has already started, including a suspended job, or the job is done.
+func (j *Job) Cancel() bool {
+	q := j.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if j.started {
+		return false
+	}
+	for i, job := range q.jobs {
+		if job == j {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			j.finish(ErrCanceled)
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Job) finish(err error) {
+	j.err = err
+	close(j.done)
+}
+
+// JobOption customizes a submitted job.
+type JobOption interface {
+	apply(*Job)
+}
+
+type jobOptionFunc func(*Job)
+
+func (fn jobOptionFunc) apply(j *Job) {
+	fn(j)
+}
+
+// WithPriority sets the priority of the job, by default PriorityNormal.
+func WithPriority(p int) JobOption {
+	return jobOptionFunc(func(j *Job) { j.priority = p })
+}
+
+// Queue is a print queue writing the jobs to the printer.
+//
+// Queue is safe for concurrent use.
+type Queue struct {
+	w io.Writer
+
+	// retries is the number of times a write the printer didn't accept is repeated, after delay.
+	retries int
+	delay   time.Duration
+
+	// preemption is the policy of the job being transmitted, which is written in chunks of about chunk bytes.
+	preemption byte
+	chunk      int
+
+	mu     sync.Mutex
+	jobs   []*Job
+	closed bool
+	active bool
+
+	wake    chan struct{}
+	stopped chan struct{}
+}
+
+// Option customizes the queue.
+type Option interface {
+	apply(*Queue)
+}
+
+type optionFunc func(*Queue)
+
+func (fn optionFunc) apply(q *Queue) {
+	fn(q)
+}
+
+// WithRetry sends the data of a job again up to n times, after the delay, if the printer didn't accept any of it,
+// e.g. while the connection is being reestablished. The data the printer accepted partly is never sent again,
+// since it would be printed twice.
+func WithRetry(n int, delay time.Duration) Option {
+	return optionFunc(func(q *Queue) { q.retries, q.delay = n, delay })
+}
+
+// WithPreemption sets the policy of the job being transmitted when a job of a higher priority arrives:
+// PreemptNever, PreemptSuspend or PreemptAbort. Unless the policy is PreemptNever, the jobs are transmitted
+// in chunks of about chunk bytes, 4096 by default, cut at the boundaries of the ESC/POS commands
+// (see thermalize.EscapeBoundaries), and the queue is checked between the chunks.
+func WithPreemption(policy byte, chunk int) Option {
+	return optionFunc(func(q *Queue) { q.preemption, q.chunk = policy, chunk })
+}
+
+// New returns a queue printing the jobs on w.
+func New(w io.Writer, opts ...Option) *Queue {
+	q := &Queue{w: w, wake: make(chan struct{}, 1), stopped: make(chan struct{})}
+	for _, opt := range opts {
+		opt.apply(q)
+	}
+	if q.chunk <= 0 {
+		q.chunk = 4096
+	}
+	go q.run()
+	return q
+}
+
+// Submit adds the job to the queue, after the jobs of the same or a higher priority.
+// The data must not be modified until the job is done.
+func (q *Queue) Submit(id string, data []byte, opts ...JobOption) (*Job, error) {
+	j := &Job{ID: id, data: data, done: make(chan struct{}), q: q}
+	for _, opt := range opts {
+		opt.apply(j)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrClosed
+	}
+	q.insert(j, false)
+	q.signal()
+	return j, nil
+}
+
+// Len returns the number of jobs waiting to be printed, including the suspended jobs.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.jobs)
+}
+
+// Load returns the number of jobs waiting to be printed or being printed.
+func (q *Queue) Load() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.active {
+		return len(q.jobs) + 1
+	}
+	return len(q.jobs)
+}
+
+// Healthy reports whether the printer is healthy, if the writer reports it, such as transport.TCP,
+// otherwise it reports true.
+func (q *Queue) Healthy() bool {
+	if h, ok := q.w.(interface{ Healthy() bool }); ok {
+		return h.Healthy()
+	}
+	return true
+}
+
+// Close stops accepting jobs and waits until the queued jobs are done.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrClosed
+	}
+	q.closed = true
+	q.signal()
+	q.mu.Unlock()
+
+	<-q.stopped
+	return nil
+}
+
+// insert inserts the job after the jobs of a higher priority, and after the jobs of the same priority
+// unless first is set, as for a suspended job continued before them. q.mu must be held.
+func (q *Queue) insert(j *Job, first bool) {
+	i := len(q.jobs)
+	for i > 0 && (q.jobs[i-1].priority < j.priority || first && q.jobs[i-1].priority == j.priority) {
+		i--
+	}
+	q.jobs = append(q.jobs, nil)
+	copy(q.jobs[i+1:], q.jobs[i:])
+	q.jobs[i] = j
+}
+
+// signal wakes the worker up, q.mu must be held.
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.stopped)
+	for {
+		q.mu.Lock()
+		if len(q.jobs) == 0 {
+			closed := q.closed
+			q.mu.Unlock()
+			if closed {
+				return
+			}
+			<-q.wake
+			continue
+		}
+		j := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		j.started, q.active = true, true
+		q.mu.Unlock()
+
+		suspended, err := q.print(j)
+		q.mu.Lock()
+		q.active = false
+		q.mu.Unlock()
+		if !suspended {
+			j.finish(err)
+		}
+	}
+}
+
+// print transmits the rest of the job and reports whether it has been suspended, in which case it is queued again.
+func (q *Queue) print(j *Job) (bool, error) {
+	if q.preemption == PreemptNever {
+		n, err := q.write(j.data[j.sent:])
+		j.sent += n
+		return false, err
+	}
+
+	if j.bounds == nil {
+		j.bounds = thermalize.EscapeBoundaries(j.data)
+	}
+	if j.suspended {
+		modes := append([]byte{thermalize.ESC, '@'}, thermalize.EscapeModes(j.data[:j.sent])...)
+		if _, err := q.write(modes); err != nil {
+			return false, err
+		}
+		j.suspended = false
+	}
+	for pos := j.sent; pos < len(j.data); {
+		end := q.chunkEnd(j, pos)
+		n, err := q.write(j.data[pos:end])
+		j.sent = pos + n
+		if err != nil {
+			return false, err
+		}
+		pos = end
+		if pos == len(j.data) {
+			break
+		}
+
+		q.mu.Lock()
+		preempted := len(q.jobs) > 0 && q.jobs[0].priority > j.priority
+		q.mu.Unlock()
+		if !preempted {
+			continue
+		}
+
+		// The printer discards the line it holds when initialized, so the suspended job prints it first [ESC J 0].
+		reset := []byte{thermalize.ESC, '@'}
+		if q.preemption == PreemptSuspend {
+			reset = []byte{thermalize.ESC, 'J', 0, thermalize.ESC, '@'}
+		}
+		if _, err := q.write(reset); err != nil {
+			return false, err
+		}
+		if q.preemption == PreemptSuspend {
+			j.suspended = true
+			q.mu.Lock()
+			q.insert(j, true)
+			q.mu.Unlock()
+			return true, nil
+		}
+		return false, ErrPreempted
+	}
+	return false, nil
+}
+
+// chunkEnd returns the end of the chunk of the job starting at pos, the first command boundary
+// at least q.chunk bytes after pos, or the end of the data.
+func (q *Queue) chunkEnd(j *Job, pos int) int {
+	if i := sort.SearchInts(j.bounds, pos+q.chunk); i < len(j.bounds) {
+		return j.bounds[i]
+	}
+	return len(j.data)
+}
+
+// write writes the data to the printer, retrying while the printer accepts none of it,
+// and returns the number of bytes the printer accepted.
+func (q *Queue) write(data []byte) (int, error) {
+	for attempt := 0; ; attempt++ {
+		n, err := q.w.Write(data)
+		if err == nil || n > 0 || attempt >= q.retries {
+			return n, err
+		}
+		time.Sleep(q.delay)
+	}
+}
@@ -0,0 +1,245 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+// printer records the data written to it. hook is called before each write, with the number of the write from 1,
+// and the write fails with the error it returns.
+type printer struct {
+	mu     sync.Mutex
+	data   bytes.Buffer
+	writes int
+	hook   func(n int) error
+}
+
+func (p *printer) Write(bs []byte) (int, error) {
+	p.mu.Lock()
+	p.writes++
+	n, hook := p.writes, p.hook
+	p.mu.Unlock()
+
+	if hook != nil {
+		if err := hook(n); err != nil {
+			return 0, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.data.Write(bs)
+}
+
+func (p *printer) bytes() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]byte(nil), p.data.Bytes()...)
+}
+
+// blocked holds the first write until release is closed, started is closed once it has begun.
+func blocked(p *printer) (started, release chan struct{}) {
+	started, release = make(chan struct{}), make(chan struct{})
+	p.hook = func(n int) error {
+		if n == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	}
+	return started, release
+}
+
+func wait(t *testing.T, jobs ...*Job) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, j := range jobs {
+		if err := j.Wait(ctx); err != nil {
+			t.Fatalf("job %s: %v", j.ID, err)
+		}
+	}
+}
+
+func submit(t *testing.T, q *Queue, id string, data []byte, opts ...JobOption) *Job {
+	t.Helper()
+	j, err := q.Submit(id, data, opts...)
+	if err != nil {
+		t.Fatalf("Submit %s: %v", id, err)
+	}
+	return j
+}
+
+func TestQueuePriorities(t *testing.T) {
+	p := &printer{}
+	started, release := blocked(p)
+	q := New(p)
+
+	first := submit(t, q, "first", []byte("first;"))
+	<-started
+	low := submit(t, q, "low", []byte("low;"), WithPriority(PriorityLow))
+	normal := submit(t, q, "normal", []byte("normal;"))
+	high := submit(t, q, "high", []byte("high;"), WithPriority(PriorityHigh))
+	again := submit(t, q, "high again", []byte("high again;"), WithPriority(PriorityHigh))
+	if n := q.Load(); n != 5 {
+		t.Errorf("Load = %d, want 5", n)
+	}
+	close(release)
+	wait(t, first, low, normal, high, again)
+
+	if got, want := string(p.bytes()), "first;high;high again;normal;low;"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+	if err := q.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if _, err := q.Submit("late", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Close: %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	p := &printer{}
+	started, release := blocked(p)
+	q := New(p)
+
+	first := submit(t, q, "first", []byte("first;"))
+	<-started
+	canceled := submit(t, q, "canceled", []byte("canceled;"))
+	kept := submit(t, q, "kept", []byte("kept;"))
+
+	if first.Cancel() {
+		t.Error("the job being transmitted is canceled")
+	}
+	if !canceled.Cancel() {
+		t.Error("the waiting job isn't canceled")
+	}
+	if canceled.Cancel() {
+		t.Error("the job is canceled twice")
+	}
+	if err := canceled.Err(); !errors.Is(err, ErrCanceled) {
+		t.Errorf("the canceled job: %v, want %v", err, ErrCanceled)
+	}
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+	close(release)
+	wait(t, first, kept)
+	q.Close()
+
+	if got, want := string(p.bytes()), "first;kept;"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+// preemptedJob is a job of several commands selecting print modes, whose first chunk ends after [ESC R 3]
+// with chunks of 8 bytes.
+var preemptedJob = []byte{
+	thermalize.ESC, '@', thermalize.ESC, 'a', 1, thermalize.ESC, 'R', 3,
+	'f', 'i', 'r', 's', 't', thermalize.LF,
+	thermalize.ESC, 'E', 1, 's', 'e', 'c', 'o', 'n', 'd', thermalize.LF,
+}
+
+// preempt submits preemptedJob and, once its first chunk has been written, an urgent job of a higher priority.
+func preempt(t *testing.T, policy byte) (*printer, *Job, *Job) {
+	t.Helper()
+	p := &printer{}
+	q := New(p, WithPreemption(policy, 8))
+	defer q.Close()
+
+	urgent := make(chan *Job, 1)
+	p.hook = func(n int) error {
+		if n == 1 {
+			// The queue is checked once the first chunk has been written.
+			j, _ := q.Submit("urgent", []byte("urgent"), WithPriority(PriorityHigh))
+			urgent <- j
+		}
+		return nil
+	}
+	long := submit(t, q, "long", preemptedJob)
+	u := <-urgent
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := u.Wait(ctx); err != nil {
+		t.Fatalf("the urgent job: %v", err)
+	}
+	long.Wait(ctx)
+	return p, long, u
+}
+
+func TestQueuePreemptSuspend(t *testing.T) {
+	p, long, _ := preempt(t, PreemptSuspend)
+	if err := long.Err(); err != nil {
+		t.Fatalf("the suspended job: %v", err)
+	}
+
+	var want []byte
+	want = append(want, preemptedJob[:8]...)
+	// The pending line is printed and the printer initialized before the urgent job.
+	want = append(want, thermalize.ESC, 'J', 0, thermalize.ESC, '@')
+	want = append(want, "urgent"...)
+	// The printer is initialized again and the modes of the suspended job are restored with their parameters.
+	want = append(want, thermalize.ESC, '@', thermalize.ESC, 'a', 1, thermalize.ESC, 'R', 3)
+	want = append(want, preemptedJob[8:]...)
+
+	if got := p.bytes(); !bytes.Equal(got, want) {
+		t.Errorf("printed\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestQueuePreemptAbort(t *testing.T) {
+	p, long, _ := preempt(t, PreemptAbort)
+	if err := long.Err(); !errors.Is(err, ErrPreempted) {
+		t.Fatalf("the aborted job: %v, want %v", err, ErrPreempted)
+	}
+
+	want := append(append([]byte(nil), preemptedJob[:8]...), thermalize.ESC, '@')
+	want = append(want, "urgent"...)
+	if got := p.bytes(); !bytes.Equal(got, want) {
+		t.Errorf("printed\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestQueuePreemptNever(t *testing.T) {
+	p, long, _ := preempt(t, PreemptNever)
+	if err := long.Err(); err != nil {
+		t.Fatalf("the long job: %v", err)
+	}
+	if got, want := p.bytes(), append(append([]byte(nil), preemptedJob...), "urgent"...); !bytes.Equal(got, want) {
+		t.Errorf("printed\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	errOffline := errors.New("offline")
+	for _, tc := range []struct {
+		retries, failures int
+		err               error
+	}{
+		{retries: 2, failures: 2},
+		{retries: 1, failures: 2, err: errOffline},
+	} {
+		p := &printer{}
+		p.hook = func(n int) error {
+			if n <= tc.failures {
+				return errOffline
+			}
+			return nil
+		}
+		q := New(p, WithRetry(tc.retries, time.Millisecond))
+		j := submit(t, q, "job", []byte("data"))
+		j.Wait(context.Background())
+		q.Close()
+
+		if err := j.Err(); !errors.Is(err, tc.err) {
+			t.Errorf("%d retries of %d failures: %v, want %v", tc.retries, tc.failures, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package thermalize
+
+import "sync"
+
+// Quirks are the deviations of the ESC/POS clones from the Epson printers, which the escape command set works around.
+type Quirks uint32
+
+const (
+	// QuirkRasterNUL sends a NUL after the data of the [GS v] raster images, which some clones expect.
+	QuirkRasterNUL Quirks = 1 << iota
+
+	// QuirkNoQRCode marks the printers without the [GS ( k] QR code commands, the QR codes are printed as images
+	// with the function set by WithQRCodeFunc, or reported through Err if it isn't set.
+	QuirkNoQRCode
+
+	// QuirkCP437Only marks the printers supporting only code page 437, the selection of the other code pages
+	// is reported and the runes code page 437 lacks are transliterated to ASCII.
+	QuirkCP437Only
+
+	// QuirkNoCut marks the printers without a cutter, the cuts feed the paper to the tear bar instead.
+	QuirkNoCut
+
+	// QuirkRotationKeepsSize marks the printers applying the width and the height of [GS !] to the rotated characters
+	// in the same directions as to the upright ones. The Epson printers swap them in 90° clockwise rotation mode,
+	// which the escape command set compensates for unless the quirk is set.
+	QuirkRotationKeepsSize
+)
+
+// tearFeed is the feed to the tear bar replacing the cuts in the vertical motion units, 15 mm at 203 dpi.
+const tearFeed = 120
+
+var profiles = struct {
+	sync.RWMutex
+	m map[string]Quirks
+}{m: make(map[string]Quirks)}
+
+// RegisterProfile registers the quirks of the printer model, which are selected with WithProfile.
+//
+// Example Usage:
+//
+//	thermalize.RegisterProfile("pos58", thermalize.QuirkRasterNUL|thermalize.QuirkNoCut)
+//	cmd := thermalize.NewEscape(32, 384, conn, thermalize.WithProfile("pos58"))
+func RegisterProfile(name string, q Quirks) {
+	profiles.Lock()
+	profiles.m[name] = q
+	profiles.Unlock()
+}
+
+// LookupProfile returns the quirks of the printer model registered with the name.
+func LookupProfile(name string) (Quirks, bool) {
+	profiles.RLock()
+	defer profiles.RUnlock()
+
+	q, ok := profiles.m[name]
+	return q, ok
+}
+
+// addQuirks adds the quirks to the command set, QuirkCP437Only selects code page 437 on Init.
+func (c *skipper) addQuirks(q Quirks) {
+	c.quirks |= q
+	if q&QuirkCP437Only != 0 {
+		c.page, c.hasPage, c.pageEnc = CodePageCP437, true, cp437Encoder
+	}
+}
+
+// supportedCodePage returns the code page b, unless the printer supports only CP437 (see QuirkCP437Only)
+// and b isn't CP437 in the codes. Otherwise b is reported and CP437 is returned, the second result is false in strict mode.
+func (c *skipper) supportedCodePage(codes map[byte]byte, b byte) (byte, bool) {
+	if c.quirks&QuirkCP437Only == 0 {
+		return b, true
+	}
+	if page, ok := symbolicCodePage(codes, b); ok && page == CodePageCP437 {
+		return b, true
+	}
+	if c.invalid("CodePage", "code page %#x is not supported by the printer, only CP437", b) {
+		return 0, false
+	}
+	return CodePageCP437, true
+}
@@ -0,0 +1,76 @@
+package thermalize
+
+import "errors"
+
+var (
+	// ErrCoverOpen is returned by RecoverAndReprint while the cover of the printer is open.
+	ErrCoverOpen = errors.New("thermalize: printer cover is open")
+
+	// ErrPaperEnd is returned by RecoverAndReprint while the printer is out of paper.
+	ErrPaperEnd = errors.New("thermalize: printer is out of paper")
+
+	// ErrUnrecoverable is returned by RecoverAndReprint if the printer reports an unrecoverable error,
+	// which requires switching the printer off and on.
+	ErrUnrecoverable = errors.New("thermalize: unrecoverable printer error")
+)
+
+// ErrorStatus is the error state of an ESC/POS printer,
+// parsed from the replies to the offline cause and error cause status requests (see StatusQuerier).
+type ErrorStatus struct {
+	// CoverOpen, PaperEnd and Error are the causes of the printer being offline.
+	CoverOpen bool
+	PaperEnd  bool
+	Error     bool
+
+	// AutocutterError is set after a cutter jam, Unrecoverable and AutoRecoverable classify the error.
+	AutocutterError bool
+	Unrecoverable   bool
+	AutoRecoverable bool
+}
+
+// ParseErrorStatus parses the reply to the offline cause status request (RequestStatus(2))
+// and the reply to the error cause status request (RequestStatus(3)).
+func ParseErrorStatus(offline, cause byte) ErrorStatus {
+	return ErrorStatus{
+		CoverOpen:       offline&0x04 != 0,
+		PaperEnd:        offline&0x20 != 0,
+		Error:           offline&0x40 != 0,
+		AutocutterError: cause&0x08 != 0,
+		Unrecoverable:   cause&0x20 != 0,
+		AutoRecoverable: cause&0x40 != 0,
+	}
+}
+
+// OK reports whether the printer is ready to print.
+func (s ErrorStatus) OK() bool {
+	return !s.CoverOpen && !s.PaperEnd && !s.Error
+}
+
+// RecoverAndReprint recovers the printer from the error reported by the status and prints the document again.
+//
+// The recovery flow of an application is:
+//   - request the offline cause and error cause statuses with RequestStatus(2) and RequestStatus(3)
+//     when a job fails or on the printer's automatic status back, and parse the replies with ParseErrorStatus;
+//   - ask the operator to fix the cause, such as to remove the jammed paper and to close the cover;
+//   - call RecoverAndReprint, which fails with ErrCoverOpen or ErrPaperEnd until the cause has been fixed.
+//
+// The interrupted job is cleared with RecoverError(RecoverClear), since a part of it may have been printed,
+// and the document is printed again from the start. Auto-recoverable errors, such as the print head overheating,
+// recover by themselves, so only the document is printed again. The command sets that don't implement Recoverer
+// print the document again without the recovery.
+func RecoverAndReprint(cmd Cmd, d *Document, s ErrorStatus) error {
+	switch {
+	case s.Unrecoverable:
+		return ErrUnrecoverable
+	case s.CoverOpen:
+		return ErrCoverOpen
+	case s.PaperEnd:
+		return ErrPaperEnd
+	}
+
+	if r, ok := cmd.(Recoverer); ok && s.Error && !s.AutoRecoverable {
+		r.RecoverError(RecoverClear)
+	}
+
+	return d.Render(cmd)
+}
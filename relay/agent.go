@@ -0,0 +1,90 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gromey/thermalize/queue"
+)
+
+// Agent receives the documents of a printer from the relay and prints them with the print queue.
+//
+// A document is acknowledged once the queue has printed it, a document that failed is delivered again by the relay
+// after the retry interval. The documents printed but not acknowledged, e.g. because the network failed,
+// are acknowledged again without being printed twice.
+type Agent struct {
+	cfg     config
+	base    string
+	printer string
+	queue   *queue.Queue
+
+	// printed holds the IDs of the printed documents, which haven't been acknowledged yet.
+	printed map[string]bool
+}
+
+// NewAgent returns the agent of the printer at the relay at the base URL, printing the documents with q.
+func NewAgent(base, printer string, q *queue.Queue, opts ...Option) *Agent {
+	return &Agent{cfg: newConfig(opts), base: base, printer: printer, queue: q, printed: make(map[string]bool)}
+}
+
+// Run receives and prints the documents until the context is done, and returns the error of the context.
+func (a *Agent) Run(ctx context.Context) error {
+	for {
+		docs, err := a.fetch(ctx)
+		if err == nil {
+			err = a.print(ctx, docs)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			a.cfg.onError(err)
+			if !sleep(ctx, a.cfg.retry) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// fetch returns the pending documents, waiting for one up to the poll wait.
+func (a *Agent) fetch(ctx context.Context) ([]Document, error) {
+	url := documentsURL(a.base, a.printer) + "?wait=" + strconv.Itoa(int(a.cfg.wait.Seconds()))
+	resp, err := a.cfg.do(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var docs []Document
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(&docs)
+	return docs, err
+}
+
+// print prints the documents in order and acknowledges them, it stops at the first error.
+func (a *Agent) print(ctx context.Context, docs []Document) error {
+	for _, doc := range docs {
+		if !a.printed[doc.ID] {
+			job, err := a.queue.Submit(doc.ID, doc.Data)
+			if err != nil {
+				return err
+			}
+			if err = job.Wait(ctx); err != nil {
+				return err
+			}
+			a.printed[doc.ID] = true
+		}
+
+		resp, err := a.cfg.do(ctx, http.MethodDelete, documentsURL(a.base, a.printer, doc.ID), nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		delete(a.printed, doc.ID)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Client sends the documents to the relay. The documents are stored in the outbox, in the spool directory
+// if WithSpool is used, and forwarded in order in the background, retrying while the relay is unreachable.
+// A document the relay rejects permanently (see StatusError.Permanent) is reported to the error handler
+// and dropped from the outbox, so it doesn't hold up the documents sent after it. Its file is kept
+// in the spool directory with the ".rejected" extension.
+//
+// Client is safe for concurrent use.
+type Client struct {
+	cfg  config
+	base string
+
+	mu     sync.Mutex
+	outbox []outgoing
+	seq    int64
+	empty  chan struct{}
+	closed bool
+
+	wake    chan struct{}
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// outgoing is a document in the outbox, file is its path in the spool directory.
+type outgoing struct {
+	Printer  string   `json:"printer"`
+	Document Document `json:"document"`
+
+	file string
+}
+
+// NewClient returns a client of the relay at the base URL, such as "https://relay.example.com".
+// The documents left in the spool directory by the previous run are forwarded first.
+func NewClient(base string, opts ...Option) (*Client, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		cfg:     newConfig(opts),
+		base:    base,
+		empty:   make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+	if err := c.load(); err != nil {
+		cancel()
+		return nil, err
+	}
+	if len(c.outbox) == 0 {
+		close(c.empty)
+	}
+	go c.run(ctx)
+	return c, nil
+}
+
+// Send stores the document for the printer in the outbox, it is forwarded to the relay in the background.
+// The document must have an ID, otherwise ErrNoID is returned.
+func (c *Client) Send(printer string, doc Document) error {
+	if doc.ID == "" {
+		return ErrNoID
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	o := outgoing{Printer: printer, Document: doc}
+	if c.cfg.spool != "" {
+		if err := c.store(&o); err != nil {
+			return err
+		}
+	}
+	if len(c.outbox) == 0 {
+		c.empty = make(chan struct{})
+	}
+	c.outbox = append(c.outbox, o)
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Pending returns the number of documents waiting in the outbox.
+func (c *Client) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.outbox)
+}
+
+// Flush waits until all documents sent so far have been forwarded to the relay, or the context is done.
+func (c *Client) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	empty := c.empty
+	c.mu.Unlock()
+
+	select {
+	case <-empty:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops forwarding the documents, those left in the spool directory are forwarded by the next client.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	c.cancel()
+	<-c.stopped
+	return nil
+}
+
+func (c *Client) run(ctx context.Context) {
+	defer close(c.stopped)
+	for {
+		c.mu.Lock()
+		if len(c.outbox) == 0 {
+			c.mu.Unlock()
+			select {
+			case <-c.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		o := c.outbox[0]
+		c.mu.Unlock()
+
+		err := c.forward(ctx, o)
+		var status *StatusError
+		rejected := errors.As(err, &status) && status.Permanent()
+		if err != nil && !rejected {
+			if ctx.Err() != nil {
+				return
+			}
+			c.cfg.onError(err)
+			if !sleep(ctx, c.cfg.retry) {
+				return
+			}
+			continue
+		}
+		if rejected {
+			c.cfg.onError(fmt.Errorf("relay: document %q rejected: %w", o.Document.ID, err))
+		}
+
+		c.mu.Lock()
+		c.outbox[0] = outgoing{}
+		c.outbox = c.outbox[1:]
+		if len(c.outbox) == 0 {
+			close(c.empty)
+		}
+		c.mu.Unlock()
+		if o.file != "" {
+			if rejected {
+				err = os.Rename(o.file, o.file+".rejected")
+			} else {
+				err = os.Remove(o.file)
+			}
+			if err != nil {
+				c.cfg.onError(err)
+			}
+		}
+	}
+}
+
+// forward posts the document to the relay, which ignores it if it already has the ID.
+func (c *Client) forward(ctx context.Context, o outgoing) error {
+	body, err := json.Marshal(o.Document)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	resp, err := c.cfg.do(ctx, http.MethodPost, documentsURL(c.base, o.Printer), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// store writes the document to the spool directory, the names of the files keep the order of the outbox.
+func (c *Client) store(o *outgoing) error {
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if c.seq < time.Now().UnixNano() {
+		c.seq = time.Now().UnixNano()
+	} else {
+		c.seq++
+	}
+	o.file = filepath.Join(c.cfg.spool, fmt.Sprintf("%020d.json", c.seq))
+
+	tmp := o.file + ".tmp"
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.file)
+}
+
+// load reads the documents left in the spool directory.
+func (c *Client) load() error {
+	if c.cfg.spool == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.cfg.spool, 0700); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(c.cfg.spool, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		var o outgoing
+		if err = json.Unmarshal(data, &o); err != nil {
+			return fmt.Errorf("relay: %s: %w", filepath.Base(name), err)
+		}
+		o.file = name
+		c.outbox = append(c.outbox, o)
+	}
+	return nil
+}
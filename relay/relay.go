@@ -0,0 +1,179 @@
+// Package relay ships the rendered documents to the printers behind a cloud relay,
+// the usual architecture of the receipt printing of online orders: the ordering service sends the documents
+// to the relay with a Client, and an Agent running next to the printer receives them and prints them with a print queue.
+//
+// The client stores the documents and forwards them once the relay is reachable, and the relay keeps them
+// until the agent acknowledges that they have been printed, so the documents survive the outages of both networks.
+// The relay speaks HTTPS with JSON bodies, authenticated with a bearer token (see WithToken):
+//
+//	POST   /printers/{printer}/documents         stores the document in the body, repeating its ID is ignored
+//	GET    /printers/{printer}/documents?wait=30 returns the pending documents, waiting up to 30 seconds for one
+//	DELETE /printers/{printer}/documents/{id}    acknowledges that the document has been printed
+//
+// Server implements the relay with the documents kept in memory.
+//
+// Example Usage:
+//
+//	// In the ordering service.
+//	client, err := relay.NewClient("https://relay.example.com", relay.WithToken(token), relay.WithSpool("/var/spool/orders"))
+//	err = client.Send("store-7", relay.Document{ID: "order-42", Data: data})
+//
+//	// Next to the printer.
+//	agent := relay.NewAgent("https://relay.example.com", "store-7", queue.New(conn), relay.WithToken(token))
+//	err = agent.Run(ctx)
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrClosed is returned by the methods of a closed client.
+	ErrClosed = errors.New("relay: closed")
+
+	// ErrNoID is returned by Client.Send for a document without an ID, which the relay rejects.
+	ErrNoID = errors.New("relay: document without ID")
+)
+
+// StatusError is returned for the requests the relay answers with a status other than 2xx.
+type StatusError struct {
+	Method string
+	URL    string
+
+	// Status is the status line of the response, such as "400 Bad Request", Code its code.
+	Status string
+	Code   int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("relay: %s %s: %s", e.Method, e.URL, e.Status)
+}
+
+// Permanent reports whether repeating the request can't succeed, the relay rejected it with a 4xx status
+// other than 408 Request Timeout and 429 Too Many Requests.
+func (e *StatusError) Permanent() bool {
+	return e.Code/100 == 4 && e.Code != http.StatusRequestTimeout && e.Code != http.StatusTooManyRequests
+}
+
+// Document is a rendered document, the data is sent to the printer as it is.
+type Document struct {
+	// ID identifies the document, it must be unique for the printer, e.g. the number of the order.
+	ID string `json:"id"`
+
+	Data []byte            `json:"data"`
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// config holds the settings of the clients and the agents.
+type config struct {
+	http    *http.Client
+	token   string
+	retry   time.Duration
+	wait    time.Duration
+	spool   string
+	onError func(error)
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{http: http.DefaultClient, retry: 5 * time.Second, wait: 30 * time.Second, onError: func(error) {}}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	return cfg
+}
+
+// Option customizes the client or the agent.
+type Option interface {
+	apply(*config)
+}
+
+type optionFunc func(*config)
+
+func (fn optionFunc) apply(cfg *config) {
+	fn(cfg)
+}
+
+// WithHTTPClient sends the requests with the client, by default http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return optionFunc(func(cfg *config) { cfg.http = c })
+}
+
+// WithToken authenticates the requests with the bearer token.
+func WithToken(token string) Option {
+	return optionFunc(func(cfg *config) { cfg.token = token })
+}
+
+// WithRetryInterval sets the delay before a failed request is repeated, by default 5 seconds.
+func WithRetryInterval(d time.Duration) Option {
+	return optionFunc(func(cfg *config) { cfg.retry = d })
+}
+
+// WithPollWait sets how long the agent waits for a document in each request, by default 30 seconds.
+func WithPollWait(d time.Duration) Option {
+	return optionFunc(func(cfg *config) { cfg.wait = d })
+}
+
+// WithSpool stores the documents of the client in the directory until they are forwarded,
+// so they survive the restarts of the application. By default, they are kept in memory.
+func WithSpool(dir string) Option {
+	return optionFunc(func(cfg *config) { cfg.spool = dir })
+}
+
+// WithErrorHandler calls fn with the errors of the background forwarding and printing,
+// which are retried after the retry interval, unless the relay rejected the document permanently.
+func WithErrorHandler(fn func(error)) Option {
+	return optionFunc(func(cfg *config) { cfg.onError = fn })
+}
+
+// documentsURL returns the URL of the documents of the printer, followed by the path elements.
+func documentsURL(base, printer string, elem ...string) string {
+	u := strings.TrimSuffix(base, "/") + "/printers/" + url.PathEscape(printer) + "/documents"
+	for _, e := range elem {
+		u += "/" + url.PathEscape(e)
+	}
+	return u
+}
+
+// do sends the request and returns the response if its status is 2xx.
+func (cfg *config) do(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cfg.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.token)
+	}
+
+	resp, err := cfg.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, &StatusError{Method: method, URL: url, Status: resp.Status, Code: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// sleep waits for the duration and reports whether the context is still active.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,189 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gromey/thermalize/queue"
+)
+
+// printer records the data written to it.
+type printer struct {
+	mu   sync.Mutex
+	data bytes.Buffer
+}
+
+func (p *printer) Write(bs []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.data.Write(bs)
+}
+
+func (p *printer) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.data.String()
+}
+
+// eventually waits until cond holds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func flush(t *testing.T, c *Client) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+}
+
+func (s *Server) pendingIDs(printer string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for _, d := range s.mailbox(printer).docs {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+func TestRelay(t *testing.T) {
+	srv := NewServer()
+	srv.Authorize = func(r *http.Request, printer string) bool {
+		return r.Header.Get("Authorization") == "Bearer secret"
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, WithToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Send("store 7", Document{Data: []byte("no id")}); !errors.Is(err, ErrNoID) {
+		t.Errorf("Send without an ID: %v, want %v", err, ErrNoID)
+	}
+	for _, doc := range []Document{{ID: "order-1", Data: []byte("first;")}, {ID: "order-2", Data: []byte("second;")}} {
+		if err := client.Send("store 7", doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A repeated ID is ignored by the relay.
+	client.Send("store 7", Document{ID: "order-1", Data: []byte("repeated;")})
+	flush(t, client)
+	if got := srv.pendingIDs("store 7"); len(got) != 2 {
+		t.Errorf("the relay holds %v, want order-1 and order-2", got)
+	}
+
+	p := &printer{}
+	q := queue.New(p)
+	defer q.Close()
+	agent := NewAgent(ts.URL, "store 7", q, WithToken("secret"), WithPollWait(time.Second))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- agent.Run(ctx) }()
+
+	eventually(t, "the acknowledgements", func() bool { return len(srv.pendingIDs("store 7")) == 0 })
+	// The acknowledged documents repeated by a client are not printed again.
+	client.Send("store 7", Document{ID: "order-2", Data: []byte("repeated;")})
+	client.Send("store 7", Document{ID: "order-3", Data: []byte("third;")})
+	flush(t, client)
+	eventually(t, "the third document", func() bool { return len(srv.pendingIDs("store 7")) == 0 })
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run = %v, want %v", err, context.Canceled)
+	}
+	if got, want := p.String(), "first;second;third;"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestClientSpool(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "spool")
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	client, err := NewClient(down.URL, WithSpool(spool), WithRetryInterval(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Send("store 7", Document{ID: "order-1", Data: []byte("first;")})
+	client.Send("store 7", Document{ID: "order-2", Data: []byte("second;")})
+	client.Close()
+	if n := client.Pending(); n != 2 {
+		t.Errorf("Pending = %d, want 2", n)
+	}
+
+	// The next client forwards the documents left in the spool directory in order.
+	srv := NewServer()
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client, err = NewClient(ts.URL, WithSpool(spool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	flush(t, client)
+
+	if got := srv.pendingIDs("store 7"); len(got) != 2 || got[0] != "order-1" || got[1] != "order-2" {
+		t.Errorf("the relay holds %v, want order-1 and order-2", got)
+	}
+	if files, _ := filepath.Glob(filepath.Join(spool, "*")); len(files) != 0 {
+		t.Errorf("the forwarded documents are left in the spool directory: %v", files)
+	}
+}
+
+func TestClientRejected(t *testing.T) {
+	srv := NewServer()
+	srv.Authorize = func(r *http.Request, printer string) bool { return printer != "closed store" }
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	spool := t.TempDir()
+	var mu sync.Mutex
+	var errs []error
+	client, err := NewClient(ts.URL, WithSpool(spool), WithErrorHandler(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// The rejected document doesn't hold up the next one.
+	client.Send("closed store", Document{ID: "order-1"})
+	client.Send("store 7", Document{ID: "order-2"})
+	flush(t, client)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var status *StatusError
+	if len(errs) != 1 || !errors.As(errs[0], &status) || status.Code != http.StatusUnauthorized || !status.Permanent() {
+		t.Errorf("the errors are %v, want the rejection of order-1", errs)
+	}
+	if got := srv.pendingIDs("store 7"); len(got) != 1 {
+		t.Errorf("the relay holds %v, want order-2", got)
+	}
+	files, _ := filepath.Glob(filepath.Join(spool, "*.rejected"))
+	if len(files) != 1 {
+		t.Errorf("the rejected files are %v, want one", files)
+	}
+}
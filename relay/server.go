@@ -0,0 +1,165 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxWait limits the time a request of an agent waits for a document.
+const maxWait = 60 * time.Second
+
+// Server is the relay keeping the documents in memory until they are acknowledged, see the package documentation
+// for the protocol. The IDs of the acknowledged documents are remembered for a day, so the documents repeated
+// by the clients aren't printed again.
+//
+// Server is safe for concurrent use.
+type Server struct {
+	// Authorize reports whether the request may access the documents of the printer, e.g. by checking its bearer token.
+	// If it is nil, all requests are authorized.
+	Authorize func(r *http.Request, printer string) bool
+
+	mu       sync.Mutex
+	printers map[string]*mailbox
+}
+
+// mailbox holds the pending documents of a printer, ready is closed and replaced when a document arrives.
+type mailbox struct {
+	docs  []Document
+	acked map[string]time.Time
+	ready chan struct{}
+}
+
+// NewServer returns an empty relay.
+func NewServer() *Server {
+	return &Server{printers: make(map[string]*mailbox)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The path is /printers/{printer}/documents[/{id}].
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) < 3 || len(parts) > 4 || parts[0] != "printers" || parts[2] != "documents" {
+		http.NotFound(w, r)
+		return
+	}
+	printer, err := url.PathUnescape(parts[1])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if s.Authorize != nil && !s.Authorize(r, printer) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		s.store(w, r, printer)
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		s.pending(w, r, printer)
+	case len(parts) == 4 && r.Method == http.MethodDelete:
+		id, err := url.PathUnescape(parts[3])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.ack(printer, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// mailbox returns the mailbox of the printer, s.mu must be held.
+func (s *Server) mailbox(printer string) *mailbox {
+	m, ok := s.printers[printer]
+	if !ok {
+		m = &mailbox{acked: make(map[string]time.Time), ready: make(chan struct{})}
+		s.printers[printer] = m
+	}
+	return m
+}
+
+func (s *Server) store(w http.ResponseWriter, r *http.Request, printer string) {
+	var doc Document
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil || doc.ID == "" {
+		http.Error(w, "invalid document", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.mailbox(printer)
+	for id, t := range m.acked {
+		if time.Since(t) > 24*time.Hour {
+			delete(m.acked, id)
+		}
+	}
+	if _, ok := m.acked[doc.ID]; ok {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	for _, d := range m.docs {
+		if d.ID == doc.ID {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+	m.docs = append(m.docs, doc)
+	close(m.ready)
+	m.ready = make(chan struct{})
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) pending(w http.ResponseWriter, r *http.Request, printer string) {
+	wait, _ := strconv.Atoi(r.URL.Query().Get("wait"))
+	timer := time.NewTimer(minDuration(time.Duration(wait)*time.Second, maxWait))
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		m := s.mailbox(printer)
+		docs, ready := append([]Document(nil), m.docs...), m.ready
+		s.mu.Unlock()
+
+		if len(docs) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(docs)
+			return
+		}
+		select {
+		case <-ready:
+		case <-timer.C:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) ack(printer, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.mailbox(printer)
+	for i, d := range m.docs {
+		if d.ID == id {
+			m.docs = append(m.docs[:i], m.docs[i+1:]...)
+			break
+		}
+	}
+	m.acked[id] = time.Now()
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
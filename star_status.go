@@ -0,0 +1,49 @@
+package thermalize
+
+import "errors"
+
+// ErrStarStatus is returned by ParseStarStatus if the data isn't a complete automatic status.
+var ErrStarStatus = errors.New("thermalize: invalid star automatic status")
+
+// StarStatus is the automatic status of a StarPRNT printer, which is transmitted by the printer
+// when its state changes if ASB is enabled, and in reply to RequestLabelTaken.
+type StarStatus struct {
+	// Offline is set while the printer is offline, CoverOpen and PaperEnd are the usual causes.
+	Offline   bool
+	CoverOpen bool
+	PaperEnd  bool
+
+	// CutterError and MechanicalError are set after the errors that require the recovery of the printer.
+	CutterError     bool
+	MechanicalError bool
+
+	// Checkpoints is the ETB counter, the number of checkpoints reached modulo 32 (see DocumentMarker).
+	Checkpoints byte
+}
+
+// ParseStarStatus parses the automatic status at the beginning of bs and returns its length,
+// the rest of bs may hold the next status.
+//
+// The first byte of the status encodes its length in the bits 1-3 and 5, and the status bytes keep
+// the bits 0 and 4 off. The ETB counter is reported by the statuses of at least 9 bytes.
+func ParseStarStatus(bs []byte) (StarStatus, int, error) {
+	if len(bs) == 0 || bs[0]&0x91 != 0x01 {
+		return StarStatus{}, 0, ErrStarStatus
+	}
+	n := int(bs[0]>>2&0x08 | bs[0]>>1&0x07)
+	if n < 7 || len(bs) < n {
+		return StarStatus{}, 0, ErrStarStatus
+	}
+
+	s := StarStatus{
+		Offline:         bs[2]&0x08 != 0,
+		CoverOpen:       bs[2]&0x20 != 0,
+		CutterError:     bs[3]&0x08 != 0,
+		MechanicalError: bs[3]&0x20 != 0,
+		PaperEnd:        bs[5]&0x08 != 0,
+	}
+	if n >= 9 {
+		s.Checkpoints = bs[7]>>1&0x07 | bs[7]>>2&0x18
+	}
+	return s, n, nil
+}
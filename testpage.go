@@ -0,0 +1,160 @@
+package thermalize
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// testBarcodes holds the barcodes printed by TestPage with valid sample data.
+var testBarcodes = []struct {
+	name string
+	m    byte
+	data string
+}{
+	{"UPC-A", UpcA, "01234567890"},
+	{"UPC-E", UpcE, "0123456"},
+	{"EAN-8", JanEAN8, "1234567"},
+	{"EAN-13", JanEAN13, "123456789012"},
+	{"Code 39", Code39, "TEST39"},
+	{"Code 93", Code93, "TEST93"},
+	{"Code 128", Code128, "TEST128"},
+	{"ITF", ITF, "12345678"},
+	{"NW-7", NW7, "A12345B"},
+	{"GS1-128", GS1128, "0101234567890128"},
+	{"GS1 DataBar Omnidirectional", GS1Omnidirectional, "0123456789012"},
+	{"GS1 DataBar Truncated", GS1Truncated, "0123456789012"},
+	{"GS1 DataBar Limited", GS1Limited, "0123456789012"},
+	{"GS1 DataBar Expanded", GS1Expanded, "0101234567890128"},
+}
+
+// TestPage prints a calibration page for validating the settings of a printer, such as CPL, PPL and the gray level:
+// alignment markers, a ruler of the characters per line, a grid of the character sizes,
+// a sample of each barcode system, a QR code and a gray gradient strip, followed by a cut.
+//
+// The ruler must fill exactly one line and the gradient the full width of the paper,
+// the gradient turns black at the gray level set by GrayLevel or SetGrayLevel.
+func TestPage(cmd Cmd) {
+	cpl, ppl := cmd.CPL(), cmd.PPL()
+
+	cmd.Init()
+	cmd.Align(Center)
+	cmd.Bold(true)
+	cmd.CharSize(1, 1)
+	cmd.Text(Translate(cmd, MessageTestPage), nil)
+	cmd.LineFeed()
+	cmd.CharSize(0, 0)
+	cmd.Bold(false)
+	cmd.Text(fmt.Sprintf("CPL %d, PPL %d", cpl, ppl), nil)
+	cmd.LineFeed()
+	cmd.Align(Left)
+
+	testSection(cmd, Translate(cmd, MessageAlignment))
+	for _, a := range []struct {
+		align byte
+		text  string
+	}{
+		{Left, "|< " + Translate(cmd, MessageLeft)},
+		{Center, Translate(cmd, MessageCenter)},
+		{Right, Translate(cmd, MessageRight) + " >|"},
+	} {
+		cmd.Align(a.align)
+		cmd.Text(a.text, nil)
+		cmd.LineFeed()
+	}
+	cmd.Align(Left)
+	if t, ok := cmd.(TriLiner); ok {
+		t.TriLine("L", "C", "R")
+	}
+
+	testSection(cmd, Translate(cmd, MessageCharsPerLine))
+	for _, l := range testRuler(cpl) {
+		cmd.Text(l, nil)
+		cmd.LineFeed()
+	}
+
+	testSection(cmd, Translate(cmd, MessageCharSizes))
+	for n := byte(0); n < 4; n++ {
+		cmd.CharSize(n, n)
+		cmd.Text(fmt.Sprintf("%dx%d", n+1, n+1), nil)
+		cmd.LineFeed()
+	}
+	cmd.CharSize(0, 0)
+	cmd.Bold(true)
+	cmd.Text(Translate(cmd, MessageBold), nil)
+	cmd.Bold(false)
+	cmd.Text(" ", nil)
+	cmd.Underling(OneDotUnderling)
+	cmd.Text(Translate(cmd, MessageUnderlined), nil)
+	cmd.Underling(NoUnderling)
+	cmd.LineFeed()
+
+	testSection(cmd, Translate(cmd, MessageBarcodes))
+	cmd.HRIPosition(HRIBelow)
+	for _, b := range testBarcodes {
+		cmd.Text(b.name, nil)
+		cmd.LineFeed()
+		cmd.Barcode(b.m, b.data)
+		cmd.LineFeed()
+	}
+	cmd.Text(Translate(cmd, MessageQRCode), nil)
+	cmd.LineFeed()
+	cmd.QRCodeSize(4)
+	cmd.QRCode("TEST PAGE")
+	cmd.LineFeed()
+
+	testSection(cmd, Translate(cmd, MessageGrayGradient))
+	cmd.Image(testGradient(ppl, 48), false)
+	cmd.LineFeed()
+	cmd.Text(Translate(cmd, MessageGrayLevel, imageLevelOf(cmd)), nil)
+	cmd.LineFeed()
+
+	cmd.Text(strings.Repeat("=", maxByte(cpl, 0)), nil)
+	cmd.LineFeed()
+	cutWithFeed(cmd, false, 0)
+}
+
+// imageLevelOf returns the gray level of the images printed by the command set.
+func imageLevelOf(cmd Cmd) uint8 {
+	if c := skipperOf(cmd); c != nil {
+		return c.imageLevel()
+	}
+	return uint8(grayLevel.Load())
+}
+
+// testSection prints the title of a section of the test page.
+func testSection(cmd Cmd, title string) {
+	cmd.LineFeed()
+	cmd.Bold(true)
+	cmd.Text(title, nil)
+	cmd.Bold(false)
+	cmd.LineFeed()
+}
+
+// testRuler returns the lines of a ruler of n characters, the tens above the units.
+func testRuler(n int) []string {
+	var tens, units strings.Builder
+	for i := 1; i <= n; i++ {
+		if i%10 == 0 {
+			tens.WriteByte(byte('0' + i/10%10))
+		} else {
+			tens.WriteByte(' ')
+		}
+		units.WriteByte(byte('0' + i%10))
+	}
+	return []string{strings.TrimRight(tens.String(), " "), units.String()}
+}
+
+// testGradient returns a gray gradient strip of the width, from white on the left to black on the right.
+func testGradient(width, height int) image.Image {
+	img := image.NewGray(image.Rect(0, 0, maxByte(width, 1), height))
+	w := img.Bounds().Dx()
+	for x := 0; x < w; x++ {
+		y := uint8(255 - x*255/maxByte(w-1, 1))
+		for row := 0; row < height; row++ {
+			img.SetGray(x, row, color.Gray{Y: y})
+		}
+	}
+	return img
+}
@@ -0,0 +1,194 @@
+package thermalize
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Wrap splits the string into lines of at most width characters, breaking them between words.
+// Words longer than width are broken into several lines.
+func Wrap(s string, width int) []string {
+	if width <= 0 {
+		return []string{s}
+	}
+
+	var lines []string
+	var line strings.Builder
+	n := 0
+
+	for _, word := range strings.Fields(s) {
+		l := utf8.RuneCountInString(word)
+
+		if n > 0 && n+1+l > width {
+			lines = append(lines, line.String())
+			line.Reset()
+			n = 0
+		}
+
+		for l > width {
+			head, tail := splitRunes(word, width)
+			lines = append(lines, head)
+			word, l = tail, l-width
+		}
+
+		if n > 0 {
+			line.WriteByte(' ')
+			n++
+		}
+		line.WriteString(word)
+		n += l
+	}
+
+	if n > 0 || len(lines) == 0 {
+		lines = append(lines, line.String())
+	}
+
+	return lines
+}
+
+// textWrap adds the string wrapped by TextWrap, or wraps it to CPL and writes it line by line
+// if the command set doesn't implement TextFormatter.
+func textWrap(cmd Cmd, s string, enc func(string) []byte) {
+	if f, ok := cmd.(TextFormatter); ok {
+		f.TextWrap(s, enc)
+		return
+	}
+	writeLines(cmd, Wrap(s, cmd.CPL()), enc)
+}
+
+// writeLines adds each line followed by a line feed.
+func writeLines(cmd Cmd, lines []string, enc func(string) []byte) {
+	for _, l := range lines {
+		cmd.Text(l, enc)
+		cmd.LineFeed()
+	}
+}
+
+// splitRunes splits the string after n characters.
+func splitRunes(s string, n int) (string, string) {
+	for i := range s {
+		if n == 0 {
+			return s[:i], s[i:]
+		}
+		n--
+	}
+	return s, ""
+}
+
+// paragraph wraps the string into lines of at most width characters.
+// The lines are indented by indent spaces and the continuation lines by further hang spaces.
+// If justify is set, the spaces between the words are distributed so that all lines but the last
+// are flush on both margins.
+func paragraph(s string, width, indent, hang int, justify bool) []string {
+	indent = minByte(maxByte(indent, 0), width-1)
+	hang = minByte(maxByte(hang, 0), width-1-indent)
+	first := strings.Repeat(" ", maxByte(indent, 0))
+	rest := strings.Repeat(" ", maxByte(indent+hang, 0))
+
+	lines := Wrap(s, width-len(first))
+	if len(lines) > 1 {
+		lines = append(lines[:1], Wrap(strings.Join(lines[1:], " "), width-len(rest))...)
+	}
+
+	for i, l := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if justify && i < len(lines)-1 {
+			l = justifyLine(l, width-len(prefix))
+		}
+		lines[i] = prefix + l
+	}
+	return lines
+}
+
+// justifyLine widens the spaces between the words, so the line is width characters long.
+// The leftmost spaces get the extra space first.
+func justifyLine(s string, width int) string {
+	words := strings.Fields(s)
+	if len(words) < 2 {
+		return s
+	}
+
+	n := 0
+	for _, w := range words {
+		n += utf8.RuneCountInString(w)
+	}
+
+	gaps := len(words) - 1
+	spaces := width - n
+	if spaces <= gaps {
+		return s
+	}
+
+	var sb strings.Builder
+	for i, w := range words {
+		if i > 0 {
+			k := spaces / gaps
+			if i <= spaces%gaps {
+				k++
+			}
+			sb.WriteString(strings.Repeat(" ", k))
+		}
+		sb.WriteString(w)
+	}
+	return sb.String()
+}
+
+// triLine lays out the segments on a line of width characters, separated by at least one space.
+// It returns the segments, truncated as needed, and the columns of the center and right segments.
+// The center segment is centered on the line if it fits between the other segments, otherwise it is moved between them.
+func triLine(left, center, right string, width int) (l, m, r string, mcol, rcol int) {
+	r = truncateRunes(right, width)
+	rn := utf8.RuneCountInString(r)
+	end := width - rn
+	if rn > 0 {
+		end--
+	}
+
+	l = truncateRunes(left, end)
+	start := utf8.RuneCountInString(l)
+	if start > 0 {
+		start++
+	}
+
+	m = truncateRunes(center, end-start)
+	mn := utf8.RuneCountInString(m)
+	mcol = minByte(maxByte((width-mn)/2, start), end-mn)
+
+	return l, m, r, mcol, width - rn
+}
+
+// truncateRunes returns the first n characters of the string, without the trailing spaces left by the truncation.
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	head, tail := splitRunes(s, n)
+	if tail != "" {
+		head = strings.TrimRight(head, " ")
+	}
+	return head
+}
+
+// printTriLine prints the segments laid out by triLine, converting the columns to dots
+// by the ratio of pixels to characters per line.
+func printTriLine(cmd Cmd, left, center, right string) {
+	cpl, ppl := cmd.CPL(), cmd.PPL()
+	if cpl <= 0 {
+		return
+	}
+	l, m, r, mcol, rcol := triLine(left, center, right, cpl)
+
+	cmd.Text(l, nil)
+	if m != "" {
+		cmd.AbsolutePosition(mcol * ppl / cpl)
+		cmd.Text(m, nil)
+	}
+	if r != "" {
+		cmd.AbsolutePosition(rcol * ppl / cpl)
+		cmd.Text(r, nil)
+	}
+	cmd.LineFeed()
+}
@@ -0,0 +1,139 @@
+// Package thermalizetest provides utilities for testing receipts built with thermalize.
+package thermalizetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gromey/thermalize"
+)
+
+// Golden renders a receipt and compares it with the golden file testdata/<name>.golden.
+//
+// The receipt is built by fn on an escape command set with 48 characters per line and 576 pixels per line,
+// customized by opts. The resulting command stream is decoded with thermalize.DecodeEscape and normalized
+// to one command per line, so differences are reported as a readable diff of command sequences.
+//
+// If update is set, the golden file is created or updated instead, e.g. by a flag of the tests of the package,
+// which the helper doesn't register, so it doesn't add flags to the programs importing it.
+//
+// Example Usage:
+//
+//	var update = flag.Bool("update-golden", false, "update the golden files")
+//
+//	func TestReceipt(t *testing.T) {
+//		thermalizetest.Golden(t, "receipt", *update, func(cmd thermalize.Cmd) {
+//			cmd.Init()
+//			cmd.Text("Hello world!", nil)
+//			cmd.LineFeed()
+//			cmd.FullCut()
+//		})
+//	}
+func Golden(t testing.TB, name string, update bool, fn func(thermalize.Cmd), opts ...thermalize.Options) {
+	t.Helper()
+
+	got := Render(fn, opts...)
+	path := filepath.Join("testdata", name+".golden")
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("thermalizetest: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("thermalizetest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("thermalizetest: golden file %s does not exist, run the test with update set to create it", path)
+	} else if err != nil {
+		t.Fatalf("thermalizetest: %v", err)
+	}
+
+	if diff := Diff(string(want), got); diff != "" {
+		t.Errorf("thermalizetest: %s mismatch (-want +got):\n%s", path, diff)
+	}
+}
+
+// Render builds a receipt on an escape command set and returns its normalized representation,
+// one decoded command per line.
+func Render(fn func(thermalize.Cmd), opts ...thermalize.Options) string {
+	buf := new(bytes.Buffer)
+
+	cmd := thermalize.NewEscape(48, 576, buf, opts...)
+	fn(cmd)
+	cmd.(thermalize.Flusher).Flush()
+
+	return Normalize(buf.Bytes())
+}
+
+// Normalize decodes an ESC/POS command stream and returns one command per line.
+func Normalize(bs []byte) string {
+	var sb strings.Builder
+	for _, c := range thermalize.DecodeEscape(bs) {
+		sb.WriteString(c.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// Diff returns a line-oriented diff of want and got, or an empty string if they are equal.
+// Removed lines are prefixed with "-", added lines with "+" and unchanged lines with a space.
+// The lines are compared as commands by thermalize.DiffCommands.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+
+	a, b := splitLines(want), splitLines(got)
+
+	var sb strings.Builder
+	i, j := 0, 0
+	common := func(i2, j2 int) {
+		for i < i2 && j < j2 {
+			fmt.Fprintf(&sb, "  %s\n", a[i])
+			i++
+			j++
+		}
+	}
+	for _, c := range thermalize.DiffCommands(lineCommands(a), lineCommands(b)) {
+		switch c.Kind {
+		case thermalize.Removed:
+			common(c.OldIndex, len(b))
+			fmt.Fprintf(&sb, "- %s\n", a[c.OldIndex])
+			i++
+		case thermalize.Added:
+			common(len(a), c.NewIndex)
+			fmt.Fprintf(&sb, "+ %s\n", b[c.NewIndex])
+			j++
+		}
+	}
+	common(len(a), len(b))
+
+	return sb.String()
+}
+
+// lineCommands wraps the lines in commands, so the lines are compared as a whole.
+func lineCommands(lines []string) []thermalize.Command {
+	cmds := make([]thermalize.Command, len(lines))
+	for i, l := range lines {
+		cmds[i] = thermalize.Command{Name: l}
+	}
+	return cmds
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,97 @@
+package thermalizetest
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gromey/thermalize"
+)
+
+var update = flag.Bool("update-golden", false, "update the golden files")
+
+func receipt(cmd thermalize.Cmd) {
+	cmd.Init()
+	cmd.Align(thermalize.Center)
+	cmd.Bold(true)
+	cmd.Text("Hello world!", nil)
+	cmd.LineFeed()
+	cmd.Bold(false)
+	cmd.Align(thermalize.Left)
+	cmd.Text("Total", nil)
+	cmd.Tab()
+	cmd.Text("4.20", nil)
+	cmd.LineFeed()
+	cmd.FullCut()
+}
+
+func TestGolden(t *testing.T) {
+	Golden(t, "receipt", *update, receipt)
+}
+
+// recorder records the failures reported by Golden.
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+// Fatalf stops the goroutine of golden, as testing.T does.
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// golden runs Golden with a recorder and returns the reported failure.
+func golden(t *testing.T, name string, fn func(thermalize.Cmd)) string {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Golden(r, name, false, fn)
+	}()
+	<-done
+	return r.failure
+}
+
+func TestGoldenMismatch(t *testing.T) {
+	failure := golden(t, "receipt", func(cmd thermalize.Cmd) {
+		receipt(cmd)
+		cmd.Text("extra", nil)
+	})
+	if !strings.Contains(failure, `+ TEXT "extra"`) {
+		t.Errorf("failure = %q, want the added text", failure)
+	}
+
+	if failure = golden(t, "missing", receipt); !strings.Contains(failure, "does not exist") {
+		t.Errorf("failure = %q, want the missing golden file", failure)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		diff      string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"added", "a\nc\n", "a\nb\nc\n", "  a\n+ b\n  c\n"},
+		{"removed", "a\nb\nc\n", "a\nc\n", "  a\n- b\n  c\n"},
+		{"replaced", "a\nb\nc\n", "a\nx\nc\n", "  a\n- b\n+ x\n  c\n"},
+		{"empty", "", "a\n", "+ a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := Diff(tt.want, tt.got); diff != tt.diff {
+				t.Errorf("Diff() = %q, want %q", diff, tt.diff)
+			}
+		})
+	}
+}
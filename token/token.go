@@ -0,0 +1,287 @@
+// Package token prints queue-number (token) tickets.
+//
+// A ticket has a branding header, a huge queue number rendered as a raster image,
+// the estimated wait time and a QR code for the status page of the queue.
+package token
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+// ErrNoNumber is returned by Print if the ticket has no number.
+var ErrNoNumber = errors.New("token: no number to print")
+
+// Ticket is a queue-number ticket.
+type Ticket struct {
+	// Header is the branding to print above the number, such as the name of the shop.
+	// The lines are separated with "\n".
+	Header string
+
+	// Logo is printed above the header, if it is not nil.
+	Logo image.Image
+
+	// Number is the queue number. Digits, the letters A-F, H, L, P, U and the dash
+	// are drawn as seven-segment characters, other numbers are drawn with thermalize.TextImage.
+	Number string
+
+	// Wait is the estimated wait time, it is not printed if it is zero.
+	Wait time.Duration
+
+	// StatusURL is encoded into a QR code, if it is not empty.
+	StatusURL string
+
+	Issued time.Time
+	Footer string
+}
+
+// Option customizes the printed ticket.
+type Option interface {
+	apply(*config)
+}
+
+type config struct {
+	height     int
+	rotate     bool
+	timeLayout string
+	enc        thermalize.Encoder
+	cut        bool
+}
+
+type optionFunc func(*config)
+
+func (fn optionFunc) apply(c *config) {
+	fn(c)
+}
+
+// WithNumberHeight sets the height of the number in dots, by default 240 dots (3 cm at 203 dpi).
+// The height is reduced if the number does not fit the print width.
+func WithNumberHeight(dots int) Option {
+	return optionFunc(func(c *config) { c.height = dots })
+}
+
+// WithRotation prints the number rotated 90° clockwise, running along the paper,
+// so it can be taller than the print width.
+func WithRotation() Option {
+	return optionFunc(func(c *config) { c.rotate = true })
+}
+
+// WithTimeLayout sets the layout of the issue time, by default "02.01.2006 15:04".
+func WithTimeLayout(layout string) Option {
+	return optionFunc(func(c *config) { c.timeLayout = layout })
+}
+
+// WithEncoder sets the encoder of the ticket text.
+func WithEncoder(enc thermalize.Encoder) Option {
+	return optionFunc(func(c *config) { c.enc = enc })
+}
+
+// WithoutCut disables the cut at the end of the ticket.
+func WithoutCut() Option {
+	return optionFunc(func(c *config) { c.cut = false })
+}
+
+// Print prints the ticket and returns the error reported by the command set, if any.
+func Print(cmd thermalize.Cmd, t Ticket, opts ...Option) error {
+	if t.Number == "" {
+		return ErrNoNumber
+	}
+
+	cfg := config{height: 240, timeLayout: "02.01.2006 15:04", cut: true}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+
+	cmd.Init()
+	cmd.Align(thermalize.Center)
+
+	if t.Logo != nil {
+		cmd.Image(t.Logo, false)
+	}
+
+	if t.Header != "" {
+		cmd.Bold(true)
+		for _, l := range strings.Split(t.Header, "\n") {
+			line(cmd, l, cfg.enc)
+		}
+		cmd.Bold(false)
+		cmd.Feed(20)
+	}
+
+	number(cmd, t.Number, cfg)
+	cmd.Feed(20)
+
+	if t.Wait > 0 {
+		line(cmd, "Estimated wait: "+wait(t.Wait), cfg.enc)
+	}
+
+	if t.StatusURL != "" {
+		cmd.Feed(10)
+		cmd.QRCodeSize(6)
+		cmd.QRCodeCorrectionLevel(1)
+		cmd.QRCode(t.StatusURL)
+	}
+
+	if !t.Issued.IsZero() {
+		line(cmd, t.Issued.Format(cfg.timeLayout), cfg.enc)
+	}
+
+	if t.Footer != "" {
+		for _, l := range strings.Split(t.Footer, "\n") {
+			line(cmd, l, cfg.enc)
+		}
+	}
+
+	cmd.Feed(100)
+	if cfg.cut {
+		cmd.FullCut()
+	}
+	return cmd.Print()
+}
+
+func line(cmd thermalize.Cmd, s string, enc thermalize.Encoder) {
+	if enc != nil {
+		cmd.Text(s, enc.Encode)
+	} else {
+		cmd.Text(s, nil)
+	}
+	cmd.LineFeed()
+}
+
+// wait formats the wait time rounded up to minutes.
+func wait(d time.Duration) string {
+	m := int((d + time.Minute - 1) / time.Minute)
+	if m < 60 {
+		return "~" + strconv.Itoa(m) + " min"
+	}
+	return "~" + strconv.Itoa(m/60) + " h " + strconv.Itoa(m%60) + " min"
+}
+
+// number prints the number as a seven-segment raster image,
+// or as thermalize.TextImage if it has characters without a segment pattern.
+func number(cmd thermalize.Cmd, s string, cfg config) {
+	draw := SevenSegment
+	for _, r := range s {
+		if _, ok := segments[r]; !ok {
+			draw = thermalize.TextImage
+			break
+		}
+	}
+
+	img := draw(s, cfg.height)
+	if w := img.Bounds().Dx(); !cfg.rotate && w > cmd.PPL() {
+		img = draw(s, cfg.height*cmd.PPL()/w)
+	}
+	if h := img.Bounds().Dy(); cfg.rotate && h > cmd.PPL() {
+		img = draw(s, cmd.PPL())
+	}
+
+	if cfg.rotate {
+		img = thermalize.RotateImage(img, 90)
+	}
+	cmd.Image(img, false)
+}
+
+// Segment bits: a (top), b (top right), c (bottom right), d (bottom), e (bottom left), f (top left), g (middle).
+const (
+	segA = 1 << iota
+	segB
+	segC
+	segD
+	segE
+	segF
+	segG
+)
+
+var segments = map[rune]byte{
+	'0': segA | segB | segC | segD | segE | segF,
+	'1': segB | segC,
+	'2': segA | segB | segD | segE | segG,
+	'3': segA | segB | segC | segD | segG,
+	'4': segB | segC | segF | segG,
+	'5': segA | segC | segD | segF | segG,
+	'6': segA | segC | segD | segE | segF | segG,
+	'7': segA | segB | segC,
+	'8': segA | segB | segC | segD | segE | segF | segG,
+	'9': segA | segB | segC | segD | segF | segG,
+	'A': segA | segB | segC | segE | segF | segG,
+	'B': segC | segD | segE | segF | segG,
+	'C': segA | segD | segE | segF,
+	'D': segB | segC | segD | segE | segG,
+	'E': segA | segD | segE | segF | segG,
+	'F': segA | segE | segF | segG,
+	'H': segB | segC | segE | segF | segG,
+	'L': segD | segE | segF,
+	'P': segA | segB | segE | segF | segG,
+	'U': segB | segC | segD | segE | segF,
+	'-': segG,
+	' ': 0,
+}
+
+// SevenSegment draws the string as black seven-segment characters of the height in dots on a white background.
+// Characters without a segment pattern are drawn as blanks.
+func SevenSegment(s string, height int) image.Image {
+	n := len([]rune(s))
+	w := height / 2
+	gap := height / 10
+	t := maxInt(height/10, 1)
+
+	img := image.NewGray(image.Rect(0, 0, maxInt(n*(w+gap)-gap, 1), maxInt(height, 1)))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	black := color.Gray{}
+	fill := func(x0, y0, x1, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				img.SetGray(x, y, black)
+			}
+		}
+	}
+
+	mid := height / 2
+	i := 0
+	for _, r := range s {
+		x := i * (w + gap)
+		i++
+
+		seg := segments[r]
+		if seg&segA != 0 {
+			fill(x, 0, x+w, t)
+		}
+		if seg&segB != 0 {
+			fill(x+w-t, 0, x+w, mid+t/2)
+		}
+		if seg&segC != 0 {
+			fill(x+w-t, mid-t/2, x+w, height)
+		}
+		if seg&segD != 0 {
+			fill(x, height-t, x+w, height)
+		}
+		if seg&segE != 0 {
+			fill(x, mid-t/2, x+t, height)
+		}
+		if seg&segF != 0 {
+			fill(x, 0, x+t, mid+t/2)
+		}
+		if seg&segG != 0 {
+			fill(x, mid-t/2, x+w, mid-t/2+t)
+		}
+	}
+
+	return img
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,131 @@
+package token
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+func TestWait(t *testing.T) {
+	for _, tc := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "~1 min"},
+		{5 * time.Minute, "~5 min"},
+		{5*time.Minute + time.Second, "~6 min"},
+		{61 * time.Minute, "~1 h 1 min"},
+		{2 * time.Hour, "~2 h 0 min"},
+	} {
+		if got := wait(tc.d); got != tc.want {
+			t.Errorf("wait(%v) = %q, want %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestSevenSegment(t *testing.T) {
+	img := SevenSegment("18", 100)
+	// The characters are 50 dots wide with a gap of 10 dots.
+	if b := img.Bounds(); b.Dx() != 110 || b.Dy() != 100 {
+		t.Fatalf("the bounds are %v, want 110 x 100", b)
+	}
+	black := func(x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r == 0
+	}
+	for _, tc := range []struct {
+		name  string
+		x, y  int
+		black bool
+	}{
+		{"the top right segment of 1", 47, 20, true},
+		{"the bottom right segment of 1", 47, 80, true},
+		{"the top of 1", 20, 2, false},
+		{"the middle of 1", 20, 50, false},
+		{"the gap", 55, 50, false},
+		{"the top of 8", 80, 2, true},
+		{"the middle of 8", 80, 50, true},
+		{"the bottom left segment of 8", 62, 80, true},
+	} {
+		if got := black(tc.x, tc.y); got != tc.black {
+			t.Errorf("%s at %d, %d is black: %v, want %v", tc.name, tc.x, tc.y, got, tc.black)
+		}
+	}
+}
+
+// numberImage prints the ticket on a document of 384 dots and returns the image of the number.
+func numberImage(t *testing.T, tk Ticket, opts ...Option) image.Image {
+	t.Helper()
+	doc := thermalize.NewDocument(32, 384)
+	if err := Print(doc, tk, opts...); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range doc.Ops() {
+		if op.Name == "Image" {
+			return op.Args[0].(image.Image)
+		}
+	}
+	t.Fatal("the number isn't printed as an image")
+	return nil
+}
+
+func TestPrintNumber(t *testing.T) {
+	if b := numberImage(t, Ticket{Number: "12"}).Bounds(); b.Dy() != 240 || b.Dx() > 384 {
+		t.Errorf("the number is %v, want 240 dots high within 384 dots", b)
+	}
+	// The number wider than the print width is reduced.
+	if b := numberImage(t, Ticket{Number: "A1234"}).Bounds(); b.Dx() > 384 || b.Dy() >= 240 {
+		t.Errorf("the wide number is %v, want it reduced to 384 dots", b)
+	}
+	// The rotated number runs along the paper.
+	if b := numberImage(t, Ticket{Number: "A1234"}, WithRotation()).Bounds(); b.Dx() != 240 || b.Dy() <= 384 {
+		t.Errorf("the rotated number is %v, want 240 dots wide", b)
+	}
+
+	if err := Print(thermalize.NewDocument(32, 384), Ticket{}); !errors.Is(err, ErrNoNumber) {
+		t.Errorf("Print without a number = %v, want %v", err, ErrNoNumber)
+	}
+}
+
+func TestPrintTicket(t *testing.T) {
+	doc := thermalize.NewDocument(32, 384)
+	err := Print(doc, Ticket{
+		Header:    "Coffee Corner\nMain Street",
+		Number:    "42",
+		Wait:      90 * time.Second,
+		StatusURL: "https://example.com/q/42",
+		Issued:    time.Date(2024, 5, 1, 9, 15, 0, 0, time.UTC),
+	}, WithoutCut())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+	qr, cut := "", false
+	for _, op := range doc.Ops() {
+		switch op.Name {
+		case "Text":
+			texts = append(texts, op.Args[0].(string))
+		case "QRCode":
+			qr = op.Args[0].(string)
+		case "FullCut":
+			cut = true
+		}
+	}
+	want := []string{"Coffee Corner", "Main Street", "Estimated wait: ~2 min", "01.05.2024 09:15"}
+	if len(texts) != len(want) {
+		t.Fatalf("the texts are %q, want %q", texts, want)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Errorf("the texts are %q, want %q", texts, want)
+			break
+		}
+	}
+	if qr != "https://example.com/q/42" || cut {
+		t.Errorf("the QR code is %q and the ticket is cut: %v", qr, cut)
+	}
+}
@@ -0,0 +1,170 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/gromey/thermalize"
+)
+
+// ErrNoReader is returned by Read if the wrapped connection can't be read.
+var ErrNoReader = errors.New("transport: connection can't be read")
+
+// Realtime wraps the connection to an ESC/POS printer and sends real-time commands, such as the status request,
+// on demand while a large job is being transferred. The job is written in chunks, and the real-time commands
+// sent in the meantime are injected between the chunks, at the boundaries of the commands of the job,
+// so they never end up in the parameters or the data of another command, such as an image.
+//
+// The boundaries are found by decoding the job (see thermalize.EscapeBoundaries), a command cut off
+// at the end of a Write is continued by the next one, and the queued commands wait until it is complete.
+//
+// Realtime is safe for concurrent use.
+type Realtime struct {
+	w     io.Writer
+	chunk int
+
+	// mu is held while writing, tail is the written part of a command continued by the next Write.
+	mu   sync.Mutex
+	tail []byte
+
+	qmu   sync.Mutex
+	queue [][]byte
+}
+
+// NewRealtime wraps the connection, writing the jobs in chunks of about chunk bytes, 4096 by default.
+func NewRealtime(w io.Writer, chunk int) *Realtime {
+	if chunk <= 0 {
+		chunk = 4096
+	}
+	return &Realtime{w: w, chunk: chunk}
+}
+
+// Write writes the job in chunks, sending the queued real-time commands between them.
+func (r *Realtime) Write(p []byte) (int, error) {
+	r.mu.Lock()
+
+	buf := append(r.tail, p...)
+	start := len(r.tail)
+	pos, end := start, 0
+
+	for _, offset := range thermalize.EscapeBoundaries(buf) {
+		end = offset
+		if end-pos < r.chunk {
+			continue
+		}
+		if err := r.write(buf[pos:end]); err != nil {
+			r.mu.Unlock()
+			return pos - start, err
+		}
+		pos = end
+		if err := r.flushQueue(); err != nil {
+			r.mu.Unlock()
+			return pos - start, err
+		}
+	}
+
+	if err := r.write(buf[pos:]); err != nil {
+		r.mu.Unlock()
+		return pos - start, err
+	}
+	r.tail = append(r.tail[:0], buf[end:]...)
+
+	return len(p), r.release()
+}
+
+// Send sends the real-time command at once if no job is being written,
+// otherwise it is queued and sent at the next boundary of the commands of the job.
+// The errors of the queued commands are returned by the Write that sends them.
+func (r *Realtime) Send(cmd []byte) error {
+	r.qmu.Lock()
+	r.queue = append(r.queue, append([]byte(nil), cmd...))
+	r.qmu.Unlock()
+
+	if !r.mu.TryLock() {
+		return nil
+	}
+	return r.release()
+}
+
+// RequestStatus sends the real-time status request [DLE EOT n], the reply must be read with Read.
+func (r *Realtime) RequestStatus(n byte) error {
+	return r.Send([]byte{thermalize.DLE, thermalize.EOT, n})
+}
+
+// Recover sends the real-time request [DLE ENQ n] recovering the printer from a recoverable error,
+// the data received before the error is cleared if clear is set.
+func (r *Realtime) Recover(clear bool) error {
+	n := byte(1)
+	if clear {
+		n = 2
+	}
+	return r.Send([]byte{thermalize.DLE, thermalize.ENQ, n})
+}
+
+// Pulse sends the real-time pulse [DLE DC4 1 m t] to the cash drawer pin m (thermalize.DrawerPin2 or DrawerPin5)
+// for t × 100 ms.
+func (r *Realtime) Pulse(m, t byte) error {
+	return r.Send([]byte{thermalize.DLE, thermalize.DC4, 1, m, t})
+}
+
+// Read reads the data transmitted by the printer, such as the status, if the wrapped connection can be read.
+func (r *Realtime) Read(p []byte) (int, error) {
+	if rd, ok := r.w.(io.Reader); ok {
+		return rd.Read(p)
+	}
+	return 0, ErrNoReader
+}
+
+// release is called with mu held, sends the queued commands if the job isn't cut off in a command, and releases mu.
+// A command queued after the queue has been sent is sent by its Send, which acquires mu once it is released.
+func (r *Realtime) release() error {
+	for {
+		var err error
+		mid := len(r.tail) > 0
+		if !mid {
+			err = r.flushQueue()
+		}
+		r.mu.Unlock()
+
+		if err != nil || mid || r.queued() == 0 || !r.mu.TryLock() {
+			return err
+		}
+	}
+}
+
+// flushQueue is called with mu held and sends the queued commands.
+func (r *Realtime) flushQueue() error {
+	r.qmu.Lock()
+	queue := r.queue
+	r.queue = nil
+	r.qmu.Unlock()
+
+	for _, cmd := range queue {
+		if err := r.write(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Realtime) queued() int {
+	r.qmu.Lock()
+	defer r.qmu.Unlock()
+	return len(r.queue)
+}
+
+// write writes the data in chunks, commands longer than a chunk are split without sending the queued commands.
+func (r *Realtime) write(p []byte) error {
+	for len(p) > 0 {
+		n := len(p)
+		if n > r.chunk {
+			n = r.chunk
+		}
+		if _, err := r.w.Write(p[:n]); err != nil {
+			return err
+		}
+		p = p[n:]
+	}
+	return nil
+}
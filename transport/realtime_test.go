@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gromey/thermalize"
+)
+
+// recorder records the writes, hook is called after each of them.
+type recorder struct {
+	data   bytes.Buffer
+	writes int
+	hook   func(n int)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.data.Write(p)
+	r.writes++
+	if r.hook != nil {
+		r.hook(r.writes)
+	}
+	return len(p), nil
+}
+
+var statusRequest = []byte{thermalize.DLE, thermalize.EOT, 1}
+
+// rasterJob is a line of text, a raster image of 32 bytes and another line of text.
+func rasterJob() []byte {
+	job := []byte("first line\n")
+	job = append(job, thermalize.GS, 'v', '0', 0, 2, 0, 16, 0)
+	job = append(job, bytes.Repeat([]byte{thermalize.DLE}, 32)...)
+	return append(job, "second line\n"...)
+}
+
+// injected returns the position of the real-time command injected into the job, or -1 if it isn't at a boundary.
+func injected(job, got, cmd []byte) int {
+	for _, b := range append([]int{0}, thermalize.EscapeBoundaries(job)...) {
+		want := append(append(append([]byte(nil), job[:b]...), cmd...), job[b:]...)
+		if bytes.Equal(got, want) {
+			return b
+		}
+	}
+	return -1
+}
+
+func TestRealtimeIdle(t *testing.T) {
+	w := &recorder{}
+	r := NewRealtime(w, 8)
+	if err := r.RequestStatus(1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.data.Bytes(), statusRequest) {
+		t.Errorf("wrote % x, want % x", w.data.Bytes(), statusRequest)
+	}
+}
+
+func TestRealtimeBetweenCommands(t *testing.T) {
+	job := rasterJob()
+	w := &recorder{}
+	r := NewRealtime(w, 8)
+	w.hook = func(n int) {
+		// The command is sent while the job is being written, in the middle of the first line.
+		if n == 1 {
+			r.Pulse(thermalize.DrawerPin2, 1)
+		}
+	}
+	if n, err := r.Write(job); n != len(job) || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+
+	pulse := []byte{thermalize.DLE, thermalize.DC4, 1, thermalize.DrawerPin2, 1}
+	if b := injected(job, w.data.Bytes(), pulse); b < 8 || b > 11 {
+		t.Errorf("the pulse is injected at %d, want the end of the first line\n% x", b, w.data.Bytes())
+	}
+}
+
+func TestRealtimeCutOff(t *testing.T) {
+	job := rasterJob()
+	w := &recorder{}
+	r := NewRealtime(w, 8)
+
+	// The job is cut off in the data of the image, the request waits until its end.
+	cut := 30
+	r.Write(job[:cut])
+	r.RequestStatus(1)
+	if bytes.Contains(w.data.Bytes(), statusRequest) {
+		t.Fatal("the request is sent in the data of the image")
+	}
+	r.Write(job[cut:])
+
+	if b := injected(job, w.data.Bytes(), statusRequest); b != 51 {
+		t.Errorf("the request is injected at %d, want 51 after the image\n% x", b, w.data.Bytes())
+	}
+}
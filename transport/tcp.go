@@ -0,0 +1,274 @@
+// Package transport provides connections to network printers and writers controlling the data sent to them.
+package transport
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+// ErrClosed is returned by the methods of a closed connection.
+var ErrClosed = errors.New("transport: connection closed")
+
+// TCP is a connection to a printer listening on a raw TCP port, usually 9100.
+//
+// Embedded print servers often keep a connection open after the printer has been switched off
+// or the network has failed, so writes succeed until the send buffer fills up.
+// Ping detects such half-open connections with a status query and reconnects,
+// WithKeepAlive does it periodically, so the next job doesn't fail.
+//
+// TCP is safe for concurrent use, a ping never interleaves with a write or a read, and it never takes
+// the reply to a query of the application: a ping is skipped while a Read is waiting.
+type TCP struct {
+	addr     string
+	timeout  time.Duration
+	interval time.Duration
+	query    []byte
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+
+	// rmu is held while reading, last is the time of the last successful write or read in Unix nanoseconds.
+	rmu  sync.Mutex
+	last atomic.Int64
+
+	healthy atomic.Bool
+	done    chan struct{}
+}
+
+// Option customizes the connection.
+type Option interface {
+	apply(*TCP)
+}
+
+type optionFunc func(*TCP)
+
+func (fn optionFunc) apply(t *TCP) {
+	fn(t)
+}
+
+// WithTimeout sets the timeout of dialing, writing and waiting for the reply to a ping, by default 5 seconds.
+func WithTimeout(d time.Duration) Option {
+	return optionFunc(func(t *TCP) { t.timeout = d })
+}
+
+// WithKeepAlive pings the printer every interval while the connection is idle.
+func WithKeepAlive(interval time.Duration) Option {
+	return optionFunc(func(t *TCP) { t.interval = interval })
+}
+
+// WithStatusQuery sets the harmless query sent by Ping, which must make the printer reply.
+// By default, the ESC/POS real-time printer status request [DLE EOT 1] is sent,
+// star printers need the automatic status request [ESC ACK SOH].
+func WithStatusQuery(q []byte) Option {
+	return optionFunc(func(t *TCP) { t.query = q })
+}
+
+// DialTCP connects to the printer at the address, such as "192.168.1.100:9100".
+func DialTCP(addr string, opts ...Option) (*TCP, error) {
+	t := &TCP{
+		addr:    addr,
+		timeout: 5 * time.Second,
+		query:   []byte{thermalize.DLE, thermalize.EOT, 1},
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(t)
+	}
+
+	if err := t.connect(); err != nil {
+		return nil, err
+	}
+
+	if t.interval > 0 {
+		go t.keepAlive()
+	}
+
+	return t, nil
+}
+
+// Write writes the data to the printer, reconnecting first if the connection is not healthy.
+func (t *TCP) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.ready(); err != nil {
+		return 0, err
+	}
+
+	_ = t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+	n, err := t.conn.Write(p)
+	if err != nil {
+		t.drop()
+		return n, err
+	}
+	t.alive()
+	return n, nil
+}
+
+// Read reads the data transmitted by the printer, such as the status.
+func (t *TCP) Read(p []byte) (int, error) {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+
+	if conn == nil {
+		return 0, ErrClosed
+	}
+
+	t.rmu.Lock()
+	defer t.rmu.Unlock()
+	n, err := conn.Read(p)
+	if n > 0 {
+		t.alive()
+	}
+	return n, err
+}
+
+// SetReadDeadline sets the deadline of the reads, such as the reads of thermalize.WithCompletion.
+func (t *TCP) SetReadDeadline(d time.Time) error {
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+
+	if conn == nil {
+		return ErrClosed
+	}
+	return conn.SetReadDeadline(d)
+}
+
+// Ping sends the status query and waits for the reply.
+// If the printer doesn't reply in time, the connection is dropped and reestablished.
+// The ping is skipped while a Read is waiting, which would take the reply.
+func (t *TCP) Ping() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.ready(); err != nil {
+		return err
+	}
+	if !t.rmu.TryLock() {
+		return nil
+	}
+	defer t.rmu.Unlock()
+
+	err := t.ping()
+	if err == nil {
+		return nil
+	}
+
+	t.drop()
+	if err = t.dial(); err == nil {
+		err = t.ping()
+	}
+	if err != nil {
+		t.drop()
+	}
+	return err
+}
+
+// Healthy reports whether the last write, read or ping succeeded.
+func (t *TCP) Healthy() bool {
+	return t.healthy.Load()
+}
+
+// Close stops the keepalive and closes the connection.
+func (t *TCP) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return ErrClosed
+	}
+	t.closed = true
+	close(t.done)
+
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	t.healthy.Store(false)
+	return err
+}
+
+func (t *TCP) connect() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dial()
+}
+
+// ready is called with the lock held and reconnects if the connection has been dropped.
+func (t *TCP) ready() error {
+	if t.closed {
+		return ErrClosed
+	}
+	if t.conn == nil {
+		return t.dial()
+	}
+	return nil
+}
+
+func (t *TCP) dial() error {
+	d := net.Dialer{Timeout: t.timeout, KeepAlive: t.interval}
+	conn, err := d.Dial("tcp", t.addr)
+	if err != nil {
+		return err
+	}
+	t.conn = conn
+	t.alive()
+	return nil
+}
+
+func (t *TCP) ping() error {
+	deadline := time.Now().Add(t.timeout)
+	_ = t.conn.SetDeadline(deadline)
+	defer t.conn.SetDeadline(time.Time{})
+
+	if _, err := t.conn.Write(t.query); err != nil {
+		return err
+	}
+
+	var reply [64]byte
+	if _, err := t.conn.Read(reply[:]); err != nil {
+		return err
+	}
+	t.alive()
+	return nil
+}
+
+// alive marks the connection healthy after a successful write or read.
+func (t *TCP) alive() {
+	t.healthy.Store(true)
+	t.last.Store(time.Now().UnixNano())
+}
+
+// drop is called with the lock held and closes the broken connection.
+func (t *TCP) drop() {
+	t.healthy.Store(false)
+	if t.conn != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+}
+
+func (t *TCP) keepAlive() {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, t.last.Load())) >= t.interval {
+				_ = t.Ping()
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+// server is a printer listening on a TCP port, which replies to the status queries while reply is set.
+type server struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	data     bytes.Buffer
+	queries  int
+	accepted int
+	reply    bool
+}
+
+func listen(t *testing.T, reply bool) *server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{ln: ln, reply: reply}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.accepted++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *server) handle(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 256)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		// The queries are not split across the reads of the short test data.
+		q := []byte{thermalize.DLE, thermalize.EOT, 1}
+		s.mu.Lock()
+		queries := bytes.Count(buf[:n], q)
+		s.queries += queries
+		s.data.Write(bytes.ReplaceAll(buf[:n], q, nil))
+		reply := s.reply
+		s.mu.Unlock()
+		if reply && queries > 0 {
+			conn.Write(bytes.Repeat([]byte{0x16}, queries))
+		}
+	}
+}
+
+func (s *server) stats() (data string, queries, accepted int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.String(), s.queries, s.accepted
+}
+
+func TestTCPPing(t *testing.T) {
+	s := listen(t, true)
+	conn, err := DialTCP(s.ln.Addr().String(), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("receipt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Ping(); err != nil || !conn.Healthy() {
+		t.Errorf("Ping = %v, healthy %v", err, conn.Healthy())
+	}
+	if data, queries, accepted := s.stats(); data != "receipt" || queries != 1 || accepted != 1 {
+		t.Errorf("the printer received %q and %d queries on %d connections", data, queries, accepted)
+	}
+}
+
+func TestTCPPingReconnects(t *testing.T) {
+	s := listen(t, false)
+	conn, err := DialTCP(s.ln.Addr().String(), WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The half-open connection is dropped, and the ping fails on the new connection as well.
+	if err := conn.Ping(); err == nil || conn.Healthy() {
+		t.Errorf("Ping of the silent printer = %v, healthy %v", err, conn.Healthy())
+	}
+	if _, _, accepted := s.stats(); accepted != 2 {
+		t.Errorf("the ping connected %d times, want 2", accepted)
+	}
+
+	// The next write reconnects.
+	s.mu.Lock()
+	s.reply = true
+	s.mu.Unlock()
+	if _, err := conn.Write([]byte("receipt")); err != nil || !conn.Healthy() {
+		t.Errorf("Write after the failed ping = %v, healthy %v", err, conn.Healthy())
+	}
+	if err := conn.Ping(); err != nil {
+		t.Errorf("Ping of the connection reestablished = %v", err)
+	}
+}
+
+func TestTCPPingSkippedWhileReading(t *testing.T) {
+	s := listen(t, true)
+	conn, err := DialTCP(s.ln.Addr().String(), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := make(chan error)
+	go func() {
+		var b [1]byte
+		_, err := conn.Read(b[:])
+		read <- err
+	}()
+	// The ping doesn't take the reply awaited by the Read.
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if !conn.rmu.TryLock() {
+			break
+		}
+		conn.rmu.Unlock()
+	}
+	if err := conn.Ping(); err != nil {
+		t.Errorf("Ping while reading = %v", err)
+	}
+	if _, queries, _ := s.stats(); queries != 0 {
+		t.Errorf("the ping sent %d queries while a Read was waiting", queries)
+	}
+
+	conn.Close()
+	<-read
+	if _, err := conn.Write(nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Write after Close = %v, want %v", err, ErrClosed)
+	}
+	if err := conn.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Close twice = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestTCPKeepAlive(t *testing.T) {
+	s := listen(t, true)
+	conn, err := DialTCP(s.ln.Addr().String(), WithTimeout(time.Second), WithKeepAlive(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, queries, _ := s.stats(); queries > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the idle connection isn't pinged")
+		}
+	}
+}
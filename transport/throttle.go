@@ -0,0 +1,221 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+// ErrOffline is returned by the writes of Throttle if the printer stays offline, e.g. with the cover open.
+var ErrOffline = errors.New("transport: printer is offline")
+
+// Throttle limits the data sent to a printer with a small receive buffer, which corrupts large raster jobs
+// when they are sent faster than it prints them.
+//
+// Throttle writes the job in windows of bytes ending at the boundaries of the commands, each followed
+// by the real-time status request [DLE EOT 1]. The printer stops reading the connection while its receive buffer
+// is full, so the reply arrives once the window has been buffered, and the next window isn't sent before it.
+// An offline printer is polled, and a missing reply awaited, until the offline timeout (see WithOfflineTimeout).
+// If the connection can't be read, e.g. a serial port relying on the flow control of the operating system,
+// the windows are paced by the rate instead.
+//
+// Throttle is not safe for concurrent use.
+type Throttle struct {
+	w      io.Writer
+	r      io.Reader
+	window int
+
+	// rate limits the bytes sent per second if it is positive.
+	rate int
+
+	// timeout is how long an offline printer is polled before giving up.
+	timeout time.Duration
+
+	// tail is the written part of a command continued by the next Write.
+	tail []byte
+}
+
+// ThrottleOption customizes the throttle.
+type ThrottleOption interface {
+	apply(*Throttle)
+}
+
+type throttleOptionFunc func(*Throttle)
+
+func (fn throttleOptionFunc) apply(t *Throttle) {
+	fn(t)
+}
+
+// WithRate limits the data sent to bytes per second, the writes are paced even if the status is read.
+func WithRate(bytes int) ThrottleOption {
+	return throttleOptionFunc(func(t *Throttle) { t.rate = bytes })
+}
+
+// WithOfflineTimeout sets how long an offline printer is polled before the write fails with ErrOffline,
+// by default 30 seconds.
+func WithOfflineTimeout(d time.Duration) ThrottleOption {
+	return throttleOptionFunc(func(t *Throttle) { t.timeout = d })
+}
+
+// NewThrottle wraps the connection, sending the jobs in windows of window bytes, 1024 by default.
+// The status is read from the connection if it implements io.Reader, such as TCP.
+func NewThrottle(w io.Writer, window int, opts ...ThrottleOption) *Throttle {
+	if window <= 0 {
+		window = 1024
+	}
+	t := &Throttle{w: w, window: window, timeout: 30 * time.Second}
+	t.r, _ = w.(io.Reader)
+	for _, opt := range opts {
+		opt.apply(t)
+	}
+	return t
+}
+
+// Write writes the data in windows, waiting for the printer after each of them. The windows end at the boundaries
+// of the commands (see thermalize.EscapeBoundaries), so the status requests never end up in the parameters
+// or the data of another command, such as an image. A command longer than a window is written in several windows,
+// the status is requested after its end. A command cut off at the end of a Write is continued by the next one.
+func (t *Throttle) Write(p []byte) (int, error) {
+	buf := append(t.tail, p...)
+	start := len(t.tail)
+	pos, last := start, start
+
+	for _, offset := range thermalize.EscapeBoundaries(buf) {
+		if offset-pos > t.window && last > pos {
+			if n, err := t.send(buf[pos:last], true); err != nil {
+				return maxInt(pos+n-start, 0), err
+			}
+			pos = last
+		}
+		last = offset
+	}
+
+	if n, err := t.send(buf[pos:last], true); err != nil {
+		return maxInt(pos+n-start, 0), err
+	}
+	pos = last
+	if n, err := t.send(buf[pos:], false); err != nil {
+		return maxInt(pos+n-start, 0), err
+	}
+	t.tail = append(t.tail[:0], buf[last:]...)
+	return len(p), nil
+}
+
+// send writes the data in pieces of a window paced by the rate, then waits for the printer if wait is set
+// and the connection can be read. It returns the number of bytes written.
+func (t *Throttle) send(p []byte, wait bool) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > t.window {
+			n = t.window
+		}
+
+		start := time.Now()
+		m, err := t.w.Write(p[:n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+
+		if t.rate > 0 {
+			time.Sleep(time.Duration(n)*time.Second/time.Duration(t.rate) - time.Since(start))
+		}
+	}
+	if wait && written > 0 && t.r != nil {
+		return written, t.wait()
+	}
+	return written, nil
+}
+
+// Read reads the data transmitted by the printer, if the connection can be read.
+func (t *Throttle) Read(p []byte) (int, error) {
+	if t.r == nil {
+		return 0, ErrNoReader
+	}
+	return t.r.Read(p)
+}
+
+// wait requests the printer status until the printer is online.
+func (t *Throttle) wait() error {
+	deadline := time.Now().Add(t.timeout)
+	for {
+		online, err := t.status(deadline)
+		if err != nil || online {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return ErrOffline
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// deadliner is implemented by the connections supporting read deadlines, such as net.Conn.
+type deadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type statusReply struct {
+	online bool
+	err    error
+}
+
+// status requests the printer status [DLE EOT 1] and reports whether the printer is online.
+// The reply is awaited until the deadline, then ErrOffline is returned. A connection without read deadlines
+// is read by a goroutine, which keeps waiting for the reply after the deadline.
+func (t *Throttle) status(deadline time.Time) (bool, error) {
+	if _, err := t.w.Write([]byte{thermalize.DLE, thermalize.EOT, 1}); err != nil {
+		return false, err
+	}
+
+	if d, ok := t.r.(deadliner); ok && d.SetReadDeadline(deadline) == nil {
+		defer d.SetReadDeadline(time.Time{})
+		online, err := t.reply()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return false, ErrOffline
+		}
+		return online, err
+	}
+
+	replies := make(chan statusReply, 1)
+	go func() {
+		online, err := t.reply()
+		replies <- statusReply{online, err}
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case r := <-replies:
+		return r.online, r.err
+	case <-timer.C:
+		return false, ErrOffline
+	}
+}
+
+// reply reads the reply to the status request.
+func (t *Throttle) reply() (bool, error) {
+	var reply [1]byte
+	for {
+		if _, err := io.ReadFull(t.r, reply[:]); err != nil {
+			return false, err
+		}
+		// The printer status has the bits 1 and 4 on and the bits 0 and 7 off,
+		// other bytes are automatic status backs or replies to earlier requests.
+		if reply[0]&0x93 == 0x12 {
+			return reply[0]&0x08 == 0, nil
+		}
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gromey/thermalize"
+)
+
+// statusPrinter replies to the status requests, offline with the first offline replies, or never if silent is set.
+// windows holds the data written between the requests.
+type statusPrinter struct {
+	mu      sync.Mutex
+	windows [][]byte
+	window  []byte
+	offline int
+	silent  bool
+	replies chan byte
+}
+
+func newStatusPrinter() *statusPrinter {
+	return &statusPrinter{replies: make(chan byte, 16)}
+}
+
+func (p *statusPrinter) Write(bs []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !bytes.Equal(bs, statusRequest) {
+		p.window = append(p.window, bs...)
+		return len(bs), nil
+	}
+	if len(p.window) > 0 {
+		p.windows = append(p.windows, p.window)
+		p.window = nil
+	}
+	switch {
+	case p.silent:
+	case p.offline > 0:
+		p.offline--
+		p.replies <- 0x1A
+	default:
+		p.replies <- 0x12
+	}
+	return len(bs), nil
+}
+
+func (p *statusPrinter) Read(bs []byte) (int, error) {
+	bs[0] = <-p.replies
+	return 1, nil
+}
+
+// textJob is ten lines of text followed by a raster image of 32 bytes.
+func textJob() []byte {
+	job := bytes.Repeat([]byte("line\n"), 10)
+	job = append(job, thermalize.GS, 'v', '0', 0, 2, 0, 16, 0)
+	return append(job, bytes.Repeat([]byte{0xFF}, 32)...)
+}
+
+func TestThrottleWindows(t *testing.T) {
+	job := textJob()
+	p := newStatusPrinter()
+	w := NewThrottle(p, 12)
+
+	// The job is written in two parts, the second one cut off in the image.
+	if n, err := w.Write(job[:60]); n != 60 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if n, err := w.Write(job[60:]); n != len(job)-60 || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+
+	bounds := map[int]bool{}
+	for _, b := range thermalize.EscapeBoundaries(job) {
+		bounds[b] = true
+	}
+	var got []byte
+	for _, window := range p.windows {
+		got = append(got, window...)
+		if !bounds[len(got)] {
+			t.Errorf("the window % x doesn't end at a command boundary", window)
+		}
+		// Only the image is longer than a window.
+		if len(window) > 12 && len(got) != len(job) {
+			t.Errorf("the window % x exceeds 12 bytes", window)
+		}
+	}
+	if !bytes.Equal(got, job) || len(p.window) != 0 {
+		t.Errorf("wrote % x, want % x", append(got, p.window...), job)
+	}
+}
+
+func TestThrottleOffline(t *testing.T) {
+	p := newStatusPrinter()
+	p.offline = 2
+	w := NewThrottle(p, 12, WithOfflineTimeout(5*time.Second))
+	if _, err := w.Write(textJob()); err != nil {
+		t.Errorf("Write with the printer offline twice = %v", err)
+	}
+
+	p = newStatusPrinter()
+	p.offline = 16
+	w = NewThrottle(p, 12, WithOfflineTimeout(150*time.Millisecond))
+	if n, err := w.Write(textJob()); !errors.Is(err, ErrOffline) || n != 10 {
+		t.Errorf("Write with the printer offline = %d, %v, want the first window and %v", n, err, ErrOffline)
+	}
+
+	p = newStatusPrinter()
+	p.silent = true
+	w = NewThrottle(p, 12, WithOfflineTimeout(50*time.Millisecond))
+	if _, err := w.Write(textJob()); !errors.Is(err, ErrOffline) {
+		t.Errorf("Write with the printer silent = %v, want %v", err, ErrOffline)
+	}
+}
+
+// writeOnly hides the methods of the writer other than Write.
+type writeOnly struct {
+	w io.Writer
+}
+
+func (w writeOnly) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+func TestThrottleRate(t *testing.T) {
+	// The printer can't be read, like a serial port relying on the flow control.
+	var buf bytes.Buffer
+	w := NewThrottle(writeOnly{&buf}, 50, WithRate(1000))
+	start := time.Now()
+	if _, err := w.Write(bytes.Repeat([]byte("0123456789\n"), 10)); err != nil {
+		t.Fatal(err)
+	}
+	// The 110 bytes are paced by the rate instead of the requests.
+	if d := time.Since(start); d < 80*time.Millisecond {
+		t.Errorf("110 bytes are written in %v at 1000 bytes per second", d)
+	}
+	if bytes.Contains(buf.Bytes(), statusRequest) {
+		t.Error("the status is requested from a printer that can't be read")
+	}
+}
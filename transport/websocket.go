@@ -0,0 +1,307 @@
+package transport
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gromey/thermalize/queue"
+)
+
+// The opcodes of the WebSocket frames (RFC 6455).
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// The status codes of the close frames.
+const (
+	wsNormal   = 1000
+	wsProtocol = 1002
+	wsTooBig   = 1009
+)
+
+// wsGUID is appended to the key of the handshake to compute Sec-WebSocket-Accept.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	errProtocol = errors.New("transport: websocket protocol error")
+	errTooBig   = errors.New("transport: websocket message too big")
+)
+
+// WebSocket is an HTTP handler accepting the command streams of browser-based POS frontends over WebSocket
+// and printing them on the locally connected printer with the print queue.
+//
+// Each binary or text message is a job, the jobs of a connection are printed in order and never interleave
+// with the jobs of other connections. Once a job is done, the handler replies with a text message holding
+// the JSON object {"job": n, "ok": true} or {"job": n, "error": "..."}, where n counts the jobs of the connection from 1.
+//
+// Example Usage:
+//
+//	q := queue.New(conn)
+//	http.Handle("/print", transport.NewWebSocket(q, transport.WithAuthorize(checkSession)))
+type WebSocket struct {
+	queue *queue.Queue
+
+	authorize   func(r *http.Request) bool
+	maxSize     int64
+	idleTimeout time.Duration
+
+	conns atomic.Int64
+}
+
+// WebSocketOption customizes the WebSocket handler.
+type WebSocketOption interface {
+	apply(*WebSocket)
+}
+
+type webSocketOptionFunc func(*WebSocket)
+
+func (fn webSocketOptionFunc) apply(ws *WebSocket) {
+	fn(ws)
+}
+
+// WithAuthorize accepts a connection only if fn approves the handshake request, e.g. by checking its session cookie
+// or its Origin header, otherwise the handler replies with 403 Forbidden. By default, all connections are accepted.
+func WithAuthorize(fn func(r *http.Request) bool) WebSocketOption {
+	return webSocketOptionFunc(func(ws *WebSocket) { ws.authorize = fn })
+}
+
+// WithMaxMessageSize limits the jobs to n bytes, by default 4 MiB. The connection sending a larger job is closed.
+func WithMaxMessageSize(n int64) WebSocketOption {
+	return webSocketOptionFunc(func(ws *WebSocket) { ws.maxSize = n })
+}
+
+// WithIdleTimeout closes the connections receiving nothing for the duration, by default they are kept open.
+func WithIdleTimeout(d time.Duration) WebSocketOption {
+	return webSocketOptionFunc(func(ws *WebSocket) { ws.idleTimeout = d })
+}
+
+// NewWebSocket returns the handler printing the jobs with the queue.
+func NewWebSocket(q *queue.Queue, opts ...WebSocketOption) *WebSocket {
+	ws := &WebSocket{queue: q, maxSize: 4 << 20}
+	for _, opt := range opts {
+		opt.apply(ws)
+	}
+	return ws
+}
+
+func (ws *WebSocket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "websocket handshake expected", http.StatusBadRequest)
+		return
+	}
+	if ws.authorize != nil && !ws.authorize(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err = rw.Flush(); err != nil {
+		return
+	}
+
+	s := &wsSession{ws: ws, rw: rw, id: fmt.Sprintf("ws%d", ws.conns.Add(1))}
+	for {
+		if ws.idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(ws.idleTimeout))
+		}
+		if err = s.next(); err != nil {
+			code := wsNormal
+			if errors.Is(err, errProtocol) {
+				code = wsProtocol
+			} else if errors.Is(err, errTooBig) {
+				code = wsTooBig
+			}
+			s.close(code)
+			return
+		}
+	}
+}
+
+// wsSession is a connection of the WebSocket handler, msg is the fragmented message being received.
+type wsSession struct {
+	ws   *WebSocket
+	rw   *bufio.ReadWriter
+	id   string
+	jobs int
+
+	msg     []byte
+	receive bool
+}
+
+// next handles the next frame of the connection.
+func (s *wsSession) next() error {
+	fin, op, payload, err := s.readFrame()
+	if err != nil {
+		return err
+	}
+
+	switch op {
+	case wsPing:
+		return s.writeFrame(wsPong, payload)
+	case wsPong:
+		return nil
+	case wsClose:
+		return io.EOF
+	case wsText, wsBinary:
+		if s.receive {
+			return errProtocol
+		}
+		s.msg, s.receive = append(s.msg[:0], payload...), true
+	case wsContinuation:
+		if !s.receive {
+			return errProtocol
+		}
+		s.msg = append(s.msg, payload...)
+	default:
+		return errProtocol
+	}
+	if int64(len(s.msg)) > s.ws.maxSize {
+		return errTooBig
+	}
+	if !fin {
+		return nil
+	}
+
+	s.receive = false
+	s.jobs++
+	return s.print(append([]byte(nil), s.msg...))
+}
+
+// print prints the job and replies with its result.
+func (s *wsSession) print(data []byte) error {
+	reply := struct {
+		Job   int    `json:"job"`
+		OK    bool   `json:"ok,omitempty"`
+		Error string `json:"error,omitempty"`
+	}{Job: s.jobs}
+
+	job, err := s.ws.queue.Submit(fmt.Sprintf("%s-%d", s.id, s.jobs), data)
+	if err == nil {
+		<-job.Done()
+		err = job.Err()
+	}
+	if err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.OK = true
+	}
+
+	bs, err := json.Marshal(reply)
+	if err != nil {
+		return err
+	}
+	return s.writeFrame(wsText, bs)
+}
+
+// readFrame reads a frame sent by the client, which must be masked.
+func (s *wsSession) readFrame() (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(s.rw, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op := head[0]&0x80 != 0, head[0]&0x0F
+	if head[0]&0x70 != 0 || head[1]&0x80 == 0 {
+		return false, 0, nil, errProtocol
+	}
+
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(s.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(s.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= wsClose && (n > 125 || !fin) {
+		return false, 0, nil, errProtocol
+	}
+	if n > uint64(s.ws.maxSize) {
+		return false, 0, nil, errTooBig
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(s.rw, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(s.rw, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame writes an unmasked final frame.
+func (s *wsSession) writeFrame(op byte, payload []byte) error {
+	head := []byte{0x80 | op, 0}
+	switch n := len(payload); {
+	case n <= 125:
+		head[1] = byte(n)
+	case n <= 0xFFFF:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	s.rw.Write(head)
+	s.rw.Write(payload)
+	return s.rw.Flush()
+}
+
+// close sends the close frame with the status code.
+func (s *wsSession) close(code int) {
+	var payload [2]byte
+	binary.BigEndian.PutUint16(payload[:], uint16(code))
+	s.writeFrame(wsClose, payload[:])
+}
+
+// headerContains reports whether the comma separated values of the header contain the token, ignoring the case.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
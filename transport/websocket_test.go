@@ -0,0 +1,206 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gromey/thermalize/queue"
+)
+
+// lockedBuffer is a printer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// wsClient is the client side of a WebSocket connection.
+type wsClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// dialWebSocket opens a connection with the handshake example of RFC 6455 from the origin, if it isn't empty,
+// and returns the response to the handshake.
+func dialWebSocket(t *testing.T, url, origin string) (*wsClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, url+"/print", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsClient{conn: conn, r: r}, resp
+}
+
+// send sends a frame masked with the key, or unmasked if masked is not set.
+func (c *wsClient) send(fin bool, op byte, payload []byte, masked bool) {
+	head := []byte{op, byte(len(payload))}
+	if fin {
+		head[0] |= 0x80
+	}
+	if len(payload) > 125 {
+		head[1] = 126
+		head = append(head, byte(len(payload)>>8), byte(len(payload)))
+	}
+	data := append([]byte(nil), payload...)
+	if masked {
+		head[1] |= 0x80
+		mask := []byte{0x12, 0x34, 0x56, 0x78}
+		head = append(head, mask...)
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+	c.conn.Write(append(head, data...))
+}
+
+// receive reads an unmasked frame of less than 126 bytes.
+func (c *wsClient) receive(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(c.r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, head[1])
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		t.Fatal(err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+// reply reads the reply to a job.
+func (c *wsClient) reply(t *testing.T) (job int, ok bool) {
+	t.Helper()
+	op, payload := c.receive(t)
+	var r struct {
+		Job int  `json:"job"`
+		OK  bool `json:"ok"`
+	}
+	if err := json.Unmarshal(payload, &r); op != wsText || err != nil {
+		t.Fatalf("the reply is %x %q: %v", op, payload, err)
+	}
+	return r.Job, r.OK
+}
+
+func (c *wsClient) closeCode(t *testing.T) int {
+	t.Helper()
+	op, payload := c.receive(t)
+	if op != wsClose || len(payload) != 2 {
+		t.Fatalf("the frame %x %q isn't a close frame", op, payload)
+	}
+	return int(binary.BigEndian.Uint16(payload))
+}
+
+func TestWebSocketJobs(t *testing.T) {
+	p := &lockedBuffer{}
+	q := queue.New(p)
+	defer q.Close()
+	ts := httptest.NewServer(NewWebSocket(q))
+	defer ts.Close()
+
+	c, resp := dialWebSocket(t, ts.URL, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("the handshake status is %s", resp.Status)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	c.send(true, wsBinary, []byte("first;"), true)
+	if job, ok := c.reply(t); job != 1 || !ok {
+		t.Errorf("the reply to the first job is %d %v", job, ok)
+	}
+
+	// A ping between the fragments of a message is answered at once.
+	c.send(false, wsText, []byte("sec"), true)
+	c.send(true, wsPing, []byte("ping"), true)
+	if op, payload := c.receive(t); op != wsPong || string(payload) != "ping" {
+		t.Errorf("the reply to the ping is %x %q", op, payload)
+	}
+	c.send(true, wsContinuation, []byte(strings.Repeat("o", 200)+"nd;"), true)
+	if job, ok := c.reply(t); job != 2 || !ok {
+		t.Errorf("the reply to the fragmented job is %d %v", job, ok)
+	}
+
+	c.send(true, wsClose, nil, true)
+	if code := c.closeCode(t); code != wsNormal {
+		t.Errorf("the close code is %d, want %d", code, wsNormal)
+	}
+	if got, want := p.String(), "first;sec"+strings.Repeat("o", 200)+"nd;"; got != want {
+		t.Errorf("printed %q, want %q", got, want)
+	}
+}
+
+func TestWebSocketRejected(t *testing.T) {
+	q := queue.New(&lockedBuffer{})
+	defer q.Close()
+	ts := httptest.NewServer(NewWebSocket(q, WithMaxMessageSize(8), WithAuthorize(func(r *http.Request) bool {
+		return r.Header.Get("Origin") == ""
+	})))
+	defer ts.Close()
+
+	// The frames of the clients must be masked.
+	c, _ := dialWebSocket(t, ts.URL, "")
+	c.send(true, wsBinary, []byte("job"), false)
+	if code := c.closeCode(t); code != wsProtocol {
+		t.Errorf("the close code of the unmasked frame is %d, want %d", code, wsProtocol)
+	}
+
+	c, _ = dialWebSocket(t, ts.URL, "")
+	c.send(false, wsBinary, []byte("12345"), true)
+	c.send(true, wsContinuation, []byte("6789"), true)
+	if code := c.closeCode(t); code != wsTooBig {
+		t.Errorf("the close code of the large message is %d, want %d", code, wsTooBig)
+	}
+
+	if _, resp := dialWebSocket(t, ts.URL, "https://example.com"); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("the status of the unauthorized handshake is %s", resp.Status)
+	}
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("the status of a plain request is %s", resp.Status)
+	}
+}
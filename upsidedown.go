@@ -0,0 +1,126 @@
+package thermalize
+
+import "strings"
+
+// upsideDownStyles are the commands whose setting is carried to each line printed by PrintUpsideDown,
+// with the defaults assumed before the block.
+var upsideDownStyles = []struct {
+	name string
+	def  Op
+}{
+	{"Align", Op{Name: "Align", Args: []any{byte(Left)}, play: func(c Cmd) { c.Align(Left) }}},
+	{"Bold", Op{Name: "Bold", Args: []any{false}, play: func(c Cmd) { c.Bold(false) }}},
+	{"CharSize", Op{Name: "CharSize", Args: []any{byte(0), byte(0)}, play: func(c Cmd) { c.CharSize(0, 0) }}},
+	{"Underling", Op{Name: "Underling", Args: []any{byte(0)}, play: func(c Cmd) { c.Underling(0) }}},
+	{"ClockwiseRotation", Op{Name: "ClockwiseRotation", Args: []any{false}, play: func(c Cmd) { c.ClockwiseRotation(false) }}},
+	{"Justify", Op{Name: "Justify", Args: []any{false}, play: func(c Cmd) {
+		if c, ok := c.(TextFormatter); ok {
+			c.Justify(false)
+		}
+	}}},
+}
+
+// PrintUpsideDown prints the block built by fn upside down, so it reads correctly when the receipt is torn off
+// and turned around, e.g. a header printed at the end of the receipt.
+//
+// The block is recorded in a Document and replayed line by line in reverse order with the alignment mirrored,
+// each line with the styles in effect at its start. The styles set by the block, such as Bold and CharSize,
+// are assumed to be the defaults before it, and those in effect at its end are selected again after it.
+//
+// A line ends with the commands feeding the paper, such as LineFeed, Image or TextWrap, or with a text ending with a new line.
+// The lines wrapped by a single command, such as TextWrap and Paragraph, keep their order.
+//
+// Example Usage:
+//
+//	thermalize.PrintUpsideDown(cmd, func(cmd thermalize.Cmd) {
+//		cmd.Align(thermalize.Center)
+//		cmd.Text("THE CORNER SHOP", nil)
+//		cmd.LineFeed()
+//		cmd.Text("12 Main Street", nil)
+//		cmd.LineFeed()
+//	})
+func PrintUpsideDown(cmd Cmd, fn func(Cmd)) {
+	d := NewDocument(cmd.CPL(), cmd.PPL())
+	fn(d)
+
+	// Only the styles set by the block are carried, the others are left as they are.
+	styles := make(map[string]Op, len(upsideDownStyles))
+	for _, s := range upsideDownStyles {
+		for _, op := range d.ops {
+			if op.Name == s.name {
+				styles[s.name] = s.def
+				break
+			}
+		}
+	}
+	snapshot := func() []Op {
+		ops := make([]Op, 0, len(styles))
+		for _, s := range upsideDownStyles {
+			if op, ok := styles[s.name]; ok {
+				ops = append(ops, op)
+			}
+		}
+		return ops
+	}
+
+	type line struct {
+		styles, ops []Op
+	}
+	var lines []line
+	cur := line{styles: snapshot()}
+	for _, op := range d.ops {
+		cur.ops = append(cur.ops, op)
+		if _, ok := styles[op.Name]; ok {
+			styles[op.Name] = op
+		}
+		if endsLine(op) {
+			lines = append(lines, cur)
+			cur = line{styles: snapshot()}
+		}
+	}
+	if len(cur.ops) > 0 {
+		lines = append(lines, cur)
+	}
+
+	cmd.UpsideDown(true)
+	for i := len(lines) - 1; i >= 0; i-- {
+		for _, op := range lines[i].styles {
+			playMirrored(cmd, op)
+		}
+		for _, op := range lines[i].ops {
+			playMirrored(cmd, op)
+		}
+	}
+	cmd.UpsideDown(false)
+	for _, op := range snapshot() {
+		op.play(cmd)
+	}
+}
+
+// endsLine reports whether the command ends a line of the block printed by PrintUpsideDown.
+func endsLine(op Op) bool {
+	switch op.Name {
+	case "LineFeed", "Feed", "Image", "Barcode", "QRCode", "TextWrap", "Paragraph", "List", "TriLine":
+		return true
+	case "Text":
+		s, _ := op.Args[0].(string)
+		return strings.HasSuffix(s, "\n")
+	}
+	return false
+}
+
+// playMirrored plays the command with the alignment mirrored, Left and Right are swapped.
+func playMirrored(cmd Cmd, op Op) {
+	if op.Name != "Align" {
+		op.play(cmd)
+		return
+	}
+	switch b, _ := op.Args[0].(byte); b {
+	case Left:
+		cmd.Align(Right)
+	case Right:
+		cmd.Align(Left)
+	default:
+		cmd.Align(b)
+	}
+}
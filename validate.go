@@ -0,0 +1,101 @@
+package thermalize
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes a command parameter rejected in strict mode.
+type ValidationError struct {
+	Command string
+	Reason  string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("thermalize: invalid %s: %s", e.Command, e.Reason)
+}
+
+// cmdErr returns the error recorded by the command set, if it implements ErrorReporter as all command sets do.
+func cmdErr(cmd Cmd) error {
+	if r, ok := cmd.(ErrorReporter); ok {
+		return r.Err()
+	}
+	return nil
+}
+
+// validateBarcode returns the reason why the data can't be encoded with the barcode system m,
+// or an empty string if the data is valid.
+func validateBarcode(m byte, s string) string {
+	l := len(s)
+
+	switch m {
+	case UpcA:
+		return checkDigits(s, l == 11 || l == 12, "11 or 12 digits")
+	case UpcE:
+		return checkDigits(s, (l >= 6 && l <= 8) || l == 11 || l == 12, "6, 7, 8, 11 or 12 digits")
+	case JanEAN8:
+		return checkDigits(s, l == 7 || l == 8, "7 or 8 digits")
+	case JanEAN13:
+		return checkDigits(s, l == 12 || l == 13, "12 or 13 digits")
+	case Code39:
+		return checkChars(s, l >= 1, "1 to 255 characters", "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./")
+	case Code93, GS1Expanded:
+		return checkASCII(s, l >= 1, "1 to 255 characters")
+	case Code128, GS1128:
+		return checkASCII(s, l >= 2, "2 to 255 characters")
+	case ITF:
+		return checkDigits(s, l >= 2 && l%2 == 0, "an even number of digits")
+	case NW7:
+		if reason := checkChars(s, l >= 2, "2 to 255 characters", "0123456789ABCDabcd$+-./:"); reason != "" {
+			return reason
+		}
+		if !isCodabarGuard(s[0]) || !isCodabarGuard(s[l-1]) {
+			return "requires start and stop characters A, B, C or D"
+		}
+		return ""
+	case GS1Omnidirectional, GS1Truncated:
+		return checkDigits(s, l == 13, "13 digits")
+	case GS1Limited:
+		if reason := checkDigits(s, l == 13, "13 digits"); reason != "" {
+			return reason
+		}
+		if s[0] != '0' && s[0] != '1' {
+			return "requires the first digit to be 0 or 1"
+		}
+		return ""
+	default:
+		return fmt.Sprintf("unknown barcode system %d", m)
+	}
+}
+
+func checkDigits(s string, length bool, want string) string {
+	return checkChars(s, length, want, "0123456789")
+}
+
+func checkChars(s string, length bool, want, chars string) string {
+	if !length || len(s) > 255 {
+		return fmt.Sprintf("requires %s, got %d", want, len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(chars, s[i]) < 0 {
+			return fmt.Sprintf("character %q is not allowed", s[i])
+		}
+	}
+	return ""
+}
+
+func checkASCII(s string, length bool, want string) string {
+	if !length || len(s) > 255 {
+		return fmt.Sprintf("requires %s, got %d", want, len(s))
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] > 0x7F {
+			return fmt.Sprintf("character %q is not allowed", s[i])
+		}
+	}
+	return ""
+}
+
+func isCodabarGuard(b byte) bool {
+	return strings.IndexByte("ABCDabcd", b) >= 0
+}
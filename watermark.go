@@ -0,0 +1,62 @@
+package thermalize
+
+import "image"
+
+// WatermarkOption customizes a watermark drawn by Watermark.
+type WatermarkOption interface {
+	apply(*watermark)
+}
+
+type watermark struct {
+	text  string
+	gray  float64
+	angle int
+	band  bool
+}
+
+type watermarkOptionFunc func(*watermark)
+
+func (fn watermarkOptionFunc) apply(w *watermark) {
+	fn(w)
+}
+
+// WithWatermarkGray sets the gray level of the watermark from 0 (black) to 1 (white), by default 0.85.
+func WithWatermarkGray(level float64) WatermarkOption {
+	return watermarkOptionFunc(func(w *watermark) { w.gray = level })
+}
+
+// WithWatermarkAngle sets the counterclockwise rotation of the watermark in degrees, by default 45.
+func WithWatermarkAngle(deg int) WatermarkOption {
+	return watermarkOptionFunc(func(w *watermark) { w.angle = deg })
+}
+
+// WithWatermarkBand prints the watermark on thermal printers as a band of light dithered raster text,
+// since they can't print behind the content. Otherwise, Watermark is ignored by them.
+func WithWatermarkBand() WatermarkOption {
+	return watermarkOptionFunc(func(w *watermark) { w.band = true })
+}
+
+func newWatermark(s string, opts []WatermarkOption) *watermark {
+	w := &watermark{text: s, gray: 0.85, angle: 45}
+	for _, opt := range opts {
+		opt.apply(w)
+	}
+	return w
+}
+
+// raster returns the watermark text as a band of ppl pixels wide,
+// in which only every fourth dot of the characters is printed, so it looks light.
+func (w *watermark) raster(ppl int) image.Image {
+	n := maxByte(maxLineLen(w.text), 1)
+	img := TextImage(w.text, ppl*glyphRows/(n*glyphCols)).(*image.Gray)
+
+	b := img.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if x%2 != 0 || y%2 != 0 {
+				img.Pix[y*img.Stride+x] = 0xFF
+			}
+		}
+	}
+	return img
+}
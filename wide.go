@@ -0,0 +1,239 @@
+package thermalize
+
+import (
+	"image"
+	"strings"
+)
+
+// The sizing of the 112 mm wide-format printers, such as the kitchen printers of the pass printing the tickets
+// of several stations side by side: the print width of 104 mm at 203 dpi, and the characters of font A, 12 dots wide.
+//
+// Example Usage:
+//
+//	cmd := thermalize.NewEscape(thermalize.WideCPL, thermalize.WidePPL, conn, thermalize.WithWideFormat())
+const (
+	WideCPL = 69
+	WidePPL = 832
+)
+
+// wideTabStops is the distance between the tab stops of the wide-format printers in characters.
+const wideTabStops = 8
+
+type wideFormatOption struct{}
+
+func (wideFormatOption) apply(cmd Cmd) {
+	if c := skipperOf(cmd); c != nil {
+		if c.tabStops <= 0 {
+			c.tabStops = wideTabStops
+		}
+		c.resizeTabs = true
+	}
+}
+
+// WithWideFormat sets the command set up for the 112 mm wide-format printers, sized with WideCPL and WidePPL.
+// Init sets a tab stop every 8 characters across the wider line, unless WithTabStops sets another distance,
+// and Sizing sets them within the new line (see WithResizeTabs), so the tabs of the lanes printed with Lanes
+// stay within their lane.
+func WithWideFormat() Options {
+	return wideFormatOption{}
+}
+
+// LaneOption customizes the lanes printed by Lanes.
+type LaneOption interface {
+	apply(*laneLayout)
+}
+
+type laneLayout struct {
+	gap    int
+	height int
+}
+
+type laneOptionFunc func(*laneLayout)
+
+func (fn laneOptionFunc) apply(l *laneLayout) {
+	fn(l)
+}
+
+// WithLaneGap separates the lanes by n dots, by default 16, 2 mm at 203 dpi.
+func WithLaneGap(n int) LaneOption {
+	return laneOptionFunc(func(l *laneLayout) { l.gap = maxByte(n, 0) })
+}
+
+// WithLaneHeight sets the height of the lanes in dots, instead of the height of the tallest lane
+// estimated from its lines, feeds, images and barcodes, e.g. for the lanes holding QR codes.
+func WithLaneHeight(n int) LaneOption {
+	return laneOptionFunc(func(l *laneLayout) { l.height = n })
+}
+
+// maxLanes is the maximum number of lanes printed side by side.
+const maxLanes = 3
+
+// Lanes prints the lanes side by side, splitting the width of the line into 2 or 3 columns of the same width,
+// e.g. the items of the kitchen stations on the summary printed at the pass. Each lane is written by its function
+// on a command set as wide as the lane, so the text is wrapped, aligned and tabbed within the lane.
+//
+// The command sets implementing PageModer lay the lanes out in the print areas of a page spanning the whole line
+// (see PageModer.PrintArea), which is printed at once with the height of the tallest lane, see WithLaneHeight.
+// The lanes shouldn't initialize, cut or print, those commands are dropped.
+// The other command sets print the lanes one after another across the line.
+// More than 3 lanes are reported in strict mode, otherwise they are printed in rows of 3.
+// A Document records the lanes, which are laid out when it is replayed.
+//
+// Example Usage:
+//
+//	thermalize.Lanes(cmd, []func(thermalize.Cmd){
+//		func(cmd thermalize.Cmd) { cmd.Text("GRILL\n", nil) },
+//		func(cmd thermalize.Cmd) { cmd.Text("FRYER\n", nil) },
+//	}, thermalize.WithLaneGap(24))
+func Lanes(cmd Cmd, lanes []func(Cmd), opts ...LaneOption) {
+	l := laneLayout{gap: 16}
+	for _, opt := range opts {
+		opt.apply(&l)
+	}
+
+	if d, ok := cmd.(*Document); ok {
+		docs := l.record(d, lanes)
+		ops := make([][]Op, len(docs))
+		fns := make([]func(Cmd), len(docs))
+		for i, doc := range docs {
+			doc := doc
+			ops[i], fns[i] = doc.Ops(), func(c Cmd) { doc.Render(c) }
+		}
+		d.record("Lanes", func(c Cmd) { Lanes(c, fns, opts...) }, ops)
+		return
+	}
+
+	c := skipperOf(cmd)
+	if len(lanes) > maxLanes && c != nil && c.invalid("Lanes", "%d lanes exceed the maximum of %d", len(lanes), maxLanes) {
+		return
+	}
+
+	p, ok := cmd.(PageModer)
+	if !ok || c == nil {
+		for _, fn := range lanes {
+			fn(cmd)
+		}
+		return
+	}
+
+	for start := 0; start < len(lanes); start += maxLanes {
+		row := lanes[start:minByte(start+maxLanes, len(lanes))]
+		if len(row) == 1 {
+			row[0](cmd)
+			continue
+		}
+		l.page(cmd, p, c, row)
+	}
+}
+
+// laneWidth returns the width of the lanes of the row of n lanes on the line of ppl dots,
+// and the number of characters per lane.
+func (l laneLayout) laneWidth(cpl, ppl, n int) (int, int) {
+	w := (ppl - l.gap*(n-1)) / n
+	if w <= 0 || ppl <= 0 {
+		return 0, 0
+	}
+	return w, cpl * w / ppl
+}
+
+// record records the lanes on the documents as wide as the lanes of the document d.
+func (l laneLayout) record(d *Document, lanes []func(Cmd)) []*Document {
+	w, cpl := l.laneWidth(d.cpl, d.ppl, minByte(maxByte(len(lanes), 1), maxLanes))
+	docs := make([]*Document, len(lanes))
+	for i, fn := range lanes {
+		docs[i] = NewDocument(cpl, w)
+		fn(docs[i])
+	}
+	return docs
+}
+
+// page prints the row of lanes in the print areas of a page.
+func (l laneLayout) page(cmd Cmd, p PageModer, c *skipper, lanes []func(Cmd)) {
+	cpl, ppl := c.cpl, c.ppl
+	w, laneCPL := l.laneWidth(cpl, ppl, len(lanes))
+	if laneCPL <= 0 {
+		c.invalid("Lanes", "%d lanes don't fit the line of %d dots", len(lanes), ppl)
+		return
+	}
+
+	docs := make([]*Document, len(lanes))
+	height := l.height
+	for i, fn := range lanes {
+		docs[i] = NewDocument(laneCPL, w)
+		fn(docs[i])
+		if l.height <= 0 {
+			height = maxByte(height, laneHeight(docs[i].ops, laneCPL, c.resolution()/6))
+		}
+	}
+
+	if c.col > 0 {
+		cmd.LineFeed()
+	}
+	p.PageMode(true)
+	for i, d := range docs {
+		p.PrintArea(i*(w+l.gap), 0, w, maxByte(height, 1))
+		c.newLine()
+		cmd.Sizing(laneCPL, w)
+		for _, op := range d.ops {
+			if !laneDropped[op.Name] {
+				op.play(cmd)
+			}
+		}
+	}
+	p.PrintPage()
+	c.newLine()
+	cmd.Sizing(cpl, ppl)
+}
+
+// laneDropped are the commands the lanes can't print within a page.
+var laneDropped = map[string]bool{
+	"Init": true, "Cut": true, "FullCut": true, "CutWithFeed": true, "FullCutAtPosition": true,
+	"PartialCutAtPosition": true, "FeedAndFullCut": true, "Print": true, "Flush": true,
+	"PageMode": true, "PrintArea": true, "PrintPage": true, "Lanes": true,
+}
+
+// laneHeight estimates the height of the lane of cpl characters per line in dots: the lines at the line spacing
+// in the height of the characters, the feeds, the images and the barcodes with their human readable interpretation.
+func laneHeight(ops []Op, cpl, spacing int) int {
+	h, size, pending := 0, 1, false
+	barcode := int(defaultBarcode.Height)
+	line := func(n int) {
+		h += n * spacing * size
+		pending = false
+	}
+
+	for _, op := range ops {
+		switch op.Name {
+		case "CharSize":
+			size = int(op.Args[1].(byte)) + 1
+		case "Text":
+			s := op.Args[0].(string)
+			line(strings.Count(s, "\n"))
+			pending = !strings.HasSuffix(s, "\n") && s != ""
+		case "TextWrap":
+			line(len(paragraph(op.Args[0].(string), cpl, 0, 0, false)))
+		case "Paragraph":
+			line(len(paragraph(op.Args[0].(string), cpl, op.Args[1].(int), op.Args[2].(int), false)))
+		case "List":
+			line(len(listLines(op.Args[0].([]string), cpl, false, nil)))
+		case "LineFeed":
+			line(1)
+		case "Feed":
+			h += int(op.Args[0].(byte))
+		case "BarcodeHeight":
+			barcode = int(op.Args[0].(byte))
+		case "Barcode":
+			h += barcode + spacing
+		case "Image":
+			if img, _ := op.Args[0].(image.Image); img != nil {
+				h += img.Bounds().Dy()
+			}
+		case "KeepTogether":
+			h += laneHeight(op.Args[0].([]Op), cpl, spacing)
+		}
+	}
+	if pending {
+		line(1)
+	}
+	return h
+}
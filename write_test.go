@@ -0,0 +1,140 @@
+package thermalize
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// typicalReceipt prints a typical receipt of a few items, a total and a barcode.
+func typicalReceipt(cmd Cmd) {
+	cmd.Init()
+	cmd.Align(Center)
+	cmd.Bold(true)
+	cmd.CharSize(1, 1)
+	cmd.Text("THE CORNER SHOP", nil)
+	cmd.LineFeed()
+	cmd.CharSize(0, 0)
+	cmd.Bold(false)
+	cmd.Text("12 High Street", nil)
+	cmd.LineFeed()
+	cmd.Align(Left)
+	for i := 0; i < 10; i++ {
+		cmd.Text("Item", nil)
+		cmd.Tab()
+		cmd.Text("2 x 1.50", nil)
+		cmd.Tab()
+		cmd.Text("3.00", nil)
+		cmd.LineFeed()
+	}
+	cmd.Bold(true)
+	cmd.Text("TOTAL", nil)
+	cmd.Tab()
+	cmd.Text("30.00", nil)
+	cmd.LineFeed()
+	cmd.Bold(false)
+	cmd.Barcode(Code39, "RECEIPT42")
+	cmd.FullCut()
+}
+
+func TestWriteBytesAndString(t *testing.T) {
+	for _, size := range []int{0, 4, 64} {
+		var a, b, c bytes.Buffer
+		ca, cb, cc := newSkipper(48, 576, &a), newSkipper(48, 576, &b), newSkipper(48, 576, &c)
+		ca.size, cb.size, cc.size = size, size, size
+
+		for _, s := range []string{"ab", "cdefgh", "", "ijklmnopqrstuvwxyz"} {
+			ca.Write([]byte(s)...)
+			cb.WriteBytes([]byte(s))
+			cc.WriteString(s)
+		}
+		ca.Flush()
+		cb.Flush()
+		cc.Flush()
+
+		if a.String() != "abcdefghijklmnopqrstuvwxyz" || b.String() != a.String() || c.String() != a.String() {
+			t.Errorf("size %d: Write %q, WriteBytes %q, WriteString %q", size, a.String(), b.String(), c.String())
+		}
+	}
+}
+
+// brokenWriter accepts n writes and fails the following ones, like a lost connection.
+type brokenWriter struct {
+	n, writes int
+}
+
+var errLost = errors.New("connection lost")
+
+func (w *brokenWriter) Write(bs []byte) (int, error) {
+	w.writes++
+	if w.writes > w.n {
+		return 0, errLost
+	}
+	return len(bs), nil
+}
+
+func TestWriteError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		new  func(w io.Writer) Cmd
+	}{
+		{"escape", func(w io.Writer) Cmd { return NewEscape(48, 576, w) }},
+		{"escape buffered", func(w io.Writer) Cmd { return NewEscape(48, 576, w, WithBufferSize(16)) }},
+		{"star", func(w io.Writer) Cmd { return NewStar(48, 576, w) }},
+		{"postscript", func(w io.Writer) Cmd { return NewPostscript(48, 576, w) }},
+		{"skipper", func(w io.Writer) Cmd { return NewSkipper(48, 576, w) }},
+	} {
+		w := &brokenWriter{n: 1}
+		cmd := tc.new(w)
+		typicalReceipt(cmd)
+
+		if err := cmd.Print(); !errors.Is(err, errLost) {
+			t.Errorf("%s: Print %v, want %v", tc.name, err, errLost)
+		}
+		if err := cmdErr(cmd); !errors.Is(err, errLost) {
+			t.Errorf("%s: Err %v, want %v", tc.name, err, errLost)
+		}
+		if err := cmd.(Flusher).Flush(); !errors.Is(err, errLost) {
+			t.Errorf("%s: Flush %v, want %v", tc.name, err, errLost)
+		}
+		// The writes after the failed one are skipped.
+		if w.writes != 2 {
+			t.Errorf("%s: %d writes, want 2", tc.name, w.writes)
+		}
+	}
+}
+
+func TestWriteAllocations(t *testing.T) {
+	cmd := NewEscape(48, 576, io.Discard, WithBufferSize(1<<16)).(*escape)
+	lf := []byte{LF}
+	allocs := testing.AllocsPerRun(100, func() {
+		cmd.Write(ESC, 'a', 1)
+		cmd.WriteString("text")
+		cmd.WriteBytes(lf)
+		if len(cmd.out) > 1<<15 {
+			cmd.out = cmd.out[:0]
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("writing allocates %.1f times, want 0", allocs)
+	}
+}
+
+func BenchmarkEscapeReceipt(b *testing.B) {
+	cmd := NewEscape(48, 576, io.Discard, WithBufferSize(4096))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		typicalReceipt(cmd)
+		_ = cmd.Print()
+	}
+}
+
+func BenchmarkStarReceipt(b *testing.B) {
+	cmd := NewStar(48, 576, io.Discard, WithBufferSize(4096))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		typicalReceipt(cmd)
+		_ = cmd.Print()
+	}
+}